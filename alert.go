@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// NotifyAlerts is send the alert notification of the crossed tickers.
+// The buy zone of the watch-only symbols is only in the report without BUY_ZONE_NOTIFY=true.
+func NotifyAlerts(ctx context.Context, date string, alerts []portfolio.Ticker) []NotifyError {
+	if os.Getenv("BUY_ZONE_NOTIFY") != "true" {
+		var crossed []portfolio.Ticker
+		for _, t := range alerts {
+			if t.Alert != portfolio.AlertBuy {
+				crossed = append(crossed, t)
+			}
+		}
+		alerts = crossed
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+	return Notify(ctx, Report{
+		Date:    date,
+		Subject: fmt.Sprintf("Stock Alert %s: %d symbols", date, len(alerts)),
+		Text:    report.AlertContent(alerts),
+		Payload: alerts,
+	})
+}
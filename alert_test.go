@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestNotifyAlerts(t *testing.T) {
+	mail := newFakeSES(t)
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("ATTACH_JSON", "")
+	useConfig(t)
+
+	if errs := NotifyAlerts(context.Background(), "2021-06-14", nil); errs != nil || len(mail.Subjects()) != 0 {
+		t.Errorf("NotifyAlerts(nil) = %v, %d mails, want nothing", errs, len(mail.Subjects()))
+	}
+
+	alerts := portfolio.CheckAlerts([]portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 130, Hold: 10, AlertHigh: 120}})
+	if errs := NotifyAlerts(context.Background(), "2021-06-14", alerts); len(errs) != 0 {
+		t.Fatalf("NotifyAlerts() = %v", errs)
+	}
+	subjects := mail.Subjects()
+	if len(subjects) != 1 || subjects[0] != "Stock Alert 2021-06-14: 1 symbols" {
+		t.Errorf("subjects = %q, want the alert subject", subjects)
+	}
+	if sent := mail.Sent(); len(sent) != 1 || !strings.Contains(sent[0], "AAPL 130.00 crossed alert_high 120.00") {
+		t.Errorf("mail = %q, want the alert of AAPL", sent)
+	}
+}
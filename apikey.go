@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/tora0091/stock-profit/awsclient"
+)
+
+// APIKey is a key of the api, API_KEYS (json list, it can be in the secrets) or STOCK_API_KEY.
+type APIKey struct {
+	Name string `json:"name"`
+	Key  string `json:"key,omitempty"`
+	// Secret is the hmac secret of the signed requests, a key without Key is only signed
+	Secret string `json:"secret,omitempty"`
+	// RateLimit is max requests per minute, 0 is no limit
+	RateLimit int `json:"rate_limit,omitempty"`
+	// Tenant is the portfolio of the key (TENANTS_FILE), empty is S3_STOCK_DATA
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// APIKeys is the keys of API_KEYS and STOCK_API_KEY (named default).
+func APIKeys() ([]APIKey, error) {
+	var keys []APIKey
+	if env := os.Getenv("API_KEYS"); env != "" {
+		if err := json.Unmarshal([]byte(env), &keys); err != nil {
+			return nil, fmt.Errorf("invalid API_KEYS. %s", err)
+		}
+	}
+	key, secret := os.Getenv("STOCK_API_KEY"), os.Getenv("STOCK_API_SECRET")
+	if key != "" || secret != "" {
+		keys = append(keys, APIKey{Name: "default", Key: key, Secret: secret})
+	}
+	return keys, nil
+}
+
+// Authenticate is the api key of the signed request, the stock-api-key header (any case) or APIKEY_QUERY_PARAM,
+// every key is compared in constant time.
+// Without any key, every request is the default key.
+func Authenticate(ctx context.Context, request events.APIGatewayProxyRequest) (APIKey, bool, error) {
+	keys, err := APIKeys()
+	if err != nil {
+		return APIKey{}, false, err
+	}
+	if HeaderValue(request.Headers, "stock-signature") != "" {
+		return VerifySignature(ctx, keys, request, time.Now())
+	}
+	if RequireSignature() {
+		return APIKey{}, false, nil
+	}
+
+	// the webhook callers without the header have the key in APIKEY_QUERY_PARAM (e.g. ?token=)
+	provided := []byte(HeaderValue(request.Headers, "stock-api-key"))
+	if param := os.Getenv("APIKEY_QUERY_PARAM"); len(provided) == 0 && param != "" {
+		provided = []byte(request.QueryStringParameters[param])
+	}
+	if len(keys) == 0 {
+		return APIKey{Name: "default"}, len(provided) == 0, nil
+	}
+
+	var found APIKey
+	var ok bool
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare(provided, []byte(k.Key)) == 1 && k.Key != "" && !ok {
+			found, ok = k, true
+		}
+	}
+	return found, ok, nil
+}
+
+// rateWindow is the window of the rate limit.
+const rateWindow = time.Minute
+
+// rateCounts is requests of the key in the window, of this lambda instance (without RATE_LIMIT_TABLE).
+var rateCounts = struct {
+	sync.Mutex
+	m map[string]int
+}{m: map[string]int{}}
+
+// AllowRequest is check the request of the key is under its rate limit, retry is wait until the next window.
+// RATE_LIMIT_TABLE (dynamodb, hash key `key` string, ttl `expires`) counts the requests of all lambda instances.
+func AllowRequest(ctx context.Context, key APIKey, now time.Time) (bool, time.Duration, error) {
+	if key.RateLimit <= 0 {
+		return true, 0, nil
+	}
+	window := now.Truncate(rateWindow)
+	retry := window.Add(rateWindow).Sub(now)
+	id := fmt.Sprintf("%s#%d", key.Name, window.Unix())
+
+	table := os.Getenv("RATE_LIMIT_TABLE")
+	if table == "" {
+		rateCounts.Lock()
+		defer rateCounts.Unlock()
+		for k := range rateCounts.m {
+			if k != id {
+				delete(rateCounts.m, k)
+			}
+		}
+		rateCounts.m[id]++
+		return rateCounts.m[id] <= key.RateLimit, retry, nil
+	}
+
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return false, 0, err
+	}
+	out, err := dynamodb.New(sess).UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(table),
+		Key:              map[string]*dynamodb.AttributeValue{"key": {S: aws.String(id)}},
+		UpdateExpression: aws.String("ADD #c :one SET #e = :expires"),
+		ExpressionAttributeNames: map[string]*string{
+			"#c": aws.String("count"),
+			"#e": aws.String("expires"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one":     {N: aws.String("1")},
+			":expires": {N: aws.String(strconv.FormatInt(window.Add(2*rateWindow).Unix(), 10))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if err != nil {
+		return false, 0, err
+	}
+	count, err := strconv.Atoi(aws.StringValue(out.Attributes["count"].N))
+	if err != nil {
+		return false, 0, err
+	}
+	return count <= key.RateLimit, retry, nil
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// auditRetention is the ttl of the entries in AUDIT_TABLE.
+const auditRetention = 90 * 24 * time.Hour
+
+// auditLookbackDays is the days the audit request reads back from today.
+const auditLookbackDays = 7
+
+// auditTimeLayout is the time of the entry, fixed width so that the order of the keys is the order of the times.
+const auditTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+// auditReadConcurrency is the entries of AUDIT_LOG_PREFIX read at once.
+const auditReadConcurrency = 10
+
+// defaultAuditLimit and maxAuditLimit are the entries of the audit request.
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// AuditEntry is an api invocation, keyed by day (hash) and at (range, the time and the request id).
+type AuditEntry struct {
+	Day       string `dynamodbav:"day" json:"-"`
+	At        string `dynamodbav:"at" json:"at"`
+	RequestID string `dynamodbav:"request_id,omitempty" json:"request_id,omitempty"`
+	// Caller is the name of the api key, telegram for the bot webhook and empty for the unauthorized request
+	Caller     string `dynamodbav:"caller,omitempty" json:"caller,omitempty"`
+	Tenant     string `dynamodbav:"tenant,omitempty" json:"tenant,omitempty"`
+	Method     string `dynamodbav:"method" json:"method"`
+	Path       string `dynamodbav:"path" json:"path"`
+	Action     string `dynamodbav:"action,omitempty" json:"action,omitempty"`
+	DryRun     bool   `dynamodbav:"dry_run,omitempty" json:"dry_run,omitempty"`
+	SourceIP   string `dynamodbav:"source_ip,omitempty" json:"source_ip,omitempty"`
+	Status     int    `dynamodbav:"status" json:"status"`
+	Error      string `dynamodbav:"error,omitempty" json:"error,omitempty"`
+	DurationMs int64  `dynamodbav:"duration_ms" json:"duration_ms"`
+	Expires    int64  `dynamodbav:"expires,omitempty" json:"-"`
+}
+
+// AuditEnabled is check AUDIT_TABLE or AUDIT_LOG_PREFIX is set.
+func AuditEnabled() bool {
+	return os.Getenv("AUDIT_TABLE") != "" || os.Getenv("AUDIT_LOG_PREFIX") != ""
+}
+
+// NewAuditEntry is the entry of the request and its response, err is the error of the function.
+func NewAuditEntry(request events.APIGatewayProxyRequest, caller, tenant string, response events.APIGatewayProxyResponse, err error, begin time.Time) AuditEntry {
+	at := begin.UTC()
+	entry := AuditEntry{
+		Day:        at.Format("2006-01-02"),
+		At:         at.Format(auditTimeLayout),
+		RequestID:  request.RequestContext.RequestID,
+		Caller:     caller,
+		Tenant:     tenant,
+		Method:     request.HTTPMethod,
+		Path:       request.Path,
+		Action:     request.QueryStringParameters["action"],
+		DryRun:     request.QueryStringParameters["dry_run"] == "true",
+		SourceIP:   request.RequestContext.Identity.SourceIP,
+		Status:     response.StatusCode,
+		DurationMs: time.Since(begin).Milliseconds(),
+		Expires:    at.Add(auditRetention).Unix(),
+	}
+	if entry.RequestID != "" {
+		entry.At = entry.At + "#" + entry.RequestID
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// Audit is record the entry to AUDIT_TABLE, or AUDIT_LOG_PREFIX of BUCKET without it.
+// An audit error doesn't fail the request.
+func Audit(ctx context.Context, entry AuditEntry) {
+	var err error
+	switch {
+	case os.Getenv("AUDIT_TABLE") != "":
+		err = PutAuditEntry(ctx, os.Getenv("AUDIT_TABLE"), entry)
+	case os.Getenv("AUDIT_LOG_PREFIX") != "":
+		err = PutAuditObject(ctx, entry)
+	default:
+		return
+	}
+	if err != nil {
+		logging.Error(ctx, "audit error", logging.Fields{"path": entry.Path, "error": err})
+	}
+}
+
+// PutAuditEntry is put the entry to the table (dynamodb, hash key `day` string, range key `at` string, ttl `expires`).
+func PutAuditEntry(ctx context.Context, table string, entry AuditEntry) error {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return err
+	}
+	av, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return err
+	}
+	_, err = dynamodb.New(sess).PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      av,
+	})
+	return err
+}
+
+// AuditLogPrefix is the prefix of the entries of the day under AUDIT_LOG_PREFIX (e.g. audit/2021-06-14/).
+func AuditLogPrefix(day string) string {
+	return path.Join(os.Getenv("AUDIT_LOG_PREFIX"), day) + "/"
+}
+
+// AuditObjectKey is the json object of the entry, the time and the request id of it under the prefix of the day
+// (e.g. audit/2021-06-14/2021-06-14T09:30:00.000000000Z-<request id>.json), so the order of the keys is the order of the times.
+func AuditObjectKey(entry AuditEntry) string {
+	return AuditLogPrefix(entry.Day) + strings.Replace(entry.At, "#", "-", 1) + ".json"
+}
+
+// PutAuditObject is put the entry as its own object, the concurrent requests don't write one object.
+func PutAuditObject(ctx context.Context, entry AuditEntry) error {
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return storage.New(ConfigOf(ctx).Bucket).Put(ctx, AuditObjectKey(entry), b)
+}
+
+// ReadAuditEntries is the entries of the day, the newest first and at most limit.
+func ReadAuditEntries(ctx context.Context, day string, limit int) ([]AuditEntry, error) {
+	if table := os.Getenv("AUDIT_TABLE"); table != "" {
+		return QueryAuditEntries(ctx, table, day, limit)
+	}
+
+	store := storage.New(ConfigOf(ctx).Bucket)
+	keys, err := store.List(ctx, AuditLogPrefix(day))
+	if err != nil {
+		return nil, err
+	}
+	// the newest keys are the newest entries
+	sort.Sort(sort.Reverse(sort.StringSlice(keys)))
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+
+	entries := make([]AuditEntry, len(keys))
+	read := make([]bool, len(keys))
+	errs := make([]error, len(keys))
+	sem := make(chan struct{}, auditReadConcurrency)
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func(i int, key string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			b, err := store.Get(ctx, key)
+			if errors.Is(err, storage.ErrNoSuchKey) {
+				return
+			}
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			// a broken object is skipped
+			read[i] = json.Unmarshal(b, &entries[i]) == nil
+		}(i, key)
+	}
+	wg.Wait()
+
+	var out []AuditEntry
+	for i := range keys {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if read[i] {
+			out = append(out, entries[i])
+		}
+	}
+	return out, nil
+}
+
+// QueryAuditEntries is the entries of the day in the table, the newest first.
+func QueryAuditEntries(ctx context.Context, table, day string, limit int) ([]AuditEntry, error) {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return nil, err
+	}
+	var entries []AuditEntry
+	var perr error
+	err = dynamodb.New(sess).QueryPagesWithContext(ctx, &dynamodb.QueryInput{
+		TableName:                aws.String(table),
+		KeyConditionExpression:   aws.String("#d = :d"),
+		ExpressionAttributeNames: map[string]*string{"#d": aws.String("day")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":d": {S: aws.String(day)},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(int64(limit)),
+	}, func(out *dynamodb.QueryOutput, last bool) bool {
+		var items []AuditEntry
+		if perr = dynamodbattribute.UnmarshalListOfMaps(out.Items, &items); perr != nil {
+			return false
+		}
+		entries = append(entries, items...)
+		return len(entries) < limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	if perr != nil {
+		return nil, perr
+	}
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// AuditResponse is api response of the audit request.
+type AuditResponse struct {
+	Entries []AuditEntry `json:"entries"`
+}
+
+// AuditHandler is the recent entries of the audit log, the limit query param (default 50, max 500) from today back to 7 days,
+// or the entries of the date query param (YYYY-MM-DD, UTC). The key of a tenant is only the entries of its tenant.
+func AuditHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	if !AuditEnabled() {
+		return ErrorResponse(http.StatusNotFound, "AUDIT_TABLE or AUDIT_LOG_PREFIX is not set"), nil
+	}
+
+	limit := defaultAuditLimit
+	if v := request.QueryStringParameters["limit"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid limit %q", v)), nil
+		}
+		if n > maxAuditLimit {
+			n = maxAuditLimit
+		}
+		limit = n
+	}
+
+	today := time.Now().UTC()
+	days := auditLookbackDays
+	if date := request.QueryStringParameters["date"]; date != "" {
+		t, err := time.Parse("2006-01-02", date)
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid date %q", date)), nil
+		}
+		today, days = t, 1
+	}
+
+	tenant := ConfigOf(ctx).Tenant
+	entries := []AuditEntry{}
+	for i := 0; i < days && len(entries) < limit; i++ {
+		day, err := ReadAuditEntries(ctx, today.AddDate(0, 0, -i).Format("2006-01-02"), maxAuditLimit)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		for _, entry := range day {
+			if tenant != "" && entry.Tenant != tenant {
+				continue
+			}
+			if len(entries) < limit {
+				entries = append(entries, entry)
+			}
+		}
+	}
+
+	b, err := json.Marshal(AuditResponse{Entries: entries})
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
+
+// IsAuditRequest is GET /audit (or ?action=audit).
+func IsAuditRequest(request events.APIGatewayProxyRequest) bool {
+	if request.QueryStringParameters["action"] == "audit" {
+		return true
+	}
+	return request.HTTPMethod == http.MethodGet && path.Base(request.Path) == "audit"
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// auditOf is the audit entries of GET /audit by the api key, query is ?limit and ?date.
+func auditOf(t *testing.T, apiKey, requestID string, query map[string]string) []AuditEntry {
+	t.Helper()
+	request := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/audit", Headers: map[string]string{"stock-api-key": apiKey}, QueryStringParameters: query}
+	request.RequestContext.RequestID = requestID
+	response, err := Handler(context.Background(), request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Handler() = %d %s %v, want 200", response.StatusCode, response.Body, err)
+	}
+	var audit AuditResponse
+	if err := json.Unmarshal([]byte(response.Body), &audit); err != nil {
+		t.Fatalf("body %q: %s", response.Body, err)
+	}
+	return audit.Entries
+}
+
+func TestAuditLog(t *testing.T) {
+	s3 := newFakeS3(t, "test-bucket")
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("AUDIT_LOG_PREFIX", "audit")
+	t.Setenv("STOCK_API_KEY", "")
+	t.Setenv("API_KEYS", `[{"name": "admin", "key": "k0"}, {"name": "alice", "key": "k1", "tenant": "alice"}, {"name": "bob", "key": "k2", "tenant": "bob"}]`)
+	t.Setenv("TENANTS_FILE", "tenants.json")
+	s3.put("test-bucket", "tenants.json", strings.NewReader(`[{"name": "alice", "stock_data": "alice/stock.csv", "mail_to": ["alice@example.com"]}, {"name": "bob", "stock_data": "bob/stock.csv", "mail_to": ["bob@example.com"]}]`))
+	useConfig(t)
+	day := time.Now().UTC().Format("2006-01-02")
+
+	// each request is recorded after its response
+	if entries := auditOf(t, "k1", "r1", nil); len(entries) != 0 {
+		t.Errorf("entries = %+v, want none before the first request is recorded", entries)
+	}
+	auditOf(t, "k2", "r2", nil)
+	if response, _ := Handler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/audit", Headers: map[string]string{"stock-api-key": "wrong"}}); response.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Handler() = %d, want 400 of the wrong key", response.StatusCode)
+	}
+
+	var keys []string
+	for _, k := range s3.Keys() {
+		if strings.HasPrefix(k, "audit/") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) != 3 {
+		t.Fatalf("audit objects = %v, want one for each request", keys)
+	}
+	for _, k := range keys {
+		if !strings.HasPrefix(k, "audit/"+day+"/"+day+"T") || !strings.HasSuffix(k, ".json") {
+			t.Errorf("audit object %s is not under audit/%s/", k, day)
+		}
+	}
+	if !strings.HasSuffix(keys[0], "-r1.json") || !strings.HasSuffix(keys[1], "-r2.json") {
+		t.Errorf("audit objects = %v, want the request ids in the order of the requests", keys)
+	}
+
+	// the key of a tenant reads the entries of its tenant only
+	entries := auditOf(t, "k1", "r3", nil)
+	if len(entries) != 1 || entries[0].RequestID != "r1" || entries[0].Caller != "alice" || entries[0].Tenant != "alice" || entries[0].Status != http.StatusOK {
+		t.Errorf("entries of alice = %+v, want r1 of alice", entries)
+	}
+	entries = auditOf(t, "k0", "r4", nil)
+	var callers []string
+	for _, e := range entries {
+		callers = append(callers, e.Caller+":"+e.RequestID)
+	}
+	// the newest first, the rejected request has no caller
+	if got := strings.Join(callers, ","); got != "alice:r3,:,bob:r2,alice:r1" {
+		t.Errorf("entries of admin = %s, want alice:r3,:,bob:r2,alice:r1", got)
+	}
+	if entries := auditOf(t, "k0", "r5", map[string]string{"limit": "1"}); len(entries) != 1 || entries[0].RequestID != "r4" {
+		t.Errorf("entries of limit 1 = %+v, want r4", entries)
+	}
+	if entries := auditOf(t, "k0", "r6", map[string]string{"date": "2021-06-14"}); len(entries) != 0 {
+		t.Errorf("entries of 2021-06-14 = %+v, want none", entries)
+	}
+}
@@ -0,0 +1,151 @@
+// Package awsclient is the aws sessions and clients shared by the invocations, each of them is made once at the first use.
+// The s3, ses and kms clients are interfaces, SetS3, SetSES and SetKMS replace them (e.g. by mocks).
+package awsclient
+
+import (
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/tora0091/stock-profit/tracing"
+)
+
+var (
+	mu         sync.Mutex
+	sessions   = map[string]*session.Session{}
+	traced     = map[string]*session.Session{}
+	s3Clients  = map[string]s3iface.S3API{}
+	sesClients = map[string]sesiface.SESAPI{}
+	kmsClients = map[string]kmsiface.KMSAPI{}
+)
+
+// Session is the session of the region.
+func Session(region string) (*session.Session, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return newSession(region)
+}
+
+// TracedSession is the session of the region whose clients are traced.
+// It is a copy of Session, the tracing handlers are added to it only once.
+func TracedSession(region string) (*session.Session, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return newTracedSession(region)
+}
+
+// S3 is the traced s3 client of the region.
+func S3(region string) (s3iface.S3API, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := s3Clients[region]; ok {
+		return c, nil
+	}
+	sess, err := newTracedSession(region)
+	if err != nil {
+		return nil, err
+	}
+	c := s3.New(sess)
+	s3Clients[region] = c
+	return c, nil
+}
+
+// SES is the traced ses client of the region.
+func SES(region string) (sesiface.SESAPI, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := sesClients[region]; ok {
+		return c, nil
+	}
+	sess, err := newTracedSession(region)
+	if err != nil {
+		return nil, err
+	}
+	c := ses.New(sess)
+	sesClients[region] = c
+	return c, nil
+}
+
+// KMS is the traced kms client of the region.
+func KMS(region string) (kmsiface.KMSAPI, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c, ok := kmsClients[region]; ok {
+		return c, nil
+	}
+	sess, err := newTracedSession(region)
+	if err != nil {
+		return nil, err
+	}
+	c := kms.New(sess)
+	kmsClients[region] = c
+	return c, nil
+}
+
+// SetS3 is the s3 client of the region instead of the aws one, nil is the aws client again.
+func SetS3(region string, c s3iface.S3API) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c == nil {
+		delete(s3Clients, region)
+		return
+	}
+	s3Clients[region] = c
+}
+
+// SetSES is the ses client of the region instead of the aws one, nil is the aws client again.
+func SetSES(region string, c sesiface.SESAPI) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c == nil {
+		delete(sesClients, region)
+		return
+	}
+	sesClients[region] = c
+}
+
+// SetKMS is the kms client of the region instead of the aws one, nil is the aws client again.
+func SetKMS(region string, c kmsiface.KMSAPI) {
+	mu.Lock()
+	defer mu.Unlock()
+	if c == nil {
+		delete(kmsClients, region)
+		return
+	}
+	kmsClients[region] = c
+}
+
+// newSession is the cached session of the region, mu is held.
+func newSession(region string) (*session.Session, error) {
+	if sess, ok := sessions[region]; ok {
+		return sess, nil
+	}
+	sess, err := session.NewSession(&aws.Config{
+		Region: aws.String(region),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sessions[region] = sess
+	return sess, nil
+}
+
+// newTracedSession is the cached traced session of the region, mu is held.
+func newTracedSession(region string) (*session.Session, error) {
+	if sess, ok := traced[region]; ok {
+		return sess, nil
+	}
+	sess, err := newSession(region)
+	if err != nil {
+		return nil, err
+	}
+	t := tracing.Session(sess.Copy())
+	traced[region] = t
+	return t, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// BackfillEvent is the event of the backfill of the history, e.g. {"backfill": {"from": "2024-01-01", "to": "2024-06-30"}}.
+// The days in HISTORY_TABLE are kept unless Overwrite.
+type BackfillEvent struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Overwrite bool   `json:"overwrite,omitempty"`
+}
+
+// BackfillResult is the days written by the backfill and the symbols without the history.
+type BackfillResult struct {
+	From    string                  `json:"from"`
+	To      string                  `json:"to"`
+	Days    int                     `json:"days"`
+	Skipped int                     `json:"skipped,omitempty"`
+	Errors  []portfolio.SymbolError `json:"errors,omitempty"`
+}
+
+// BackfillHandler is put the daily closes of the positions of S3_STOCK_DATA from the history api to HISTORY_TABLE.
+// The positions are the current ones of the stock data, so the past days are valued by the holdings of today.
+func BackfillHandler(ctx context.Context, event BackfillEvent) (BackfillResult, error) {
+	cfg := ConfigOf(ctx)
+	if cfg.HistoryTable == "" {
+		return BackfillResult{}, fmt.Errorf("HISTORY_TABLE is not set")
+	}
+	from, to, err := HistoryRange(event.From, event.To, time.Now().In(reportLocation))
+	if err != nil {
+		return BackfillResult{}, err
+	}
+
+	data, err := DownloadFile(ctx, cfg.Bucket, cfg.StockData)
+	if err != nil {
+		return BackfillResult{}, err
+	}
+	symbols, _, err := ParseStockData(ctx, cfg.Bucket, cfg.StockData, data)
+	if err != nil {
+		return BackfillResult{}, err
+	}
+	if symbols, err = PrepareSymbols(ctx, symbols); err != nil {
+		return BackfillResult{}, err
+	}
+
+	done := BackfillResult{From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+	provider := quotes.NewHistoryProvider()
+	closes := map[string][]quotes.Close{}
+	for _, t := range symbols {
+		if _, ok := closes[t.Symble]; ok || t.Fixed() || quotes.IsDelisted(t.Symble) {
+			continue
+		}
+		history, err := provider.History(ctx, quotes.AliasOf(t.Symble), from, to)
+		if err != nil {
+			logging.Warn(ctx, "history error", logging.Fields{"symbol": t.Symble, "provider": provider.Name(), "error": err})
+			done.Errors = append(done.Errors, portfolio.SymbolError{Symble: t.Symble, Error: err.Error()})
+		}
+		closes[t.Symble] = history
+	}
+
+	var rates map[string][]quotes.Close
+	if base := strings.ToUpper(os.Getenv("BASE_CURRENCY")); base != "" {
+		rates = map[string][]quotes.Close{}
+		for _, t := range symbols {
+			currency := portfolio.CurrencyOf(t)
+			if _, ok := rates[currency]; ok || currency == base {
+				continue
+			}
+			symbol := portfolio.FXSymbol(currency, base)
+			history, err := provider.History(ctx, symbol, from, to)
+			if err != nil {
+				logging.Warn(ctx, "fx history error", logging.Fields{"symbol": symbol, "error": err})
+				done.Errors = append(done.Errors, portfolio.SymbolError{Symble: symbol, Error: err.Error()})
+			}
+			rates[currency] = history
+		}
+	}
+
+	stored := map[string]bool{}
+	if !event.Overwrite {
+		results, err := ReadHistory(ctx, cfg.HistoryTable, from, to)
+		if err != nil {
+			return done, err
+		}
+		for _, r := range results {
+			stored[r.CreatedAt] = true
+		}
+	}
+
+	var items []HistoryItem
+	for _, r := range portfolio.HistoricalResults(symbols, closes, rates) {
+		if stored[r.CreatedAt] {
+			done.Skipped++
+			continue
+		}
+		items = append(items, HistoryItems(r.CreatedAt, r.Body)...)
+		items = append(items, HistoryTotalItem(r.CreatedAt, portfolio.Summarize(r.Body)))
+		done.Days++
+	}
+	if err := WriteHistory(ctx, cfg.HistoryTable, items); err != nil {
+		return done, err
+	}
+	logging.Info(ctx, "backfill done", logging.Fields{"from": done.From, "to": done.To, "days": done.Days, "skipped": done.Skipped, "errors": len(done.Errors)})
+	return done, nil
+}
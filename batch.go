@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// BatchResult is api response of the batch mode.
+type BatchResult struct {
+	CreatedAt    string                  `json:"created_at"`
+	Files        []string                `json:"files"`
+	Summary      portfolio.Summary       `json:"summary"`
+	Errors       []portfolio.SymbolError `json:"errors,omitempty"`
+	Failures     []portfolio.SymbolError `json:"failures,omitempty"`
+	ParseErrors  []portfolio.ParseError  `json:"parse_errors,omitempty"`
+	NotifyErrors []NotifyError           `json:"notify_errors,omitempty"`
+	// Discrepancies is the positions which differ from the holdings of the broker (BROKER_SYNC)
+	Discrepancies []portfolio.Discrepancy `json:"discrepancies,omitempty"`
+	// MarketClosed is the markets closed on the day (MARKET_CALENDAR)
+	MarketClosed []string `json:"market_closed,omitempty"`
+	// Run is the metadata of the run of all the batches
+	Run *portfolio.RunInfo `json:"run,omitempty"`
+}
+
+// BatchStatus is the status of the batch like ResultStatus, 207 when some of the symbols failed and 502 when all of them failed.
+func BatchStatus(batch BatchResult) int {
+	if len(batch.Errors) == 0 {
+		return http.StatusOK
+	}
+	if len(batch.Errors) >= batch.Summary.Count {
+		return http.StatusBadGateway
+	}
+	return http.StatusMultiStatus
+}
+
+// BatchFilePath is numbered key of the batch (e.g. result/2021/06.json -> result/2021/06-001.json).
+func BatchFilePath(filePath string, n int) string {
+	ext := path.Ext(filePath)
+	return fmt.Sprintf("%s-%03d%s", strings.TrimSuffix(filePath, ext), n, ext)
+}
+
+// RunBatches is process the symbols in chunks of size, each chunk is priced by FetchResult and uploaded to a numbered key.
+// The report of the run is the totals of the batches, the positions are kept only for the checks of the run
+// (the quiet day, the market calendar and the digest of ReportResult).
+func RunBatches(ctx context.Context, provider quotes.Provider, symbols []portfolio.Ticker, parseErrors []portfolio.ParseError, discrepancies []portfolio.Discrepancy, size int, t time.Time, filePath string) (events.APIGatewayProxyResponse, error) {
+	begin := time.Now()
+	batch := BatchResult{CreatedAt: t.Format("2006-01-02"), ParseErrors: parseErrors, Discrepancies: discrepancies, Run: portfolio.NewRunInfo(begin)}
+	ctx = WithNotifyLog(ctx)
+	dryRun := IsDryRun(ctx)
+	var fetch time.Duration
+	counts := map[string]*FetchCount{}
+	var tickers []portfolio.Ticker
+
+	for i := 0; i < len(symbols); i += size {
+		end := i + size
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+
+		start := time.Now()
+		result := FetchResult(ctx, provider, symbols[i:end], nil, t)
+		fetch += time.Since(start)
+		batch.Summary.Add(result.Body...)
+		batch.Errors = append(batch.Errors, result.Errors...)
+		CountFetches(counts, result.Body)
+		batch.Run.Count(result.Body)
+		tickers = append(tickers, result.Body...)
+
+		// dry run is only the totals of the batches
+		if dryRun {
+			continue
+		}
+
+		b, err := json.Marshal(result)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+
+		batch.NotifyErrors = append(batch.NotifyErrors, NotifyAlerts(ctx, batch.CreatedAt, portfolio.CheckAlerts(result.Body))...)
+
+		key := BatchFilePath(filePath, len(batch.Files)+1)
+		if err := UploadReport(ctx, result, b, key); err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if err := QueueFailedSymbols(ctx, result, key); err != nil {
+			logging.Error(ctx, "retry queue error", logging.Fields{"error": err})
+		}
+		if table := ConfigOf(ctx).HistoryTable; table != "" {
+			if err := WriteHistory(ctx, table, HistoryItems(batch.CreatedAt, result.Body)); err != nil {
+				logging.Error(ctx, "history write error", logging.Fields{"table": table, "error": err})
+			}
+		}
+		batch.Files = append(batch.Files, key)
+	}
+
+	// the run of all the batches, like the result of the unbatched run
+	run := portfolio.Result{CreatedAt: batch.CreatedAt, Body: tickers, Discrepancies: discrepancies}
+	quiet := QuietRun(ctx, &run, filePath, t)
+	batch.MarketClosed = run.MarketClosed
+
+	batch.Failures = batch.Errors
+	batch.Run.FetchMs = fetch.Milliseconds()
+	if dryRun {
+		batch.Run.Retries = quotes.Retries()
+		batch.Run.DurationMs = time.Since(begin).Milliseconds()
+		logging.Info(ctx, "dry run, skip upload and notification", logging.Fields{"key": filePath})
+		b, err := json.Marshal(batch)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		return events.APIGatewayProxyResponse{
+			StatusCode: BatchStatus(batch),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       string(b),
+		}, nil
+	}
+
+	EmitMetrics(ctx, RunMetrics(batch.Summary, fetch))
+	if err := UpdateMetricsState(ctx, batch.Summary, counts, fetch, t); err != nil {
+		logging.Error(ctx, "metrics state error", logging.Fields{"error": err})
+	}
+
+	if err := UpdateRollup(ctx, t, batch.Summary); err != nil {
+		logging.Error(ctx, "rollup error", logging.Fields{"error": err})
+	}
+	if err := ExportSheets(ctx, batch.CreatedAt, batch.Summary, nil); err != nil {
+		logging.Error(ctx, "sheets export error", logging.Fields{"error": err})
+	}
+
+	if table := ConfigOf(ctx).HistoryTable; table != "" {
+		if err := WriteHistory(ctx, table, []HistoryItem{HistoryTotalItem(batch.CreatedAt, batch.Summary)}); err != nil {
+			logging.Error(ctx, "history write error", logging.Fields{"table": table, "error": err})
+		}
+	}
+	drawdown, drawdownErrors := TrackDrawdown(ctx, batch.CreatedAt, batch.Summary)
+	batch.NotifyErrors = append(batch.NotifyErrors, drawdownErrors...)
+
+	// the weekly and monthly digest is sent instead of the daily report like the unbatched run
+	var digested bool
+	if !quiet {
+		var digestErrors []NotifyError
+		digestErrors, digested = NotifyDigest(ctx, run, t)
+		batch.NotifyErrors = append(batch.NotifyErrors, digestErrors...)
+	}
+
+	if !quiet && !digested {
+		content := report.MarketClosedContent(batch.MarketClosed) + fmt.Sprintf("%d symbols in %d files\n", batch.Summary.Count, len(batch.Files))
+		for _, f := range batch.Files {
+			content = content + fmt.Sprintln(f)
+		}
+		batch.NotifyErrors = append(batch.NotifyErrors, Notify(ctx, Report{
+			Date:    batch.CreatedAt,
+			Text:    content + report.SummaryContent(batch.Summary) + report.DrawdownContent(drawdown) + report.ErrorsContent(batch.Errors) + report.ParseErrorsContent(batch.ParseErrors) + report.DiscrepanciesContent(batch.Discrepancies),
+			Summary: batch.Summary,
+			Payload: batch,
+			Key:     filePath,
+		})...)
+	}
+	if quiet || digested {
+		batch.NotifyErrors = append(batch.NotifyErrors, NotifyFeed(ctx, batch.CreatedAt, batch, filePath)...)
+	}
+	if err := PostResult(ctx, batch, time.Now()); err != nil {
+		logging.Error(ctx, "result webhook error", logging.Fields{"error": err})
+		batch.NotifyErrors = append(batch.NotifyErrors, NotifyError{Channel: "result_webhook", Error: err.Error()})
+		RecordNotify(ctx, "result_webhook", "", portfolio.NotifyFailed, err)
+	} else if os.Getenv("RESULT_WEBHOOK_URL") != "" {
+		RecordNotify(ctx, "result_webhook", "", portfolio.NotifySent, nil)
+	}
+
+	EmitMetrics(ctx, NotifyMetrics(batch.NotifyErrors))
+
+	batch.Run.Retries = quotes.Retries()
+	batch.Run.DurationMs = time.Since(begin).Milliseconds()
+	batch.Run.Notifications = NotifyOutcomes(ctx)
+
+	b, err := json.Marshal(batch)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	if err := SaveRunMarker(ctx, t, filePath, b, true); err != nil {
+		logging.Error(ctx, "run marker error", logging.Fields{"error": err})
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: BatchStatus(batch),
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+func TestRunBatches(t *testing.T) {
+	fake := newFakeS3(t, "test-bucket")
+	mail := newFakeSES(t)
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("OUTPUT_FORMATS", "")
+
+	// 25 symbols, each bought at 100 and priced at 110, 10 shares (+100 each)
+	prices := map[string]string{}
+	var symbols []portfolio.Ticker
+	for i := 1; i <= 25; i++ {
+		symbol := fmt.Sprintf("S%02d", i)
+		prices[symbol] = "110"
+		symbols = append(symbols, portfolio.Ticker{Symble: symbol, Bid: 100, Hold: 10})
+	}
+	quotePages(t, prices)
+	at := time.Date(2021, 6, 14, 18, 0, 0, 0, time.UTC)
+
+	useConfig(t)
+	response, err := RunBatches(context.Background(), quotes.NewYahooProvider("", 0), symbols, nil, nil, 10, at, "stock/2021/06/14.json")
+	if err != nil {
+		t.Fatalf("RunBatches() error = %v", err)
+	}
+	var batch BatchResult
+	if err := json.Unmarshal([]byte(response.Body), &batch); err != nil {
+		t.Fatalf("RunBatches() body: %s", err)
+	}
+
+	want := []string{"stock/2021/06/14-001.json", "stock/2021/06/14-002.json", "stock/2021/06/14-003.json"}
+	if strings.Join(batch.Files, ",") != strings.Join(want, ",") {
+		t.Errorf("Files = %v, want %v", batch.Files, want)
+	}
+	for i, key := range want {
+		b, ok := fake.Object(key)
+		if !ok {
+			t.Errorf("%s is not written", key)
+			continue
+		}
+		var result portfolio.Result
+		if err := json.Unmarshal(b, &result); err != nil {
+			t.Errorf("%s: %s", key, err)
+		}
+		if n := []int{10, 10, 5}[i]; len(result.Body) != n {
+			t.Errorf("%s has %d symbols, want %d", key, len(result.Body), n)
+		}
+	}
+
+	if batch.Summary.Count != 25 || batch.Summary.ProfitLoss != 2500 {
+		t.Errorf("portfolio.Summary = count %d, profit loss %v, want 25 and 2500", batch.Summary.Count, batch.Summary.ProfitLoss)
+	}
+	if sent := mail.Sent(); len(sent) != 1 || !strings.Contains(sent[0], "25 symbols in 3 files") || !strings.Contains(sent[0], "2500.00") {
+		t.Errorf("mails = %q, want one mail of the 3 files and the total", sent)
+	}
+}
+
+func TestBatchFilePath(t *testing.T) {
+	if got := BatchFilePath("result/2021/06.json", 1); got != "result/2021/06-001.json" {
+		t.Errorf("BatchFilePath() = %q, want result/2021/06-001.json", got)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// plaidURL is the production host of the plaid api.
+const plaidURL = "https://production.plaid.com"
+
+// BrokerSource is the current holdings of the broker account.
+type BrokerSource interface {
+	Name() string
+	Holdings(ctx context.Context) ([]portfolio.Ticker, error)
+}
+
+// NewBrokerSource is the source of BROKER_SYNC, plaid or url. ok is false when it is not set.
+func NewBrokerSource(name string) (BrokerSource, bool, error) {
+	switch strings.ToLower(name) {
+	case "":
+		return nil, false, nil
+	case "plaid":
+		base := os.Getenv("PLAID_URL")
+		if base == "" {
+			base = plaidURL
+		}
+		return &PlaidSource{
+			BaseURL:     strings.TrimSuffix(base, "/"),
+			ClientID:    os.Getenv("PLAID_CLIENT_ID"),
+			Secret:      os.Getenv("PLAID_SECRET"),
+			AccessToken: os.Getenv("PLAID_ACCESS_TOKEN"),
+		}, true, nil
+	case "url":
+		return &URLBrokerSource{URL: os.Getenv("BROKER_HOLDINGS_URL"), Token: os.Getenv("BROKER_HOLDINGS_TOKEN")}, true, nil
+	}
+	return nil, false, fmt.Errorf("BROKER_SYNC %q is not plaid or url", name)
+}
+
+// PlaidSource is the holdings of the plaid investments api (/investments/holdings/get), cash is not in them.
+type PlaidSource struct {
+	BaseURL     string
+	ClientID    string
+	Secret      string
+	AccessToken string
+}
+
+// Name is source name.
+func (s *PlaidSource) Name() string {
+	return "plaid"
+}
+
+// Holdings is the holdings of the accounts of the access token, bid is the cost basis per share.
+func (s *PlaidSource) Holdings(ctx context.Context) ([]portfolio.Ticker, error) {
+	if s.ClientID == "" || s.Secret == "" || s.AccessToken == "" {
+		return nil, fmt.Errorf("PLAID_CLIENT_ID, PLAID_SECRET and PLAID_ACCESS_TOKEN are required")
+	}
+	b, err := json.Marshal(map[string]string{"client_id": s.ClientID, "secret": s.Secret, "access_token": s.AccessToken})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.BaseURL+"/investments/holdings/get", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := quotes.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+	var body struct {
+		Holdings []struct {
+			SecurityID string   `json:"security_id"`
+			Quantity   float64  `json:"quantity"`
+			CostBasis  *float64 `json:"cost_basis"`
+		} `json:"holdings"`
+		Securities []struct {
+			SecurityID   string `json:"security_id"`
+			TickerSymbol string `json:"ticker_symbol"`
+			Type         string `json:"type"`
+		} `json:"securities"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("parse json error. %s", err)
+	}
+
+	symbols := map[string]string{}
+	for _, sec := range body.Securities {
+		if sec.Type != "cash" && sec.TickerSymbol != "" {
+			symbols[sec.SecurityID] = quotes.NormalizeSymbol(sec.TickerSymbol)
+		}
+	}
+	var tickers []portfolio.Ticker
+	for _, h := range body.Holdings {
+		symbol, ok := symbols[h.SecurityID]
+		if !ok {
+			continue
+		}
+		t := portfolio.Ticker{Symble: symbol, Hold: h.Quantity}
+		if h.CostBasis != nil && h.Quantity != 0 {
+			t.Bid = *h.CostBasis / h.Quantity
+		}
+		tickers = append(tickers, t)
+	}
+	return tickers, nil
+}
+
+// URLBrokerSource is the holdings of the url of the broker api (or a proxy of it), the json positions of the watchlist format.
+// Token is sent as the bearer token.
+type URLBrokerSource struct {
+	URL   string
+	Token string
+}
+
+// Name is source name.
+func (s *URLBrokerSource) Name() string {
+	return "url"
+}
+
+// Holdings is the positions of the url.
+func (s *URLBrokerSource) Holdings(ctx context.Context) ([]portfolio.Ticker, error) {
+	if s.URL == "" {
+		return nil, fmt.Errorf("BROKER_HOLDINGS_URL is not set")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	resp, err := quotes.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return portfolio.ParseWatchlistJSON(b)
+}
+
+// SyncBroker is the discrepancies of the symbols and the holdings of BROKER_SYNC, nil without it.
+// A failure of the sync is logged, the valuation goes on with the stock data.
+func SyncBroker(ctx context.Context, symbols []portfolio.Ticker) []portfolio.Discrepancy {
+	source, ok, err := NewBrokerSource(os.Getenv("BROKER_SYNC"))
+	if err != nil || !ok {
+		return nil
+	}
+	holdings, err := source.Holdings(ctx)
+	if err != nil {
+		logging.Error(ctx, "broker sync error", logging.Fields{"source": source.Name(), "error": err})
+		return nil
+	}
+	discrepancies := portfolio.Reconcile(symbols, holdings)
+	for _, d := range discrepancies {
+		logging.Warn(ctx, "broker discrepancy", logging.Fields{"source": source.Name(), "symbol": d.Symble, "kind": d.Kind, "hold": d.Hold, "broker_hold": d.BrokerHold})
+	}
+	return discrepancies
+}
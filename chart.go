@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// chartDays is days of the value chart of the mail.
+const chartDays = 30
+
+// chartContentID is content id of the value chart in the html mail.
+const chartContentID = "value-chart"
+
+// ValueSeries is total values of the days from HISTORY_TABLE, otherwise from the rollups (ROLLUP_FILE_PATH).
+func ValueSeries(ctx context.Context, from, to time.Time) ([]report.ValuePoint, error) {
+	cfg := ConfigOf(ctx)
+	var points []report.ValuePoint
+
+	if cfg.HistoryTable != "" {
+		results, err := ReadHistory(ctx, cfg.HistoryTable, from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range results {
+			points = append(points, report.ValuePoint{Date: r.CreatedAt, Value: portfolio.Summarize(r.Body).Value})
+		}
+		return points, nil
+	}
+
+	if cfg.RollupPath == "" {
+		return nil, nil
+	}
+	store := storage.New(cfg.Bucket)
+	seen := map[string]bool{}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		key := ReportFilePath(cfg.RollupPath, d)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		data, err := store.Get(ctx, key)
+		if errors.Is(err, storage.ErrNoSuchKey) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var rollup Rollup
+		if err := json.Unmarshal(data, &rollup); err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+		for _, day := range rollup.Days {
+			if day.Date >= from.Format("2006-01-02") && day.Date <= to.Format("2006-01-02") {
+				points = append(points, report.ValuePoint{Date: day.Date, Value: day.Value})
+			}
+		}
+	}
+	return points, nil
+}
+
+// ValueChartAttachment is inline png of the values of the last 30 days until t, ok is false when there are not enough days.
+func ValueChartAttachment(ctx context.Context, t time.Time) (Attachment, bool, error) {
+	points, err := ValueSeries(ctx, t.AddDate(0, 0, 1-chartDays), t)
+	if err != nil || len(points) < 2 {
+		return Attachment{}, false, err
+	}
+	png, err := report.ValueChart(points)
+	if err != nil {
+		return Attachment{}, false, err
+	}
+	return Attachment{
+		Filename:    "value-chart.png",
+		ContentType: "image/png",
+		Data:        png,
+		ContentID:   chartContentID,
+	}, true, nil
+}
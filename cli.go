@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// RunCLI is value the local stock data file and print the report, without lambda, s3 and ses.
+// e.g. stockprofit -file portfolio.csv -format json
+func RunCLI(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("stockprofit", flag.ContinueOnError)
+	file := fs.String("file", "", "stock data file (csv, json, yaml or transaction log)")
+	format := fs.String("format", "text", "output format, text, json or html")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("-file is required")
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return err
+	}
+	symbols, parseErrors, err := portfolio.ParseWatchlist(*file, data)
+	if err != nil {
+		return err
+	}
+	symbols = portfolio.AggregateLots(portfolio.FilterSymbols(symbols, os.Getenv("INCLUDE_SYMBOLS"), os.Getenv("EXCLUDE_SYMBOLS")))
+	if len(symbols) == 0 {
+		return portfolio.ErrEmptyWatchlist
+	}
+
+	provider, err := PriceProvider()
+	if err != nil {
+		return err
+	}
+
+	t := time.Now().In(reportLocation)
+	result := FetchResult(ctx, provider, symbols, parseErrors, t)
+	portfolio.CheckAlerts(result.Body)
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(Valuation{Result: result, Summary: portfolio.Summarize(result.Body)})
+	case "html":
+		html, err := report.HTMLContent(result, report.HTMLOptions{})
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprint(w, html)
+		return err
+	case "text":
+		_, err = fmt.Fprint(w, report.MailContent(result))
+		return err
+	}
+	return fmt.Errorf("unknown format %q", *format)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// Config is the settings of the report run, it is loaded and validated once at the first invocation.
+type Config struct {
+	Bucket    string
+	StockData string
+	FilePath  string
+	// MailTo, MailCc and MailBcc are comma separated addresses
+	MailTo     []string
+	MailCc     []string
+	MailBcc    []string
+	MailSender string
+	// HistoryTable, RollupPath, ParquetPrefix and StatementPath are HISTORY_TABLE, ROLLUP_FILE_PATH, PARQUET_PREFIX and STATEMENT_FILE_PATH
+	HistoryTable  string
+	RollupPath    string
+	ParquetPrefix string
+	StatementPath string
+	// TaxPath is TAX_REPORT_PATH
+	TaxPath string
+	// Tenant is name of the tenant of TENANTS_FILE, empty in the single portfolio mode
+	Tenant string
+	// Channels is notification channels of NOTIFY_CHANNELS
+	Channels map[string]bool
+}
+
+// config is the loaded config, Invoke loads it.
+var config Config
+
+var (
+	configOnce sync.Once
+	configErr  error
+)
+
+// ConfigError is every missing and invalid variable of the config.
+type ConfigError struct {
+	Missing []string
+	Invalid []string
+}
+
+func (e *ConfigError) Error() string {
+	var problems []string
+	if len(e.Missing) > 0 {
+		problems = append(problems, "missing "+strings.Join(e.Missing, ", "))
+	}
+	return "invalid config. " + strings.Join(append(problems, e.Invalid...), "; ")
+}
+
+// configKey is context key of the config of the tenant.
+type configKey struct{}
+
+// WithConfig is the context of the config, e.g. of a tenant.
+func WithConfig(ctx context.Context, c Config) context.Context {
+	return context.WithValue(ctx, configKey{}, c)
+}
+
+// ConfigOf is the config of the context, the loaded config when it has none.
+func ConfigOf(ctx context.Context) Config {
+	if c, ok := ctx.Value(configKey{}).(Config); ok {
+		return c
+	}
+	return config
+}
+
+// InitConfig is load the config once, the error is returned every time.
+func InitConfig() error {
+	configOnce.Do(func() {
+		config, configErr = LoadConfig()
+	})
+	return configErr
+}
+
+// LoadConfig is the config of the environment variables, ConfigError when some of them are missing or invalid.
+func LoadConfig() (Config, error) {
+	c := Config{
+		Bucket:        os.Getenv("BUCKET"),
+		StockData:     os.Getenv("S3_STOCK_DATA"),
+		FilePath:      os.Getenv("S3_FILE_PATH"),
+		MailTo:        MailAddresses(os.Getenv("MAIL_TO_ADDRESS")),
+		MailCc:        MailAddresses(os.Getenv("MAIL_CC_ADDRESS")),
+		MailBcc:       MailAddresses(os.Getenv("MAIL_BCC_ADDRESS")),
+		MailSender:    os.Getenv("MAIL_SENDER_ADDRESS"),
+		HistoryTable:  os.Getenv("HISTORY_TABLE"),
+		RollupPath:    os.Getenv("ROLLUP_FILE_PATH"),
+		ParquetPrefix: os.Getenv("PARQUET_PREFIX"),
+		StatementPath: os.Getenv("STATEMENT_FILE_PATH"),
+		TaxPath:       os.Getenv("TAX_REPORT_PATH"),
+		Channels:      NotifyChannels(os.Getenv("NOTIFY_CHANNELS")),
+	}
+	if missing, invalid := CheckConfig(), InvalidConfig(); len(missing) > 0 || len(invalid) > 0 {
+		return c, &ConfigError{Missing: missing, Invalid: invalid}
+	}
+	return c, nil
+}
+
+// InvalidConfig is the variables which can't be parsed, unset ones are the defaults.
+func InvalidConfig() []string {
+	var invalid []string
+
+	// a layout without the year or month would overwrite one key forever
+	jan, feb := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	for _, name := range []string{"S3_FILE_PATH", "ROLLUP_FILE_PATH", "STATEMENT_FILE_PATH", "TAX_REPORT_PATH"} {
+		if layout := os.Getenv(name); layout != "" && ReportFilePath(layout, jan) == ReportFilePath(layout, feb) {
+			invalid = append(invalid, fmt.Sprintf("%s %q has no year or month", name, layout))
+		}
+	}
+
+	for _, name := range []string{"FETCH_CONCURRENCY", "BATCH_SIZE", "HTTP_RETRY_BUDGET", "YAHOO_API_CHUNK"} {
+		if v := os.Getenv(name); v != "" {
+			if n, err := strconv.Atoi(v); err != nil || n <= 0 {
+				invalid = append(invalid, fmt.Sprintf("%s %q is not a positive number", name, v))
+			}
+		}
+	}
+	if v := os.Getenv("HTTP_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			invalid = append(invalid, fmt.Sprintf("HTTP_RETRIES %q is not a number", v))
+		}
+	}
+	for _, name := range []string{"FETCH_TIMEOUT", "FETCH_JITTER", "FETCH_DEADLINE_MARGIN", "HTTP_TIMEOUT", "SECRETS_TTL", "SIGNATURE_TOLERANCE", "STATEMENT_LINK_TTL", "QUOTE_CACHE_TTL"} {
+		if v := os.Getenv(name); v != "" {
+			if _, err := time.ParseDuration(v); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s %q is not a duration (e.g. 30s)", name, v))
+			}
+		}
+	}
+
+	// an unknown REPORT_TIMEZONE is not invalid, it falls back to UTC with the warning (LoadReportLocation)
+	if _, _, _, err := ParseQuietHours(os.Getenv("QUIET_HOURS")); err != nil {
+		invalid = append(invalid, "QUIET_HOURS "+err.Error())
+	}
+	if _, err := ParseVacation(os.Getenv("VACATION")); err != nil {
+		invalid = append(invalid, "VACATION "+err.Error())
+	}
+	if _, err := quotes.ParseBudgets(os.Getenv("PROVIDER_BUDGETS")); err != nil {
+		invalid = append(invalid, "PROVIDER_BUDGETS "+err.Error())
+	}
+	if _, _, err := NewBrokerSource(os.Getenv("BROKER_SYNC")); err != nil {
+		invalid = append(invalid, err.Error())
+	}
+	if locale := os.Getenv("REPORT_LOCALE"); locale != "" && !report.IsLocale(locale) {
+		invalid = append(invalid, fmt.Sprintf("REPORT_LOCALE %q is not en or ja", locale))
+	}
+	if mode := os.Getenv("REPORT_MODE"); mode != "" {
+		if _, err := ParseReportMode(mode); err != nil {
+			invalid = append(invalid, "REPORT_MODE "+err.Error())
+		}
+	}
+	if os.Getenv("RESULT_WEBHOOK_URL") != "" && os.Getenv("RESULT_WEBHOOK_SECRET") == "" {
+		invalid = append(invalid, "RESULT_WEBHOOK_URL is set without RESULT_WEBHOOK_SECRET")
+	}
+	// athena can't read the encrypted parquet and the presigned link of the encrypted statement is unreadable
+	if os.Getenv("CLIENT_ENCRYPTION_KEY_ID") != "" {
+		for _, name := range []string{"PARQUET_PREFIX", "STATEMENT_FILE_PATH", "STATEMENT_LINK_TTL"} {
+			if os.Getenv(name) != "" {
+				invalid = append(invalid, fmt.Sprintf("CLIENT_ENCRYPTION_KEY_ID is set with %s", name))
+			}
+		}
+	}
+	if SheetsEnabled() && os.Getenv("GOOGLE_SERVICE_ACCOUNT") == "" {
+		invalid = append(invalid, "SHEETS_ID is set without GOOGLE_SERVICE_ACCOUNT")
+	}
+	switch mode := os.Getenv("MARKET_CALENDAR"); mode {
+	case "", "mark", "skip":
+	default:
+		invalid = append(invalid, fmt.Sprintf("MARKET_CALENDAR %q is not mark or skip", mode))
+	}
+	if v := os.Getenv("TAX_YEAR_START"); v != "" {
+		if _, _, err := portfolio.ParseFiscalYearStart(v); err != nil {
+			invalid = append(invalid, "TAX_YEAR_START "+err.Error())
+		}
+	}
+	switch method := os.Getenv("COST_BASIS"); strings.ToLower(method) {
+	case "", portfolio.CostBasisAverage, portfolio.CostBasisFIFO, portfolio.CostBasisSpecific:
+	default:
+		invalid = append(invalid, fmt.Sprintf("COST_BASIS %q is not average, fifo or specific", method))
+	}
+	switch mode := os.Getenv("OVERWRITE_MODE"); mode {
+	case "", "replace", "skip", "version":
+	default:
+		invalid = append(invalid, fmt.Sprintf("OVERWRITE_MODE %q is not replace, skip or version", mode))
+	}
+	for _, name := range strings.Split(os.Getenv("NOTIFY_CHANNELS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, known := notifiers[name]; name != "" && !known {
+			invalid = append(invalid, fmt.Sprintf("NOTIFY_CHANNELS %q is unknown", name))
+		}
+	}
+	return invalid
+}
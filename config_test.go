@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	setHealthyEnv(t)
+	c, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if c.Bucket != "test-bucket" || c.StockData != "data/stock.csv" || c.FilePath != "stock/2006/01/02.json" || !c.Channels["slack"] {
+		t.Errorf("LoadConfig() = %+v, want the config of the env", c)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	setHealthyEnv(t)
+	t.Setenv("BUCKET", "")
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("MAIL_TO_ADDRESS", "")
+	t.Setenv("MAIL_SENDER_ADDRESS", "sender@example.com")
+	t.Setenv("S3_FILE_PATH", "stock/report.json")
+	t.Setenv("FETCH_TIMEOUT", "30")
+	t.Setenv("OVERWRITE_MODE", "append")
+
+	// every problem is in the error, not only the first one
+	_, err := LoadConfig()
+	var configErr *ConfigError
+	if !errors.As(err, &configErr) {
+		t.Fatalf("LoadConfig() error = %v, want ConfigError", err)
+	}
+	if got := strings.Join(configErr.Missing, ","); got != "BUCKET,MAIL_TO_ADDRESS" {
+		t.Errorf("Missing = %s, want BUCKET and MAIL_TO_ADDRESS", got)
+	}
+	if len(configErr.Invalid) != 3 {
+		t.Errorf("Invalid = %q, want S3_FILE_PATH, FETCH_TIMEOUT and OVERWRITE_MODE", configErr.Invalid)
+	}
+	for _, want := range []string{"missing BUCKET, MAIL_TO_ADDRESS", `S3_FILE_PATH "stock/report.json" has no year or month`, `FETCH_TIMEOUT "30" is not a duration`, `OVERWRITE_MODE "append"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%q is not in %q", want, err)
+		}
+	}
+}
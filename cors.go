@@ -0,0 +1,56 @@
+package main
+
+import (
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// corsHeaders is the request headers of the api, the api key and the signature.
+const corsHeaders = "Content-Type, Accept, stock-api-key, stock-key-name, stock-timestamp, stock-signature"
+
+// CORSOrigin is the allowed origin of the request origin by CORS_ALLOW_ORIGINS (comma separated, * is any origin).
+// Empty when the origin is not allowed or CORS_ALLOW_ORIGINS is not set.
+func CORSOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, o := range strings.Split(os.Getenv("CORS_ALLOW_ORIGINS"), ",") {
+		switch o = strings.TrimSpace(o); {
+		case o == "*":
+			return "*"
+		case o != "" && strings.EqualFold(o, origin):
+			return origin
+		}
+	}
+	return ""
+}
+
+// WithCORS is the response with the cors headers of the allowed origin, as it is without the origin.
+func WithCORS(response events.APIGatewayProxyResponse, origin string) events.APIGatewayProxyResponse {
+	if origin == "" {
+		return response
+	}
+	if response.Headers == nil {
+		response.Headers = map[string]string{}
+	}
+	response.Headers["Access-Control-Allow-Origin"] = origin
+	response.Headers["Access-Control-Allow-Methods"] = "GET, POST, DELETE, OPTIONS"
+	response.Headers["Access-Control-Allow-Headers"] = corsHeaders
+	response.Headers["Access-Control-Expose-Headers"] = "Retry-After, Idempotent-Replay"
+	if origin != "*" {
+		response.Headers["Vary"] = "Origin"
+	}
+	return response
+}
+
+// PreflightResponse is the response of the cors preflight (OPTIONS) request, it has no api key.
+func PreflightResponse(origin string) events.APIGatewayProxyResponse {
+	response := WithCORS(events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, origin)
+	if response.Headers != nil {
+		response.Headers["Access-Control-Max-Age"] = "600"
+	}
+	return response
+}
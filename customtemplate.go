@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// TemplatePath is the key of the template variable (MAIL_TEXT_TEMPLATE or MAIL_HTML_TEMPLATE) under the name of the tenant, empty is not set.
+func TemplatePath(ctx context.Context, name string) string {
+	key := os.Getenv(name)
+	if key == "" {
+		return ""
+	}
+	return path.Join(ConfigOf(ctx).Tenant, key)
+}
+
+// CustomContent is the text and html bodies of the report mail by the go templates of MAIL_TEXT_TEMPLATE and MAIL_HTML_TEMPLATE in the BUCKET.
+// The body of a template which is not set, missing or invalid is the default one, the error is logged.
+func CustomContent(ctx context.Context, result portfolio.Result, opts report.HTMLOptions, text, html string) (string, string) {
+	render := func(name, body string, fn func(string, portfolio.Result, report.HTMLOptions) (string, error)) string {
+		key := TemplatePath(ctx, name)
+		if key == "" {
+			return body
+		}
+		data, err := DownloadFile(ctx, ConfigOf(ctx).Bucket, key)
+		if err != nil {
+			logging.Error(ctx, "mail template error", logging.Fields{"key": key, "error": err})
+			return body
+		}
+		s, err := fn(string(data), result, opts)
+		if err != nil {
+			logging.Error(ctx, "mail template error", logging.Fields{"key": key, "error": err})
+			return body
+		}
+		return s
+	}
+	return render("MAIL_TEXT_TEMPLATE", text, report.CustomText), render("MAIL_HTML_TEMPLATE", html, report.CustomHTML)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// PreviousResult is the latest result before the day.
+// HISTORY_TABLE is used when it is set, otherwise the report of yesterday (or today, it is not uploaded yet) in s3.
+func PreviousResult(ctx context.Context, t time.Time) (portfolio.Result, bool, error) {
+	today := t.Format("2006-01-02")
+
+	cfg := ConfigOf(ctx)
+	if table := cfg.HistoryTable; table != "" {
+		results, err := ReadHistory(ctx, table, t.AddDate(0, 0, -7), t.AddDate(0, 0, -1))
+		if err != nil || len(results) == 0 {
+			return portfolio.Result{}, false, err
+		}
+		return results[len(results)-1], true, nil
+	}
+
+	var prev portfolio.Result
+	var found bool
+	layout := cfg.FilePath
+	for _, key := range []string{ReportFilePath(layout, t.AddDate(0, 0, -1)), ReportFilePath(layout, t)} {
+		data, err := DownloadFile(ctx, cfg.Bucket, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNoSuchKey) {
+				continue
+			}
+			return portfolio.Result{}, false, err
+		}
+		result, err := portfolio.ParseResult(data)
+		if err != nil {
+			return portfolio.Result{}, false, fmt.Errorf("%s: %s", key, err)
+		}
+		if result.CreatedAt < today && result.CreatedAt > prev.CreatedAt {
+			prev, found = result, true
+		}
+	}
+	return prev, found, nil
+}
+
+// LastRunResult is the result of the last run, the report at the key or the previous result.
+// It is read before the upload overwrites the key.
+func LastRunResult(ctx context.Context, filePath string, t time.Time) (portfolio.Result, bool, error) {
+	data, err := DownloadFile(ctx, ConfigOf(ctx).Bucket, filePath)
+	if err == nil {
+		result, err := portfolio.ParseResult(data)
+		if err != nil {
+			return portfolio.Result{}, false, fmt.Errorf("%s: %s", filePath, err)
+		}
+		return result, true, nil
+	}
+	if !errors.Is(err, storage.ErrNoSuchKey) {
+		return portfolio.Result{}, false, err
+	}
+	return PreviousResult(ctx, t)
+}
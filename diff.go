@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// diffLookback is days before the date of the diff to find its snapshot, e.g. the date is a holiday.
+const diffLookback = 7
+
+// IsDiffRequest is GET /diff.
+func IsDiffRequest(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodGet && path.Base(request.Path) == "diff"
+}
+
+// SnapshotAt is the latest stored result on or before the date, within diffLookback days.
+// HISTORY_TABLE is used when it is set, otherwise the reports in s3.
+func SnapshotAt(ctx context.Context, date time.Time) (portfolio.Result, bool, error) {
+	from := date.AddDate(0, 0, -diffLookback)
+	var results []portfolio.Result
+	var err error
+	cfg := ConfigOf(ctx)
+	if table := cfg.HistoryTable; table != "" {
+		results, err = ReadHistory(ctx, table, from, date)
+	} else {
+		results, err = ReadReports(ctx, cfg.Bucket, cfg.FilePath, from, date)
+	}
+	if err != nil || len(results) == 0 {
+		return portfolio.Result{}, false, err
+	}
+	latest := results[0]
+	for _, r := range results[1:] {
+		if r.CreatedAt > latest.CreatedAt {
+			latest = r
+		}
+	}
+	return latest, true, nil
+}
+
+// DiffHandler is the change between the snapshots of from and to query params (YYYY-MM-DD).
+// The totals, the positions added and removed and the change of each symbol.
+func DiffHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	q := request.QueryStringParameters
+	if q["from"] == "" || q["to"] == "" {
+		return ErrorResponse(http.StatusBadRequest, "from and to are required (YYYY-MM-DD)."), nil
+	}
+	from, to, err := HistoryRange(q["from"], q["to"], time.Now().In(reportLocation))
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	var snapshots [2]portfolio.Result
+	for i, d := range []time.Time{from, to} {
+		result, ok, err := SnapshotAt(ctx, d)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if !ok {
+			return ErrorResponse(http.StatusNotFound, fmt.Sprintf("no snapshot of %s.", d.Format("2006-01-02"))), nil
+		}
+		snapshots[i] = result
+	}
+
+	b, err := json.Marshal(portfolio.DiffResults(snapshots[0], snapshots[1]))
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// digestMovers is best and worst symbols in the digest.
+const digestMovers = 5
+
+// reportModeKey is context key of the report mode.
+type reportModeKey struct{}
+
+// WithReportMode is the context of the report mode (daily, weekly or monthly).
+func WithReportMode(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, reportModeKey{}, mode)
+}
+
+// ReportMode is the report mode of the context, otherwise REPORT_MODE, default daily.
+func ReportMode(ctx context.Context) string {
+	if mode, ok := ctx.Value(reportModeKey{}).(string); ok && mode != "" {
+		return mode
+	}
+	if mode := strings.ToLower(os.Getenv("REPORT_MODE")); mode != "" {
+		return mode
+	}
+	return "daily"
+}
+
+// ParseReportMode is check the mode is daily, weekly or monthly.
+func ParseReportMode(mode string) (string, error) {
+	switch mode = strings.ToLower(mode); mode {
+	case "daily", "weekly", "monthly":
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown report mode %q, daily, weekly or monthly", mode)
+}
+
+// ScheduleReportMode is report_mode of the detail of the eventbridge event (e.g. {"report_mode": "weekly"}), empty when it has none.
+func ScheduleReportMode(detail json.RawMessage) (string, error) {
+	var d struct {
+		ReportMode string `json:"report_mode"`
+	}
+	if len(detail) == 0 || json.Unmarshal(detail, &d) != nil || d.ReportMode == "" {
+		return "", nil
+	}
+	return ParseReportMode(d.ReportMode)
+}
+
+// PeriodStart is first day of the period of the mode until t, a week or a month before.
+func PeriodStart(mode string, t time.Time) time.Time {
+	if mode == "monthly" {
+		return t.AddDate(0, -1, 0)
+	}
+	return t.AddDate(0, 0, -7)
+}
+
+// ValueDays is total value and cost of the results, one per day in order of the date.
+func ValueDays(results []portfolio.Result) []portfolio.ValueDay {
+	var days []portfolio.ValueDay
+	for _, r := range results {
+		s := portfolio.Summarize(r.Body)
+		day := portfolio.ValueDay{Date: r.CreatedAt, Value: s.Value, Cost: s.Cost}
+		if n := len(days); n > 0 && days[n-1].Date == day.Date {
+			days[n-1] = day
+			continue
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// RiskFreeRate is RISK_FREE_RATE, the annual % of the sharpe ratio of the monthly digest, default 0.
+func RiskFreeRate() float64 {
+	rate, _ := strconv.ParseFloat(os.Getenv("RISK_FREE_RATE"), 64)
+	return rate
+}
+
+// DigestReport is the weekly or monthly digest of the result, from the first stored result of the period.
+// ok is false when no result is stored in the period.
+func DigestReport(ctx context.Context, mode string, result portfolio.Result, t time.Time) (Report, bool, error) {
+	from, to := PeriodStart(mode, t), t.AddDate(0, 0, -1)
+	cfg := ConfigOf(ctx)
+
+	var results []portfolio.Result
+	var err error
+	if cfg.HistoryTable != "" {
+		results, err = ReadHistory(ctx, cfg.HistoryTable, from, to)
+	} else {
+		results, err = ReadReports(ctx, cfg.Bucket, cfg.FilePath, from, to)
+	}
+	if err != nil || len(results) == 0 {
+		return Report{}, false, err
+	}
+
+	digest := portfolio.NewDigest(results[0], result, digestMovers)
+	if mode == "monthly" {
+		days := ValueDays(append(results, result))
+		if r, ok := portfolio.NewReturns(days); ok {
+			digest.Returns = &r
+		}
+		if r, ok := portfolio.NewRiskReport(days, append(results, result), RiskFreeRate()); ok {
+			digest.Risk = &r
+		}
+	}
+	return Report{
+		Date:    result.CreatedAt,
+		Subject: report.DigestSubject(mode, digest),
+		Text:    report.DigestContent(mode, digest),
+		Summary: portfolio.Summarize(result.Body),
+		Payload: digest,
+	}, true, nil
+}
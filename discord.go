@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// discordMaxContent is max characters of the discord message content.
+const discordMaxContent = 2000
+
+// DiscordNotifier is post the report to the discord webhook.
+type DiscordNotifier struct {
+	URL string
+}
+
+// Name is channel name.
+func (n *DiscordNotifier) Name() string {
+	return "discord"
+}
+
+// Notify is post the report embeds, the other reports (e.g. alert) are the subject and the text.
+func (n *DiscordNotifier) Notify(ctx context.Context, r Report) error {
+	if n.URL == "" {
+		return fmt.Errorf("DISCORD_WEBHOOK_URL is not set")
+	}
+	if result, ok := r.Payload.(portfolio.Result); ok && r.Subject == "" {
+		return PostJSON(ctx, n.URL, report.DiscordContent(r.Date, result))
+	}
+	subject := r.Subject
+	if subject == "" {
+		subject = "Stock Profit " + r.Date
+	}
+	content := []rune("**" + subject + "**\n" + r.Text)
+	if len(content) > discordMaxContent {
+		content = append(content[:discordMaxContent-3], []rune("...")...)
+	}
+	return PostJSON(ctx, n.URL, report.DiscordMessage{Content: string(content)})
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// LoadDividends is the dividends of DIVIDEND_LOG in the BUCKET, nil when it is not set or can't be read.
+func LoadDividends(ctx context.Context) []portfolio.Dividend {
+	key := os.Getenv("DIVIDEND_LOG")
+	if key == "" {
+		return nil
+	}
+
+	data, err := DownloadFile(ctx, ConfigOf(ctx).Bucket, key)
+	if err != nil {
+		logging.Warn(ctx, "dividend log error", logging.Fields{"key": key, "error": err})
+		return nil
+	}
+	dividends, errs := portfolio.ParseDividends(data)
+	for _, e := range errs {
+		logging.Warn(ctx, "invalid dividend log line", logging.Fields{"key": key, "line": e.Line, "error": e.Error})
+	}
+	return dividends
+}
+
+// ApplyDividendLog is add the dividends of DIVIDEND_LOG in the BUCKET to the tickers.
+// The report is made without them when the log can't be read.
+func ApplyDividendLog(ctx context.Context, tickers []portfolio.Ticker) []portfolio.Ticker {
+	dividends := LoadDividends(ctx)
+	if len(dividends) == 0 {
+		return tickers
+	}
+	return portfolio.ApplyDividends(tickers, dividends)
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// HistoryPeak is date of the all-time high row in the history table, its symble is HistoryTotal.
+const HistoryPeak = "_PEAK"
+
+// PeakItem is the all-time high of the total value in the history table.
+type PeakItem struct {
+	Date     string  `dynamodbav:"date"`
+	Symble   string  `dynamodbav:"symble"`
+	Value    float64 `dynamodbav:"value"`
+	PeakDate string  `dynamodbav:"peak_date"`
+}
+
+// UpdatePeak is the drawdown of the total value of the date from the peak in the HISTORY_TABLE, the new peak is put to it.
+func UpdatePeak(ctx context.Context, table, date string, value float64) (portfolio.Drawdown, error) {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return portfolio.Drawdown{}, err
+	}
+	svc := dynamodb.New(sess)
+
+	key := map[string]*dynamodb.AttributeValue{
+		"date":   {S: aws.String(HistoryPeak)},
+		"symble": {S: aws.String(HistoryTotal)},
+	}
+	out, err := svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: aws.String(table), Key: key})
+	if err != nil {
+		return portfolio.Drawdown{}, err
+	}
+	var peak PeakItem
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &peak); err != nil {
+		return portfolio.Drawdown{}, err
+	}
+
+	drawdown := portfolio.NewDrawdown(peak.Value, peak.PeakDate, value, date)
+	if drawdown.PeakDate == date && (drawdown.Peak != peak.Value || peak.PeakDate != date) {
+		av, err := dynamodbattribute.MarshalMap(PeakItem{Date: HistoryPeak, Symble: HistoryTotal, Value: drawdown.Peak, PeakDate: date})
+		if err != nil {
+			return portfolio.Drawdown{}, err
+		}
+		if _, err := svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{TableName: aws.String(table), Item: av}); err != nil {
+			return portfolio.Drawdown{}, err
+		}
+	}
+	return drawdown, nil
+}
+
+// DrawdownAlert is DRAWDOWN_ALERT_PCT, the alert is sent when the drawdown is over it (e.g. 10 is -10% from the peak), zero is no alert.
+func DrawdownAlert() float64 {
+	pct, _ := strconv.ParseFloat(os.Getenv("DRAWDOWN_ALERT_PCT"), 64)
+	return pct
+}
+
+// TrackDrawdown is the drawdown of the total value from the peak of the HISTORY_TABLE and the alert over DRAWDOWN_ALERT_PCT.
+// It is nil without HISTORY_TABLE or the value.
+func TrackDrawdown(ctx context.Context, date string, summary portfolio.Summary) (*portfolio.Drawdown, []NotifyError) {
+	table := ConfigOf(ctx).HistoryTable
+	if table == "" || summary.Value <= 0 {
+		return nil, nil
+	}
+	drawdown, err := UpdatePeak(ctx, table, date, summary.Value)
+	if err != nil {
+		logging.Error(ctx, "peak error", logging.Fields{"table": table, "error": err})
+		return nil, nil
+	}
+
+	if pct := DrawdownAlert(); pct > 0 && -drawdown.Percent >= pct {
+		return &drawdown, Notify(ctx, Report{
+			Date:    date,
+			Subject: fmt.Sprintf("Drawdown Alert %s: %.2f%%", date, drawdown.Percent),
+			Text:    fmt.Sprintf("total value %.2f is %.2f%% from the peak %.2f of %s (alert at -%g%%)\n", drawdown.Value, drawdown.Percent, drawdown.Peak, drawdown.PeakDate, pct),
+			Payload: drawdown,
+		})
+	}
+	return &drawdown, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+	"os"
+)
+
+// dryRunKey is context key of the dry run.
+type dryRunKey struct{}
+
+// WithDryRun is the context of the dry run (?dry_run=true), the report is not uploaded and notified.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun is check the context is dry run or DRY_RUN is true.
+func IsDryRun(ctx context.Context) bool {
+	if os.Getenv("DRY_RUN") == "true" {
+		return true
+	}
+	dry, _ := ctx.Value(dryRunKey{}).(bool)
+	return dry
+}
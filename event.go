@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// Invoke is lambda function start point, dispatch the event to the handler by its shape.
+// ctx has the deadline of the invocation, and the request id of the logs.
+func Invoke(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		ctx = logging.WithRequestID(ctx, lc.AwsRequestID)
+	}
+	start := time.Now()
+	defer func() {
+		logging.Info(ctx, "invoke done", logging.Fields{"duration_ms": logging.Since(start)})
+	}()
+
+	if err := LoadSecrets(ctx); err != nil {
+		logging.Error(ctx, "secrets error", logging.Fields{"error": err})
+		return nil, err
+	}
+	// every missing and invalid variable is reported at the first invocation
+	if err := InitConfig(); err != nil {
+		logging.Error(ctx, "config error", logging.Fields{"error": err})
+		return nil, err
+	}
+
+	var probe struct {
+		Records []struct {
+			EventSource string `json:"eventSource"`
+		} `json:"Records"`
+		Source     string         `json:"source"`
+		DetailType string         `json:"detail-type"`
+		Stage      string         `json:"stage"`
+		Backfill   *BackfillEvent `json:"backfill"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+
+	// the backfill of the history, it is invoked directly
+	if probe.Backfill != nil {
+		return BackfillHandler(ctx, *probe.Backfill)
+	}
+
+	// a stage of the step functions run
+	if probe.Stage != "" {
+		var event StageEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, err
+		}
+		return StageHandler(ctx, event)
+	}
+
+	// failed symbols of RETRY_QUEUE_URL
+	if len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:sqs" {
+		var event events.SQSEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, err
+		}
+		return RetryHandler(ctx, event)
+	}
+
+	if len(probe.Records) > 0 && probe.Records[0].EventSource == "aws:s3" {
+		var event events.S3Event
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, err
+		}
+		return nil, S3Handler(ctx, event)
+	}
+
+	// eventbridge (cloudwatch events) schedule, it has no api key
+	if probe.Source != "" && probe.DetailType != "" {
+		var event events.CloudWatchEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, err
+		}
+		return nil, ScheduledHandler(ctx, event)
+	}
+
+	var request events.APIGatewayProxyRequest
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return nil, err
+	}
+	return Handler(ctx, request)
+}
+
+// ScheduledHandler is run the report of S3_STOCK_DATA by the eventbridge schedule.
+func ScheduledHandler(ctx context.Context, event events.CloudWatchEvent) error {
+	logging.Info(ctx, "scheduled event", logging.Fields{"detail_type": event.DetailType, "event_time": event.Time.Format(time.RFC3339), "resources": strings.Join(event.Resources, ",")})
+	mode, err := ScheduleReportMode(event.Detail)
+	if err != nil {
+		return err
+	}
+	if mode != "" {
+		ctx = WithReportMode(ctx, mode)
+	}
+	_, err = RunPortfolios(ctx)
+	return err
+}
+
+// S3Handler is run the report when the watchlist is uploaded to s3.
+// Only S3_STOCK_DATA, the stock data of the tenants or keys under S3_EVENT_PREFIX are processed, so uploaded reports don't trigger it again.
+func S3Handler(ctx context.Context, event events.S3Event) error {
+	tenants, err := LoadTenants(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range event.Records {
+		bucket := record.S3.Bucket.Name
+		key := record.S3.Object.URLDecodedKey
+		if key == "" {
+			key = record.S3.Object.Key
+		}
+
+		// the stock data of a tenant is reported to the tenant, an uploaded watchlist is run again on the day
+		rctx := WithForce(ctx)
+		if t, ok := TenantOf(tenants, key); ok {
+			rctx = WithConfig(rctx, TenantConfig(ConfigOf(ctx), t))
+		} else if !IsWatchlistKey(key) {
+			logging.Info(ctx, "not a watchlist, skip", logging.Fields{"bucket": bucket, "key": key})
+			continue
+		}
+
+		data, err := DownloadFile(rctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		symbols, parseErrors, err := ParseStockData(rctx, bucket, key, data)
+		if err != nil {
+			return err
+		}
+		if _, err := Run(rctx, symbols, parseErrors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IsWatchlistKey is check the key is S3_STOCK_DATA or under S3_EVENT_PREFIX.
+func IsWatchlistKey(key string) bool {
+	if key == config.StockData {
+		return true
+	}
+	prefix := os.Getenv("S3_EVENT_PREFIX")
+	return prefix != "" && strings.HasPrefix(key, prefix)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func s3Event(bucket string, keys ...string) events.S3Event {
+	var event events.S3Event
+	for _, key := range keys {
+		var record events.S3EventRecord
+		record.EventSource = "aws:s3"
+		record.S3.Bucket.Name = bucket
+		record.S3.Object.Key = key
+		event.Records = append(event.Records, record)
+	}
+	return event
+}
+
+func TestS3Handler(t *testing.T) {
+	quotePages(t, map[string]string{"AAPL": "130.48"})
+	fake := newFakeS3(t, "test-bucket")
+	mail := newFakeSES(t)
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("S3_EVENT_PREFIX", "uploads/")
+	t.Setenv("S3_FILE_PATH", "reports/2006/01/02.json")
+	t.Setenv("OUTPUT_FORMATS", "")
+
+	fake.put("test-bucket", "uploads/stock.csv", bytes.NewReader([]byte("AAPL,120,0,10\n")))
+	fake.put("test-bucket", "reports/2021/06/14.json", bytes.NewReader([]byte("{}")))
+
+	// the uploaded report is not a watchlist, it doesn't trigger the report again
+	useConfig(t)
+	if err := S3Handler(context.Background(), s3Event("test-bucket", "reports/2021/06/14.json", "uploads/stock.csv")); err != nil {
+		t.Fatalf("S3Handler() error = %v", err)
+	}
+
+	var reports []string
+	for _, key := range fake.Keys() {
+		if strings.HasPrefix(key, "reports/") && key != "reports/2021/06/14.json" {
+			reports = append(reports, key)
+		}
+	}
+	if len(reports) != 1 {
+		t.Fatalf("reports = %v, want the report of the upload", reports)
+	}
+	if b, _ := fake.Object(reports[0]); !strings.Contains(string(b), "130.48") {
+		t.Errorf("%s = %s, want the price of AAPL", reports[0], b)
+	}
+	if sent := mail.Sent(); len(sent) != 1 || !strings.Contains(sent[0], "AAPL") {
+		t.Errorf("mails = %q, want one mail of AAPL", sent)
+	}
+}
+
+func TestInvokeAPIGateway(t *testing.T) {
+	t.Setenv("STOCK_API_KEY", "secret")
+	raw, _ := json.Marshal(events.APIGatewayProxyRequest{Headers: map[string]string{"stock-api-key": "wrong"}})
+
+	useConfig(t)
+	// the rejected request is not an error of the function
+	response, err := Invoke(context.Background(), raw)
+	if err != nil {
+		t.Fatalf("Invoke() error = %v, want the bad request response", err)
+	}
+	if r, ok := response.(events.APIGatewayProxyResponse); !ok || r.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invoke() = %#v, want the 400 response of the api gateway", response)
+	}
+}
+
+func TestIsWatchlistKey(t *testing.T) {
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("S3_EVENT_PREFIX", "uploads/")
+
+	tests := map[string]bool{
+		"data/stock.csv":          true,
+		"uploads/stock.csv":       true,
+		"reports/2021/06/14.json": false,
+		"data/other.csv":          false,
+	}
+	useConfig(t)
+	for key, want := range tests {
+		if got := IsWatchlistKey(key); got != want {
+			t.Errorf("IsWatchlistKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// hostTransport is the handlers of the hosts instead of the network, the other hosts are sent by next.
+type hostTransport struct {
+	hosts map[string]http.Handler
+	next  http.RoundTripper
+}
+
+func (h *hostTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	handler, ok := h.hosts[r.URL.Host]
+	if !ok {
+		return h.next.RoundTrip(r)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, r)
+	res := rec.Result()
+	res.Request = r
+	return res, nil
+}
+
+// fakeHost is serve the requests to the host by the handler in the test, e.g. finance.yahoo.com.
+func fakeHost(t *testing.T, host string, handler http.Handler) {
+	t.Helper()
+	prev := http.DefaultTransport
+	http.DefaultTransport = &hostTransport{hosts: map[string]http.Handler{host: handler}, next: prev}
+	// the shared client took http.DefaultTransport at init
+	retry := quotes.HTTPClient.Transport.(*quotes.RetryTransport)
+	prevBase := retry.Base
+	retry.Base = http.DefaultTransport
+	t.Cleanup(func() {
+		http.DefaultTransport = prev
+		retry.Base = prevBase
+	})
+}
+
+// fakeCredentials is the static aws credentials of the test, the requests are signed but not sent to aws.
+func fakeCredentials(t *testing.T) {
+	t.Helper()
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDTEST")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	// the ca bundle is a transport of the sdk instead of http.DefaultTransport
+	t.Setenv("AWS_CA_BUNDLE", "")
+}
+
+// fakeS3 is the objects of the bucket in memory instead of s3 (get, head, put and the list of the rest api).
+// The etag is the md5 of the object like s3, the put of If-Match or If-None-Match is 412 when the etag isn't it.
+type fakeS3 struct {
+	mu      sync.Mutex
+	bucket  string
+	objects map[string][]byte
+	// beforePutIf is called before the conditional put checks the etag, e.g. the write of another request
+	beforePutIf func(key string)
+}
+
+// newFakeS3 is the fake s3 of BUCKET of the test.
+func newFakeS3(t *testing.T, bucket string) *fakeS3 {
+	t.Helper()
+	fakeCredentials(t)
+	t.Setenv("BUCKET", bucket)
+	f := &fakeS3{bucket: bucket, objects: map[string][]byte{}}
+	fakeHost(t, bucket+".s3.ap-northeast-1.amazonaws.com", f)
+	return f
+}
+
+// Object is the object of the key.
+func (f *fakeS3) Object(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.objects[key]
+	return b, ok
+}
+
+// Keys is the keys of the bucket.
+func (f *fakeS3) Keys() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for k := range f.objects {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// etag is the etag of the object of the key, empty when it doesn't exist.
+func (f *fakeS3) etag(key string) string {
+	b, ok := f.Object(key)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf(`"%x"`, md5.Sum(b))
+}
+
+func (f *fakeS3) put(bucket, key string, body io.Reader) {
+	b, _ := io.ReadAll(body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.objects[key] = b
+}
+
+// list is the ListObjectsV2 response of the keys under the prefix, in one page.
+func (f *fakeS3) list(w http.ResponseWriter, prefix string) {
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?><ListBucketResult><Name>%s</Name><Prefix>%s</Prefix><IsTruncated>false</IsTruncated>`, f.bucket, prefix)
+	for _, k := range f.Keys() {
+		if strings.HasPrefix(k, prefix) {
+			fmt.Fprintf(w, `<Contents><Key>%s</Key></Contents>`, k)
+		}
+	}
+	fmt.Fprint(w, `</ListBucketResult>`)
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		match, noneMatch := r.Header.Get("If-Match"), r.Header.Get("If-None-Match")
+		if (match != "" || noneMatch != "") && f.beforePutIf != nil {
+			f.beforePutIf(key)
+		}
+		etag := f.etag(key)
+		if (match != "" && match != etag) || (noneMatch == "*" && etag != "") {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>PreconditionFailed</Code><Message>At least one of the pre-conditions you specified did not hold</Message></Error>`)
+			return
+		}
+		f.put(f.bucket, key, r.Body)
+		w.Header().Set("ETag", f.etag(key))
+	case http.MethodGet, http.MethodHead:
+		if key == "" && r.URL.Query().Get("list-type") == "2" {
+			f.list(w, r.URL.Query().Get("prefix"))
+			return
+		}
+		b, ok := f.Object(key)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			}
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(b)))
+		w.Header().Set("ETag", f.etag(key))
+		if r.Method == http.MethodGet {
+			w.Write(b)
+		}
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
+
+// fakeSES is the sent mails instead of ses (the query api of SendEmail and SendRawEmail).
+type fakeSES struct {
+	mu       sync.Mutex
+	sent     []url.Values
+	verified []string
+}
+
+// newFakeSES is the fake ses of ap-northeast-1 of the test, the mails are to one address.
+func newFakeSES(t *testing.T) *fakeSES {
+	t.Helper()
+	fakeCredentials(t)
+	t.Setenv("MAIL_SENDER_ADDRESS", "sender@example.com")
+	t.Setenv("MAIL_TO_ADDRESS", "to@example.com")
+	f := &fakeSES{}
+	fakeHost(t, "email.ap-northeast-1.amazonaws.com", f)
+	return f
+}
+
+// Sent is the text bodies of the sent mails, the raw messages are not in it.
+func (f *fakeSES) Sent() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var texts []string
+	for _, form := range f.sent {
+		if form.Get("Action") == "SendEmail" {
+			texts = append(texts, form.Get("Message.Body.Text.Data"))
+		}
+	}
+	return texts
+}
+
+func (f *fakeSES) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	action := r.PostForm.Get("Action")
+	if action == "GetIdentityVerificationAttributes" {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		fmt.Fprint(w, `<GetIdentityVerificationAttributesResponse><GetIdentityVerificationAttributesResult><VerificationAttributes>`)
+		for _, id := range f.verified {
+			fmt.Fprintf(w, `<entry><key>%s</key><value><VerificationStatus>Success</VerificationStatus></value></entry>`, id)
+		}
+		fmt.Fprint(w, `</VerificationAttributes></GetIdentityVerificationAttributesResult></GetIdentityVerificationAttributesResponse>`)
+		return
+	}
+	if action != "SendEmail" && action != "SendRawEmail" {
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+		return
+	}
+	f.mu.Lock()
+	f.sent = append(f.sent, r.PostForm)
+	f.mu.Unlock()
+	fmt.Fprintf(w, `<%[1]sResponse><%[1]sResult><MessageId>message-id</MessageId></%[1]sResult></%[1]sResponse>`, action)
+}
+
+// Verify is the ses identities (address or domain) verified.
+func (f *fakeSES) Verify(identities ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.verified = append(f.verified, identities...)
+}
+
+// Subjects is the subjects of the sent mails.
+func (f *fakeSES) Subjects() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var subjects []string
+	for _, form := range f.sent {
+		subjects = append(subjects, form.Get("Message.Subject.Data"))
+	}
+	return subjects
+}
+
+// Raw is the raw messages of the sent mails.
+func (f *fakeSES) Raw() [][]byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var raws [][]byte
+	for _, form := range f.sent {
+		if form.Get("Action") != "SendRawEmail" {
+			continue
+		}
+		b, _ := base64.StdEncoding.DecodeString(form.Get("RawMessage.Data"))
+		raws = append(raws, b)
+	}
+	return raws
+}
+
+// Recipients is the addresses of each sent mail, to, cc and bcc.
+func (f *fakeSES) Recipients() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var recipients [][]string
+	for _, form := range f.sent {
+		var addresses []string
+		for _, prefix := range []string{"Destination.ToAddresses", "Destination.CcAddresses", "Destination.BccAddresses", "Destinations"} {
+			for i := 1; form.Get(fmt.Sprintf("%s.member.%d", prefix, i)) != ""; i++ {
+				addresses = append(addresses, form.Get(fmt.Sprintf("%s.member.%d", prefix, i)))
+			}
+		}
+		recipients = append(recipients, addresses)
+	}
+	return recipients
+}
+
+// HTML is the html bodies of the sent mails.
+func (f *fakeSES) HTML() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var htmls []string
+	for _, form := range f.sent {
+		if form.Get("Action") == "SendEmail" {
+			htmls = append(htmls, form.Get("Message.Body.Html.Data"))
+		}
+	}
+	return htmls
+}
+
+// noRetries is the shared http client without the retries in the test.
+func noRetries(t *testing.T) {
+	t.Helper()
+	t.Setenv("HTTP_RETRY_BUDGET", "0")
+	quotes.ResetRetryBudget()
+}
+
+// quotePages is the quote pages of the prices at finance.yahoo.com, the other symbols are not found.
+func quotePages(t *testing.T, prices map[string]string) {
+	t.Helper()
+	t.Setenv("PRICE_PROVIDER", "yahoo")
+	t.Setenv("FETCH_JITTER", "0")
+	fakeHost(t, "finance.yahoo.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		symbol := strings.TrimPrefix(r.URL.Path, "/quote/")
+		price, ok := prices[symbol]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<html><body><fin-streamer data-symbol="%s" data-field="regularMarketPrice">%s</fin-streamer></body></html>`, symbol, price)
+	}))
+}
+
+// useConfig is the config of the env in the test, as if InitConfig loaded it.
+func useConfig(t *testing.T) {
+	t.Helper()
+	c, _ := LoadConfig()
+	prev, prevErr := config, configErr
+	configOnce.Do(func() {})
+	config, configErr = c, nil
+	t.Cleanup(func() { config, configErr = prev, prevErr })
+}
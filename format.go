@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// formatKey is context key of the response format.
+type formatKey struct{}
+
+// WithFormat is the context of the response format (json, csv or text).
+func WithFormat(ctx context.Context, format string) context.Context {
+	return context.WithValue(ctx, formatKey{}, format)
+}
+
+// Format is the response format of the context, default json.
+func Format(ctx context.Context) string {
+	if format, ok := ctx.Value(formatKey{}).(string); ok && format != "" {
+		return format
+	}
+	return "json"
+}
+
+// RequestFormat is the response format of ?format=json|csv|text, or the Accept header (text/csv or text/plain).
+func RequestFormat(request events.APIGatewayProxyRequest) (string, error) {
+	if format := strings.ToLower(request.QueryStringParameters["format"]); format != "" {
+		switch format {
+		case "json", "csv", "text":
+			return format, nil
+		}
+		return "", fmt.Errorf("unknown format %q, json, csv or text", format)
+	}
+
+	accept := strings.ToLower(HeaderValue(request.Headers, "Accept"))
+	switch {
+	case strings.Contains(accept, "application/json"):
+		return "json", nil
+	case strings.Contains(accept, "text/csv"):
+		return "csv", nil
+	case strings.Contains(accept, "text/plain"):
+		return "text", nil
+	}
+	return "json", nil
+}
+
+// ResultStatus is the status of the result, 207 when some of the symbols failed and 502 when all of them failed.
+func ResultStatus(result portfolio.Result) int {
+	if len(result.Errors) == 0 {
+		return http.StatusOK
+	}
+	if len(result.Errors) >= len(result.Body) {
+		return http.StatusBadGateway
+	}
+	return http.StatusMultiStatus
+}
+
+// ResultResponse is api response of the result in the format of the context, the status is ResultStatus.
+// b is the json body, csv and text are the table of the mail.
+func ResultResponse(ctx context.Context, result portfolio.Result, b []byte) (events.APIGatewayProxyResponse, error) {
+	contentType, body := "application/json", string(b)
+	switch Format(ctx) {
+	case "csv":
+		c, err := report.CSV(result)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		contentType, body = "text/csv; charset=UTF-8", string(c)
+	case "text":
+		contentType, body = "text/plain; charset=UTF-8", report.MailContent(result)
+	}
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: ResultStatus(result),
+		Headers:    map[string]string{"Content-Type": contentType},
+		Body:       body,
+	}, nil
+}
@@ -0,0 +1,430 @@
+// Package graphql is the query subset of graphql over json values: fields, aliases, arguments and variables.
+// Fragments, directives, mutations and subscriptions are not supported.
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Field is a selected field of the query.
+type Field struct {
+	Alias      string
+	Name       string
+	Args       map[string]interface{}
+	Selections []Field
+}
+
+// Key is the key of the field in the response, the alias or the name.
+func (f Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+// Member is a key and value of Object.
+type Member struct {
+	Key   string
+	Value interface{}
+}
+
+// Object is the json object in the order of the selections.
+type Object []Member
+
+// MarshalJSON is the object in its order.
+func (o Object) MarshalJSON() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(m.Key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Error is an error of the response.
+type Error struct {
+	Message string   `json:"message"`
+	Path    []string `json:"path,omitempty"`
+}
+
+// Response is the graphql response, data is null when the query is invalid.
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// Parse is the selections of the query operation, the variables ($name) are replaced by their values.
+func Parse(query string, variables map[string]interface{}) ([]Field, error) {
+	p := &parser{lex: lexer{src: query}, variables: variables}
+	p.next()
+
+	if p.tok.kind == tokName {
+		switch p.tok.text {
+		case "query":
+			p.next()
+			if p.tok.kind == tokName {
+				p.next()
+			}
+			// the variable definitions are not checked, the values are of the request
+			if p.tok.is("(") {
+				if err := p.skipParens(); err != nil {
+					return nil, err
+				}
+			}
+		case "mutation", "subscription":
+			return nil, fmt.Errorf("%s is not supported", p.tok.text)
+		case "fragment":
+			return nil, fmt.Errorf("fragment is not supported")
+		default:
+			return nil, p.unexpected()
+		}
+	}
+
+	fields, err := p.selections()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, fmt.Errorf("only one operation is supported, %s", p.unexpected())
+	}
+	return fields, nil
+}
+
+// Select is the fields of the json value (decoded by encoding/json), lists are selected by each item.
+// A field not in the object is null, e.g. the omitted empty field.
+func Select(v interface{}, fields []Field) (interface{}, error) {
+	switch value := v.(type) {
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i, item := range value {
+			s, err := Select(item, fields)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = s
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := Object{}
+		for _, f := range fields {
+			field, ok := value[f.Name]
+			if !ok {
+				// omitempty field is null
+				obj = append(obj, Member{Key: f.Key()})
+				continue
+			}
+			if len(f.Selections) > 0 {
+				s, err := Select(field, f.Selections)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %s", f.Name, err)
+				}
+				field = s
+			}
+			obj = append(obj, Member{Key: f.Key(), Value: field})
+		}
+		return obj, nil
+	case nil:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("scalar has no fields")
+}
+
+// ToValue is v as the json value for Select.
+func ToValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// parser is the recursive descent parser of the query.
+type parser struct {
+	lex       lexer
+	tok       token
+	variables map[string]interface{}
+	err       error
+}
+
+// next is read the next token.
+func (p *parser) next() {
+	p.tok, p.err = p.lex.next()
+	if p.err != nil {
+		p.tok = token{kind: tokEOF}
+	}
+}
+
+// unexpected is the error of the current token.
+func (p *parser) unexpected() error {
+	if p.err != nil {
+		return p.err
+	}
+	if p.tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of the query")
+	}
+	return fmt.Errorf("unexpected %q at %d", p.tok.text, p.tok.pos)
+}
+
+// expect is read the punctuator.
+func (p *parser) expect(punct string) error {
+	if !p.tok.is(punct) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+// skipParens is skip the balanced parens.
+func (p *parser) skipParens() error {
+	depth := 0
+	for {
+		switch {
+		case p.tok.kind == tokEOF:
+			return p.unexpected()
+		case p.tok.is("("):
+			depth++
+		case p.tok.is(")"):
+			depth--
+		}
+		p.next()
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// selections is { field ... }.
+func (p *parser) selections() ([]Field, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var fields []Field
+	for !p.tok.is("}") {
+		if p.tok.is("...") {
+			return nil, fmt.Errorf("fragment is not supported")
+		}
+		if p.tok.kind != tokName {
+			return nil, p.unexpected()
+		}
+		f := Field{Name: p.tok.text}
+		p.next()
+		if p.tok.is(":") {
+			p.next()
+			if p.tok.kind != tokName {
+				return nil, p.unexpected()
+			}
+			f.Alias, f.Name = f.Name, p.tok.text
+			p.next()
+		}
+		if p.tok.is("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			f.Args = args
+		}
+		if p.tok.is("@") {
+			return nil, fmt.Errorf("directive is not supported")
+		}
+		if p.tok.is("{") {
+			s, err := p.selections()
+			if err != nil {
+				return nil, err
+			}
+			f.Selections = s
+		}
+		fields = append(fields, f)
+	}
+	p.next()
+	return fields, nil
+}
+
+// arguments is (name: value ...).
+func (p *parser) arguments() (map[string]interface{}, error) {
+	p.next()
+	args := map[string]interface{}{}
+	for !p.tok.is(")") {
+		if p.tok.kind != tokName {
+			return nil, p.unexpected()
+		}
+		name := p.tok.text
+		p.next()
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		args[name] = v
+	}
+	p.next()
+	return args, nil
+}
+
+// value is a literal, a list or a variable.
+func (p *parser) value() (interface{}, error) {
+	tok := p.tok
+	switch {
+	case tok.is("$"):
+		p.next()
+		if p.tok.kind != tokName {
+			return nil, p.unexpected()
+		}
+		name := p.tok.text
+		p.next()
+		v, ok := p.variables[name]
+		if !ok {
+			return nil, fmt.Errorf("variable $%s is not set", name)
+		}
+		return v, nil
+	case tok.is("["):
+		p.next()
+		list := []interface{}{}
+		for !p.tok.is("]") {
+			if p.tok.kind == tokEOF {
+				return nil, p.unexpected()
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		p.next()
+		return list, nil
+	case tok.kind == tokString:
+		p.next()
+		return tok.text, nil
+	case tok.kind == tokNumber:
+		p.next()
+		return strconv.ParseFloat(tok.text, 64)
+	case tok.kind == tokName:
+		p.next()
+		switch tok.text {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// enum value
+		return tok.text, nil
+	}
+	return nil, p.unexpected()
+}
+
+// token kinds.
+const (
+	tokEOF = iota
+	tokPunct
+	tokName
+	tokString
+	tokNumber
+)
+
+// token is a token of the query, text of the string is unquoted.
+type token struct {
+	kind int
+	text string
+	pos  int
+}
+
+// is check the token is the punctuator.
+func (t token) is(punct string) bool {
+	return t.kind == tokPunct && t.text == punct
+}
+
+// lexer is the tokens of the query, commas and comments are ignored.
+type lexer struct {
+	src string
+	pos int
+}
+
+// next is the next token.
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			l.pos++
+			continue
+		}
+		if c == '#' {
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+			continue
+		}
+		break
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.src[l.pos]
+	switch {
+	case strings.HasPrefix(l.src[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokPunct, text: "...", pos: start}, nil
+	case strings.IndexByte("{}():[]$!=@", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), pos: start}, nil
+	case c == '"':
+		l.pos++
+		for l.pos < len(l.src) && l.src[l.pos] != '"' {
+			if l.src[l.pos] == '\\' {
+				l.pos++
+			}
+			l.pos++
+		}
+		if l.pos >= len(l.src) {
+			return token{}, fmt.Errorf("unterminated string at %d", start)
+		}
+		l.pos++
+		var s string
+		if err := json.Unmarshal([]byte(l.src[start:l.pos]), &s); err != nil {
+			return token{}, fmt.Errorf("invalid string at %d", start)
+		}
+		return token{kind: tokString, text: s, pos: start}, nil
+	case c == '-' || (c >= '0' && c <= '9'):
+		l.pos++
+		for l.pos < len(l.src) && strings.IndexByte("0123456789.eE+-", l.src[l.pos]) >= 0 {
+			l.pos++
+		}
+		return token{kind: tokNumber, text: l.src[start:l.pos], pos: start}, nil
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		for l.pos < len(l.src) {
+			c := l.src[l.pos]
+			if c != '_' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') {
+				break
+			}
+			l.pos++
+		}
+		return token{kind: tokName, text: l.src[start:l.pos], pos: start}, nil
+	}
+	return token{}, fmt.Errorf("unexpected %q at %d", c, start)
+}
@@ -0,0 +1,129 @@
+package graphql
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query     string
+		variables map[string]interface{}
+		want      []Field
+	}{
+		{"{ totals }", nil, []Field{{Name: "totals"}}},
+		{"query { totals { value profit_loss } }", nil, []Field{{Name: "totals", Selections: []Field{{Name: "value"}, {Name: "profit_loss"}}}}},
+		{
+			"query Portfolio { t: totals { v: value } positions { symble lots { date } } }", nil,
+			[]Field{
+				{Alias: "t", Name: "totals", Selections: []Field{{Alias: "v", Name: "value"}}},
+				{Name: "positions", Selections: []Field{{Name: "symble"}, {Name: "lots", Selections: []Field{{Name: "date"}}}}},
+			},
+		},
+		{
+			`{ quotes(symbols: ["AAPL", "MSFT"]) { symble } history(from: "2021-06-01", to: null) }`, nil,
+			[]Field{
+				{Name: "quotes", Args: map[string]interface{}{"symbols": []interface{}{"AAPL", "MSFT"}}, Selections: []Field{{Name: "symble"}}},
+				{Name: "history", Args: map[string]interface{}{"from": "2021-06-01", "to": nil}},
+			},
+		},
+		{
+			"query Quotes($symbols: [String!]!, $n: Int = 1) { quotes(symbols: $symbols, n: $n, debug: true) }",
+			map[string]interface{}{"symbols": []interface{}{"VOO"}, "n": 2.0},
+			[]Field{{Name: "quotes", Args: map[string]interface{}{"symbols": []interface{}{"VOO"}, "n": 2.0, "debug": true}}},
+		},
+		{"# the comment\n{ a(x: -1.5e2, y: ENUM, s: \"a \\\"b\\\"\"), b }", nil, []Field{{Name: "a", Args: map[string]interface{}{"x": -150.0, "y": "ENUM", "s": `a "b"`}}, {Name: "b"}}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.query, tt.variables)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query     string
+		variables map[string]interface{}
+		want      string
+	}{
+		{"", nil, "unexpected end of the query"},
+		{"{ totals", nil, "unexpected end of the query"},
+		{"{ totals { value }", nil, "unexpected end of the query"},
+		{"{ quotes(symbols: [\"AAPL\" }", nil, `unexpected "}"`},
+		{"{ quotes(symbols: [\"AAPL\"", nil, "unexpected end of the query"},
+		{"{ quotes(symbols: \"AAPL) }", nil, "unterminated string at 18"},
+		{"{ quotes(symbols: \"AAPL\\", nil, "unterminated string at 18"},
+		{"query Q($symbols: [String] { totals }", nil, "unexpected end of the query"},
+		{"{ quotes(symbols: $symbols) }", nil, "variable $symbols is not set"},
+		{"{ totals } { positions }", nil, "only one operation is supported"},
+		{"{ ...Totals } fragment Totals on Query { totals }", nil, "fragment is not supported"},
+		{"fragment Totals on Query { totals }", nil, "fragment is not supported"},
+		{"{ totals @include(if: true) }", nil, "directive is not supported"},
+		{"mutation { trades }", nil, "mutation is not supported"},
+		{"subscription { quotes }", nil, "subscription is not supported"},
+		{"{ totals % }", nil, `unexpected '%' at 9`},
+		{"{ 1 }", nil, `unexpected "1" at 2`},
+	}
+	for _, tt := range tests {
+		done := make(chan error, 1)
+		go func(query string, variables map[string]interface{}) {
+			_, err := Parse(query, variables)
+			done <- err
+		}(tt.query, tt.variables)
+		select {
+		case err := <-done:
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %q", tt.query, err, tt.want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Parse(%q) doesn't return", tt.query)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	var value interface{}
+	if err := json.Unmarshal([]byte(`{"totals": {"value": 1100, "cost": 1000}, "positions": [{"symble": "AAPL", "value": 130, "lots": [{"date": "2021-06-01"}]}, {"symble": "MSFT", "value": 250}], "as_of": "2021-06-14"}`), &value); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"{ as_of }", `{"as_of":"2021-06-14"}`},
+		{"{ totals { cost value } }", `{"totals":{"cost":1000,"value":1100}}`},
+		{"{ t: totals { v: value } date: as_of }", `{"t":{"v":1100},"date":"2021-06-14"}`},
+		{"{ positions { symble lots { date } } }", `{"positions":[{"symble":"AAPL","lots":[{"date":"2021-06-01"}]},{"symble":"MSFT","lots":null}]}`},
+		// the unknown field is null like the omitted empty field
+		{"{ totals { value unknown } missing }", `{"totals":{"value":1100,"unknown":null},"missing":null}`},
+	}
+	for _, tt := range tests {
+		fields, err := Parse(tt.query, nil)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.query, err)
+		}
+		v, err := Select(value, fields)
+		if err != nil {
+			t.Errorf("Select(%q) error = %v", tt.query, err)
+			continue
+		}
+		b, err := json.Marshal(v)
+		if err != nil || string(b) != tt.want {
+			t.Errorf("Select(%q) = %s %v, want %s", tt.query, b, err, tt.want)
+		}
+	}
+
+	fields, _ := Parse("{ as_of { date } }", nil)
+	if _, err := Select(value, fields); err == nil || err.Error() != "as_of: scalar has no fields" {
+		t.Errorf("Select() error = %v, want the scalar error", err)
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/graphql"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// GraphQLRequest is the body of POST /graphql.
+type GraphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables"`
+}
+
+// IsGraphQLRequest is GET or POST /graphql.
+func IsGraphQLRequest(request events.APIGatewayProxyRequest) bool {
+	return path.Base(request.Path) == "graphql"
+}
+
+// graphqlResolvers is the root fields of the schema by name.
+// positions and totals are the stock data priced now, quotes(symbols) are any symbols and history(from, to) is the stored results.
+var graphqlResolvers = map[string]func(ctx context.Context, g *graphqlContext, args map[string]interface{}) (interface{}, error){
+	"positions": func(ctx context.Context, g *graphqlContext, args map[string]interface{}) (interface{}, error) {
+		result, err := g.result(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return result.Body, nil
+	},
+	"totals": func(ctx context.Context, g *graphqlContext, args map[string]interface{}) (interface{}, error) {
+		result, err := g.result(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return portfolio.Summarize(result.Body), nil
+	},
+	"quotes": func(ctx context.Context, g *graphqlContext, args map[string]interface{}) (interface{}, error) {
+		var list []string
+		switch v := args["symbols"].(type) {
+		case string:
+			list = []string{v}
+		case []interface{}:
+			for _, s := range v {
+				list = append(list, fmt.Sprint(s))
+			}
+		}
+		symbols := QuoteSymbols(strings.Join(list, ","))
+		if len(symbols) == 0 {
+			return nil, fmt.Errorf("symbols is required")
+		}
+		if max := QuotesMaxSymbols(); len(symbols) > max {
+			return nil, fmt.Errorf("%d symbols, max %d", len(symbols), max)
+		}
+		provider, err := g.provider()
+		if err != nil {
+			return nil, err
+		}
+		tickers := make([]portfolio.Ticker, len(symbols))
+		for i, s := range symbols {
+			tickers[i] = portfolio.Ticker{Symble: s}
+		}
+		var items []QuoteItem
+		for _, t := range portfolio.FetchPrices(ctx, provider, tickers) {
+			items = append(items, QuoteItem{Symble: t.Symble, Price: t.Value, Currency: t.Currency, AsOf: t.AsOf, Stale: t.Stale, Session: t.Session, Provider: t.Provider, Fundamentals: t.Fundamentals, Error: t.Error})
+		}
+		return items, nil
+	},
+	"history": func(ctx context.Context, g *graphqlContext, args map[string]interface{}) (interface{}, error) {
+		from, _ := args["from"].(string)
+		to, _ := args["to"].(string)
+		start, end, err := HistoryRange(from, to, time.Now().In(reportLocation))
+		if err != nil {
+			return nil, err
+		}
+		cfg := ConfigOf(ctx)
+		if cfg.HistoryTable != "" {
+			return ReadHistory(ctx, cfg.HistoryTable, start, end)
+		}
+		return ReadReports(ctx, cfg.Bucket, cfg.FilePath, start, end)
+	},
+}
+
+// graphqlContext is the provider and the result of the stock data shared by the fields of a query.
+type graphqlContext struct {
+	source quotes.Provider
+	priced *portfolio.Result
+	err    error
+}
+
+// provider is PRICE_PROVIDER of the query.
+func (g *graphqlContext) provider() (quotes.Provider, error) {
+	if g.source == nil {
+		quotes.ResetRetryBudget()
+		provider, err := PriceProvider()
+		if err != nil {
+			return nil, err
+		}
+		g.source = provider
+	}
+	return g.source, nil
+}
+
+// result is the stock data priced once for the query.
+func (g *graphqlContext) result(ctx context.Context) (portfolio.Result, error) {
+	if g.err != nil {
+		return portfolio.Result{}, g.err
+	}
+	if g.priced != nil {
+		return *g.priced, nil
+	}
+
+	cfg := ConfigOf(ctx)
+	data, err := DownloadFile(ctx, cfg.Bucket, cfg.StockData)
+	if err != nil {
+		g.err = err
+		return portfolio.Result{}, err
+	}
+	symbols, parseErrors, err := ParseStockData(ctx, cfg.Bucket, cfg.StockData, data)
+	if err == nil {
+		symbols, err = PrepareSymbols(ctx, symbols)
+	}
+	if err != nil {
+		g.err = err
+		return portfolio.Result{}, err
+	}
+	provider, err := g.provider()
+	if err != nil {
+		g.err = err
+		return portfolio.Result{}, err
+	}
+	t := time.Now().In(reportLocation)
+	result := FetchResult(ctx, provider, symbols, parseErrors, t)
+	g.priced = &result
+	return result, nil
+}
+
+// GraphQLHandler is the graphql query of POST /graphql ({"query", "variables"}) or GET /graphql?query=.
+// The fields are the json keys of the api responses, e.g. { totals { value profit_loss } positions { symble value } }.
+// A failed field is null and in errors, the status is 200 like the other graphql servers.
+func GraphQLHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	var req GraphQLRequest
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		req.Query = request.QueryStringParameters["query"]
+		if v := request.QueryStringParameters["variables"]; v != "" {
+			if err := json.Unmarshal([]byte(v), &req.Variables); err != nil {
+				return ErrorResponse(http.StatusBadRequest, "invalid variables. "+err.Error()), nil
+			}
+		}
+	case http.MethodPost:
+		if err := json.Unmarshal([]byte(request.Body), &req); err != nil {
+			return ErrorResponse(http.StatusBadRequest, "invalid request body. "+err.Error()), nil
+		}
+	default:
+		return ErrorResponse(http.StatusMethodNotAllowed, "method not allowed."), nil
+	}
+
+	response := graphql.Response{}
+	status := http.StatusOK
+	fields, err := graphql.Parse(req.Query, req.Variables)
+	if err != nil {
+		response.Errors = append(response.Errors, graphql.Error{Message: err.Error()})
+		status = http.StatusBadRequest
+	} else {
+		g := &graphqlContext{}
+		data := graphql.Object{}
+		for _, f := range fields {
+			value, err := resolveGraphQL(ctx, g, f)
+			if err != nil {
+				response.Errors = append(response.Errors, graphql.Error{Message: err.Error(), Path: []string{f.Key()}})
+			}
+			data = append(data, graphql.Member{Key: f.Key(), Value: value})
+		}
+		response.Data = data
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
+
+// resolveGraphQL is the selected value of the root field.
+func resolveGraphQL(ctx context.Context, g *graphqlContext, f graphql.Field) (interface{}, error) {
+	resolve, ok := graphqlResolvers[f.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s", f.Name)
+	}
+	v, err := resolve(ctx, g, f.Args)
+	if err != nil {
+		return nil, err
+	}
+	value, err := graphql.ToValue(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Selections) == 0 {
+		return value, nil
+	}
+	return graphql.Select(value, f.Selections)
+}
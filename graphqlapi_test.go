@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+func TestGraphQLHandler(t *testing.T) {
+	quotePages(t, map[string]string{"AAPL": "130.48", "MSFT": "257.89"})
+	noRetries(t)
+	useConfig(t)
+
+	tests := []struct {
+		body string
+		code int
+		want string
+	}{
+		{
+			`{"query": "query Q($s: [String!]!) { q: quotes(symbols: $s) { symble price } }", "variables": {"s": ["AAPL", "MSFT"]}}`,
+			http.StatusOK, `{"data":{"q":[{"symble":"AAPL","price":130.48},{"symble":"MSFT","price":257.89}]}}`,
+		},
+		{
+			`{"query": "{ quotes(symbols: \"AAPL\") { symble unknown } }"}`,
+			http.StatusOK, `{"data":{"quotes":[{"symble":"AAPL","unknown":null}]}}`,
+		},
+		{
+			`{"query": "{ unknown quotes(symbols: []) }"}`,
+			http.StatusOK, `{"data":{"unknown":null,"quotes":null},"errors":[{"message":"unknown field unknown","path":["unknown"]},{"message":"symbols is required","path":["quotes"]}]}`,
+		},
+		{`{"query": "{ ...Totals }"}`, http.StatusBadRequest, `{"data":null,"errors":[{"message":"fragment is not supported"}]}`},
+		{`{"query": "{ quotes(symbols: \"AAPL) }"}`, http.StatusBadRequest, `{"data":null,"errors":[{"message":"unterminated string at 18"}]}`},
+	}
+	for _, tt := range tests {
+		response, err := GraphQLHandler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/graphql", Body: tt.body})
+		if err != nil || response.StatusCode != tt.code || strings.TrimSpace(response.Body) != tt.want {
+			t.Errorf("GraphQLHandler(%s) = %d %s %v, want %d %s", tt.body, response.StatusCode, response.Body, err, tt.code, tt.want)
+		}
+	}
+}
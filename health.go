@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// requiredEnv is environment variables of the report run.
+var requiredEnv = []string{"BUCKET", "S3_STOCK_DATA", "S3_FILE_PATH"}
+
+// defaultHealthSymbol is the test quote of GET /health.
+const defaultHealthSymbol = "AAPL"
+
+// Health is status of the health check, ok or ng with the reason.
+// Storage and Mail are checked by GET /health, the access of S3_STOCK_DATA and the verification of MAIL_SENDER_ADDRESS.
+type Health struct {
+	Status   string   `json:"status"`
+	Config   string   `json:"config"`
+	Missing  []string `json:"missing,omitempty"`
+	Invalid  []string `json:"invalid,omitempty"`
+	Provider string   `json:"provider,omitempty"`
+	Storage  string   `json:"storage,omitempty"`
+	Mail     string   `json:"mail,omitempty"`
+}
+
+// IsHealthRequest is GET /health.
+func IsHealthRequest(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodGet && path.Base(request.Path) == "health"
+}
+
+// HealthSymbol is the symbol of the test quote, ?symbol or HEALTH_SYMBOL, default AAPL.
+func HealthSymbol(symbol string) string {
+	if symbol != "" {
+		return symbol
+	}
+	if s := os.Getenv("HEALTH_SYMBOL"); s != "" {
+		return s
+	}
+	return defaultHealthSymbol
+}
+
+// CheckMailIdentity is check the ses identity of the sender address (or its domain) is verified.
+func CheckMailIdentity(ctx context.Context, sender string) error {
+	if sender == "" {
+		return fmt.Errorf("MAIL_SENDER_ADDRESS is not set")
+	}
+	// the address may be "name <address>"
+	if addr, err := mail.ParseAddress(sender); err == nil {
+		sender = addr.Address
+	}
+	identities := []string{sender}
+	if i := strings.LastIndex(sender, "@"); i >= 0 {
+		identities = append(identities, sender[i+1:])
+	}
+
+	svc, err := awsclient.SES(Region("SES_REGION"))
+	if err != nil {
+		return err
+	}
+	out, err := svc.GetIdentityVerificationAttributesWithContext(ctx, &ses.GetIdentityVerificationAttributesInput{
+		Identities: aws.StringSlice(identities),
+	})
+	if err != nil {
+		return err
+	}
+	for _, id := range identities {
+		if attr, ok := out.VerificationAttributes[id]; ok && aws.StringValue(attr.VerificationStatus) == ses.VerificationStatusSuccess {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not verified", sender)
+}
+
+// CheckConfig is missing environment variables.
+func CheckConfig() []string {
+	env := append([]string{}, requiredEnv...)
+	// the tenants have their own stock data and recipients
+	tenants := os.Getenv("TENANTS_FILE") != ""
+	if tenants {
+		env = []string{"BUCKET", "S3_FILE_PATH"}
+	}
+	channels := NotifyChannels(os.Getenv("NOTIFY_CHANNELS"))
+	if channels["mail"] {
+		if !tenants {
+			env = append(env, "MAIL_TO_ADDRESS")
+		}
+		env = append(env, "MAIL_SENDER_ADDRESS")
+	}
+	if channels["slack"] {
+		env = append(env, "SLACK_WEBHOOK_URL")
+	}
+	if channels["sns"] {
+		env = append(env, "SNS_TOPIC_ARN")
+	}
+	if channels["webhook"] {
+		env = append(env, "NOTIFY_WEBHOOK_URL")
+	}
+
+	var missing []string
+	for _, e := range env {
+		if os.Getenv(e) == "" {
+			missing = append(missing, e)
+		}
+	}
+	return missing
+}
+
+// HealthCheck is validate the config, and scrape the symbol when it is given.
+// deep is check the storage and the mail sender too. It never uploads or sends mail.
+func HealthCheck(ctx context.Context, symbol string, deep bool) events.APIGatewayProxyResponse {
+	health := Health{Config: "ok"}
+	code := http.StatusOK
+
+	health.Missing, health.Invalid = CheckConfig(), InvalidConfig()
+	if len(health.Missing) > 0 || len(health.Invalid) > 0 {
+		health.Config = "ng"
+		code = http.StatusServiceUnavailable
+	}
+
+	if symbol != "" {
+		// the provider of the runs, its fallbacks, routes, cache and request budgets
+		provider, err := PriceProvider()
+		if err != nil {
+			health.Provider = fmt.Sprintf("ng: %s", err)
+			code = http.StatusServiceUnavailable
+		} else if t := portfolio.GetStockPrice(ctx, provider, portfolio.Ticker{Symble: quotes.NormalizeSymbol(symbol)}); t.Priced() {
+			health.Provider = "ok"
+		} else {
+			health.Provider = fmt.Sprintf("ng: %s %s", t.Symble, t.Error)
+			code = http.StatusServiceUnavailable
+		}
+	}
+
+	if deep {
+		cfg := ConfigOf(ctx)
+		// the tenants have the stock data of their own (TENANTS_FILE)
+		if cfg.StockData != "" {
+			health.Storage = "ok"
+			if ok, err := storage.New(cfg.Bucket).Exists(ctx, cfg.StockData); err != nil {
+				health.Storage = fmt.Sprintf("ng: %s", err)
+				code = http.StatusServiceUnavailable
+			} else if !ok {
+				health.Storage = fmt.Sprintf("ng: %s not found", cfg.StockData)
+				code = http.StatusServiceUnavailable
+			}
+		}
+
+		if cfg.Channels["mail"] {
+			if err := CheckMailIdentity(ctx, cfg.MailSender); err != nil {
+				health.Mail = fmt.Sprintf("ng: %s", err)
+				code = http.StatusServiceUnavailable
+			} else {
+				health.Mail = "ok"
+			}
+		}
+	}
+
+	health.Status = "ok"
+	if code != http.StatusOK {
+		health.Status = "ng"
+	}
+	b, _ := json.Marshal(health)
+	return events.APIGatewayProxyResponse{
+		StatusCode: code,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}
+}
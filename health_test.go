@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// setHealthyEnv is the env of a valid config which notifies slack only.
+func setHealthyEnv(t *testing.T) {
+	t.Helper()
+	for k, v := range map[string]string{
+		"BUCKET":            "test-bucket",
+		"S3_STOCK_DATA":     "data/stock.csv",
+		"S3_FILE_PATH":      "stock/2006/01/02.json",
+		"NOTIFY_CHANNELS":   "slack",
+		"SLACK_WEBHOOK_URL": "https://hooks.slack.com/services/T000/B000/XXXX",
+	} {
+		t.Setenv(k, v)
+	}
+}
+
+func healthOf(t *testing.T, symbol string) (int, Health) {
+	t.Helper()
+	response := HealthCheck(context.Background(), symbol, false)
+	var health Health
+	if err := json.Unmarshal([]byte(response.Body), &health); err != nil {
+		t.Fatalf("body %q: %s", response.Body, err)
+	}
+	return response.StatusCode, health
+}
+
+func TestHealthCheckOK(t *testing.T) {
+	setHealthyEnv(t)
+	code, health := healthOf(t, "")
+	if code != http.StatusOK || health.Config != "ok" {
+		t.Errorf("HealthCheck() = %d %+v, want 200 ok", code, health)
+	}
+}
+
+func TestHealthCheckMissingEnv(t *testing.T) {
+	setHealthyEnv(t)
+	t.Setenv("S3_STOCK_DATA", "")
+	code, health := healthOf(t, "")
+	if code != http.StatusServiceUnavailable || health.Config != "ng" {
+		t.Errorf("HealthCheck() = %d %+v, want 503 ng", code, health)
+	}
+	if len(health.Missing) != 1 || health.Missing[0] != "S3_STOCK_DATA" {
+		t.Errorf("Missing = %v, want S3_STOCK_DATA", health.Missing)
+	}
+}
+
+func TestHealthCheckProvider(t *testing.T) {
+	setHealthyEnv(t)
+	quotePages(t, map[string]string{"AAPL": "130.48"})
+
+	if code, health := healthOf(t, "AAPL"); code != http.StatusOK || health.Provider != "ok" {
+		t.Errorf("HealthCheck(AAPL) = %d %+v, want 200 and the provider ok", code, health)
+	}
+	if code, health := healthOf(t, "MSFT"); code != http.StatusServiceUnavailable || health.Provider == "ok" {
+		t.Errorf("HealthCheck(MSFT) = %d %+v, want 503 and the provider ng", code, health)
+	}
+}
+
+func TestHandlerHealthAction(t *testing.T) {
+	setHealthyEnv(t)
+	newFakeS3(t, "test-bucket")
+	t.Setenv("STOCK_API_KEY", "")
+
+	response, err := Handler(context.Background(), events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"action": "health"}})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Handler(health) = %d %v, want 200", response.StatusCode, err)
+	}
+	var health Health
+	if err := json.Unmarshal([]byte(response.Body), &health); err != nil || health.Config != "ok" {
+		t.Errorf("body = %q, want the health", response.Body)
+	}
+}
+
+func TestHandlerHealth(t *testing.T) {
+	setHealthyEnv(t)
+	s3 := newFakeS3(t, "test-bucket")
+	ses := newFakeSES(t)
+	quotePages(t, map[string]string{"AAPL": "130.48"})
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("STOCK_API_KEY", "")
+	request := events.APIGatewayProxyRequest{HTTPMethod: http.MethodGet, Path: "/health"}
+
+	useConfig(t)
+	response, err := Handler(context.Background(), request)
+	var health Health
+	if err := json.Unmarshal([]byte(response.Body), &health); err != nil {
+		t.Fatalf("body %q: %s", response.Body, err)
+	}
+	if err != nil || response.StatusCode != http.StatusServiceUnavailable || health.Storage == "ok" || health.Mail == "ok" {
+		t.Errorf("Handler() = %d %+v %v, want 503 and the storage and mail ng", response.StatusCode, health, err)
+	}
+
+	s3.put("test-bucket", "data/stock.csv", strings.NewReader("AAPL,10,100,0\n"))
+	ses.Verify("example.com")
+	response, err = Handler(context.Background(), request)
+	health = Health{}
+	if err := json.Unmarshal([]byte(response.Body), &health); err != nil {
+		t.Fatalf("body %q: %s", response.Body, err)
+	}
+	if err != nil || response.StatusCode != http.StatusOK || health.Storage != "ok" || health.Mail != "ok" || health.Provider != "ok" {
+		t.Errorf("Handler() = %d %+v %v, want 200 and all ok", response.StatusCode, health, err)
+	}
+	if len(ses.Sent()) != 0 || len(s3.Keys()) != 1 {
+		t.Errorf("Handler() sent %d mails and wrote %v, want none", len(ses.Sent()), s3.Keys())
+	}
+}
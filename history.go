@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// HistoryTotal is symble of the total row in the history table.
+const HistoryTotal = "_TOTAL"
+
+// historyBatchSize is max items of BatchWriteItem.
+const historyBatchSize = 25
+
+// historyRetries is max retry count of the unprocessed items.
+const historyRetries = 3
+
+// historyBackoff is first wait of the unprocessed items retry, it is doubled each retry.
+var historyBackoff = 200 * time.Millisecond
+
+// HistoryItem is a row of the history table, keyed by date (hash) and symble (range).
+// Cost and Earning are in BASE_CURRENCY.
+type HistoryItem struct {
+	Date     string  `dynamodbav:"date" json:"date"`
+	Symble   string  `dynamodbav:"symble" json:"symble"`
+	Bid      float64 `dynamodbav:"bid,omitempty" json:"bid,omitempty"`
+	Value    float64 `dynamodbav:"value,omitempty" json:"value,omitempty"`
+	Hold     float64 `dynamodbav:"hold,omitempty" json:"hold,omitempty"`
+	Dividend float64 `dynamodbav:"dividend,omitempty" json:"dividend,omitempty"`
+	Category string  `dynamodbav:"category,omitempty" json:"category,omitempty"`
+	Cost     float64 `dynamodbav:"cost" json:"cost"`
+	Earning  float64 `dynamodbav:"earning" json:"earning"`
+	Error    string  `dynamodbav:"error,omitempty" json:"error,omitempty"`
+}
+
+// HistoryItems is rows of the tickers.
+func HistoryItems(date string, tickers []portfolio.Ticker) []HistoryItem {
+	var items []HistoryItem
+	for _, t := range tickers {
+		items = append(items, HistoryItem{
+			Date:     date,
+			Symble:   t.Symble,
+			Bid:      t.Bid,
+			Value:    t.Value,
+			Hold:     t.Hold,
+			Dividend: t.Dividend,
+			Category: t.Category,
+			Cost:     t.Bid * t.Hold * t.FX(),
+			Earning:  t.Earning() * t.FX(),
+			Error:    t.Error,
+		})
+	}
+	return items
+}
+
+// HistoryTotalItem is the total row of the summary.
+func HistoryTotalItem(date string, summary portfolio.Summary) HistoryItem {
+	return HistoryItem{
+		Date:     date,
+		Symble:   HistoryTotal,
+		Value:    summary.Value,
+		Dividend: summary.Dividend,
+		Cost:     summary.Cost,
+		Earning:  summary.ProfitLoss,
+	}
+}
+
+// WriteHistory is put the items to the HISTORY_TABLE.
+// Unprocessed items are retried a few times.
+func WriteHistory(ctx context.Context, table string, items []HistoryItem) error {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return err
+	}
+	svc := dynamodb.New(sess)
+
+	for i := 0; i < len(items); i += historyBatchSize {
+		end := i + historyBatchSize
+		if end > len(items) {
+			end = len(items)
+		}
+
+		var requests []*dynamodb.WriteRequest
+		for _, item := range items[i:end] {
+			av, err := dynamodbattribute.MarshalMap(item)
+			if err != nil {
+				return err
+			}
+			requests = append(requests, &dynamodb.WriteRequest{
+				PutRequest: &dynamodb.PutRequest{Item: av},
+			})
+		}
+
+		unprocessed := map[string][]*dynamodb.WriteRequest{table: requests}
+		for retry := 0; len(unprocessed) > 0; retry++ {
+			if retry > historyRetries {
+				return fmt.Errorf("history write error. %d items unprocessed", len(unprocessed[table]))
+			}
+			if retry > 0 {
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(historyBackoff << (retry - 1)):
+				}
+			}
+			out, err := svc.BatchWriteItemWithContext(ctx, &dynamodb.BatchWriteItemInput{RequestItems: unprocessed})
+			if err != nil {
+				return err
+			}
+			unprocessed = out.UnprocessedItems
+		}
+	}
+	return nil
+}
+
+// historyMaxDays is max range of the history request.
+const historyMaxDays = 366
+
+// HistoryRange is parse from/to (YYYY-MM-DD) of the history request.
+// Default is the last 30 days until today.
+func HistoryRange(from, to string, now time.Time) (time.Time, time.Time, error) {
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if to != "" {
+		t, err := time.ParseInLocation("2006-01-02", to, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid to %q", to)
+		}
+		end = t
+	}
+	start := end.AddDate(0, 0, -30)
+	if from != "" {
+		t, err := time.ParseInLocation("2006-01-02", from, now.Location())
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid from %q", from)
+		}
+		start = t
+	}
+
+	if start.After(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("from %s is after to %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	}
+	if end.Sub(start) > historyMaxDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("range is over %d days", historyMaxDays)
+	}
+	return start, end, nil
+}
+
+// ReadHistory is results of the days from the HISTORY_TABLE.
+func ReadHistory(ctx context.Context, table string, from, to time.Time) ([]portfolio.Result, error) {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return nil, err
+	}
+	svc := dynamodb.New(sess)
+
+	var results []portfolio.Result
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		var tickers []portfolio.Ticker
+		var perr error
+		err := svc.QueryPagesWithContext(ctx, &dynamodb.QueryInput{
+			TableName:                aws.String(table),
+			KeyConditionExpression:   aws.String("#d = :d"),
+			ExpressionAttributeNames: map[string]*string{"#d": aws.String("date")},
+			ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+				":d": {S: aws.String(date)},
+			},
+		}, func(out *dynamodb.QueryOutput, last bool) bool {
+			var items []HistoryItem
+			if perr = dynamodbattribute.UnmarshalListOfMaps(out.Items, &items); perr != nil {
+				return false
+			}
+			for _, item := range items {
+				if item.Symble == HistoryTotal {
+					continue
+				}
+				tickers = append(tickers, portfolio.Ticker{
+					Symble:   item.Symble,
+					Bid:      item.Bid,
+					Value:    item.Value,
+					Hold:     item.Hold,
+					Dividend: item.Dividend,
+					Category: item.Category,
+					Error:    item.Error,
+				})
+			}
+			return true
+		})
+		if err != nil {
+			return nil, err
+		}
+		if perr != nil {
+			return nil, perr
+		}
+		if len(tickers) > 0 {
+			results = append(results, portfolio.NewResult(date, tickers))
+		}
+	}
+	return results, nil
+}
+
+// ReadReports is results of the days from the S3_FILE_PATH reports.
+// A report key is read once, and missing keys are skipped.
+func ReadReports(ctx context.Context, bucket, layout string, from, to time.Time) ([]portfolio.Result, error) {
+	seen := map[string]bool{}
+	var results []portfolio.Result
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		key := ReportFilePath(layout, d)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		data, err := DownloadFile(ctx, bucket, key)
+		if err != nil {
+			if errors.Is(err, storage.ErrNoSuchKey) {
+				continue
+			}
+			return nil, err
+		}
+
+		result, err := portfolio.ParseResult(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+		if result.CreatedAt >= from.Format("2006-01-02") && result.CreatedAt <= to.Format("2006-01-02") {
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// HistoryResponse is api response of the history request.
+type HistoryResponse struct {
+	From    string             `json:"from"`
+	To      string             `json:"to"`
+	Results []portfolio.Result `json:"results"`
+}
+
+// HistoryHandler is past results between from and to query params.
+// HISTORY_TABLE is used when it is set, otherwise the reports in s3.
+func HistoryHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	from, to, err := HistoryRange(request.QueryStringParameters["from"], request.QueryStringParameters["to"], time.Now().In(reportLocation))
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+
+	var results []portfolio.Result
+	cfg := ConfigOf(ctx)
+	if table := cfg.HistoryTable; table != "" {
+		results, err = ReadHistory(ctx, table, from, to)
+	} else {
+		results, err = ReadReports(ctx, cfg.Bucket, cfg.FilePath, from, to)
+	}
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	if results == nil {
+		results = []portfolio.Result{}
+	}
+
+	b, err := json.Marshal(HistoryResponse{
+		From:    from.Format("2006-01-02"),
+		To:      to.Format("2006-01-02"),
+		Results: results,
+	})
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
+
+// IsHistoryRequest is GET /history (or ?action=history).
+func IsHistoryRequest(request events.APIGatewayProxyRequest) bool {
+	if request.QueryStringParameters["action"] == "history" {
+		return true
+	}
+	return request.HTTPMethod == http.MethodGet && path.Base(request.Path) == "history"
+}
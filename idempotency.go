@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// forceKey is context key of the forced run.
+type forceKey struct{}
+
+// WithForce is the context of the forced run (?force=true), the run of the day is made again.
+func WithForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// IsForce is check the context is the forced run.
+func IsForce(ctx context.Context) bool {
+	force, _ := ctx.Value(forceKey{}).(bool)
+	return force
+}
+
+// RunMarker is the marker of the run of the day, its response is returned to the repeated runs.
+type RunMarker struct {
+	CreatedAt string `json:"created_at"`
+	Key       string `json:"key"`
+	// Batch is true when the response is BatchResult
+	Batch    bool            `json:"batch,omitempty"`
+	Response json.RawMessage `json:"response"`
+}
+
+// RunMarkerPath is the key of the marker of the day under RUN_MARKER_PREFIX (e.g. runs/2021-06-14.json),
+// under the tenant name for a tenant. Empty when RUN_MARKER_PREFIX is not set.
+func RunMarkerPath(ctx context.Context, t time.Time) string {
+	prefix := os.Getenv("RUN_MARKER_PREFIX")
+	if prefix == "" {
+		return ""
+	}
+	return path.Join(ConfigOf(ctx).Tenant, prefix, t.Format("2006-01-02")+".json")
+}
+
+// LoadRunMarker is the marker of the run of the day, ok is false when the day has no run yet.
+func LoadRunMarker(ctx context.Context, t time.Time) (marker RunMarker, ok bool, err error) {
+	key := RunMarkerPath(ctx, t)
+	if key == "" {
+		return RunMarker{}, false, nil
+	}
+	data, err := storage.New(ConfigOf(ctx).Bucket).Get(ctx, key)
+	if errors.Is(err, storage.ErrNoSuchKey) {
+		return RunMarker{}, false, nil
+	}
+	if err != nil {
+		return RunMarker{}, false, err
+	}
+	if err := json.Unmarshal(data, &marker); err != nil {
+		return RunMarker{}, false, err
+	}
+	return marker, true, nil
+}
+
+// SaveRunMarker is put the marker of the run of the day with its response, after the report is uploaded.
+func SaveRunMarker(ctx context.Context, t time.Time, filePath string, b []byte, batch bool) error {
+	key := RunMarkerPath(ctx, t)
+	if key == "" {
+		return nil
+	}
+	data, err := json.Marshal(RunMarker{CreatedAt: t.Format("2006-01-02"), Key: filePath, Batch: batch, Response: b})
+	if err != nil {
+		return err
+	}
+	return storage.New(ConfigOf(ctx).Bucket).Put(ctx, key, data)
+}
+
+// ReplayRun is the response of the run of the day, nothing is fetched, uploaded or notified again.
+func ReplayRun(ctx context.Context, marker RunMarker) (events.APIGatewayProxyResponse, error) {
+	logging.Info(ctx, "already run today, return the result", logging.Fields{"created_at": marker.CreatedAt, "key": marker.Key})
+
+	var response events.APIGatewayProxyResponse
+	var err error
+	if marker.Batch {
+		var batch BatchResult
+		if err := json.Unmarshal(marker.Response, &batch); err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		response = events.APIGatewayProxyResponse{
+			StatusCode: BatchStatus(batch),
+			Headers:    map[string]string{"Content-Type": "application/json"},
+			Body:       string(marker.Response),
+		}
+	} else {
+		var result portfolio.Result
+		if err := json.Unmarshal(marker.Response, &result); err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if response, err = ResultResponse(ctx, result, marker.Response); err != nil {
+			return response, err
+		}
+	}
+	response.Headers["Idempotent-Replay"] = "true"
+	return response, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// lineNotifyURL is the line notify api.
+const lineNotifyURL = "https://notify-api.line.me/api/notify"
+
+// LineNotifier is send the compact report to line notify.
+type LineNotifier struct {
+	Token string
+}
+
+// Name is channel name.
+func (n *LineNotifier) Name() string {
+	return "line"
+}
+
+// Notify is send the total profit loss and the top movers, the other reports (e.g. alert) are the subject and the text.
+func (n *LineNotifier) Notify(ctx context.Context, r Report) error {
+	if n.Token == "" {
+		return fmt.Errorf("LINE_NOTIFY_TOKEN is not set")
+	}
+	if result, ok := r.Payload.(portfolio.Result); ok && r.Subject == "" {
+		return PostLine(ctx, n.Token, report.LineContent(r.Date, result))
+	}
+	subject := r.Subject
+	if subject == "" {
+		subject = "Stock Profit " + r.Date
+	}
+	return PostLine(ctx, n.Token, report.LineTruncate("\n"+subject+"\n"+r.Text))
+}
+
+// PostLine is post the message to line notify, non 2xx status is an error.
+func PostLine(ctx context.Context, token, message string) error {
+	form := url.Values{"message": {message}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, lineNotifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := quotes.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s error. %d", resp.Request.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
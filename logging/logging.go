@@ -0,0 +1,113 @@
+// Package logging is json lines log for cloudwatch logs insights.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fields is keys of the log line, e.g. symbol, provider and duration_ms.
+type Fields map[string]interface{}
+
+// levels is order of the log levels.
+var levels = map[string]int{"debug": 0, "info": 1, "warn": 2, "error": 3}
+
+// minLevel is LOG_LEVEL, default info.
+var minLevel = func() int {
+	if l, ok := levels[strings.ToLower(os.Getenv("LOG_LEVEL"))]; ok {
+		return l
+	}
+	return levels["info"]
+}()
+
+// mu is keep the lines of the concurrent fetches apart.
+var mu sync.Mutex
+
+// requestIDKey is context key of the request id.
+type requestIDKey struct{}
+
+// WithRequestID is the context with the request id, it is in every line logged with the context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID is the request id of the context.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Since is milliseconds from the start, for duration_ms.
+func Since(start time.Time) int64 {
+	return time.Since(start).Milliseconds()
+}
+
+// Debug is log the debug line.
+func Debug(ctx context.Context, msg string, fields ...Fields) {
+	write(ctx, "debug", msg, fields)
+}
+
+// Info is log the info line.
+func Info(ctx context.Context, msg string, fields ...Fields) {
+	write(ctx, "info", msg, fields)
+}
+
+// Warn is log the warn line.
+func Warn(ctx context.Context, msg string, fields ...Fields) {
+	write(ctx, "warn", msg, fields)
+}
+
+// Error is log the error line.
+func Error(ctx context.Context, msg string, fields ...Fields) {
+	write(ctx, "error", msg, fields)
+}
+
+// Emit is log the info line at any LOG_LEVEL, e.g. embedded metric format.
+func Emit(ctx context.Context, msg string, fields ...Fields) {
+	encode(ctx, "info", msg, fields)
+}
+
+// write is write the line as json to stdout (stderr in the command line), error value is its message.
+func write(ctx context.Context, level, msg string, fields []Fields) {
+	if levels[level] < minLevel {
+		return
+	}
+	encode(ctx, level, msg, fields)
+}
+
+// encode is write the line.
+func encode(ctx context.Context, level, msg string, fields []Fields) {
+	line := map[string]interface{}{}
+	for _, f := range fields {
+		for k, v := range f {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			line[k] = v
+		}
+	}
+	line["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	line["level"] = level
+	line["msg"] = msg
+	if id := RequestID(ctx); id != "" {
+		line["request_id"] = id
+	}
+
+	// provider chain (yahooapi>yahoo) is kept as it is
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(line); err != nil {
+		buf.Reset()
+		enc.Encode(map[string]string{"level": level, "msg": msg, "error": err.Error()})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	os.Stdout.Write(buf.Bytes())
+}
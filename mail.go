@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+)
+
+// sesMaxRecipients is max destinations of a ses message.
+const sesMaxRecipients = 50
+
+// MailDestination is recipients of a mail.
+type MailDestination struct {
+	To  []string
+	Cc  []string
+	Bcc []string
+}
+
+// MailAddresses is parse comma separated addresses.
+func MailAddresses(env string) []string {
+	var addresses []string
+	for _, a := range strings.Split(env, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			addresses = append(addresses, a)
+		}
+	}
+	return addresses
+}
+
+// Chunks is the destination split into the ones of max size recipients, in order of to, cc and bcc.
+func (d MailDestination) Chunks(size int) []MailDestination {
+	var chunks []MailDestination
+	var n int
+	next := func() *MailDestination {
+		if len(chunks) == 0 || n == size {
+			chunks = append(chunks, MailDestination{})
+			n = 0
+		}
+		n++
+		return &chunks[len(chunks)-1]
+	}
+	for _, a := range d.To {
+		c := next()
+		c.To = append(c.To, a)
+	}
+	for _, a := range d.Cc {
+		c := next()
+		c.Cc = append(c.Cc, a)
+	}
+	for _, a := range d.Bcc {
+		c := next()
+		c.Bcc = append(c.Bcc, a)
+	}
+	return chunks
+}
+
+// Attachment is a file attached to the report mail.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+	// ContentID is the inline image of the html body (<img src="cid:...">), empty is attached
+	ContentID string
+}
+
+// RawMessage is make mime multipart mail with the text (and html) body and attachments.
+// Bcc is not in the header.
+func RawMessage(from string, dest MailDestination, subject string, report Report) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := multipart.NewWriter(buf)
+
+	fmt.Fprintf(buf, "From: %s\r\n", from)
+	if len(dest.To) > 0 {
+		fmt.Fprintf(buf, "To: %s\r\n", strings.Join(dest.To, ", "))
+	}
+	if len(dest.Cc) > 0 {
+		fmt.Fprintf(buf, "Cc: %s\r\n", strings.Join(dest.Cc, ", "))
+	}
+	fmt.Fprintf(buf, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(buf, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", w.Boundary())
+
+	// text and html body are alternatives of each other
+	if report.HTML == "" {
+		if err := writeQuotedPrintable(w, "text/plain; charset=UTF-8", report.Text); err != nil {
+			return nil, err
+		}
+	} else {
+		alt := new(bytes.Buffer)
+		aw := multipart.NewWriter(alt)
+		if err := writeQuotedPrintable(aw, "text/plain; charset=UTF-8", report.Text); err != nil {
+			return nil, err
+		}
+		if err := writeHTML(aw, report.HTML, report.Attachments); err != nil {
+			return nil, err
+		}
+		if err := aw.Close(); err != nil {
+			return nil, err
+		}
+
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {fmt.Sprintf("multipart/alternative; boundary=%q", aw.Boundary())},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(alt.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range report.Attachments {
+		if a.ContentID != "" {
+			continue
+		}
+		if err := writeAttachment(w, a); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeHTML is write the html part, with the inline images of the attachments it is a multipart/related part.
+func writeHTML(w *multipart.Writer, html string, attachments []Attachment) error {
+	var inline []Attachment
+	for _, a := range attachments {
+		if a.ContentID != "" {
+			inline = append(inline, a)
+		}
+	}
+	if len(inline) == 0 {
+		return writeQuotedPrintable(w, "text/html; charset=UTF-8", html)
+	}
+
+	related := new(bytes.Buffer)
+	rw := multipart.NewWriter(related)
+	if err := writeQuotedPrintable(rw, "text/html; charset=UTF-8", html); err != nil {
+		return err
+	}
+	for _, a := range inline {
+		if err := writeAttachment(rw, a); err != nil {
+			return err
+		}
+	}
+	if err := rw.Close(); err != nil {
+		return err
+	}
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {fmt.Sprintf("multipart/related; boundary=%q", rw.Boundary())},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = part.Write(related.Bytes())
+	return err
+}
+
+// writeAttachment is write the attachment as a base64 part, inline when it has the content id.
+func writeAttachment(w *multipart.Writer, a Attachment) error {
+	header := textproto.MIMEHeader{
+		"Content-Type":              {a.ContentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+	}
+	if a.ContentID != "" {
+		header.Set("Content-ID", "<"+a.ContentID+">")
+		header.Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": a.Filename}))
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	// base64 lines are wrapped at 76 characters
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > 76 {
+		fmt.Fprintf(part, "%s\r\n", encoded[:76])
+		encoded = encoded[76:]
+	}
+	_, err = fmt.Fprintf(part, "%s\r\n", encoded)
+	return err
+}
+
+// writeQuotedPrintable is write the content as a quoted-printable part.
+func writeQuotedPrintable(w *multipart.Writer, contentType, content string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ses"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestNotifyMailSubject(t *testing.T) {
+	mail := newFakeSES(t)
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("MAIL_SUBJECT", "Stock P/L {{.Date}}: {{.Total}}")
+	useConfig(t)
+
+	summary := portfolio.Summarize([]portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 120, Hold: 10}})
+	Notify(context.Background(), Report{Date: "2021-06-14", Text: "body", Summary: summary})
+	if got := mail.Subjects(); len(got) != 1 || got[0] != "Stock P/L 2021-06-14: 200.00" {
+		t.Errorf("subjects = %q, want the rendered subject", got)
+	}
+}
+
+// shortMailBackoff is the backoff of the mail retry in the test.
+func shortMailBackoff(t *testing.T) {
+	t.Helper()
+	prev := mailBackoff
+	mailBackoff = time.Millisecond
+	t.Cleanup(func() { mailBackoff = prev })
+}
+
+// failingSend is the send of the mail, errs are returned in order before it succeeds.
+func failingSend(calls *int, errs ...error) func() error {
+	return func() error {
+		*calls++
+		if len(errs) > 0 {
+			err := errs[0]
+			errs = errs[1:]
+			return err
+		}
+		return nil
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	shortMailBackoff(t)
+	throttling := awserr.New("Throttling", "Maximum sending rate exceeded.", nil)
+
+	calls := 0
+	if err := SendWithRetry(context.Background(), failingSend(&calls, throttling, throttling)); err != nil || calls != 3 {
+		t.Errorf("SendWithRetry() = %v after %d calls, want sent after 2 throttled", err, calls)
+	}
+
+	calls = 0
+	var errs []error
+	for i := 0; i <= mailRetries; i++ {
+		errs = append(errs, throttling)
+	}
+	if err := SendWithRetry(context.Background(), failingSend(&calls, errs...)); err == nil || calls != mailRetries+1 {
+		t.Errorf("SendWithRetry() = %v after %d calls, want the throttling error after %d", err, calls, mailRetries+1)
+	}
+
+	// the rejected mail is not retried
+	calls = 0
+	if err := SendWithRetry(context.Background(), failingSend(&calls, awserr.New(ses.ErrCodeMessageRejected, "Email address is not verified.", nil))); err == nil || calls != 1 {
+		t.Errorf("SendWithRetry() = %v after %d calls, want the rejected error at once", err, calls)
+	}
+}
+
+func TestIsRetryableMailError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{awserr.New("Throttling", "rate exceeded", nil), true},
+		{awserr.New("ThrottlingException", "rate exceeded", nil), true},
+		{awserr.NewRequestFailure(awserr.New("InternalFailure", "internal error", nil), 503, "id"), true},
+		{awserr.NewRequestFailure(awserr.New("InvalidParameterValue", "invalid", nil), 400, "id"), false},
+		{awserr.New(ses.ErrCodeMessageRejected, "rejected", nil), false},
+		{errors.New("connection reset"), false},
+	}
+	for _, tt := range tests {
+		if got := IsRetryableMailError(tt.err); got != tt.want {
+			t.Errorf("IsRetryableMailError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func attachmentsOf(t *testing.T, raw []byte) map[string][]byte {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("raw mail: %s", err)
+	}
+	_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("raw mail content type: %s", err)
+	}
+	attachments := map[string][]byte{}
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return attachments
+		}
+		if err != nil {
+			t.Fatalf("raw mail part: %s", err)
+		}
+		if part.FileName() == "" {
+			continue
+		}
+		b, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, part))
+		if err != nil {
+			t.Fatalf("%s: %s", part.FileName(), err)
+		}
+		attachments[part.FileName()] = b
+	}
+}
+
+func TestSenderMailJSONAttachment(t *testing.T) {
+	mail := newFakeSES(t)
+	// longer than a base64 line
+	data := []byte(`{"created_at":"2021-06-14","body":[{"symble":"AAPL","bid":120,"value":130.48,"hold":10}]}`)
+
+	useConfig(t)
+	err := SenderMail(context.Background(), "subject", Report{Text: "body", Attachments: []Attachment{
+		{Filename: "stock-profit-2021-06-14.json", ContentType: "application/json", Data: data},
+	}})
+	if err != nil {
+		t.Fatalf("SenderMail() error = %v", err)
+	}
+	raws := mail.Raw()
+	if len(mail.Sent()) != 0 || len(raws) != 1 {
+		t.Fatalf("mails = %d, raw mails = %d, want one raw mail", len(mail.Sent()), len(raws))
+	}
+	if !strings.Contains(string(raws[0]), "To: to@example.com\r\n") {
+		t.Errorf("the address is not in\n%s", raws[0])
+	}
+	got := attachmentsOf(t, raws[0])
+	if !bytes.Equal(got["stock-profit-2021-06-14.json"], data) {
+		t.Errorf("attachments = %q, want the json", got)
+	}
+	if !strings.Contains(string(raws[0]), "Content-Type: application/json") {
+		t.Errorf("the content type of the json is not in\n%s", raws[0])
+	}
+}
+
+func TestSenderMailWithoutAttachment(t *testing.T) {
+	mail := newFakeSES(t)
+	useConfig(t)
+	if err := SenderMail(context.Background(), "subject", Report{Text: "body"}); err != nil {
+		t.Fatalf("SenderMail() error = %v", err)
+	}
+	if sent := mail.Sent(); len(sent) != 1 || sent[0] != "body" || len(mail.Raw()) != 0 {
+		t.Errorf("mails = %q, want the one text mail", sent)
+	}
+}
+
+func TestRunAttachJSON(t *testing.T) {
+	quotePages(t, map[string]string{"AAPL": "130.48"})
+	fake := newFakeS3(t, "test-bucket")
+	mail := newFakeSES(t)
+	t.Setenv("NOTIFY_CHANNELS", "mail")
+	t.Setenv("ATTACH_JSON", "true")
+	t.Setenv("S3_FILE_PATH", "stock/report.json")
+	t.Setenv("OUTPUT_FORMATS", "")
+	t.Setenv("BATCH_SIZE", "")
+
+	useConfig(t)
+	if _, err := Run(context.Background(), []portfolio.Ticker{{Symble: "AAPL", Bid: 120, Hold: 10}}, nil); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	raws := mail.Raw()
+	if len(raws) != 1 {
+		t.Fatalf("raw mails = %d, want one", len(raws))
+	}
+	var attached []byte
+	for name, b := range attachmentsOf(t, raws[0]) {
+		if strings.HasPrefix(name, "stock-profit-") && strings.HasSuffix(name, ".json") {
+			attached = b
+		}
+	}
+	var got portfolio.Result
+	if err := json.Unmarshal(attached, &got); err != nil {
+		t.Fatalf("attachment %q: %s", attached, err)
+	}
+	if len(got.Body) != 1 || got.Body[0].Symble != "AAPL" || got.Body[0].Value != 130.48 {
+		t.Errorf("attachment = %+v, want the result of AAPL", got.Body)
+	}
+	if uploaded, ok := fake.Object("stock/report.json"); !ok || !bytes.Equal(uploaded, attached) {
+		t.Errorf("the attachment is not the uploaded report (%v)\n%s\n%s", fake.Keys(), uploaded, attached)
+	}
+}
+
+func TestSenderMailHTML(t *testing.T) {
+	mail := newFakeSES(t)
+	useConfig(t)
+	if err := SenderMail(context.Background(), "subject", Report{Text: "body", HTML: "<p>body</p>"}); err != nil {
+		t.Fatalf("SenderMail() error = %v", err)
+	}
+	if sent, html := mail.Sent(), mail.HTML(); len(sent) != 1 || sent[0] != "body" || html[0] != "<p>body</p>" {
+		t.Errorf("mails = %q %q, want the text and the html body", sent, html)
+	}
+
+	// the html is an alternative of the text in the raw mail
+	err := SenderMail(context.Background(), "subject", Report{Text: "body", HTML: "<p>body</p>", Attachments: []Attachment{
+		{Filename: "report.json", ContentType: "application/json", Data: []byte("{}")},
+	}})
+	if err != nil {
+		t.Fatalf("SenderMail() error = %v", err)
+	}
+	raws := mail.Raw()
+	if len(raws) != 1 || !strings.Contains(string(raws[0]), "multipart/alternative") || !strings.Contains(string(raws[0]), "<p>body</p>") {
+		t.Errorf("raw mails = %q, want the alternative html", raws)
+	}
+}
+
+func TestMailDestinationChunks(t *testing.T) {
+	if got := MailAddresses(" a@example.com, ,b@example.com "); strings.Join(got, ",") != "a@example.com,b@example.com" {
+		t.Errorf("MailAddresses() = %q, want the two addresses", got)
+	}
+
+	chunks := MailDestination{To: []string{"t1", "t2"}, Cc: []string{"c1"}, Bcc: []string{"b1", "b2"}}.Chunks(2)
+	want := []MailDestination{{To: []string{"t1", "t2"}}, {Cc: []string{"c1"}, Bcc: []string{"b1"}}, {Bcc: []string{"b2"}}}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("Chunks(2) = %+v, want %+v", chunks, want)
+	}
+}
+
+func TestSenderMailRecipients(t *testing.T) {
+	mail := newFakeSES(t)
+	var to []string
+	for i := 1; i <= 55; i++ {
+		to = append(to, fmt.Sprintf("to%02d@example.com", i))
+	}
+	t.Setenv("MAIL_TO_ADDRESS", strings.Join(to, ","))
+	t.Setenv("MAIL_CC_ADDRESS", "cc@example.com")
+	t.Setenv("MAIL_BCC_ADDRESS", "bcc@example.com")
+	useConfig(t)
+
+	// 57 recipients are 2 mails of the ses limit
+	if err := SenderMail(context.Background(), "subject", Report{Text: "body"}); err != nil {
+		t.Fatalf("SenderMail() error = %v", err)
+	}
+	recipients := mail.Recipients()
+	if len(recipients) != 2 || len(recipients[0]) != 50 || len(recipients[1]) != 7 {
+		t.Fatalf("recipients = %q, want 50 and 7 of 2 mails", recipients)
+	}
+	if last := recipients[1]; last[5] != "cc@example.com" || last[6] != "bcc@example.com" {
+		t.Errorf("recipients of the last mail = %q, want cc and bcc after to", last)
+	}
+
+	// bcc is a destination of the raw mail, not the header
+	raw, err := RawMessage("sender@example.com", MailDestination{To: []string{"to@example.com"}, Bcc: []string{"bcc@example.com"}}, "subject", Report{Text: "body"})
+	if err != nil {
+		t.Fatalf("RawMessage() error = %v", err)
+	}
+	if strings.Contains(string(raw), "bcc@example.com") || !strings.Contains(string(raw), "To: to@example.com\r\n") {
+		t.Errorf("RawMessage() header is\n%s", raw)
+	}
+}
+
+func TestSenderMailConfigOf(t *testing.T) {
+	mail := newFakeSES(t)
+	useConfig(t)
+	cfg := ConfigOf(context.Background())
+	cfg.MailSender, cfg.MailTo = "tenant@example.com", []string{"alice@example.com"}
+
+	if err := SenderMail(WithConfig(context.Background(), cfg), "subject", Report{Text: "body"}); err != nil {
+		t.Fatalf("SenderMail() error = %v", err)
+	}
+	if len(mail.sent) != 1 || mail.sent[0].Get("Source") != "tenant@example.com" {
+		t.Fatalf("sent = %v, want the mail from the sender of the context", mail.sent)
+	}
+	if recipients := mail.Recipients(); len(recipients[0]) != 1 || recipients[0][0] != "alice@example.com" {
+		t.Errorf("recipients = %q, want alice@example.com", recipients)
+	}
+}
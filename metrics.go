@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// Metric is a cloudwatch metric of the run.
+type Metric struct {
+	Name  string  `json:"Name"`
+	Unit  string  `json:"Unit"`
+	Value float64 `json:"-"`
+}
+
+// RunMetrics is metrics of the run, totals of the summary, failed symbols and fetch latency.
+func RunMetrics(summary portfolio.Summary, fetch time.Duration) []Metric {
+	return []Metric{
+		{Name: "TotalValue", Unit: "None", Value: summary.Value},
+		{Name: "ProfitLoss", Unit: "None", Value: summary.ProfitLoss},
+		{Name: "Symbols", Unit: "Count", Value: float64(summary.Count)},
+		{Name: "FailedSymbols", Unit: "Count", Value: float64(summary.Count - summary.Priced)},
+		{Name: "FetchLatency", Unit: "Milliseconds", Value: float64(fetch.Milliseconds())},
+	}
+}
+
+// NotifyMetrics is failed notifications of the run, all of the channels and the mail (after the retries).
+func NotifyMetrics(errs []NotifyError) []Metric {
+	var mail int
+	for _, e := range errs {
+		if e.Channel == "mail" {
+			mail++
+		}
+	}
+	return []Metric{
+		{Name: "NotifyFailures", Unit: "Count", Value: float64(len(errs))},
+		{Name: "MailFailures", Unit: "Count", Value: float64(mail)},
+	}
+}
+
+// EmitMetrics is log the metrics in cloudwatch embedded metric format, to METRICS_NAMESPACE.
+// Nothing is logged when METRICS_NAMESPACE is not set.
+func EmitMetrics(ctx context.Context, metrics []Metric) {
+	namespace := os.Getenv("METRICS_NAMESPACE")
+	if namespace == "" {
+		return
+	}
+
+	fields := logging.Fields{
+		"_aws": map[string]interface{}{
+			"Timestamp": time.Now().UnixNano() / int64(time.Millisecond),
+			"CloudWatchMetrics": []map[string]interface{}{{
+				"Namespace":  namespace,
+				"Dimensions": [][]string{{}},
+				"Metrics":    metrics,
+			}},
+		},
+	}
+	for _, m := range metrics {
+		fields[m.Name] = m.Value
+	}
+	logging.Emit(ctx, "metrics", fields)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// Report is contents of the notification.
+type Report struct {
+	Date string
+	// Subject is used instead of MAIL_SUBJECT and the summary, e.g. alert
+	Subject     string
+	Text        string
+	HTML        string
+	Summary     portfolio.Summary
+	Attachments []Attachment
+	// Payload is published as json by sns and webhook (Result or BatchResult)
+	Payload interface{}
+	// Key is the report of the payload in BUCKET, sns publishes it instead of the large payload
+	Key string
+}
+
+// Notifier is a channel of the report notification.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, report Report) error
+}
+
+// NotifyError is a failed notification.
+type NotifyError struct {
+	Channel string `json:"channel"`
+	Error   string `json:"error"`
+}
+
+// notifiers is notifier constructors by NOTIFY_CHANNELS name.
+var notifiers = map[string]func() Notifier{
+	"discord": func() Notifier { return &DiscordNotifier{URL: os.Getenv("DISCORD_WEBHOOK_URL")} },
+	"line":    func() Notifier { return &LineNotifier{Token: os.Getenv("LINE_NOTIFY_TOKEN")} },
+	"mail":    func() Notifier { return &MailNotifier{} },
+	"slack":   func() Notifier { return &SlackNotifier{URL: os.Getenv("SLACK_WEBHOOK_URL")} },
+	"sns":     func() Notifier { return &SNSNotifier{TopicArn: os.Getenv("SNS_TOPIC_ARN")} },
+	"telegram": func() Notifier {
+		return &TelegramNotifier{Token: os.Getenv("TELEGRAM_BOT_TOKEN"), ChatID: os.Getenv("TELEGRAM_CHAT_ID")}
+	},
+	"webhook": func() Notifier { return &WebhookNotifier{URL: os.Getenv("NOTIFY_WEBHOOK_URL")} },
+}
+
+// NotifyChannels is parse NOTIFY_CHANNELS (e.g. "mail,slack,sns,webhook,line,telegram,discord").
+// Default is mail, and the other channels whose destination is set.
+func NotifyChannels(env string) map[string]bool {
+	channels := map[string]bool{}
+	if env == "" {
+		channels["mail"] = true
+		channels["slack"] = os.Getenv("SLACK_WEBHOOK_URL") != ""
+		channels["sns"] = os.Getenv("SNS_TOPIC_ARN") != ""
+		channels["webhook"] = os.Getenv("NOTIFY_WEBHOOK_URL") != ""
+		channels["line"] = os.Getenv("LINE_NOTIFY_TOKEN") != ""
+		channels["discord"] = os.Getenv("DISCORD_WEBHOOK_URL") != ""
+		channels["telegram"] = os.Getenv("TELEGRAM_BOT_TOKEN") != "" && os.Getenv("TELEGRAM_CHAT_ID") != ""
+		return channels
+	}
+	for _, c := range strings.Split(env, ",") {
+		channels[strings.ToLower(strings.TrimSpace(c))] = true
+	}
+	return channels
+}
+
+// Notifiers is the notifiers of the channels, unknown channel is logged.
+func Notifiers(channels map[string]bool) []Notifier {
+	var names []string
+	for name, ok := range channels {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var list []Notifier
+	for _, name := range names {
+		n, ok := notifiers[name]
+		if !ok {
+			logging.Warn(context.Background(), "unknown notify channel", logging.Fields{"channel": name})
+			continue
+		}
+		list = append(list, n())
+	}
+	return list
+}
+
+// Notify is send the report to NOTIFY_CHANNELS.
+// A failed channel doesn't stop the others, failures are logged and returned. Nothing is sent while SuppressReason is set.
+func Notify(ctx context.Context, report Report) []NotifyError {
+	// the notifications are suppressed in the quiet hours and the vacation, the report is still stored
+	if reason := SuppressReason(ctx, time.Now().In(reportLocation)); reason != "" {
+		logging.Info(ctx, "notification suppressed", logging.Fields{"reason": reason, "subject": report.Subject, "date": report.Date})
+		for _, n := range Notifiers(NotifyChannels(os.Getenv("NOTIFY_CHANNELS"))) {
+			RecordNotify(ctx, n.Name(), report.Subject, portfolio.NotifySuppressed, nil)
+		}
+		return nil
+	}
+	var errs []NotifyError
+	for _, n := range Notifiers(NotifyChannels(os.Getenv("NOTIFY_CHANNELS"))) {
+		if err := n.Notify(ctx, report); err != nil {
+			logging.Error(ctx, "notify error", logging.Fields{"channel": n.Name(), "error": err})
+			errs = append(errs, NotifyError{Channel: n.Name(), Error: err.Error()})
+			RecordNotify(ctx, n.Name(), report.Subject, portfolio.NotifyFailed, err)
+			continue
+		}
+		RecordNotify(ctx, n.Name(), report.Subject, portfolio.NotifySent, nil)
+	}
+	return errs
+}
+
+// MailNotifier is report mail by ses.
+type MailNotifier struct{}
+
+// Name is channel name.
+func (n *MailNotifier) Name() string {
+	return "mail"
+}
+
+// Notify is send the report mail.
+func (n *MailNotifier) Notify(ctx context.Context, r Report) error {
+	if r.Subject != "" {
+		return SenderMail(ctx, r.Subject, r)
+	}
+	subject := report.MailSubject(os.Getenv("MAIL_SUBJECT"), r.Date, r.Summary)
+	return SenderMail(ctx, subject, r)
+}
+
+// WebhookNotifier is post the payload json to the url.
+type WebhookNotifier struct {
+	URL string
+}
+
+// Name is channel name.
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+// Notify is post the payload.
+func (n *WebhookNotifier) Notify(ctx context.Context, report Report) error {
+	if n.URL == "" {
+		return fmt.Errorf("NOTIFY_WEBHOOK_URL is not set")
+	}
+	return PostJSON(ctx, n.URL, report.Payload)
+}
+
+// PostJSON is post the value as json, non 2xx status is an error.
+func PostJSON(ctx context.Context, url string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, url, b, nil)
+}
+
+// postJSON is post the json body with the headers.
+func postJSON(ctx context.Context, url string, b []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := quotes.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s error. %d", resp.Request.URL.Host, resp.StatusCode)
+	}
+	return nil
+}
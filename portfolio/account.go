@@ -0,0 +1,56 @@
+package portfolio
+
+import (
+	"sort"
+)
+
+// AccountSubtotal is the totals of the positions of an account (e.g. NISA, taxable, IRA), in BASE_CURRENCY.
+type AccountSubtotal struct {
+	Account      string  `json:"account"`
+	Count        int     `json:"count"`
+	Cost         float64 `json:"cost"`
+	Value        float64 `json:"value"`
+	UnpricedCost float64 `json:"unpriced_cost,omitempty"`
+	ProfitLoss   float64 `json:"profit_loss"`
+	Percent      float64 `json:"percent"`
+	Dividend     float64 `json:"dividend,omitempty"`
+	Realized     float64 `json:"realized,omitempty"`
+}
+
+// AccountSubtotals is the subtotals by account sorted by name, the positions without the account are Other (last).
+// It is nil when no ticker has the account.
+func AccountSubtotals(tickers []Ticker) []AccountSubtotal {
+	groups := map[string][]Ticker{}
+	var names []string
+	for _, t := range tickers {
+		name := t.Group("account")
+		if _, ok := groups[name]; !ok && name != OtherCategory {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], t)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	if _, ok := groups[OtherCategory]; ok {
+		names = append(names, OtherCategory)
+	}
+
+	subtotals := make([]AccountSubtotal, len(names))
+	for i, name := range names {
+		s := Summarize(groups[name])
+		subtotals[i] = AccountSubtotal{
+			Account:      name,
+			Count:        s.Count,
+			Cost:         s.Cost,
+			Value:        s.Value,
+			UnpricedCost: s.UnpricedCost,
+			ProfitLoss:   s.ProfitLoss,
+			Percent:      s.Percent(),
+			Dividend:     s.Dividend,
+			Realized:     s.Realized,
+		}
+	}
+	return subtotals
+}
@@ -0,0 +1,41 @@
+package portfolio
+
+import (
+	"testing"
+)
+
+func TestParseCSVAccount(t *testing.T) {
+	tickers, errs := ParseCSV([]byte("AAPL,100,110,10,0,Tech,USD,0,0,NISA\nMSFT,200,0,5\n"))
+	if len(errs) != 0 || len(tickers) != 2 {
+		t.Fatalf("ParseCSV() = %d tickers %v, want 2", len(tickers), errs)
+	}
+	if tickers[0].Account != "NISA" || tickers[1].Account != "" {
+		t.Errorf("accounts = %q %q, want NISA and none", tickers[0].Account, tickers[1].Account)
+	}
+}
+
+func TestAccountSubtotals(t *testing.T) {
+	if got := AccountSubtotals([]Ticker{{Symble: "AAPL", Bid: 100, Value: 110, Hold: 1}}); got != nil {
+		t.Errorf("AccountSubtotals() = %+v, want nil without the accounts", got)
+	}
+
+	got := AccountSubtotals([]Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 110, Hold: 1, Account: "taxable"},
+		{Symble: "MSFT", Bid: 200, Value: 180, Hold: 1},
+		{Symble: "VOO", Bid: 300, Value: 330, Hold: 2, Account: "NISA"},
+		{Symble: "VTI", Bid: 100, Value: 120, Hold: 1, Account: "NISA"},
+	})
+	want := []struct {
+		account     string
+		count       int
+		cost, value float64
+	}{{"NISA", 2, 700, 780}, {"taxable", 1, 100, 110}, {OtherCategory, 1, 200, 180}}
+	if len(got) != len(want) {
+		t.Fatalf("AccountSubtotals() = %+v, want %d accounts", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].Account != w.account || got[i].Count != w.count || got[i].Cost != w.cost || got[i].Value != w.value {
+			t.Errorf("AccountSubtotals()[%d] = %+v, want %s %d %v %v", i, got[i], w.account, w.count, w.cost, w.value)
+		}
+	}
+}
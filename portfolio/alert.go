@@ -0,0 +1,55 @@
+package portfolio
+
+// AlertBuy is the alert of the watch-only symbol under its target buy price.
+const AlertBuy = "buy"
+
+// BuyTarget is the target buy price of the watch-only ticker, target_price or the bid of the row without it.
+// It is zero for the held position.
+func (t Ticker) BuyTarget() float64 {
+	if !t.WatchOnly() {
+		return 0
+	}
+	if t.TargetPrice > 0 {
+		return t.TargetPrice
+	}
+	return t.Bid
+}
+
+// InBuyZone is true when the price of the watch-only ticker is at or under its target buy price.
+func (t Ticker) InBuyZone() bool {
+	target := t.BuyTarget()
+	return t.Priced() && target > 0 && t.Value <= target
+}
+
+// CheckAlerts is flag the tickers whose price crossed alert_high or alert_low, or the watch-only ones in the buy zone, and return them.
+func CheckAlerts(tickers []Ticker) []Ticker {
+	var alerts []Ticker
+	for i, t := range tickers {
+		if !t.Priced() {
+			continue
+		}
+		switch {
+		case t.InBuyZone():
+			tickers[i].Alert = AlertBuy
+		case t.AlertHigh > 0 && t.Value >= t.AlertHigh:
+			tickers[i].Alert = "high"
+		case t.AlertLow > 0 && t.Value <= t.AlertLow:
+			tickers[i].Alert = "low"
+		default:
+			continue
+		}
+		alerts = append(alerts, tickers[i])
+	}
+	return alerts
+}
+
+// BuyZone is the watch-only tickers in the buy zone.
+func BuyZone(tickers []Ticker) []Ticker {
+	var zone []Ticker
+	for _, t := range tickers {
+		if t.InBuyZone() {
+			zone = append(zone, t)
+		}
+	}
+	return zone
+}
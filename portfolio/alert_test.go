@@ -0,0 +1,33 @@
+package portfolio
+
+import (
+	"testing"
+)
+
+func TestCheckAlerts(t *testing.T) {
+	tickers := []Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 130, Hold: 10, AlertHigh: 130},
+		{Symble: "MSFT", Bid: 200, Value: 150, Hold: 5, AlertHigh: 300, AlertLow: 160},
+		{Symble: "GOOG", Bid: 100, Value: 110, Hold: 1, AlertHigh: 120, AlertLow: 90},
+		{Symble: "XXXX", Bid: 50, Hold: 2, AlertLow: 60},
+	}
+	alerts := CheckAlerts(tickers)
+	if len(alerts) != 2 || alerts[0].Symble != "AAPL" || alerts[1].Symble != "MSFT" {
+		t.Fatalf("CheckAlerts() = %+v, want AAPL and MSFT", alerts)
+	}
+	for i, want := range []string{"high", "low", "", ""} {
+		if tickers[i].Alert != want {
+			t.Errorf("%s: Alert = %q, want %q", tickers[i].Symble, tickers[i].Alert, want)
+		}
+	}
+}
+
+func TestParseCSVAlerts(t *testing.T) {
+	tickers, errs := ParseCSV([]byte("AAPL,100,0,10,0,Tech,USD,150,90\nMSFT,200,0,5,0,Tech,USD,250\nGOOG,100,0,1,0,Tech,USD,high\n"))
+	if len(tickers) != 2 || len(errs) != 1 || errs[0].Line != 3 {
+		t.Fatalf("ParseCSV() = %+v, %+v, want 2 tickers and the error of line 3", tickers, errs)
+	}
+	if tickers[0].AlertHigh != 150 || tickers[0].AlertLow != 90 || tickers[1].AlertHigh != 250 || tickers[1].AlertLow != 0 {
+		t.Errorf("alerts = %+v, want 150/90 and 250/0", tickers)
+	}
+}
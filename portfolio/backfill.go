@@ -0,0 +1,57 @@
+package portfolio
+
+import (
+	"sort"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// HistoricalResults is the results of the days of the closes, the positions are valued by the close of the day.
+// A symbol without the close of the day (e.g. a holiday of its market) is of its last close, and unpriced before the first one.
+// rates is the closes of the rates to the base currency by currency (a currency without the closes is unpriced), nil is not converted.
+// The fixed positions are of the value of the stock data.
+func HistoricalResults(tickers []Ticker, closes map[string][]quotes.Close, rates map[string][]quotes.Close) []Result {
+	days := map[string]bool{}
+	for _, cs := range closes {
+		for _, c := range cs {
+			days[c.Date] = true
+		}
+	}
+	var dates []string
+	for d := range days {
+		dates = append(dates, d)
+	}
+	sort.Strings(dates)
+
+	// last is the last close until the date, the closes are in order of the date
+	last := func(cs []quotes.Close, date string) float64 {
+		i := sort.Search(len(cs), func(i int) bool { return cs[i].Date > date })
+		if i == 0 {
+			return 0
+		}
+		return cs[i-1].Price
+	}
+
+	var results []Result
+	for _, date := range dates {
+		body := make([]Ticker, 0, len(tickers))
+		for _, t := range tickers {
+			if !t.Fixed() {
+				t.Value = last(closes[t.Symble], date)
+				t.Error = ""
+			}
+			if rates != nil {
+				t.Currency = CurrencyOf(t)
+				if cs, ok := rates[t.Currency]; ok {
+					t.Rate = last(cs, date)
+					if t.Rate == 0 {
+						t.Value = 0
+					}
+				}
+			}
+			body = append(body, t)
+		}
+		results = append(results, NewResult(date, body))
+	}
+	return results
+}
@@ -0,0 +1,47 @@
+package portfolio
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// Benchmark is the index which the portfolio is compared with, e.g. SPY or ^N225.
+type Benchmark struct {
+	Symble string  `json:"symble"`
+	Value  float64 `json:"value"`
+	// Base is price of the start (BENCHMARK_BASE), Change is since then
+	Base      float64  `json:"base,omitempty"`
+	DayChange *float64 `json:"day_change,omitempty"`
+	Change    *float64 `json:"change,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// FetchBenchmark is the benchmark of BENCHMARK_SYMBOL and BENCHMARK_BASE, nil when it is not set.
+func FetchBenchmark(ctx context.Context, provider quotes.Provider) *Benchmark {
+	symbol := strings.TrimSpace(os.Getenv("BENCHMARK_SYMBOL"))
+	if symbol == "" {
+		return nil
+	}
+	base, _ := strconv.ParseFloat(os.Getenv("BENCHMARK_BASE"), 64)
+
+	b := &Benchmark{Symble: quotes.NormalizeSymbol(symbol), Base: base}
+	quote, err := provider.Quote(ctx, b.Symble)
+	if err != nil {
+		b.Error = err.Error()
+		return b
+	}
+	b.Value = quote.Price
+	if quote.PreviousClose > 0 {
+		percent := (quote.Price - quote.PreviousClose) / quote.PreviousClose * 100
+		b.DayChange = &percent
+	}
+	if base > 0 {
+		percent := (quote.Price - base) / base * 100
+		b.Change = &percent
+	}
+	return b
+}
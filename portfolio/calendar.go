@@ -0,0 +1,26 @@
+package portfolio
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// ClosedMarkets is the markets of the tickers which don't trade at t, all is true when every market is closed.
+func ClosedMarkets(tickers []Ticker, t time.Time) (closed []string, all bool) {
+	markets := map[string]bool{}
+	for _, ticker := range tickers {
+		suffix := quotes.ExchangeSuffix(ticker.Symble)
+		if _, ok := markets[suffix]; !ok {
+			markets[suffix] = quotes.IsTradingDay(suffix, t)
+		}
+	}
+	for suffix, open := range markets {
+		if !open {
+			closed = append(closed, quotes.MarketName(suffix))
+		}
+	}
+	sort.Strings(closed)
+	return closed, len(markets) > 0 && len(closed) == len(markets)
+}
@@ -0,0 +1,167 @@
+package portfolio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// ParseError is an invalid line of the stock data.
+// Warning is true when the line is still used.
+type ParseError struct {
+	Line    int    `json:"line"`
+	Error   string `json:"error"`
+	Warning bool   `json:"warning,omitempty"`
+}
+
+// ParseCSV is my stock symbole of the csv stock data.
+// Invalid lines are skipped and returned as parse errors, duplicate symbol is kept as a lot with a warning.
+func ParseCSV(buf []byte) ([]Ticker, []ParseError) {
+	var tickers []Ticker
+	var errs []ParseError
+	lines := map[string]int{}
+
+	for n := 1; ; n++ {
+		advance, token, err := bufio.ScanLines(buf, false)
+		if err != nil {
+			return nil, append(errs, ParseError{Line: n, Error: err.Error()})
+		}
+		if advance == 0 {
+			break
+		}
+		if advance <= len(buf) {
+			buf = buf[advance:]
+		}
+
+		line := strings.TrimSpace(string(token))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		stocks := strings.Split(line, ",")
+
+		// header line
+		if n == 1 && strings.EqualFold(strings.TrimSpace(stocks[0]), "symbol") {
+			continue
+		}
+
+		fail := func(format string, a ...interface{}) {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf(format, a...)})
+		}
+		number := func(name, col string) (float64, bool) {
+			col = strings.TrimSpace(col)
+			if col == "" {
+				return 0, true
+			}
+			v, err := strconv.ParseFloat(col, 64)
+			if err != nil {
+				fail("invalid %s %q", name, col)
+				return 0, false
+			}
+			return v, true
+		}
+
+		if len(stocks) < 4 || len(stocks) > 10 {
+			fail("expected 4 to 10 columns, got %d", len(stocks))
+			continue
+		}
+		symble := quotes.NormalizeSymbol(stocks[0])
+		if symble == "" {
+			fail("empty symbol")
+			continue
+		}
+		bid, ok1 := number("bid", stocks[1])
+		value, ok2 := number("value", stocks[2])
+		hold, ok3 := number("hold", stocks[3])
+		if !ok1 || !ok2 || !ok3 {
+			continue
+		}
+
+		t := Ticker{
+			Symble: symble,
+			Bid:    bid,
+			Value:  value,
+			Hold:   hold,
+		}
+
+		// optional columns, dividend per share and category
+		// 5th column is category when it is not a number
+		switch len(stocks) {
+		case 5:
+			dividend, err := strconv.ParseFloat(strings.TrimSpace(stocks[4]), 64)
+			if err != nil {
+				t.Category = strings.TrimSpace(stocks[4])
+			}
+			t.Dividend = dividend
+		case 6, 7, 8, 9, 10:
+			dividend, ok := number("dividend", stocks[4])
+			if !ok {
+				continue
+			}
+			t.Dividend = dividend
+			t.Category = strings.TrimSpace(stocks[5])
+		}
+		if len(stocks) >= 7 {
+			t.Currency = strings.ToUpper(strings.TrimSpace(stocks[6]))
+		}
+		if len(stocks) >= 8 {
+			high, ok1 := number("alert_high", stocks[7])
+			low, ok2 := 0.0, true
+			if len(stocks) >= 9 {
+				low, ok2 = number("alert_low", stocks[8])
+			}
+			if !ok1 || !ok2 {
+				continue
+			}
+			t.AlertHigh, t.AlertLow = high, low
+		}
+		if len(stocks) == 10 {
+			t.Account = strings.TrimSpace(stocks[9])
+		}
+
+		if cash, ok := CashTicker(t); ok {
+			t = cash
+		}
+
+		if first, ok := lines[symble]; ok {
+			errs = append(errs, ParseError{
+				Line:    n,
+				Error:   fmt.Sprintf("duplicate symbol %s of line %d, reported as a lot", symble, first),
+				Warning: true,
+			})
+		} else {
+			lines[symble] = n
+		}
+		tickers = append(tickers, t)
+	}
+	return tickers, errs
+}
+
+// WatchlistCSV is the tickers in the stock data csv, optional columns are written only when needed.
+func WatchlistCSV(tickers []Ticker) []byte {
+	f := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	buf := new(bytes.Buffer)
+	for _, t := range tickers {
+		cols := []string{t.Symble, f(t.Bid), f(t.Value), f(t.Hold)}
+		switch {
+		case t.Account != "":
+			cols = append(cols, f(t.Dividend), t.Category, t.Currency, f(t.AlertHigh), f(t.AlertLow), t.Account)
+		case t.AlertHigh != 0 || t.AlertLow != 0:
+			cols = append(cols, f(t.Dividend), t.Category, t.Currency, f(t.AlertHigh), f(t.AlertLow))
+		case t.Currency != "":
+			cols = append(cols, f(t.Dividend), t.Category, t.Currency)
+		case t.Category != "":
+			cols = append(cols, f(t.Dividend), t.Category)
+		case t.Dividend != 0:
+			cols = append(cols, f(t.Dividend))
+		}
+		buf.WriteString(strings.Join(cols, ",") + "\n")
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,103 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// suffixCurrencies is currency by exchange suffix, no suffix is USD.
+var suffixCurrencies = map[string]string{
+	"T":  "JPY",
+	"HK": "HKD",
+	"TO": "CAD",
+	"AX": "AUD",
+	"DE": "EUR",
+	"PA": "EUR",
+	"WA": "PLN",
+	"PL": "PLN",
+	"HU": "HUF",
+}
+
+// CurrencyOf is currency of the ticker, the currency column or by the exchange suffix.
+func CurrencyOf(t Ticker) string {
+	if t.Currency != "" {
+		return strings.ToUpper(t.Currency)
+	}
+	if _, currency, ok := quotes.CryptoPair(t.Symble); ok {
+		return currency
+	}
+	if c, ok := suffixCurrencies[quotes.ExchangeSuffix(t.Symble)]; ok {
+		return c
+	}
+	return "USD"
+}
+
+// FX is rate to the base currency, 1 when it is not converted.
+func (t Ticker) FX() float64 {
+	if t.Rate == 0 {
+		return 1
+	}
+	return t.Rate
+}
+
+// FXSymbol is quote symbol of the currency pair (e.g. USDJPY=X).
+func FXSymbol(from, to string) string {
+	return from + to + "=X"
+}
+
+// FXRates is the rates to BASE_CURRENCY of the tickers by their currency, nil when they are not converted.
+func FXRates(tickers []Ticker) map[string]float64 {
+	var rates map[string]float64
+	for _, t := range tickers {
+		if t.Rate <= 0 || t.Currency == "" {
+			continue
+		}
+		if rates == nil {
+			rates = map[string]float64{}
+		}
+		rates[t.Currency] = t.Rate
+	}
+	return rates
+}
+
+// ApplyCurrency is set the currency and the rate to BASE_CURRENCY of the tickers.
+// Rates are got from the provider, a ticker without rate is unpriced.
+func ApplyCurrency(ctx context.Context, provider quotes.Provider, tickers []Ticker) []Ticker {
+	base := strings.ToUpper(os.Getenv("BASE_CURRENCY"))
+	if base == "" {
+		return tickers
+	}
+
+	rates := map[string]float64{base: 1}
+	errs := map[string]error{}
+	for i, t := range tickers {
+		currency := CurrencyOf(t)
+		tickers[i].Currency = currency
+
+		if _, ok := rates[currency]; !ok && errs[currency] == nil {
+			q, err := provider.Quote(ctx, FXSymbol(currency, base))
+			if err == nil && q.Price <= 0 {
+				err = fmt.Errorf("invalid rate %f", q.Price)
+			}
+			if err != nil {
+				logging.Warn(ctx, "fx rate error", logging.Fields{"symbol": FXSymbol(currency, base), "provider": provider.Name(), "error": err})
+				errs[currency] = err
+			} else {
+				rates[currency] = q.Price
+			}
+		}
+
+		if rate, ok := rates[currency]; ok {
+			tickers[i].Rate = rate
+		} else if tickers[i].Priced() {
+			tickers[i].Value = 0.0
+			tickers[i].Error = fmt.Sprintf("fx rate %s unavailable", FXSymbol(currency, base))
+		}
+	}
+	return tickers
+}
@@ -0,0 +1,83 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+)
+
+// DayOverDay is total change from the previous result.
+type DayOverDay struct {
+	Date string `json:"date"`
+	// Change and Percent are of the positions priced in both results
+	Change  float64 `json:"change"`
+	Percent float64 `json:"percent"`
+}
+
+// ApplyDayOverDay is set the change from the previous result to the tickers and the result.
+func ApplyDayOverDay(result *Result, prev Result) {
+	values := map[string]float64{}
+	for _, t := range prev.Body {
+		if t.Priced() {
+			values[t.Symble] = t.Value
+		}
+	}
+
+	var change, base float64
+	for i, t := range result.Body {
+		v, ok := values[t.Symble]
+		if !ok || !t.Priced() {
+			continue
+		}
+		percent := (t.Value - v) / v * 100
+		result.Body[i].DayChange = &percent
+		change += (t.Value - v) * t.Hold * t.FX()
+		base += v * t.Hold * t.FX()
+	}
+
+	// benchmark change is from the previous result when the provider has no previous close
+	if b, pb := result.Benchmark, prev.Benchmark; b != nil && pb != nil && b.DayChange == nil && b.Symble == pb.Symble && b.Value > 0 && pb.Value > 0 {
+		percent := (b.Value - pb.Value) / pb.Value * 100
+		b.DayChange = &percent
+	}
+
+	dod := &DayOverDay{Date: prev.CreatedAt, Change: change}
+	if base != 0 {
+		dod.Percent = change / base * 100
+	}
+	result.DayOverDay = dod
+}
+
+// ProfitLossChange is change of the total profit loss from the last result, percent of the last total value.
+func ProfitLossChange(last, result Result) float64 {
+	prev := Summarize(last.Body)
+	if prev.Value == 0 {
+		return math.Inf(1)
+	}
+	return math.Abs(Summarize(result.Body).ProfitLoss-prev.ProfitLoss) / prev.Value * 100
+}
+
+// DailyMovers is n tickers of the largest day change each way, gainers are up and losers are down.
+// Tickers without the day change are not in them.
+func DailyMovers(tickers []Ticker, n int) (gainers, losers []Ticker) {
+	var moved []Ticker
+	for _, t := range tickers {
+		if t.DayChange != nil {
+			moved = append(moved, t)
+		}
+	}
+	sort.SliceStable(moved, func(i, j int) bool { return *moved[i].DayChange > *moved[j].DayChange })
+
+	for _, t := range moved {
+		if len(gainers) == n || *t.DayChange <= 0 {
+			break
+		}
+		gainers = append(gainers, t)
+	}
+	for i := len(moved) - 1; i >= 0; i-- {
+		if len(losers) == n || *moved[i].DayChange >= 0 {
+			break
+		}
+		losers = append(losers, moved[i])
+	}
+	return gainers, losers
+}
@@ -0,0 +1,118 @@
+package portfolio
+
+import "sort"
+
+// Status of SymbolDiff.
+const (
+	DiffAdded     = "added"
+	DiffRemoved   = "removed"
+	DiffChanged   = "changed"
+	DiffUnchanged = "unchanged"
+)
+
+// SymbolDiff is the change of a position between two results, the values are in the currency of the results.
+// Status is added, removed, changed (the hold or the price) or unchanged. An unpriced side has no value.
+type SymbolDiff struct {
+	Symble    string  `json:"symble"`
+	Status    string  `json:"status"`
+	FromHold  float64 `json:"from_hold"`
+	ToHold    float64 `json:"to_hold"`
+	FromPrice float64 `json:"from_price,omitempty"`
+	ToPrice   float64 `json:"to_price,omitempty"`
+	FromValue float64 `json:"from_value"`
+	ToValue   float64 `json:"to_value"`
+	Change    float64 `json:"change"`
+	// PriceChange is % of the price change, nil when either side is unpriced
+	PriceChange *float64 `json:"price_change,omitempty"`
+	// ProfitLossChange is the change of the profit loss of the position
+	ProfitLossChange float64 `json:"profit_loss_change"`
+}
+
+// ResultDiff is the change of the totals and the positions from a result to another.
+type ResultDiff struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Summary Summary `json:"summary"`
+	// Change and Percent are of the total value, ProfitLossChange is of the total profit loss
+	Change           float64      `json:"change"`
+	Percent          float64      `json:"percent"`
+	ProfitLossChange float64      `json:"profit_loss_change"`
+	Added            []string     `json:"added,omitempty"`
+	Removed          []string     `json:"removed,omitempty"`
+	Symbols          []SymbolDiff `json:"symbols"`
+}
+
+// DiffResults is the change from the result to the other one by symbol, the watch-only symbols are not in it.
+// The unchanged positions are in the symbols too, the symbols are in the order of the change.
+func DiffResults(from, to Result) ResultDiff {
+	prev := map[string]Ticker{}
+	for _, t := range from.Body {
+		if !t.WatchOnly() {
+			prev[t.Symble] = t
+		}
+	}
+
+	fromSummary, toSummary := Summarize(from.Body), Summarize(to.Body)
+	diff := ResultDiff{
+		From:             from.CreatedAt,
+		To:               to.CreatedAt,
+		Summary:          toSummary,
+		Change:           toSummary.Value - fromSummary.Value,
+		ProfitLossChange: toSummary.ProfitLoss - fromSummary.ProfitLoss,
+		Symbols:          []SymbolDiff{},
+	}
+	if fromSummary.Value != 0 {
+		diff.Percent = diff.Change / fromSummary.Value * 100
+	}
+
+	seen := map[string]bool{}
+	for _, t := range to.Body {
+		if t.WatchOnly() || seen[t.Symble] {
+			continue
+		}
+		seen[t.Symble] = true
+		p, ok := prev[t.Symble]
+		if !ok {
+			diff.Added = append(diff.Added, t.Symble)
+			diff.Symbols = append(diff.Symbols, symbolDiff(t.Symble, DiffAdded, Ticker{}, t))
+			continue
+		}
+		status := DiffUnchanged
+		if p.Hold != t.Hold || p.Value != t.Value {
+			status = DiffChanged
+		}
+		diff.Symbols = append(diff.Symbols, symbolDiff(t.Symble, status, p, t))
+	}
+	for _, t := range from.Body {
+		if t.WatchOnly() || seen[t.Symble] {
+			continue
+		}
+		seen[t.Symble] = true
+		diff.Removed = append(diff.Removed, t.Symble)
+		diff.Symbols = append(diff.Symbols, symbolDiff(t.Symble, DiffRemoved, t, Ticker{}))
+	}
+
+	sort.SliceStable(diff.Symbols, func(i, j int) bool { return diff.Symbols[i].Change > diff.Symbols[j].Change })
+	return diff
+}
+
+// symbolDiff is the diff of the position, the empty ticker is the side without it.
+func symbolDiff(symbol, status string, from, to Ticker) SymbolDiff {
+	d := SymbolDiff{Symble: symbol, Status: status, FromHold: from.Hold, ToHold: to.Hold, FromPrice: from.Value, ToPrice: to.Value}
+	var fromPL, toPL float64
+	if from.Priced() {
+		d.FromValue = from.Value * from.Hold * from.FX()
+		fromPL = from.Earning() * from.FX()
+	}
+	if to.Priced() {
+		d.ToValue = to.Value * to.Hold * to.FX()
+		toPL = to.Earning() * to.FX()
+	}
+	d.Change = d.ToValue - d.FromValue
+	d.ProfitLossChange = toPL - fromPL
+	if from.Priced() && to.Priced() {
+		percent := (to.Value - from.Value) / from.Value * 100
+		d.PriceChange = &percent
+	}
+	return d
+}
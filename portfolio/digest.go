@@ -0,0 +1,76 @@
+package portfolio
+
+import (
+	"sort"
+)
+
+// SymbolChange is price change of a symbol over the period.
+type SymbolChange struct {
+	Symble  string  `json:"symble"`
+	From    float64 `json:"from"`
+	To      float64 `json:"to"`
+	Percent float64 `json:"percent"`
+}
+
+// Digest is change of the portfolio over the period, from the first result to the last result.
+type Digest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// StartValue and EndValue are total values, Change and Percent are of the positions priced in both results
+	StartValue       float64        `json:"start_value"`
+	EndValue         float64        `json:"end_value"`
+	Change           float64        `json:"change"`
+	Percent          float64        `json:"percent"`
+	ProfitLossChange float64        `json:"profit_loss_change"`
+	Best             []SymbolChange `json:"best"`
+	Worst            []SymbolChange `json:"worst"`
+	// Returns and Risk are of the monthly digest
+	Returns *Returns    `json:"returns,omitempty"`
+	Risk    *RiskReport `json:"risk,omitempty"`
+}
+
+// NewDigest is the digest from start to end, best and worst are n symbols at most each.
+func NewDigest(start, end Result, n int) Digest {
+	startSummary, endSummary := Summarize(start.Body), Summarize(end.Body)
+	d := Digest{
+		From:             start.CreatedAt,
+		To:               end.CreatedAt,
+		StartValue:       startSummary.Value,
+		EndValue:         endSummary.Value,
+		ProfitLossChange: endSummary.ProfitLoss - startSummary.ProfitLoss,
+	}
+
+	values := map[string]float64{}
+	for _, t := range start.Body {
+		if t.Priced() {
+			values[t.Symble] = t.Value
+		}
+	}
+
+	var changes []SymbolChange
+	var base float64
+	for _, t := range end.Body {
+		v, ok := values[t.Symble]
+		if !ok || !t.Priced() {
+			continue
+		}
+		changes = append(changes, SymbolChange{Symble: t.Symble, From: v, To: t.Value, Percent: (t.Value - v) / v * 100})
+		d.Change += (t.Value - v) * t.Hold * t.FX()
+		base += v * t.Hold * t.FX()
+	}
+	if base != 0 {
+		d.Percent = d.Change / base * 100
+	}
+
+	// a symbol is in best or worst, not both
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Percent > changes[j].Percent })
+	best := n
+	if best > len(changes) {
+		best = len(changes)
+	}
+	d.Best = changes[:best]
+	for i := len(changes) - 1; i >= best && len(d.Worst) < n; i-- {
+		d.Worst = append(d.Worst, changes[i])
+	}
+	return d
+}
@@ -0,0 +1,63 @@
+package portfolio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// Dividend is a dividend received of the dividend log (date,symbol,amount).
+type Dividend struct {
+	Date   string
+	Symble string
+	// Amount is the total received, in the currency of the symbol
+	Amount float64
+}
+
+// ParseDividends is parse the dividend log (date,symbol,amount), the first line is the header.
+// Invalid line is skipped and returned as a parse error.
+func ParseDividends(buf []byte) ([]Dividend, []ParseError) {
+	var dividends []Dividend
+	var errs []ParseError
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 || line == "" {
+			continue
+		}
+
+		cols := strings.Split(line, ",")
+		if len(cols) != 3 {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("expected 3 columns, got %d", len(cols))})
+			continue
+		}
+		amount, err := strconv.ParseFloat(strings.TrimSpace(cols[2]), 64)
+		if err != nil {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("invalid amount %q", cols[2])})
+			continue
+		}
+
+		dividends = append(dividends, Dividend{
+			Date:   strings.TrimSpace(cols[0]),
+			Symble: quotes.NormalizeSymbol(cols[1]),
+			Amount: amount,
+		})
+	}
+	return dividends, errs
+}
+
+// ApplyDividends is add the received dividends to the tickers of the symbols, it is in the profit loss (total return).
+func ApplyDividends(tickers []Ticker, dividends []Dividend) []Ticker {
+	received := map[string]float64{}
+	for _, d := range dividends {
+		received[d.Symble] += d.Amount
+	}
+	for i, t := range tickers {
+		tickers[i].DividendReceived += received[t.Symble]
+	}
+	return tickers
+}
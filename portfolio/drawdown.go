@@ -0,0 +1,17 @@
+package portfolio
+
+// Drawdown is the total value from the all-time high of the portfolio, Percent is negative under the peak.
+type Drawdown struct {
+	Peak     float64 `json:"peak"`
+	PeakDate string  `json:"peak_date"`
+	Value    float64 `json:"value"`
+	Percent  float64 `json:"percent"`
+}
+
+// NewDrawdown is the drawdown of the value of the date from the peak, the value over the peak is the new peak.
+func NewDrawdown(peak float64, peakDate string, value float64, date string) Drawdown {
+	if value >= peak || peakDate == "" {
+		return Drawdown{Peak: value, PeakDate: date, Value: value}
+	}
+	return Drawdown{Peak: peak, PeakDate: peakDate, Value: value, Percent: (value - peak) / peak * 100}
+}
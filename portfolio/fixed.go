@@ -0,0 +1,60 @@
+package portfolio
+
+import (
+	"strings"
+	"time"
+)
+
+// asset types of the positions without the market price.
+const (
+	AssetCash    = "cash"
+	AssetBond    = "bond"
+	AssetDeposit = "deposit"
+)
+
+// cashSymbol is the csv symbol of the cash balance, CASH or CASH:<currency> (e.g. CASH:JPY).
+const cashSymbol = "CASH"
+
+// Fixed is true for the cash, bond and deposit, their price is not fetched.
+func (t Ticker) Fixed() bool {
+	switch t.Asset {
+	case AssetCash, AssetBond, AssetDeposit:
+		return true
+	}
+	return false
+}
+
+// CashTicker is the cash balance of the csv symbol CASH[:currency], hold is the amount (bid 0 is 1).
+func CashTicker(t Ticker) (Ticker, bool) {
+	symbol, currency, _ := strings.Cut(t.Symble, ":")
+	if symbol != cashSymbol {
+		return t, false
+	}
+	t.Asset = AssetCash
+	if t.Bid <= 0 {
+		t.Bid = 1
+	}
+	if currency != "" {
+		t.Currency = currency
+	}
+	return t, true
+}
+
+// FixedValue is the price of the fixed position at now.
+// Cash is the bid (default 1), bond and deposit are the bid (price per unit) with the simple interest
+// of the coupon (annual %) accrued from since.
+func FixedValue(t Ticker, now time.Time) float64 {
+	value := t.Bid
+	if value <= 0 {
+		value = 1
+	}
+	if t.Asset == AssetCash || t.Coupon <= 0 {
+		return value
+	}
+	since, err := time.Parse("2006-01-02", t.Since)
+	if err != nil || now.Before(since) {
+		return value
+	}
+	years := now.Sub(since).Hours() / 24 / 365
+	return value * (1 + t.Coupon/100*years)
+}
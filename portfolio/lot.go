@@ -0,0 +1,50 @@
+package portfolio
+
+// Lot is a purchase of the symbol.
+type Lot struct {
+	Bid      float64 `json:"bid"`
+	Hold     float64 `json:"hold"`
+	Dividend float64 `json:"dividend,omitempty"`
+}
+
+// Earning is profit loss of the lot at the value.
+func (l Lot) Earning(value float64) float64 {
+	return (value - l.Bid + l.Dividend) * l.Hold
+}
+
+// AggregateLots is merge the rows of the same symbol into one ticker of average cost basis.
+// Each row is kept in Lots when the symbol has more than one row.
+func AggregateLots(tickers []Ticker) []Ticker {
+	index := map[string]int{}
+	var merged []Ticker
+	for _, t := range tickers {
+		i, ok := index[t.Symble]
+		if !ok {
+			index[t.Symble] = len(merged)
+			merged = append(merged, t)
+			continue
+		}
+
+		m := &merged[i]
+		if len(m.Lots) == 0 {
+			m.Lots = []Lot{{Bid: m.Bid, Hold: m.Hold, Dividend: m.Dividend}}
+		}
+		m.Lots = append(m.Lots, Lot{Bid: t.Bid, Hold: t.Hold, Dividend: t.Dividend})
+		if m.Category == "" {
+			m.Category = t.Category
+		}
+
+		var hold, cost, dividend float64
+		for _, l := range m.Lots {
+			hold += l.Hold
+			cost += l.Bid * l.Hold
+			dividend += l.Dividend * l.Hold
+		}
+		m.Hold = hold
+		if hold != 0 {
+			m.Bid = cost / hold
+			m.Dividend = dividend / hold
+		}
+	}
+	return merged
+}
@@ -0,0 +1,223 @@
+// Package portfolio is the positions of the stock data and the valuation of them.
+package portfolio
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+type Ticker struct {
+	Symble   string  `json:"symble"`
+	Bid      float64 `json:"bid"`
+	Value    float64 `json:"value"`
+	Hold     float64 `json:"hold"`
+	Dividend float64 `json:"dividend,omitempty"`
+	// DividendReceived is total of the dividend log (DIVIDEND_LOG)
+	DividendReceived float64 `json:"dividend_received,omitempty"`
+	Category         string  `json:"category,omitempty"`
+	// Currency is currency of bid and value, Rate is rate to BASE_CURRENCY
+	Currency string  `json:"currency,omitempty"`
+	Rate     float64 `json:"fx_rate,omitempty"`
+	// Account and TargetPrice are metadata of the json or yaml stock data
+	Account     string  `json:"account,omitempty"`
+	TargetPrice float64 `json:"target_price,omitempty"`
+	// AlertHigh and AlertLow are price thresholds, Alert is high or low when the price crossed it
+	AlertHigh float64 `json:"alert_high,omitempty"`
+	AlertLow  float64 `json:"alert_low,omitempty"`
+	Alert     string  `json:"alert,omitempty"`
+	AsOf      string  `json:"as_of,omitempty"`
+	Stale     bool    `json:"stale,omitempty"`
+	// Session is pre or post when the price is of the pre-market or after-hours (EXTENDED_HOURS)
+	Session  string `json:"session,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	// Cached is the price of the quote cache (QUOTE_CACHE_TTL)
+	Cached bool `json:"cached,omitempty"`
+	// Degraded is the stale price of the quote cache when the providers are out of their request budgets (PROVIDER_BUDGETS)
+	Degraded bool `json:"degraded,omitempty"`
+	// Realized is profit loss of the sold shares (transaction log)
+	Realized float64 `json:"realized,omitempty"`
+	// Lots is the purchases when the symbol has more than one row, Bid is average of them
+	Lots []Lot `json:"lots,omitempty"`
+	// DayChange is price change rate from the previous result
+	DayChange *float64 `json:"day_change,omitempty"`
+	// GainPercent, MarketValue and Weight (% of the total value) are set for the priced ticker
+	GainPercent float64 `json:"gain_percent,omitempty"`
+	MarketValue float64 `json:"market_value,omitempty"`
+	Weight      float64 `json:"weight,omitempty"`
+	// TargetWeight is the target % of the total value for the rebalancing
+	TargetWeight float64 `json:"target_weight,omitempty"`
+	// Asset is cash, bond or deposit for the position without the market price,
+	// Coupon is annual % of the bond and deposit accrued from Since (yyyy-mm-dd)
+	Asset  string  `json:"asset,omitempty"`
+	Coupon float64 `json:"coupon,omitempty"`
+	Since  string  `json:"since,omitempty"`
+	// Alias is the symbol of the price of the renamed symbol (SYMBOL_ALIASES)
+	Alias string `json:"alias,omitempty"`
+	// Watch is the watch-only symbol, it is priced but not in the totals (hold 0 is watch-only too)
+	Watch bool `json:"watch,omitempty"`
+	// Fundamentals is 52 week range, p/e and market cap of the quote
+	Fundamentals *quotes.Fundamentals `json:"fundamentals,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+type Result struct {
+	// SchemaVersion is the version of the format, a stored result is read by ParseResult
+	SchemaVersion int    `json:"schema_version"`
+	CreatedAt     string `json:"created_at"`
+	Currency      string `json:"currency,omitempty"`
+	// Rates is the fx rates of the day to the currency by the currency of the tickers, for the valuation later
+	Rates  map[string]float64 `json:"rates,omitempty"`
+	Body   []Ticker           `json:"body"`
+	Errors []SymbolError      `json:"errors,omitempty"`
+	// ParseErrors is invalid lines of the stock data
+	ParseErrors []ParseError `json:"parse_errors,omitempty"`
+	// Discrepancies is the positions which differ from the holdings of the broker (BROKER_SYNC)
+	Discrepancies []Discrepancy `json:"discrepancies,omitempty"`
+	// Signals is the moving averages and the crosses of the positions (MA_SIGNALS)
+	Signals []Signal `json:"signals,omitempty"`
+	// Drawdown is the total value from the all-time high (HISTORY_TABLE)
+	Drawdown *Drawdown `json:"drawdown,omitempty"`
+	// DayOverDay is change from the previous result (DAY_OVER_DAY)
+	DayOverDay *DayOverDay `json:"day_over_day,omitempty"`
+	// Accounts is the subtotals by account, nil when no position has the account
+	Accounts []AccountSubtotal `json:"accounts,omitempty"`
+	// Allocation is percent of the value by category
+	Allocation map[string]float64 `json:"allocation,omitempty"`
+	// Benchmark is the index of BENCHMARK_SYMBOL
+	Benchmark *Benchmark `json:"benchmark,omitempty"`
+	// Rebalance is the trades to the target weights (target_weight and TARGET_ALLOCATION)
+	Rebalance []Trade `json:"rebalance,omitempty"`
+	// MarketClosed is the markets closed on the day (MARKET_CALENDAR), their prices are of the last trading day
+	MarketClosed []string `json:"market_closed,omitempty"`
+	// Run is the metadata of the run, the duration, the fetches, the retries and the notifications
+	Run *RunInfo `json:"run,omitempty"`
+}
+
+// SymbolError is a symbol which couldn't be priced and why.
+type SymbolError struct {
+	Symble string `json:"symble"`
+	Error  string `json:"error"`
+}
+
+// NewResult is the result of the tickers, unpriced tickers are collected in errors.
+func NewResult(createdAt string, tickers []Ticker) Result {
+	ApplyWeights(tickers)
+	SortTickers(tickers, os.Getenv("SORT_BY"), os.Getenv("GROUP_BY"))
+	return Result{
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     createdAt,
+		Body:          tickers,
+		Errors:        SymbolErrors(tickers),
+		Rates:         FXRates(tickers),
+		Accounts:      AccountSubtotals(tickers),
+		Allocation:    Summarize(tickers).Allocation(),
+		Rebalance:     Rebalance(tickers, TargetAllocation(), RebalanceThreshold()),
+	}
+}
+
+// SymbolErrors is errors of the unpriced tickers.
+func SymbolErrors(tickers []Ticker) []SymbolError {
+	var errs []SymbolError
+	for _, t := range tickers {
+		if !t.Priced() {
+			message := t.Error
+			if message == "" {
+				message = "price unavailable"
+			}
+			errs = append(errs, SymbolError{Symble: t.Symble, Error: message})
+		}
+	}
+	return errs
+}
+
+// Earning is profit loss of the ticker, include dividend.
+// The hold of the short position is negative, so it is (bid - value) * qty and the dividend is paid.
+func (t Ticker) Earning() float64 {
+	return (t.Value-t.Bid+t.Dividend)*t.Hold + t.DividendReceived
+}
+
+// Priced is true when the current price was fetched.
+func (t Ticker) Priced() bool {
+	return t.Value > 0
+}
+
+// WatchOnly is true for the symbol without the holdings, the watch flag or hold 0 (not cash, bond or deposit).
+func (t Ticker) WatchOnly() bool {
+	return t.Watch || (t.Hold == 0 && !t.Fixed())
+}
+
+// Short is true for the short position, its hold is negative.
+func (t Ticker) Short() bool {
+	return t.Hold < 0
+}
+
+// Percent is price change rate from bid, the short position gains when the price goes down.
+func (t Ticker) Percent() float64 {
+	if t.Bid == 0 {
+		return 0
+	}
+	if t.Short() {
+		return (t.Bid - t.Value) / t.Bid * 100
+	}
+	return (t.Value - t.Bid) / t.Bid * 100
+}
+
+// ApplyWeights is set gain percent, market value and weight of the priced tickers.
+// Weight is share of the market value in BASE_CURRENCY.
+func ApplyWeights(tickers []Ticker) {
+	total := Summarize(tickers).Value
+	for i, t := range tickers {
+		if !t.Priced() {
+			continue
+		}
+		tickers[i].GainPercent = t.Percent()
+		tickers[i].MarketValue = t.Value * t.Hold
+		if total != 0 {
+			tickers[i].Weight = t.Value * t.Hold * t.FX() / total * 100
+		}
+	}
+}
+
+// ErrEmptyWatchlist is returned when the stock data file has no valid row.
+var ErrEmptyWatchlist = errors.New("no valid tickers in watchlist")
+
+// FilterSymbols is filter the tickers by comma separated symbols.
+// When include is set, only included symbols are returned and exclude is ignored.
+func FilterSymbols(tickers []Ticker, include, exclude string) []Ticker {
+	list := func(env string) map[string]bool {
+		m := map[string]bool{}
+		for _, s := range strings.Split(env, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				m[quotes.NormalizeSymbol(s)] = true
+			}
+		}
+		return m
+	}
+	includes, excludes := list(include), list(exclude)
+	if len(includes) == 0 && len(excludes) == 0 {
+		return tickers
+	}
+
+	var filtered []Ticker
+	for _, t := range tickers {
+		if len(includes) > 0 {
+			if includes[t.Symble] {
+				filtered = append(filtered, t)
+			}
+			continue
+		}
+		if !excludes[t.Symble] {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// FormatHold is hold without trailing zeros (e.g. 10, 2.5).
+func FormatHold(hold float64) string {
+	return strconv.FormatFloat(hold, 'f', -1, 64)
+}
@@ -0,0 +1,122 @@
+package portfolio
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCSVDividend(t *testing.T) {
+	tickers, _ := ParseCSV([]byte("AAPL,100,0,10,2.5\nMSFT,200,0,5\n"))
+	if len(tickers) != 2 {
+		t.Fatalf("ParseCSV() = %d tickers, want 2", len(tickers))
+	}
+	if tickers[0].Dividend != 2.5 || tickers[1].Dividend != 0 {
+		t.Errorf("dividends = %v and %v, want 2.5 and 0", tickers[0].Dividend, tickers[1].Dividend)
+	}
+}
+
+func TestParseCSVFractionalHold(t *testing.T) {
+	tickers, _ := ParseCSV([]byte("AAPL,100,0,2.5\nMSFT,200,0,10\n"))
+	if len(tickers) != 2 || tickers[0].Hold != 2.5 || tickers[1].Hold != 10 {
+		t.Fatalf("ParseCSV() = %+v, want holds 2.5 and 10", tickers)
+	}
+}
+
+func TestTickerEarningFractionalHold(t *testing.T) {
+	ticker := Ticker{Symble: "AAPL", Bid: 100, Value: 110, Hold: 2.5, Dividend: 2}
+	if got := ticker.Earning(); got != 30 {
+		t.Errorf("Earning() = %v, want 30", got)
+	}
+}
+
+func TestParseCSVCategory(t *testing.T) {
+	tickers, _ := ParseCSV([]byte("AAPL,100,0,10,2.5\nMSFT,200,0,5,Tech\nVOO,300,0,1,1.5,ETF\n"))
+	want := []struct {
+		dividend float64
+		category string
+	}{{2.5, ""}, {0, "Tech"}, {1.5, "ETF"}}
+	if len(tickers) != len(want) {
+		t.Fatalf("ParseCSV() = %d tickers, want %d", len(tickers), len(want))
+	}
+	for i, w := range want {
+		if tickers[i].Dividend != w.dividend || tickers[i].Category != w.category {
+			t.Errorf("%s: dividend %v category %q, want %v %q", tickers[i].Symble, tickers[i].Dividend, tickers[i].Category, w.dividend, w.category)
+		}
+	}
+}
+
+func TestParseCSVParseErrors(t *testing.T) {
+	buf := "symbol,bid,value,hold\nAAPL,100,0,10\n# comment\nMSFT,abc,0,5\nGOOG,100\n,100,0,1\nAAPL,110,0,5\n"
+	tickers, errs := ParseCSV([]byte(buf))
+	if len(tickers) != 2 || tickers[0].Symble != "AAPL" || tickers[1].Symble != "AAPL" {
+		t.Errorf("ParseCSV() = %+v, want 2 lots of AAPL", tickers)
+	}
+	want := []ParseError{
+		{Line: 4, Error: `invalid bid "abc"`},
+		{Line: 5, Error: "expected 4 to 10 columns, got 2"},
+		{Line: 6, Error: "empty symbol"},
+		{Line: 7, Error: "duplicate symbol AAPL of line 2, reported as a lot", Warning: true},
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("ParseCSV() errors = %+v, want %+v", errs, want)
+	}
+}
+
+func TestFilterSymbols(t *testing.T) {
+	tickers := []Ticker{{Symble: "AAPL"}, {Symble: "MSFT"}, {Symble: "7203.T"}}
+	tests := []struct {
+		include, exclude string
+		want             string
+	}{
+		{"", "", "AAPL,MSFT,7203.T"},
+		{"msft, 7203.tyo", "", "MSFT,7203.T"},
+		{"", "AAPL", "MSFT,7203.T"},
+		// include wins over exclude
+		{"AAPL", "AAPL", "AAPL"},
+		{" , ", "", "AAPL,MSFT,7203.T"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, ticker := range FilterSymbols(tickers, tt.include, tt.exclude) {
+			got = append(got, ticker.Symble)
+		}
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("FilterSymbols(%q, %q) = %v, want %s", tt.include, tt.exclude, got, tt.want)
+		}
+	}
+}
+
+func TestNewResultErrors(t *testing.T) {
+	result := NewResult("2021-06-14", []Ticker{
+		{Symble: "AAPL", Bid: 120, Value: 130, Hold: 10},
+		{Symble: "XXXX", Bid: 50, Hold: 2, Error: "fetch error. 404 Not Found"},
+		{Symble: "YYYY", Bid: 30, Hold: 1},
+	})
+	want := []SymbolError{{Symble: "XXXX", Error: "fetch error. 404 Not Found"}, {Symble: "YYYY", Error: "price unavailable"}}
+	if len(result.Errors) != len(want) || result.Errors[0] != want[0] || result.Errors[1] != want[1] {
+		t.Errorf("Errors = %+v, want %+v", result.Errors, want)
+	}
+	if errs := NewResult("2021-06-14", result.Body[:1]).Errors; errs != nil {
+		t.Errorf("Errors of the priced result = %+v, want none", errs)
+	}
+}
+
+func TestApplyWeights(t *testing.T) {
+	result := NewResult("2021-06-14", []Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 10},
+		{Symble: "MSFT", Bid: 200, Value: 190, Hold: 5},
+		{Symble: "XXXX", Bid: 50, Hold: 3},
+	})
+	aapl, msft, xxxx := result.Body[0], result.Body[1], result.Body[2]
+	if aapl.GainPercent != 20 || aapl.MarketValue != 1200 || msft.GainPercent != -5 || msft.MarketValue != 950 {
+		t.Errorf("gain percent and market value = %+v %+v, want 20%% 1200 and -5%% 950", aapl, msft)
+	}
+	// the weight is the share of 2150, the unpriced symbol has none
+	if w := aapl.Weight + msft.Weight; w < 99.99 || w > 100.01 || aapl.Weight < 55.8 || aapl.Weight > 55.82 {
+		t.Errorf("weights = %v and %v, want 55.81 and 44.19", aapl.Weight, msft.Weight)
+	}
+	if xxxx.GainPercent != 0 || xxxx.MarketValue != 0 || xxxx.Weight != 0 {
+		t.Errorf("XXXX = %+v, want no weight of the unpriced symbol", xxxx)
+	}
+}
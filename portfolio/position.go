@@ -0,0 +1,240 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path"
+	"strings"
+
+	"github.com/tora0091/stock-profit/quotes"
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a position of the json or yaml stock data.
+type Position struct {
+	Symbol string `json:"symbol" yaml:"symbol"`
+	// Symble is same as Symbol, for the json of the report
+	Symble   string  `json:"symble,omitempty" yaml:"symble,omitempty"`
+	Bid      float64 `json:"bid" yaml:"bid"`
+	Hold     float64 `json:"hold" yaml:"hold"`
+	Dividend float64 `json:"dividend,omitempty" yaml:"dividend,omitempty"`
+	Category string  `json:"category,omitempty" yaml:"category,omitempty"`
+	// Sector is same as Category, sector or asset class tag
+	Sector      string  `json:"sector,omitempty" yaml:"sector,omitempty"`
+	Currency    string  `json:"currency,omitempty" yaml:"currency,omitempty"`
+	Account     string  `json:"account,omitempty" yaml:"account,omitempty"`
+	TargetPrice float64 `json:"target_price,omitempty" yaml:"target_price,omitempty"`
+	AlertHigh   float64 `json:"alert_high,omitempty" yaml:"alert_high,omitempty"`
+	AlertLow    float64 `json:"alert_low,omitempty" yaml:"alert_low,omitempty"`
+	// Side is short for the short position, same as the negative hold
+	Side string `json:"side,omitempty" yaml:"side,omitempty"`
+	// TargetWeight is the target % of the total value for the rebalancing
+	TargetWeight float64 `json:"target_weight,omitempty" yaml:"target_weight,omitempty"`
+	// Asset is cash, bond or deposit, Coupon (annual %) is accrued from Since
+	Asset  string  `json:"asset,omitempty" yaml:"asset,omitempty"`
+	Coupon float64 `json:"coupon,omitempty" yaml:"coupon,omitempty"`
+	Since  string  `json:"since,omitempty" yaml:"since,omitempty"`
+	// Watch is the watch-only symbol without the holdings
+	Watch bool `json:"watch,omitempty" yaml:"watch,omitempty"`
+}
+
+// Ticker is the ticker of the position.
+func (p Position) Ticker() Ticker {
+	symbol := p.Symbol
+	if symbol == "" {
+		symbol = p.Symble
+	}
+	category := p.Category
+	if category == "" {
+		category = p.Sector
+	}
+	hold := p.Hold
+	if strings.EqualFold(strings.TrimSpace(p.Side), "short") {
+		hold = -math.Abs(hold)
+	}
+	t := Ticker{
+		Symble:       quotes.NormalizeSymbol(symbol),
+		Bid:          p.Bid,
+		Hold:         hold,
+		Dividend:     p.Dividend,
+		Category:     strings.TrimSpace(category),
+		Currency:     strings.ToUpper(strings.TrimSpace(p.Currency)),
+		Account:      strings.TrimSpace(p.Account),
+		TargetPrice:  p.TargetPrice,
+		AlertHigh:    p.AlertHigh,
+		AlertLow:     p.AlertLow,
+		TargetWeight: p.TargetWeight,
+		Asset:        strings.ToLower(strings.TrimSpace(p.Asset)),
+		Coupon:       p.Coupon,
+		Since:        strings.TrimSpace(p.Since),
+		Watch:        p.Watch,
+	}
+	if cash, ok := CashTicker(t); ok && t.Asset == "" {
+		return cash
+	}
+	return t
+}
+
+// PositionOf is the position of the ticker.
+func PositionOf(t Ticker) Position {
+	return Position{
+		Symbol:       t.Symble,
+		Bid:          t.Bid,
+		Hold:         t.Hold,
+		Dividend:     t.Dividend,
+		Category:     t.Category,
+		Currency:     t.Currency,
+		Account:      t.Account,
+		TargetPrice:  t.TargetPrice,
+		AlertHigh:    t.AlertHigh,
+		AlertLow:     t.AlertLow,
+		TargetWeight: t.TargetWeight,
+		Asset:        t.Asset,
+		Coupon:       t.Coupon,
+		Since:        t.Since,
+		Watch:        t.Watch,
+	}
+}
+
+// positionFile is stock data file of the positions, a list or {"positions": [...]}.
+type positionFile struct {
+	Positions []Position `json:"positions" yaml:"positions"`
+}
+
+// WatchlistFormat is format of the stock data, by the extension or the first character.
+// It is one of csv, json, yaml and transactions.
+func WatchlistFormat(name string, buf []byte) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+
+	head := bytes.TrimSpace(buf)
+	switch {
+	case bytes.HasPrefix(head, []byte("[")), bytes.HasPrefix(head, []byte("{")):
+		return "json"
+	case bytes.HasPrefix(head, []byte("---")), bytes.HasPrefix(head, []byte("positions:")), bytes.HasPrefix(head, []byte("- ")):
+		return "yaml"
+	case IsTransactionLog(buf):
+		return "transactions"
+	}
+	return "csv"
+}
+
+// ParseWatchlist is tickers of the stock data in csv, json, yaml or transaction log.
+// Invalid lines of csv and transaction log are returned as parse errors, invalid json or yaml is an error.
+func ParseWatchlist(name string, buf []byte) ([]Ticker, []ParseError, error) {
+	switch WatchlistFormat(name, buf) {
+	case "json":
+		tickers, err := ParseWatchlistJSON(buf)
+		return tickers, nil, err
+	case "yaml":
+		tickers, err := ParseWatchlistYAML(buf)
+		return tickers, nil, err
+	case "transactions":
+		transactions, errs := ParseTransactions(buf)
+		return Holdings(transactions), errs, nil
+	}
+	tickers, errs := ParseCSV(buf)
+	return tickers, errs, nil
+}
+
+// ParseWatchlistJSON is parse json array (or {"positions": [...]}) of the positions.
+func ParseWatchlistJSON(b []byte) ([]Ticker, error) {
+	var positions []Position
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("{")) {
+		var file positionFile
+		if err := json.Unmarshal(b, &file); err != nil {
+			return nil, fmt.Errorf("invalid json watchlist. %s", err)
+		}
+		positions = file.Positions
+	} else if err := json.Unmarshal(b, &positions); err != nil {
+		return nil, fmt.Errorf("invalid json watchlist. %s", err)
+	}
+	return PositionTickers(positions), nil
+}
+
+// ParseWatchlistYAML is parse yaml list (or positions:) of the positions.
+func ParseWatchlistYAML(b []byte) ([]Ticker, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, fmt.Errorf("invalid yaml watchlist. %s", err)
+	}
+
+	var positions []Position
+	if len(node.Content) > 0 && node.Content[0].Kind == yaml.MappingNode {
+		var file positionFile
+		if err := node.Decode(&file); err != nil {
+			return nil, fmt.Errorf("invalid yaml watchlist. %s", err)
+		}
+		positions = file.Positions
+	} else if err := node.Decode(&positions); err != nil {
+		return nil, fmt.Errorf("invalid yaml watchlist. %s", err)
+	}
+	return PositionTickers(positions), nil
+}
+
+// PositionTickers is tickers of the positions, position without symbol is skipped.
+func PositionTickers(positions []Position) []Ticker {
+	var tickers []Ticker
+	for _, p := range positions {
+		if t := p.Ticker(); t.Symble != "" {
+			tickers = append(tickers, t)
+		}
+	}
+	return tickers
+}
+
+// UpsertPositions is replace all rows of the symbol by the position, new symbol is appended.
+func UpsertPositions(tickers []Ticker, positions []Ticker) []Ticker {
+	for _, p := range positions {
+		var updated []Ticker
+		var done bool
+		for _, t := range tickers {
+			if t.Symble != p.Symble {
+				updated = append(updated, t)
+				continue
+			}
+			if !done {
+				updated = append(updated, p)
+				done = true
+			}
+		}
+		if !done {
+			updated = append(updated, p)
+		}
+		tickers = updated
+	}
+	return tickers
+}
+
+// RemovePosition is remove all rows of the symbol.
+func RemovePosition(tickers []Ticker, symbol string) ([]Ticker, bool) {
+	var kept []Ticker
+	for _, t := range tickers {
+		if t.Symble != symbol {
+			kept = append(kept, t)
+		}
+	}
+	return kept, len(kept) != len(tickers)
+}
+
+// WatchlistFile is the tickers in the format of the stock data.
+func WatchlistFile(format string, tickers []Ticker) ([]byte, error) {
+	var positions []Position
+	for _, t := range tickers {
+		positions = append(positions, PositionOf(t))
+	}
+
+	switch format {
+	case "json":
+		return json.MarshalIndent(positionFile{Positions: positions}, "", "  ")
+	case "yaml":
+		return yaml.Marshal(positionFile{Positions: positions})
+	}
+	return WatchlistCSV(tickers), nil
+}
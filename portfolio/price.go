@@ -0,0 +1,241 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/tracing"
+)
+
+// fetchOne is GetStockPrice with panic recovery.
+func fetchOne(ctx context.Context, provider quotes.Provider, symbol Ticker) (ticker Ticker) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Error(ctx, "fetch panic", logging.Fields{"symbol": symbol.Symble, "error": fmt.Sprint(r)})
+			ticker = symbol
+			ticker.Value = 0.0
+			ticker.Error = fmt.Sprintf("panic. %v", r)
+		}
+	}()
+	return GetStockPrice(ctx, provider, symbol)
+}
+
+// FetchConcurrency is number of the fetch workers (FETCH_CONCURRENCY, default 5)
+// and max random wait before each request (FETCH_JITTER, default 500ms).
+func FetchConcurrency() (int, time.Duration) {
+	concurrency, err := strconv.Atoi(os.Getenv("FETCH_CONCURRENCY"))
+	if err != nil || concurrency <= 0 {
+		concurrency = 5
+	}
+	jitter, err := time.ParseDuration(os.Getenv("FETCH_JITTER"))
+	if err != nil || jitter < 0 {
+		jitter = 500 * time.Millisecond
+	}
+	return concurrency, jitter
+}
+
+// fetchTimeout is default of FETCH_TIMEOUT.
+const fetchTimeout = time.Minute
+
+// fetchDeadlineMargin is default of FETCH_DEADLINE_MARGIN, time left for the upload and the notification.
+const fetchDeadlineMargin = 15 * time.Second
+
+// FetchDeadline is end of the fetch, FETCH_TIMEOUT from now
+// or FETCH_DEADLINE_MARGIN before the deadline of the context (lambda deadline) when it is earlier.
+func FetchDeadline(ctx context.Context, now time.Time) time.Time {
+	timeout, err := time.ParseDuration(os.Getenv("FETCH_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = fetchTimeout
+	}
+	margin, err := time.ParseDuration(os.Getenv("FETCH_DEADLINE_MARGIN"))
+	if err != nil || margin < 0 {
+		margin = fetchDeadlineMargin
+	}
+
+	end := now.Add(timeout)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Add(-margin).Before(end) {
+		end = deadline.Add(-margin)
+	}
+	return end
+}
+
+// FetchPrices is get current price of the symbols concurrently.
+// A symbol not returned until FetchDeadline remains as unpriced, so a lost result never blocks
+// and the fetched prices are still reported when the lambda deadline is near.
+func FetchPrices(ctx context.Context, provider quotes.Provider, symbols []Ticker) []Ticker {
+	// outstanding requests are canceled at the deadline
+	start := time.Now()
+	deadline := FetchDeadline(ctx, start)
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	// batch provider gets all symbols at once, the rest is fetched one by one
+	if bp, ok := provider.(quotes.BatchProvider); ok {
+		var names []string
+		for _, s := range symbols {
+			if !s.Fixed() && !quotes.IsDelisted(s.Symble) {
+				names = append(names, quotes.AliasOf(s.Symble))
+			}
+		}
+		provider = quotes.Prefetch(ctx, bp, names)
+	}
+
+	type indexed struct {
+		i      int
+		ticker Ticker
+	}
+
+	jobs := make(chan int, len(symbols))
+	for i := range symbols {
+		jobs <- i
+	}
+	close(jobs)
+
+	concurrency, jitter := FetchConcurrency()
+	done := make(chan indexed, len(symbols))
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// jitter is only for the symbols which need a request
+				var wait time.Duration
+				if pp, ok := provider.(*quotes.PrefetchedProvider); jitter > 0 && !symbols[i].Fixed() && (!ok || !pp.Has(symbols[i].Symble)) {
+					wait = time.Duration(rand.Int63n(int64(jitter)))
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+				done <- indexed{i, fetchOne(ctx, provider, symbols[i])}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	tickers := make([]Ticker, len(symbols))
+	received := make([]bool, len(symbols))
+collect:
+	for {
+		select {
+		case r, ok := <-done:
+			if !ok {
+				break collect
+			}
+			tickers[r.i], received[r.i] = r.ticker, true
+		case <-ctx.Done():
+			logging.Warn(ctx, "fetch stopped", logging.Fields{"deadline": deadline.Format(time.RFC3339), "error": ctx.Err()})
+			break collect
+		}
+	}
+
+	for i, ok := range received {
+		if !ok {
+			tickers[i] = symbols[i]
+			tickers[i].Value = 0.0
+			tickers[i].Error = "price not fetched"
+		}
+	}
+
+	var priced int
+	for _, t := range tickers {
+		if t.Priced() {
+			priced++
+		}
+	}
+	logging.Info(ctx, "fetch done", logging.Fields{"count": len(tickers), "priced": priced, "duration_ms": logging.Since(start)})
+	return tickers
+}
+
+// GetStockPrice is get current price of the symbol from the provider.
+// The position is kept even if fetch failed, only value is zero (price unavailable).
+func GetStockPrice(ctx context.Context, provider quotes.Provider, symbol Ticker) Ticker {
+	ticker := symbol
+	ticker.Value = 0.0
+	ticker.AsOf = ""
+	ticker.Stale = false
+	ticker.Session = ""
+	ticker.Error = ""
+	ticker.Provider = ""
+	ticker.Cached = false
+	ticker.Degraded = false
+	ticker.Fundamentals = nil
+	ticker.Alias = ""
+
+	// cash, bond and deposit are not in the market
+	if symbol.Fixed() {
+		ticker.Value = FixedValue(symbol, time.Now())
+		ticker.Provider = "fixed"
+		return ticker
+	}
+
+	// the delisted symbol is not fetched, the renamed one is priced by the new symbol (SYMBOL_ALIASES)
+	if quotes.IsDelisted(symbol.Symble) {
+		ticker.Error = "delisted (SYMBOL_ALIASES)"
+		return ticker
+	}
+	name := quotes.AliasOf(symbol.Symble)
+	if name != symbol.Symble {
+		ticker.Alias = name
+	}
+
+	deviation, _ := strconv.ParseFloat(os.Getenv("MAX_PRICE_DEVIATION"), 64)
+
+	start := time.Now()
+	var quote quotes.Quote
+	err := tracing.Capture(ctx, "quote", func(ctx context.Context) error {
+		tracing.Annotate(ctx, "symbol", symbol.Symble)
+		var err error
+		quote, err = provider.Quote(ctx, name)
+		return err
+	})
+	if err == nil {
+		err = CheckPrice(quote.Price, symbol.Bid, deviation)
+	}
+	if err != nil {
+		logging.Warn(ctx, "quote error", logging.Fields{"symbol": symbol.Symble, "provider": provider.Name(), "duration_ms": logging.Since(start), "error": err})
+		ticker.Error = err.Error()
+		if s := quotes.RenameSuggestion(symbol.Symble); s != "" {
+			ticker.Error = ticker.Error + ". " + s
+		}
+		return ticker
+	}
+
+	ticker.Value = quote.Price
+	ticker.AsOf = quote.AsOf
+	ticker.Stale = quote.Stale
+	ticker.Session = quote.Session
+	ticker.Provider = quote.Provider
+	ticker.Cached = quote.Cached
+	ticker.Degraded = quote.Degraded
+	ticker.Fundamentals = quote.Fundamentals
+	if ticker.Provider == "" {
+		ticker.Provider = provider.Name()
+	}
+	logging.Info(ctx, "quote", logging.Fields{"symbol": symbol.Symble, "provider": ticker.Provider, "price": quote.Price, "stale": quote.Stale, "duration_ms": logging.Since(start)})
+	return ticker
+}
+
+// CheckPrice is reject price out of range bid/deviation to bid*deviation.
+// deviation <= 1 is disable the check.
+func CheckPrice(value, bid, deviation float64) error {
+	if deviation <= 1 || bid <= 0 {
+		return nil
+	}
+	if value < bid/deviation || value > bid*deviation {
+		return fmt.Errorf("price %.4f is out of range from bid %.4f (deviation %g)", value, bid, deviation)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package portfolio
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// stubProvider is the quotes of the prices, the other symbols are not found.
+type stubProvider struct {
+	prices map[string]float64
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) Quote(ctx context.Context, symbol string) (quotes.Quote, error) {
+	price, ok := p.prices[symbol]
+	if !ok {
+		return quotes.Quote{}, fmt.Errorf("fetch error. 404 Not Found")
+	}
+	return quotes.Quote{Price: price}, nil
+}
+
+func TestGetStockPriceKeepsPositionOn404(t *testing.T) {
+	ticker := GetStockPrice(context.Background(), &stubProvider{}, Ticker{Symble: "AAPL", Bid: 150, Value: 160, Hold: 10, Dividend: 1.5})
+	if ticker.Priced() || ticker.Error != "fetch error. 404 Not Found" {
+		t.Fatalf("GetStockPrice() = %+v, want unpriced with the error", ticker)
+	}
+	if ticker.Symble != "AAPL" || ticker.Bid != 150 || ticker.Hold != 10 || ticker.Dividend != 1.5 {
+		t.Errorf("GetStockPrice() = %+v, want the position of the input", ticker)
+	}
+}
+
+func TestGetStockPriceProvider(t *testing.T) {
+	t.Setenv("MAX_PRICE_DEVIATION", "")
+	provider := &stubProvider{prices: map[string]float64{"AAPL": 130}}
+
+	ticker := GetStockPrice(context.Background(), provider, Ticker{Symble: "AAPL", Bid: 120, Hold: 10, Error: "old"})
+	if ticker.Value != 130 || ticker.Error != "" || ticker.Provider != "stub" {
+		t.Errorf("GetStockPrice(AAPL) = %+v, want 130 of stub", ticker)
+	}
+}
+
+func TestCheckPrice(t *testing.T) {
+	tests := []struct {
+		value, bid, deviation float64
+		ok                    bool
+	}{
+		{150, 150, 10, true},
+		{15, 150, 10, true},
+		{1500, 150, 10, true},
+		{0.05, 150, 10, false},
+		{14.9, 150, 10, false},
+		{1500.1, 150, 10, false},
+		// disabled by the deviation and without the bid
+		{0.05, 150, 0, true},
+		{0.05, 150, 1, true},
+		{0.05, 0, 10, true},
+	}
+	for _, tt := range tests {
+		if err := CheckPrice(tt.value, tt.bid, tt.deviation); (err == nil) != tt.ok {
+			t.Errorf("CheckPrice(%v, %v, %v) = %v, want ok %v", tt.value, tt.bid, tt.deviation, err, tt.ok)
+		}
+	}
+}
+
+func TestGetStockPriceDeviation(t *testing.T) {
+	t.Setenv("MAX_PRICE_DEVIATION", "10")
+	provider := &stubProvider{prices: map[string]float64{"AAPL": 0.05, "MSFT": 1250.5}}
+
+	if ticker := GetStockPrice(context.Background(), provider, Ticker{Symble: "AAPL", Bid: 150, Hold: 10}); ticker.Priced() || ticker.Bid != 150 || ticker.Hold != 10 {
+		t.Errorf("out of range: GetStockPrice() = %+v, want the unpriced position", ticker)
+	}
+	if ticker := GetStockPrice(context.Background(), provider, Ticker{Symble: "MSFT", Bid: 200, Hold: 5}); ticker.Value != 1250.5 {
+		t.Errorf("in range: GetStockPrice() = %+v, want 1250.5", ticker)
+	}
+}
+
+// blockingProvider is the provider which doesn't return the quote of the symbol until release is closed.
+type blockingProvider struct {
+	stubProvider
+	symbol  string
+	release chan struct{}
+}
+
+func (p *blockingProvider) Quote(ctx context.Context, symbol string) (quotes.Quote, error) {
+	if symbol == p.symbol {
+		<-p.release
+	}
+	return p.stubProvider.Quote(ctx, symbol)
+}
+
+func TestFetchPricesTimeout(t *testing.T) {
+	t.Setenv("FETCH_TIMEOUT", "100ms")
+	t.Setenv("FETCH_JITTER", "0")
+	provider := &blockingProvider{
+		stubProvider: stubProvider{prices: map[string]float64{"AAPL": 130, "MSFT": 250, "SLOW": 10}},
+		symbol:       "SLOW",
+		release:      make(chan struct{}),
+	}
+	defer close(provider.release)
+
+	start := time.Now()
+	tickers := FetchPrices(context.Background(), provider, []Ticker{
+		{Symble: "AAPL", Bid: 100, Hold: 1},
+		{Symble: "SLOW", Bid: 10, Hold: 1},
+		{Symble: "MSFT", Bid: 200, Hold: 1},
+	})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("FetchPrices() took %s, want it returns at FETCH_TIMEOUT", elapsed)
+	}
+	// the tickers are in the order of the symbols
+	if len(tickers) != 3 || tickers[0].Value != 130 || tickers[2].Value != 250 {
+		t.Fatalf("FetchPrices() = %+v, want the prices of AAPL and MSFT", tickers)
+	}
+	if tickers[1].Symble != "SLOW" || tickers[1].Priced() || tickers[1].Error != "price not fetched" {
+		t.Errorf("SLOW = %+v, want price not fetched", tickers[1])
+	}
+}
+
+// panicProvider is the provider which panics for the symbol.
+type panicProvider struct {
+	stubProvider
+	symbol string
+}
+
+func (p *panicProvider) Quote(ctx context.Context, symbol string) (quotes.Quote, error) {
+	if symbol == p.symbol {
+		panic("broken page")
+	}
+	return p.stubProvider.Quote(ctx, symbol)
+}
+
+func TestFetchPricesPanic(t *testing.T) {
+	t.Setenv("FETCH_JITTER", "0")
+	t.Setenv("FETCH_CONCURRENCY", "2")
+	provider := &panicProvider{stubProvider: stubProvider{prices: map[string]float64{"AAPL": 130, "MSFT": 250}}, symbol: "BAD"}
+
+	tickers := FetchPrices(context.Background(), provider, []Ticker{{Symble: "AAPL", Bid: 100, Hold: 1}, {Symble: "BAD", Bid: 10, Hold: 1}, {Symble: "MSFT", Bid: 200, Hold: 1}})
+	if len(tickers) != 3 || tickers[0].Value != 130 || tickers[2].Value != 250 {
+		t.Fatalf("FetchPrices() = %+v, want the prices of AAPL and MSFT", tickers)
+	}
+	if tickers[1].Priced() || tickers[1].Error != "panic. broken page" {
+		t.Errorf("BAD = %+v, want the panic error", tickers[1])
+	}
+}
+
+func TestFetchConcurrency(t *testing.T) {
+	t.Setenv("FETCH_CONCURRENCY", "")
+	t.Setenv("FETCH_JITTER", "")
+	if c, j := FetchConcurrency(); c != 5 || j != 500*time.Millisecond {
+		t.Errorf("FetchConcurrency() = %d, %s, want 5 and 500ms", c, j)
+	}
+	t.Setenv("FETCH_CONCURRENCY", "10")
+	t.Setenv("FETCH_JITTER", "0")
+	if c, j := FetchConcurrency(); c != 10 || j != 0 {
+		t.Errorf("FetchConcurrency() = %d, %s, want 10 and 0", c, j)
+	}
+}
+
+func TestFetchDeadline(t *testing.T) {
+	now := time.Date(2021, 6, 14, 18, 0, 0, 0, time.UTC)
+	t.Setenv("FETCH_TIMEOUT", "30s")
+	t.Setenv("FETCH_DEADLINE_MARGIN", "")
+	if got := FetchDeadline(context.Background(), now); !got.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("FetchDeadline() = %s, want FETCH_TIMEOUT from now", got)
+	}
+
+	// the lambda deadline is earlier, the margin is left for the upload and the notification
+	ctx, cancel := context.WithDeadline(context.Background(), now.Add(20*time.Second))
+	defer cancel()
+	if got := FetchDeadline(ctx, now); !got.Equal(now.Add(5 * time.Second)) {
+		t.Errorf("FetchDeadline() = %s, want 15s before the deadline of the context", got)
+	}
+}
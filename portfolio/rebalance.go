@@ -0,0 +1,94 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+)
+
+// defaultRebalanceThreshold is default of REBALANCE_THRESHOLD, percent points of the drift.
+const defaultRebalanceThreshold = 1.0
+
+// Trade is the suggestion to return the holding to its target weight.
+// Amount is the value to buy (negative is sell) in BASE_CURRENCY, Shares is it in the shares at the price (2 decimals).
+type Trade struct {
+	Symble string `json:"symble"`
+	// Category is set when the target is of the category (TARGET_ALLOCATION)
+	Category string  `json:"category,omitempty"`
+	Target   float64 `json:"target"`
+	Weight   float64 `json:"weight"`
+	Amount   float64 `json:"amount"`
+	Shares   float64 `json:"shares"`
+}
+
+// TargetAllocation is the target weight (%) by category of TARGET_ALLOCATION (json, e.g. {"Tech": 40, "Bond": 30}).
+func TargetAllocation() map[string]float64 {
+	var targets map[string]float64
+	if err := json.Unmarshal([]byte(os.Getenv("TARGET_ALLOCATION")), &targets); err != nil {
+		return nil
+	}
+	return targets
+}
+
+// RebalanceThreshold is REBALANCE_THRESHOLD, a holding whose weight is nearer its target is not traded.
+func RebalanceThreshold() float64 {
+	v, err := strconv.ParseFloat(os.Getenv("REBALANCE_THRESHOLD"), 64)
+	if err != nil || v < 0 {
+		return defaultRebalanceThreshold
+	}
+	return v
+}
+
+// Rebalance is the trades of the priced tickers to their target weights, the target_weight of the position
+// or the category target of TARGET_ALLOCATION split by the values of the holdings in the category.
+// The tickers need ApplyWeights, and the trades are largest amount first.
+func Rebalance(tickers []Ticker, targets map[string]float64, threshold float64) []Trade {
+	total := Summarize(tickers).Value
+	if total <= 0 {
+		return nil
+	}
+
+	// category value of the holdings without the target of the position
+	values := map[string]float64{}
+	for _, t := range tickers {
+		if t.Priced() && !t.Fixed() && t.TargetWeight == 0 {
+			values[categoryOf(t)] += t.Value * t.Hold * t.FX()
+		}
+	}
+
+	var trades []Trade
+	for _, t := range tickers {
+		if !t.Priced() || t.Fixed() {
+			continue
+		}
+		value := t.Value * t.Hold * t.FX()
+		trade := Trade{Symble: t.Symble, Target: t.TargetWeight, Weight: t.Weight}
+		if t.TargetWeight == 0 {
+			category := categoryOf(t)
+			target, ok := targets[category]
+			if !ok || values[category] <= 0 {
+				continue
+			}
+			// the holding keeps its share of the category
+			trade.Category, trade.Target = category, target*value/values[category]
+		}
+		if math.Abs(trade.Target-trade.Weight) < threshold {
+			continue
+		}
+		trade.Amount = trade.Target/100*total - value
+		trade.Shares = math.Round(trade.Amount/(t.Value*t.FX())*100) / 100
+		trades = append(trades, trade)
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return math.Abs(trades[i].Amount) > math.Abs(trades[j].Amount) })
+	return trades
+}
+
+// categoryOf is the category of the ticker, Other when it has none.
+func categoryOf(t Ticker) string {
+	if t.Category == "" {
+		return OtherCategory
+	}
+	return t.Category
+}
@@ -0,0 +1,57 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+)
+
+// Kind of Discrepancy.
+const (
+	DiscrepancyMissing = "missing"
+	DiscrepancyExtra   = "extra"
+	DiscrepancyHold    = "hold"
+)
+
+// holdTolerance is difference of the holds which is the same, the fractional shares of the broker are rounded.
+const holdTolerance = 1e-6
+
+// Discrepancy is a position which differs between the portfolio and the broker.
+// Kind is missing (only in the broker), extra (only in the portfolio) or hold (the holds differ).
+type Discrepancy struct {
+	Symble     string  `json:"symble"`
+	Kind       string  `json:"kind"`
+	Hold       float64 `json:"hold"`
+	BrokerHold float64 `json:"broker_hold"`
+}
+
+// Reconcile is the discrepancies of the positions of the portfolio and the broker by symbol, in the order of the symbol.
+// The rows of a symbol are summed, cash, bond, deposit and the watch-only symbols are not in the portfolio side.
+func Reconcile(tickers, broker []Ticker) []Discrepancy {
+	holds, brokerHolds := map[string]float64{}, map[string]float64{}
+	for _, t := range tickers {
+		if !t.Fixed() && !t.WatchOnly() {
+			holds[t.Symble] += t.Hold
+		}
+	}
+	for _, t := range broker {
+		brokerHolds[t.Symble] += t.Hold
+	}
+
+	var discrepancies []Discrepancy
+	for symbol, hold := range holds {
+		broker, ok := brokerHolds[symbol]
+		switch {
+		case !ok:
+			discrepancies = append(discrepancies, Discrepancy{Symble: symbol, Kind: DiscrepancyExtra, Hold: hold})
+		case math.Abs(hold-broker) > holdTolerance:
+			discrepancies = append(discrepancies, Discrepancy{Symble: symbol, Kind: DiscrepancyHold, Hold: hold, BrokerHold: broker})
+		}
+	}
+	for symbol, broker := range brokerHolds {
+		if _, ok := holds[symbol]; !ok && math.Abs(broker) > holdTolerance {
+			discrepancies = append(discrepancies, Discrepancy{Symble: symbol, Kind: DiscrepancyMissing, BrokerHold: broker})
+		}
+	}
+	sort.Slice(discrepancies, func(i, j int) bool { return discrepancies[i].Symble < discrepancies[j].Symble })
+	return discrepancies
+}
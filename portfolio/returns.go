@@ -0,0 +1,148 @@
+package portfolio
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ValueDay is total value and cost of the portfolio of a day, a change of the cost is money in or out of it.
+type ValueDay struct {
+	Date  string
+	Value float64
+	Cost  float64
+}
+
+// CashFlow is money in (negative) or out (positive) of the portfolio at the date.
+type CashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// Returns is return of the portfolio over the period, in %.
+// TWR is time-weighted, Annualized is TWR per year, XIRR is money-weighted per year.
+type Returns struct {
+	From       string  `json:"from"`
+	To         string  `json:"to"`
+	TWR        float64 `json:"twr"`
+	Annualized float64 `json:"annualized"`
+	XIRR       float64 `json:"xirr"`
+}
+
+// ErrNoRoot is returned by XIRR when the flows have no rate, e.g. all of them are in or out.
+var ErrNoRoot = errors.New("no rate of the cash flows")
+
+// xirrDays is days of a year of XIRR.
+const xirrDays = 365.0
+
+// NewReturns is returns of the days in order of the date, ok is false when it has less than two days of value.
+// The cost of a buy or sell is the cash flow at the day, so the price changes are only in TWR.
+func NewReturns(days []ValueDay) (Returns, bool) {
+	var valued []ValueDay
+	for _, d := range days {
+		if d.Value > 0 {
+			valued = append(valued, d)
+		}
+	}
+	if len(valued) < 2 {
+		return Returns{}, false
+	}
+	first, last := valued[0], valued[len(valued)-1]
+	start, err := time.Parse("2006-01-02", first.Date)
+	if err != nil {
+		return Returns{}, false
+	}
+	end, err := time.Parse("2006-01-02", last.Date)
+	if err != nil || !end.After(start) {
+		return Returns{}, false
+	}
+
+	growth := 1.0
+	flows := []CashFlow{{Date: start, Amount: -first.Value}}
+	for i := 1; i < len(valued); i++ {
+		prev, d := valued[i-1], valued[i]
+		flow := d.Cost - prev.Cost
+		growth *= (d.Value - flow) / prev.Value
+		if flow != 0 {
+			date, err := time.Parse("2006-01-02", d.Date)
+			if err != nil {
+				return Returns{}, false
+			}
+			flows = append(flows, CashFlow{Date: date, Amount: -flow})
+		}
+	}
+	flows = append(flows, CashFlow{Date: end, Amount: last.Value})
+
+	r := Returns{From: first.Date, To: last.Date, TWR: (growth - 1) * 100}
+	years := end.Sub(start).Hours() / 24 / xirrDays
+	if growth > 0 {
+		r.Annualized = (math.Pow(growth, 1/years) - 1) * 100
+	}
+	if rate, err := XIRR(flows); err == nil {
+		r.XIRR = rate * 100
+	}
+	return r, true
+}
+
+// XIRR is annual rate whose net present value of the flows is zero, by the newton method and the bisection when it doesn't converge.
+func XIRR(flows []CashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, ErrNoRoot
+	}
+	in, out := false, false
+	for _, f := range flows {
+		in = in || f.Amount < 0
+		out = out || f.Amount > 0
+	}
+	if !in || !out {
+		return 0, ErrNoRoot
+	}
+
+	npv := func(rate float64) (float64, float64) {
+		var v, dv float64
+		for _, f := range flows {
+			t := f.Date.Sub(flows[0].Date).Hours() / 24 / xirrDays
+			d := math.Pow(1+rate, t)
+			v += f.Amount / d
+			dv -= t * f.Amount / (d * (1 + rate))
+		}
+		return v, dv
+	}
+
+	rate := 0.1
+	for i := 0; i < 50; i++ {
+		v, dv := npv(rate)
+		if math.Abs(v) < 1e-7 {
+			return rate, nil
+		}
+		if dv == 0 {
+			break
+		}
+		next := rate - v/dv
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		rate = next
+	}
+
+	// the rate is between -99.99% and 1000000%
+	lo, hi := -0.9999, 10000.0
+	vlo, _ := npv(lo)
+	vhi, _ := npv(hi)
+	if vlo*vhi > 0 {
+		return 0, ErrNoRoot
+	}
+	for i := 0; i < 200; i++ {
+		mid := (lo + hi) / 2
+		v, _ := npv(mid)
+		if math.Abs(v) < 1e-7 || hi-lo < 1e-10 {
+			return mid, nil
+		}
+		if v*vlo < 0 {
+			hi = mid
+		} else {
+			lo, vlo = mid, v
+		}
+	}
+	return (lo + hi) / 2, nil
+}
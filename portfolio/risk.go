@@ -0,0 +1,105 @@
+package portfolio
+
+import (
+	"math"
+	"sort"
+)
+
+// tradingDays is trading days of a year, for the annualized volatility.
+const tradingDays = 252
+
+// Risk is the risk of the daily returns over the period, in %.
+// Volatility is the annualized standard deviation, MaxDrawdown is the largest fall from a peak (negative),
+// Sharpe is the annualized return over the risk-free rate per the volatility.
+type Risk struct {
+	Volatility  float64 `json:"volatility"`
+	MaxDrawdown float64 `json:"max_drawdown"`
+	Sharpe      float64 `json:"sharpe"`
+}
+
+// SymbolRisk is the risk of the price of a symbol.
+type SymbolRisk struct {
+	Symble string `json:"symble"`
+	Risk
+}
+
+// RiskReport is the risk of the portfolio and the positions over the period, RiskFree is the annual % of the sharpe ratio.
+type RiskReport struct {
+	RiskFree  float64      `json:"risk_free"`
+	Portfolio Risk         `json:"portfolio"`
+	Symbols   []SymbolRisk `json:"symbols,omitempty"`
+}
+
+// NewRisk is the risk of the daily returns, the levels are the value of each day after the return.
+// ok is false with less than two returns.
+func NewRisk(returns []float64, levels []float64, riskFree float64) (Risk, bool) {
+	if len(returns) < 2 {
+		return Risk{}, false
+	}
+	var mean float64
+	for _, r := range returns {
+		mean += r
+	}
+	mean /= float64(len(returns))
+	var variance float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+	}
+	variance /= float64(len(returns) - 1)
+
+	risk := Risk{Volatility: math.Sqrt(variance) * math.Sqrt(tradingDays) * 100}
+	peak := 0.0
+	for _, v := range levels {
+		if v > peak {
+			peak = v
+		}
+		if peak > 0 {
+			risk.MaxDrawdown = math.Min(risk.MaxDrawdown, (v-peak)/peak*100)
+		}
+	}
+	if risk.Volatility > 0 {
+		risk.Sharpe = (mean*tradingDays*100 - riskFree) / risk.Volatility
+	}
+	return risk, true
+}
+
+// NewRiskReport is the risk of the days of the portfolio and the prices of the positions of the results in order of the date.
+// The change of the cost of a day is the money in or out, it is not in the return of the portfolio.
+func NewRiskReport(days []ValueDay, results []Result, riskFree float64) (RiskReport, bool) {
+	var returns, levels []float64
+	level := 1.0
+	for i := 1; i < len(days); i++ {
+		prev, d := days[i-1], days[i]
+		if prev.Value <= 0 || d.Value <= 0 {
+			continue
+		}
+		r := (d.Value-(d.Cost-prev.Cost))/prev.Value - 1
+		level *= 1 + r
+		returns, levels = append(returns, r), append(levels, level)
+	}
+	portfolio, ok := NewRisk(returns, append([]float64{1}, levels...), riskFree)
+	if !ok {
+		return RiskReport{}, false
+	}
+	report := RiskReport{RiskFree: riskFree, Portfolio: portfolio}
+
+	prices := map[string][]float64{}
+	for _, r := range results {
+		for _, t := range r.Body {
+			if t.Priced() && !t.Fixed() {
+				prices[t.Symble] = append(prices[t.Symble], t.Value)
+			}
+		}
+	}
+	for symbol, p := range prices {
+		var returns []float64
+		for i := 1; i < len(p); i++ {
+			returns = append(returns, p[i]/p[i-1]-1)
+		}
+		if risk, ok := NewRisk(returns, p, riskFree); ok {
+			report.Symbols = append(report.Symbols, SymbolRisk{Symble: symbol, Risk: risk})
+		}
+	}
+	sort.Slice(report.Symbols, func(i, j int) bool { return report.Symbols[i].Volatility > report.Symbols[j].Volatility })
+	return report, true
+}
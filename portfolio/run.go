@@ -0,0 +1,63 @@
+package portfolio
+
+import "time"
+
+// RunInfo is the metadata of the run, what was fetched and notified and how long it took.
+type RunInfo struct {
+	StartedAt string `json:"started_at"`
+	// DurationMs is the whole run and FetchMs is the price fetch of it, the stored result has the duration until the upload
+	DurationMs int64 `json:"duration_ms"`
+	FetchMs    int64 `json:"fetch_ms"`
+	// Fetched is the symbols got from the providers, CacheHits is the symbols of the quote cache and Failed is the unpriced symbols
+	Fetched   int `json:"fetched"`
+	CacheHits int `json:"cache_hits"`
+	Failed    int `json:"failed"`
+	// Retries is the http retries of the run (HTTP_RETRY_BUDGET)
+	Retries int `json:"retries"`
+	// Degraded is the symbols of the stale prices when the providers are out of their request budgets (PROVIDER_BUDGETS)
+	Degraded []string `json:"degraded,omitempty"`
+	// Providers is the provider of the price by symbol
+	Providers map[string]string `json:"providers,omitempty"`
+	// Notifications is the outcome of every notification of the run, only in the api response
+	Notifications []NotifyOutcome `json:"notifications,omitempty"`
+}
+
+// NotifyOutcome is a notification of the run, Status is sent, failed or suppressed.
+type NotifyOutcome struct {
+	Channel string `json:"channel"`
+	Subject string `json:"subject,omitempty"`
+	Status  string `json:"status"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Notification status of NotifyOutcome.
+const (
+	NotifySent       = "sent"
+	NotifyFailed     = "failed"
+	NotifySuppressed = "suppressed"
+)
+
+// NewRunInfo is the run started at start.
+func NewRunInfo(start time.Time) *RunInfo {
+	return &RunInfo{StartedAt: start.UTC().Format(time.RFC3339), Providers: map[string]string{}}
+}
+
+// Count is add the fetch of the tickers, the fixed assets are not fetched.
+func (r *RunInfo) Count(tickers []Ticker) {
+	for _, t := range tickers {
+		switch {
+		case t.Fixed():
+		case t.Value <= 0:
+			r.Failed++
+		case t.Cached:
+			r.CacheHits++
+			if t.Degraded {
+				r.Degraded = append(r.Degraded, t.Symble)
+			}
+			r.Providers[t.Symble] = t.Provider
+		default:
+			r.Fetched++
+			r.Providers[t.Symble] = t.Provider
+		}
+	}
+}
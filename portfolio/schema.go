@@ -0,0 +1,59 @@
+package portfolio
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SchemaVersion is schema_version of the result written now.
+// 1 is the result without schema_version, before the errors and the weights of the tickers. 2 is before the rates, 3 is before the accounts.
+// Increment it and add the upgrade to migrations when a field of the result is changed.
+const SchemaVersion = 4
+
+// migrations is the upgrade of the result from version n to n+1, by n.
+var migrations = map[int]func(*Result){
+	1: func(r *Result) {
+		ApplyWeights(r.Body)
+		if r.Errors == nil {
+			r.Errors = SymbolErrors(r.Body)
+		}
+	},
+	2: func(r *Result) {
+		if r.Rates == nil {
+			r.Rates = FXRates(r.Body)
+		}
+	},
+	3: func(r *Result) {
+		if r.Accounts == nil {
+			r.Accounts = AccountSubtotals(r.Body)
+		}
+	},
+}
+
+// ErrNewerSchema is returned by ParseResult when the result is written by a newer version.
+var ErrNewerSchema = fmt.Errorf("result schema is newer than %d", SchemaVersion)
+
+// ParseResult is the stored result json upgraded to SchemaVersion.
+func ParseResult(b []byte) (Result, error) {
+	var r Result
+	if err := json.Unmarshal(b, &r); err != nil {
+		return Result{}, err
+	}
+	if r.SchemaVersion > SchemaVersion {
+		return Result{}, fmt.Errorf("%w: %d", ErrNewerSchema, r.SchemaVersion)
+	}
+	MigrateResult(&r)
+	return r, nil
+}
+
+// MigrateResult is upgrade the result to SchemaVersion, the result without schema_version is version 1.
+func MigrateResult(r *Result) {
+	if r.SchemaVersion == 0 {
+		r.SchemaVersion = 1
+	}
+	for ; r.SchemaVersion < SchemaVersion; r.SchemaVersion++ {
+		if m, ok := migrations[r.SchemaVersion]; ok {
+			m(r)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package portfolio
+
+import (
+	"sort"
+)
+
+// Cross of the 50 and 200 day moving averages.
+const (
+	CrossGolden = "golden"
+	CrossDeath  = "death"
+)
+
+// Signal is the moving averages of the daily prices of the symbol, zero when there are not enough days.
+// Cross is golden or death when the 50 day average crossed the 200 day one on the day.
+type Signal struct {
+	Symble string  `json:"symble"`
+	MA20   float64 `json:"ma20,omitempty"`
+	MA50   float64 `json:"ma50,omitempty"`
+	MA200  float64 `json:"ma200,omitempty"`
+	Cross  string  `json:"cross,omitempty"`
+}
+
+// MovingAverage is the simple average of the last n prices, zero when there are less than n.
+func MovingAverage(prices []float64, n int) float64 {
+	if n <= 0 || len(prices) < n {
+		return 0
+	}
+	var sum float64
+	for _, p := range prices[len(prices)-n:] {
+		sum += p
+	}
+	return sum / float64(n)
+}
+
+// Signals is the moving averages of the priced positions of the result, over the daily prices of the history and the result.
+// A history result of the day of the result is replaced by it.
+func Signals(history []Result, result Result) []Signal {
+	sort.SliceStable(history, func(i, j int) bool { return history[i].CreatedAt < history[j].CreatedAt })
+	prices := map[string][]float64{}
+	for _, r := range history {
+		if r.CreatedAt >= result.CreatedAt {
+			continue
+		}
+		for _, t := range r.Body {
+			if t.Priced() && !t.Fixed() {
+				prices[t.Symble] = append(prices[t.Symble], t.Value)
+			}
+		}
+	}
+
+	var signals []Signal
+	seen := map[string]bool{}
+	for _, t := range result.Body {
+		if !t.Priced() || t.Fixed() || seen[t.Symble] {
+			continue
+		}
+		seen[t.Symble] = true
+		p := append(prices[t.Symble], t.Value)
+
+		s := Signal{Symble: t.Symble, MA20: MovingAverage(p, 20), MA50: MovingAverage(p, 50), MA200: MovingAverage(p, 200)}
+		if prev := p[:len(p)-1]; s.MA200 > 0 && len(prev) >= 200 {
+			before := MovingAverage(prev, 50) - MovingAverage(prev, 200)
+			now := s.MA50 - s.MA200
+			switch {
+			case before <= 0 && now > 0:
+				s.Cross = CrossGolden
+			case before >= 0 && now < 0:
+				s.Cross = CrossDeath
+			}
+		}
+		if s.MA20 > 0 {
+			signals = append(signals, s)
+		}
+	}
+	return signals
+}
@@ -0,0 +1,52 @@
+package portfolio
+
+import (
+	"sort"
+	"strings"
+)
+
+// Group is account or category of the ticker by GROUP_BY, Other when it is not set.
+func (t Ticker) Group(groupBy string) string {
+	var group string
+	switch strings.ToLower(groupBy) {
+	case "account":
+		group = t.Account
+	case "category", "sector":
+		group = t.Category
+	default:
+		return ""
+	}
+	if group == "" {
+		return OtherCategory
+	}
+	return group
+}
+
+// SortTickers is sort the tickers by symbol, profit_loss or percent (winners first) in the groups.
+// Groups are sorted by name and Other is last, unknown sort key keeps the order of the stock data.
+func SortTickers(tickers []Ticker, sortBy, groupBy string) {
+	less := func(a, b Ticker) bool { return false }
+	switch strings.ToLower(sortBy) {
+	case "symbol":
+		less = func(a, b Ticker) bool { return a.Symble < b.Symble }
+	case "profit_loss":
+		less = func(a, b Ticker) bool { return a.Earning()*a.FX() > b.Earning()*b.FX() }
+	case "percent":
+		less = func(a, b Ticker) bool { return a.Percent() > b.Percent() }
+	}
+
+	sort.SliceStable(tickers, func(i, j int) bool {
+		a, b := tickers[i], tickers[j]
+		if ga, gb := a.Group(groupBy), b.Group(groupBy); ga != gb {
+			if ga == OtherCategory || gb == OtherCategory {
+				return gb == OtherCategory
+			}
+			return ga < gb
+		}
+		// unpriced ticker is last
+		if a.Priced() != b.Priced() {
+			return a.Priced()
+		}
+		return less(a, b)
+	})
+}
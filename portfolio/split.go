@@ -0,0 +1,107 @@
+package portfolio
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// Split is a stock split of the splits log (date,symbol,ratio), ratio is new shares per old share (4 is 4:1).
+type Split struct {
+	Date   string
+	Symble string
+	Ratio  float64
+}
+
+// ParseSplits is parse the splits log (date,symbol,ratio), the first line is the header.
+// A reverse split is a ratio less than one, e.g. 0.1 is 1:10.
+func ParseSplits(buf []byte) ([]Split, []ParseError) {
+	var splits []Split
+	var errs []ParseError
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 || line == "" {
+			continue
+		}
+
+		cols := strings.Split(line, ",")
+		if len(cols) != 3 {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("expected 3 columns, got %d", len(cols))})
+			continue
+		}
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(cols[2]), 64)
+		if err != nil || ratio <= 0 {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("invalid ratio %q", cols[2])})
+			continue
+		}
+
+		splits = append(splits, Split{
+			Date:   strings.TrimSpace(cols[0]),
+			Symble: quotes.NormalizeSymbol(cols[1]),
+			Ratio:  ratio,
+		})
+	}
+	return splits, errs
+}
+
+// ApplySplits is adjust hold and per share prices of the tickers by the splits after since (YYYY-MM-DD).
+// since is the date of the stock data, positions written after the split are already adjusted.
+func ApplySplits(tickers []Ticker, splits []Split, since string) []Ticker {
+	for _, s := range splits {
+		if s.Date <= since {
+			continue
+		}
+		for i, t := range tickers {
+			if t.Symble == s.Symble {
+				tickers[i] = t.Split(s.Ratio)
+			}
+		}
+	}
+	return tickers
+}
+
+// AdjustTransactions is adjust qty and price of the transactions before the splits.
+func AdjustTransactions(transactions []Transaction, splits []Split) []Transaction {
+	for _, s := range splits {
+		for i, tr := range transactions {
+			if tr.Symble == s.Symble && tr.Date < s.Date {
+				transactions[i].Qty = tr.Qty * s.Ratio
+				transactions[i].Price = tr.Price / s.Ratio
+			}
+		}
+	}
+	return transactions
+}
+
+// Split is the ticker after the split of the ratio, cost basis is not changed.
+func (t Ticker) Split(ratio float64) Ticker {
+	t.Hold = t.Hold * ratio
+	t.Bid = t.Bid / ratio
+	t.Dividend = t.Dividend / ratio
+	t.TargetPrice = t.TargetPrice / ratio
+	t.AlertHigh = t.AlertHigh / ratio
+	t.AlertLow = t.AlertLow / ratio
+	return t
+}
+
+// ParseWatchlistSplits is ParseWatchlist adjusted by the splits, since is the date of the stock data.
+// Transactions are adjusted by their dates and the positions by the splits after since.
+func ParseWatchlistSplits(name string, buf []byte, splits []Split, since string) ([]Ticker, []ParseError, error) {
+	if len(splits) == 0 {
+		return ParseWatchlist(name, buf)
+	}
+	if WatchlistFormat(name, buf) == "transactions" {
+		transactions, errs := ParseTransactions(buf)
+		return Holdings(AdjustTransactions(transactions, splits)), errs, nil
+	}
+	tickers, errs, err := ParseWatchlist(name, buf)
+	if err != nil {
+		return nil, errs, err
+	}
+	return ApplySplits(tickers, splits, since), errs, nil
+}
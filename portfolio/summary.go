@@ -0,0 +1,123 @@
+package portfolio
+
+import (
+	"sort"
+)
+
+// Summary is aggregate of the tickers.
+type Summary struct {
+	Count  int `json:"count"`
+	Priced int `json:"priced"`
+	// Watching is the priced watch-only symbols of Priced
+	Watching   int     `json:"watching,omitempty"`
+	ProfitLoss float64 `json:"profit_loss"`
+	Dividend   float64 `json:"dividend"`
+	// Cost and Value are priced positions only, UnpricedCost is cost of the others
+	Cost         float64 `json:"cost"`
+	Value        float64 `json:"value"`
+	UnpricedCost float64 `json:"unpriced_cost"`
+	// ShortCost is cost of the priced short positions (positive), Cost is net of it
+	ShortCost float64 `json:"short_cost,omitempty"`
+	// Realized is profit loss of the sold shares, it is not in ProfitLoss
+	Realized float64 `json:"realized,omitempty"`
+	// Categories is profit loss by category, CategoryValues is value by category
+	Categories     map[string]float64 `json:"categories,omitempty"`
+	CategoryValues map[string]float64 `json:"category_values,omitempty"`
+	Gainer         Ticker             `json:"-"`
+	Loser          Ticker             `json:"-"`
+}
+
+// Add is aggregate the tickers, unpriced ticker is only counted.
+func (s *Summary) Add(tickers ...Ticker) {
+	for _, t := range tickers {
+		s.Count++
+		s.Realized += t.Realized * t.FX()
+		// totals are in BASE_CURRENCY
+		fx := t.FX()
+		if !t.Priced() {
+			s.UnpricedCost += t.Bid * t.Hold * fx
+			continue
+		}
+		// the watch-only symbol is priced, but not in the totals
+		if t.WatchOnly() {
+			s.Priced++
+			s.Watching++
+			continue
+		}
+		s.Cost += t.Bid * t.Hold * fx
+		if t.Short() {
+			s.ShortCost -= t.Bid * t.Hold * fx
+		}
+		s.Value += t.Value * t.Hold * fx
+		s.ProfitLoss += t.Earning() * fx
+
+		category := t.Category
+		if category == "" {
+			category = OtherCategory
+		}
+		if s.Categories == nil {
+			s.Categories = map[string]float64{}
+			s.CategoryValues = map[string]float64{}
+		}
+		s.Categories[category] += t.Earning() * fx
+		s.CategoryValues[category] += t.Value * t.Hold * fx
+		s.Dividend += (t.Dividend*t.Hold + t.DividendReceived) * fx
+
+		if s.Priced == s.Watching || t.Percent() > s.Gainer.Percent() {
+			s.Gainer = t
+		}
+		if s.Priced == s.Watching || t.Percent() < s.Loser.Percent() {
+			s.Loser = t
+		}
+		s.Priced++
+	}
+}
+
+// Percent is overall return rate of the priced positions, of the gross cost of the long and short positions.
+func (s Summary) Percent() float64 {
+	gross := s.Cost + 2*s.ShortCost
+	if gross == 0 {
+		return 0
+	}
+	return s.ProfitLoss / gross * 100
+}
+
+// Allocation is percent of the value by category (sector or asset class).
+// It is nil when no ticker has the category.
+func (s Summary) Allocation() map[string]float64 {
+	if _, other := s.Categories[OtherCategory]; len(s.Categories) <= 1 && other {
+		return nil
+	}
+	allocation := map[string]float64{}
+	for name, v := range s.CategoryValues {
+		if s.Value != 0 {
+			allocation[name] = v / s.Value * 100
+		}
+	}
+	return allocation
+}
+
+// OtherCategory is category of the uncategorized ticker.
+const OtherCategory = "Other"
+
+// CategoryNames is sorted category names, Other is last.
+func (s Summary) CategoryNames() []string {
+	var names []string
+	for name := range s.Categories {
+		if name != OtherCategory {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if _, ok := s.Categories[OtherCategory]; ok {
+		names = append(names, OtherCategory)
+	}
+	return names
+}
+
+// Summarize is aggregate of the tickers.
+func Summarize(tickers []Ticker) Summary {
+	var s Summary
+	s.Add(tickers...)
+	return s
+}
@@ -0,0 +1,86 @@
+package portfolio
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// TaxSymbol is realized profit loss and dividends of a symbol in the fiscal year, in the currency of the symbol.
+type TaxSymbol struct {
+	Symble    string  `json:"symble"`
+	Sales     int     `json:"sales"`
+	Proceeds  float64 `json:"proceeds"`
+	Cost      float64 `json:"cost"`
+	Gain      float64 `json:"gain"`
+	Dividends float64 `json:"dividends"`
+}
+
+// TaxReport is the annual summary of the fiscal year for the tax filing, From and To are the first and last day of it.
+type TaxReport struct {
+	FiscalYear int         `json:"fiscal_year"`
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	CostBasis  string      `json:"cost_basis"`
+	Symbols    []TaxSymbol `json:"symbols"`
+	Total      TaxSymbol   `json:"total"`
+}
+
+// FiscalYear is the first day of the fiscal year starting at month/day which t is in, the year is of the first day.
+func FiscalYear(t time.Time, month time.Month, day int) time.Time {
+	start := time.Date(t.Year(), month, day, 0, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+// ParseFiscalYearStart is parse the first day of the fiscal year (MM-DD, e.g. 04-01).
+func ParseFiscalYearStart(v string) (time.Month, int, error) {
+	d, err := time.Parse("01-02", v)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not MM-DD", v)
+	}
+	return d.Month(), d.Day(), nil
+}
+
+// NewTaxReport is the totals by symbol of the sales and dividends between from and to (YYYY-MM-DD) of the fiscal year.
+func NewTaxReport(year int, from, to, method string, sales []Sale, dividends []Dividend) TaxReport {
+	index := map[string]*TaxSymbol{}
+	symbol := func(name string) *TaxSymbol {
+		if _, ok := index[name]; !ok {
+			index[name] = &TaxSymbol{Symble: name}
+		}
+		return index[name]
+	}
+	for _, s := range sales {
+		if s.Date < from || s.Date > to {
+			continue
+		}
+		t := symbol(s.Symble)
+		t.Sales++
+		t.Proceeds += s.Proceeds
+		t.Cost += s.Cost
+		t.Gain += s.Gain
+	}
+	for _, d := range dividends {
+		if d.Date < from || d.Date > to {
+			continue
+		}
+		symbol(d.Symble).Dividends += d.Amount
+	}
+
+	r := TaxReport{FiscalYear: year, From: from, To: to, CostBasis: method, Total: TaxSymbol{Symble: "TOTAL"}}
+	for _, t := range index {
+		r.Symbols = append(r.Symbols, *t)
+		r.Total.Sales += t.Sales
+		r.Total.Proceeds += t.Proceeds
+		r.Total.Cost += t.Cost
+		r.Total.Gain += t.Gain
+		r.Total.Dividends += t.Dividends
+	}
+	sort.Slice(r.Symbols, func(i, j int) bool {
+		return r.Symbols[i].Symble < r.Symbols[j].Symble
+	})
+	return r
+}
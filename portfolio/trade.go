@@ -0,0 +1,231 @@
+package portfolio
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// TradeConfirmation is a trade execution of the broker webhook, the common names of the fields are accepted
+// (id, execution_id or trade_id, symbol or ticker, qty, quantity or shares, side or action, date or executed_at).
+type TradeConfirmation struct {
+	ID          string  `json:"id"`
+	ExecutionID string  `json:"execution_id"`
+	TradeID     string  `json:"trade_id"`
+	Date        string  `json:"date"`
+	ExecutedAt  string  `json:"executed_at"`
+	Symbol      string  `json:"symbol"`
+	Symble      string  `json:"symble"`
+	Ticker      string  `json:"ticker"`
+	Qty         float64 `json:"qty"`
+	Quantity    float64 `json:"quantity"`
+	Shares      float64 `json:"shares"`
+	Price       float64 `json:"price"`
+	Side        string  `json:"side"`
+	Action      string  `json:"action"`
+	Lot         string  `json:"lot"`
+}
+
+// tradeFile is {"trades": [...]} of the trade confirmations.
+type tradeFile struct {
+	Trades []TradeConfirmation `json:"trades"`
+}
+
+// tradeSides is the side of the transaction log by the side of the broker.
+var tradeSides = map[string]string{
+	"buy":    "buy",
+	"b":      "buy",
+	"bought": "buy",
+	"sell":   "sell",
+	"s":      "sell",
+	"sold":   "sell",
+}
+
+// Transaction is the trade as the transaction of the log, the date of executed_at (RFC 3339) is in loc.
+func (c TradeConfirmation) Transaction(loc *time.Location) (Transaction, error) {
+	tr := Transaction{Date: c.Date, Symble: c.Symble, Qty: c.Qty, Price: c.Price, Lot: c.Lot}
+	for _, id := range []string{c.ID, c.ExecutionID, c.TradeID} {
+		if tr.ID == "" {
+			tr.ID = strings.TrimSpace(id)
+		}
+	}
+	if err := validExecutionID(tr.ID); err != nil {
+		return tr, err
+	}
+	if tr.Date == "" && c.ExecutedAt != "" {
+		t, err := time.Parse(time.RFC3339, c.ExecutedAt)
+		if err != nil {
+			return tr, fmt.Errorf("invalid executed_at %q", c.ExecutedAt)
+		}
+		tr.Date = t.In(loc).Format("2006-01-02")
+	}
+	if _, err := time.Parse("2006-01-02", tr.Date); err != nil {
+		return tr, fmt.Errorf("invalid date %q", tr.Date)
+	}
+	for _, s := range []string{c.Symbol, c.Ticker} {
+		if tr.Symble == "" {
+			tr.Symble = s
+		}
+	}
+	if tr.Symble = quotes.NormalizeSymbol(tr.Symble); tr.Symble == "" {
+		return tr, fmt.Errorf("symbol is required")
+	}
+	if err := validLogField("symbol", tr.Symble); err != nil {
+		return tr, err
+	}
+	if err := validLogField("lot", tr.Lot); err != nil {
+		return tr, err
+	}
+	for _, q := range []float64{c.Quantity, c.Shares} {
+		if tr.Qty == 0 {
+			tr.Qty = q
+		}
+	}
+	if tr.Qty <= 0 {
+		return tr, fmt.Errorf("invalid qty %g", tr.Qty)
+	}
+	if tr.Price < 0 {
+		return tr, fmt.Errorf("invalid price %g", tr.Price)
+	}
+	side := c.Side
+	if side == "" {
+		side = c.Action
+	}
+	var ok bool
+	if tr.Side, ok = tradeSides[strings.ToLower(strings.TrimSpace(side))]; !ok {
+		return tr, fmt.Errorf("invalid side %q", side)
+	}
+	return tr, nil
+}
+
+// ParseTradesJSON is the transactions of the trade confirmations, a json object, an array or {"trades": [...]}.
+// The trades are all or nothing, the error has the number of the invalid one.
+func ParseTradesJSON(b []byte, loc *time.Location) ([]Transaction, error) {
+	var trades []TradeConfirmation
+	body := bytes.TrimSpace(b)
+	switch {
+	case bytes.HasPrefix(body, []byte("[")):
+		if err := json.Unmarshal(body, &trades); err != nil {
+			return nil, fmt.Errorf("invalid json trades. %s", err)
+		}
+	case bytes.Contains(body, []byte(`"trades"`)):
+		var file tradeFile
+		if err := json.Unmarshal(body, &file); err != nil {
+			return nil, fmt.Errorf("invalid json trades. %s", err)
+		}
+		trades = file.Trades
+	default:
+		var trade TradeConfirmation
+		if err := json.Unmarshal(body, &trade); err != nil {
+			return nil, fmt.Errorf("invalid json trades. %s", err)
+		}
+		trades = []TradeConfirmation{trade}
+	}
+
+	transactions := make([]Transaction, 0, len(trades))
+	for i, c := range trades {
+		tr, err := c.Transaction(loc)
+		if err != nil {
+			return nil, fmt.Errorf("trade %d: %s", i+1, err)
+		}
+		transactions = append(transactions, tr)
+	}
+	return transactions, nil
+}
+
+// ParseTradesCSV is the transactions of the csv in the transaction log format with the id column (date,symbol,qty,price,side,lot,id).
+// The trades are all or nothing like ParseTradesJSON.
+func ParseTradesCSV(b []byte) ([]Transaction, error) {
+	if !IsTransactionLog(b) {
+		return nil, fmt.Errorf("first line of the csv is not %s", transactionHeader)
+	}
+	transactions, errs := ParseTransactions(b)
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("line %d: %s", errs[0].Line, errs[0].Error)
+	}
+	for i, tr := range transactions {
+		if _, err := time.Parse("2006-01-02", tr.Date); err != nil {
+			return nil, fmt.Errorf("trade %d: invalid date %q", i+1, tr.Date)
+		}
+		if err := validExecutionID(tr.ID); err != nil {
+			return nil, fmt.Errorf("trade %d: %s", i+1, err)
+		}
+	}
+	return transactions, nil
+}
+
+// validExecutionID is check the execution id of the trade, it is the key of the duplicate delivery.
+func validExecutionID(id string) error {
+	if id == "" {
+		return fmt.Errorf("execution id is required")
+	}
+	return validLogField("execution id", id)
+}
+
+// validLogField is check the field of the trade has no separator of the columns or the lines of the transaction log,
+// so a crafted field can't add a column or a trade to the log.
+func validLogField(name, s string) error {
+	if strings.ContainsAny(s, ",\r\n") {
+		return fmt.Errorf("invalid %s %q", name, s)
+	}
+	return nil
+}
+
+// UnrecordedTrades is the trades whose execution ids are not in the transactions of the log, and the ids of the rest.
+// A trade repeated in the trades is recorded once.
+func UnrecordedTrades(log, trades []Transaction) ([]Transaction, []string) {
+	recorded := map[string]bool{}
+	for _, tr := range log {
+		if tr.ID != "" {
+			recorded[tr.ID] = true
+		}
+	}
+	var unrecorded []Transaction
+	var skipped []string
+	for _, tr := range trades {
+		if recorded[tr.ID] {
+			skipped = append(skipped, tr.ID)
+			continue
+		}
+		recorded[tr.ID] = true
+		unrecorded = append(unrecorded, tr)
+	}
+	return unrecorded, skipped
+}
+
+// TransactionLine is the line of the transaction in the log, the lot column only when it or the id is set.
+func TransactionLine(tr Transaction) string {
+	line := strings.Join([]string{
+		tr.Date,
+		tr.Symble,
+		strconv.FormatFloat(tr.Qty, 'f', -1, 64),
+		strconv.FormatFloat(tr.Price, 'f', -1, 64),
+		tr.Side,
+	}, ",")
+	if tr.Lot != "" || tr.ID != "" {
+		line += "," + tr.Lot
+	}
+	if tr.ID != "" {
+		line += "," + tr.ID
+	}
+	return line
+}
+
+// AppendTransactions is the transaction log with the lines of the transactions at the end, the header is written to the empty log.
+func AppendTransactions(buf []byte, transactions []Transaction) []byte {
+	out := append([]byte{}, buf...)
+	if len(bytes.TrimSpace(out)) == 0 {
+		out = []byte(transactionHeader + ",lot,id\n")
+	} else if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	for _, tr := range transactions {
+		out = append(out, TransactionLine(tr)+"\n"...)
+	}
+	return out
+}
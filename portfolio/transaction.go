@@ -0,0 +1,237 @@
+package portfolio
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// transactionHeader is first line of the transaction log format, the lot and id columns are optional.
+const transactionHeader = "date,symbol,qty,price,side"
+
+// Cost basis methods of the realized gain (COST_BASIS).
+const (
+	CostBasisAverage  = "average"
+	CostBasisFIFO     = "fifo"
+	CostBasisSpecific = "specific"
+)
+
+// Transaction is a buy or sell of the transaction log.
+// Lot of a sell is the date of the bought lot (specific cost basis), ID is the execution id of the broker trade.
+type Transaction struct {
+	Date   string  `json:"date"`
+	Symble string  `json:"symble"`
+	Qty    float64 `json:"qty"`
+	Price  float64 `json:"price"`
+	Side   string  `json:"side"`
+	Lot    string  `json:"lot,omitempty"`
+	ID     string  `json:"id,omitempty"`
+}
+
+// lot is shares bought at the date, remaining after the sells.
+type lot struct {
+	Date  string
+	Qty   float64
+	Price float64
+}
+
+// IsTransactionLog is check the watchlist starts with the transaction log header.
+func IsTransactionLog(buf []byte) bool {
+	line, _, _ := bufio.NewReader(bytes.NewReader(buf)).ReadLine()
+	header := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(string(line)), " ", ""))
+	return header == transactionHeader || header == transactionHeader+",lot" || header == transactionHeader+",lot,id"
+}
+
+// CostBasis is COST_BASIS, average (moving average, default), fifo or specific.
+func CostBasis() string {
+	switch method := strings.ToLower(os.Getenv("COST_BASIS")); method {
+	case CostBasisFIFO, CostBasisSpecific:
+		return method
+	default:
+		return CostBasisAverage
+	}
+}
+
+// ParseTransactions is parse the transaction log (date,symbol,qty,price,side[,lot[,id]]).
+// Invalid line is skipped and returned as a parse error.
+func ParseTransactions(buf []byte) ([]Transaction, []ParseError) {
+	var transactions []Transaction
+	var errs []ParseError
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if n == 1 || line == "" {
+			continue
+		}
+
+		cols := strings.Split(line, ",")
+		if len(cols) < 5 || len(cols) > 7 {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("expected 5 to 7 columns, got %d", len(cols))})
+			continue
+		}
+		qty, err := strconv.ParseFloat(strings.TrimSpace(cols[2]), 64)
+		if err != nil || qty <= 0 {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("invalid qty %q", cols[2])})
+			continue
+		}
+		price, err := strconv.ParseFloat(strings.TrimSpace(cols[3]), 64)
+		if err != nil || price < 0 {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("invalid price %q", cols[3])})
+			continue
+		}
+		side := strings.ToLower(strings.TrimSpace(cols[4]))
+		if side != "buy" && side != "sell" {
+			errs = append(errs, ParseError{Line: n, Error: fmt.Sprintf("invalid side %q", cols[4])})
+			continue
+		}
+
+		tr := Transaction{
+			Date:   strings.TrimSpace(cols[0]),
+			Symble: quotes.NormalizeSymbol(cols[1]),
+			Qty:    qty,
+			Price:  price,
+			Side:   side,
+		}
+		if len(cols) >= 6 {
+			tr.Lot = strings.TrimSpace(cols[5])
+		}
+		if len(cols) == 7 {
+			tr.ID = strings.TrimSpace(cols[6])
+		}
+		transactions = append(transactions, tr)
+	}
+	return transactions, errs
+}
+
+// Holdings is current positions of the transactions by the cost basis of COST_BASIS.
+func Holdings(transactions []Transaction) []Ticker {
+	return HoldingsBy(transactions, CostBasis())
+}
+
+// HoldingsBy is current positions of the transactions by the cost basis method.
+// A sell realizes (price - cost) * qty, the cost is the average cost, the oldest lots (fifo),
+// or the lots bought at the lot date of the sell (specific, the oldest for the rest).
+// Sold out symbol remains with zero hold for the realized gain.
+func HoldingsBy(transactions []Transaction, method string) []Ticker {
+	tickers, _ := Ledger(transactions, method)
+	return tickers
+}
+
+// Sale is a sell of the transaction log and its realized gain by the cost basis.
+type Sale struct {
+	Date     string
+	Symble   string
+	Qty      float64
+	Proceeds float64
+	Cost     float64
+	Gain     float64
+}
+
+// Ledger is current positions and the sales of the transactions by the cost basis method, like HoldingsBy.
+func Ledger(transactions []Transaction, method string) ([]Ticker, []Sale) {
+	// transactions are applied in date order, same date keeps the file order
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].Date < transactions[j].Date
+	})
+
+	index := map[string]int{}
+	lots := map[string][]lot{}
+	var tickers []Ticker
+	var sales []Sale
+	for _, tr := range transactions {
+		i, ok := index[tr.Symble]
+		if !ok {
+			index[tr.Symble] = len(tickers)
+			i = len(tickers)
+			tickers = append(tickers, Ticker{Symble: tr.Symble})
+		}
+
+		t := &tickers[i]
+		switch tr.Side {
+		case "buy":
+			cost := t.Bid*t.Hold + tr.Price*tr.Qty
+			t.Hold += tr.Qty
+			t.Bid = cost / t.Hold
+			lots[tr.Symble] = append(lots[tr.Symble], lot{Date: tr.Date, Qty: tr.Qty, Price: tr.Price})
+		case "sell":
+			qty := tr.Qty
+			if qty > t.Hold {
+				logging.Warn(context.Background(), "sell is over hold", logging.Fields{"date": tr.Date, "symbol": tr.Symble, "qty": qty, "hold": t.Hold})
+				qty = t.Hold
+			}
+			sale := Sale{Date: tr.Date, Symble: tr.Symble, Qty: qty, Proceeds: tr.Price * qty, Cost: t.Bid * qty}
+			if method == CostBasisAverage {
+				t.Hold -= qty
+			} else {
+				remaining, cost := sellLots(lots[tr.Symble], qty, tr.Lot, method == CostBasisSpecific)
+				lots[tr.Symble] = remaining
+				sale.Cost = cost
+				t.Hold -= qty
+				t.Bid = 0
+				if t.Hold > 0 {
+					var left float64
+					for _, l := range remaining {
+						left += l.Price * l.Qty
+					}
+					t.Bid = left / t.Hold
+				}
+			}
+			sale.Gain = sale.Proceeds - sale.Cost
+			t.Realized += sale.Gain
+			if qty > 0 {
+				sales = append(sales, sale)
+			}
+		}
+	}
+	return tickers, sales
+}
+
+// sellLots is the lots after the sell of qty and the cost of the sold shares.
+// The lots of the date are sold first when specific, and the oldest lots for the rest.
+func sellLots(lots []lot, qty float64, date string, specific bool) ([]lot, float64) {
+	var cost float64
+	take := func(l *lot) {
+		n := l.Qty
+		if n > qty {
+			n = qty
+		}
+		cost += n * l.Price
+		l.Qty -= n
+		qty -= n
+	}
+
+	if specific && date != "" {
+		found := false
+		for i := range lots {
+			if lots[i].Date == date && qty > 0 {
+				found = true
+				take(&lots[i])
+			}
+		}
+		if !found || qty > 0 {
+			logging.Warn(context.Background(), "lot is not enough, sold from the oldest", logging.Fields{"lot": date, "qty": qty})
+		}
+	}
+	for i := range lots {
+		if qty <= 0 {
+			break
+		}
+		take(&lots[i])
+	}
+
+	var remaining []lot
+	for _, l := range lots {
+		if l.Qty > 0 {
+			remaining = append(remaining, l)
+		}
+	}
+	return remaining, cost
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// IsPortfolioRequest is /portfolio or /portfolio/{symbol}.
+func IsPortfolioRequest(request events.APIGatewayProxyRequest) bool {
+	p := strings.TrimSuffix(request.Path, "/")
+	return strings.HasSuffix(p, "/portfolio") || strings.HasSuffix(path.Dir(p), "/portfolio")
+}
+
+// PortfolioHandler is add, update and remove positions of S3_STOCK_DATA.
+// POST /portfolio is upsert the positions in the json body by symbol, DELETE /portfolio/{symbol} is remove the symbol.
+func PortfolioHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	cfg := ConfigOf(ctx)
+	bucket, key := cfg.Bucket, cfg.StockData
+
+	data, err := DownloadFile(ctx, bucket, key)
+	if err != nil && !errors.Is(err, storage.ErrNoSuchKey) {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	format := portfolio.WatchlistFormat(key, data)
+	if format == "transactions" {
+		return ErrorResponse(http.StatusConflict, "transaction log can't be updated by the portfolio api"), nil
+	}
+	// lots are kept as rows of the file, the splits since the last update are written to it
+	var tickers []portfolio.Ticker
+	var parseErrors []portfolio.ParseError
+	if len(data) > 0 {
+		tickers, parseErrors, err = ParseStockData(ctx, bucket, key, data)
+	} else {
+		tickers, parseErrors, err = portfolio.ParseWatchlist(key, data)
+	}
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	// invalid lines would be lost by the update
+	for _, e := range parseErrors {
+		if !e.Warning && request.HTTPMethod != http.MethodGet {
+			return ErrorResponse(http.StatusConflict, fmt.Sprintf("stock data has invalid lines. line %d: %s", e.Line, e.Error)), nil
+		}
+	}
+
+	switch request.HTTPMethod {
+	case http.MethodGet:
+	case http.MethodPost:
+		body := strings.TrimSpace(request.Body)
+		// a single position is accepted too
+		if strings.HasPrefix(body, "{") {
+			body = "[" + body + "]"
+		}
+		positions, err := portfolio.ParseWatchlistJSON([]byte(body))
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+		}
+		if len(positions) == 0 {
+			return ErrorResponse(http.StatusBadRequest, portfolio.ErrEmptyWatchlist.Error()), nil
+		}
+		tickers = portfolio.UpsertPositions(tickers, positions)
+	case http.MethodDelete:
+		symbol := request.PathParameters["symbol"]
+		if symbol == "" {
+			symbol = path.Base(request.Path)
+		}
+		var removed bool
+		if tickers, removed = portfolio.RemovePosition(tickers, quotes.NormalizeSymbol(symbol)); !removed {
+			return ErrorResponse(http.StatusNotFound, "symbol not found. "+symbol), nil
+		}
+	default:
+		return ErrorResponse(http.StatusMethodNotAllowed, "method not allowed."), nil
+	}
+
+	if request.HTTPMethod != http.MethodGet {
+		b, err := portfolio.WatchlistFile(format, tickers)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if err := PutFile(ctx, bucket, key, b); err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+	}
+
+	if tickers == nil {
+		tickers = []portfolio.Ticker{}
+	}
+	b, err := json.Marshal(tickers)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
+
+// PutFile is put the file to the storage as it is, OVERWRITE_MODE is not applied.
+func PutFile(ctx context.Context, bucket, key string, b []byte) error {
+	return storage.New(bucket).Put(ctx, key, b)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// defaultPositionDays is days of the history of the position request.
+const defaultPositionDays = 30
+
+// PositionDay is the position of a stored result.
+type PositionDay struct {
+	Date    string  `json:"date"`
+	Value   float64 `json:"value"`
+	Hold    float64 `json:"hold"`
+	Earning float64 `json:"earning"`
+}
+
+// PositionResponse is api response of the position request, the ticker priced now and its history of the stored results.
+type PositionResponse struct {
+	portfolio.Ticker
+	Cost    float64       `json:"cost"`
+	Earning float64       `json:"earning"`
+	Percent float64       `json:"percent"`
+	History []PositionDay `json:"history"`
+}
+
+// IsPositionRequest is GET /positions/{symbol}.
+func IsPositionRequest(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodGet && path.Base(path.Dir(strings.TrimSuffix(request.Path, "/"))) == "positions"
+}
+
+// PositionDays is ?days of the request (max 366), default 30.
+func PositionDays(v string) int {
+	if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 366 {
+		return n
+	}
+	return defaultPositionDays
+}
+
+// PositionHandler is the position of the symbol of S3_STOCK_DATA priced now, and the days of it in the stored results
+// (HISTORY_TABLE, otherwise the reports in s3) of the last ?days.
+func PositionHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	symbol := request.PathParameters["symbol"]
+	if symbol == "" {
+		symbol = path.Base(strings.TrimSuffix(request.Path, "/"))
+	}
+	symbol = quotes.NormalizeSymbol(symbol)
+
+	cfg := ConfigOf(ctx)
+	data, err := DownloadFile(ctx, cfg.Bucket, cfg.StockData)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoSuchKey) {
+			return ErrorResponse(http.StatusNotFound, err.Error()), nil
+		}
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	tickers, _, err := ParseStockData(ctx, cfg.Bucket, cfg.StockData, data)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	tickers = portfolio.AggregateLots(portfolio.FilterSymbols(tickers, symbol, ""))
+	if len(tickers) == 0 {
+		return ErrorResponse(http.StatusNotFound, "symbol not found. "+symbol), nil
+	}
+
+	quotes.ResetRetryBudget()
+	provider, err := PriceProvider()
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	t := time.Now().In(reportLocation)
+	ticker := portfolio.ApplyCurrency(ctx, provider, portfolio.FetchPrices(ctx, provider, ApplyDividendLog(ctx, tickers)))[0]
+
+	response := PositionResponse{Ticker: ticker, Cost: ticker.Bid * ticker.Hold, History: []PositionDay{}}
+	if ticker.Priced() {
+		response.Earning, response.Percent = ticker.Earning(), ticker.Percent()
+	}
+
+	from, to := t.AddDate(0, 0, -PositionDays(request.QueryStringParameters["days"])), t.AddDate(0, 0, -1)
+	var results []portfolio.Result
+	if cfg.HistoryTable != "" {
+		results, err = ReadHistory(ctx, cfg.HistoryTable, from, to)
+	} else {
+		results, err = ReadReports(ctx, cfg.Bucket, cfg.FilePath, from, to)
+	}
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	for _, r := range results {
+		for _, p := range r.Body {
+			if p.Symble == symbol && p.Priced() {
+				response.History = append(response.History, PositionDay{Date: r.CreatedAt, Value: p.Value, Hold: p.Hold, Earning: p.Earning()})
+			}
+		}
+	}
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	status := http.StatusOK
+	if !ticker.Priced() {
+		status = http.StatusBadGateway
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
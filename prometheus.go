@@ -0,0 +1,189 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// fetchBuckets is upper bounds (seconds) of the fetch duration histogram.
+var fetchBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60}
+
+// FetchCount is quotes of a provider, priced or failed.
+type FetchCount struct {
+	Success int `json:"success"`
+	Failure int `json:"failure"`
+}
+
+// MetricsState is the counters and the last run of the scrape, it is kept in METRICS_STATE_PATH over the invocations.
+// Buckets are counts of the runs whose fetch is up to each of fetchBuckets (not cumulative), the last is over them.
+type MetricsState struct {
+	LastRun    int64                  `json:"last_run"`
+	Value      float64                `json:"value"`
+	ProfitLoss float64                `json:"profit_loss"`
+	Fetches    map[string]*FetchCount `json:"fetches"`
+	Buckets    []int                  `json:"buckets"`
+	Sum        float64                `json:"sum"`
+	Count      int                    `json:"count"`
+}
+
+// MetricsStatePath is the key of METRICS_STATE_PATH, under the name of the tenant. Empty is no state.
+func MetricsStatePath(ctx context.Context) string {
+	key := os.Getenv("METRICS_STATE_PATH")
+	if key == "" {
+		return ""
+	}
+	return path.Join(ConfigOf(ctx).Tenant, key)
+}
+
+// LoadMetricsState is the state of METRICS_STATE_PATH, empty before the first run.
+func LoadMetricsState(ctx context.Context) (MetricsState, error) {
+	state := MetricsState{Fetches: map[string]*FetchCount{}, Buckets: make([]int, len(fetchBuckets)+1)}
+	key := MetricsStatePath(ctx)
+	if key == "" {
+		return state, nil
+	}
+	data, err := storage.New(ConfigOf(ctx).Bucket).Get(ctx, key)
+	if errors.Is(err, storage.ErrNoSuchKey) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("%s: %s", key, err)
+	}
+	if state.Fetches == nil {
+		state.Fetches = map[string]*FetchCount{}
+	}
+	if len(state.Buckets) != len(fetchBuckets)+1 {
+		state.Buckets = make([]int, len(fetchBuckets)+1)
+	}
+	return state, nil
+}
+
+// CountFetches is add the quotes of the tickers to the counts by provider, the fixed rows are not fetched.
+func CountFetches(counts map[string]*FetchCount, tickers []portfolio.Ticker) {
+	for _, ticker := range tickers {
+		if ticker.Fixed() {
+			continue
+		}
+		provider := ticker.Provider
+		if provider == "" {
+			provider = "none"
+		}
+		if counts[provider] == nil {
+			counts[provider] = &FetchCount{}
+		}
+		if ticker.Priced() {
+			counts[provider].Success++
+		} else {
+			counts[provider].Failure++
+		}
+	}
+}
+
+// Add is add the counts of the quotes and the fetch of the run at t, the totals are of the summary.
+func (s *MetricsState) Add(summary portfolio.Summary, counts map[string]*FetchCount, fetch time.Duration, t time.Time) {
+	s.LastRun, s.Value, s.ProfitLoss = t.Unix(), summary.Value, summary.ProfitLoss
+	for provider, c := range counts {
+		if s.Fetches[provider] == nil {
+			s.Fetches[provider] = &FetchCount{}
+		}
+		s.Fetches[provider].Success += c.Success
+		s.Fetches[provider].Failure += c.Failure
+	}
+
+	seconds := fetch.Seconds()
+	i := sort.SearchFloat64s(fetchBuckets, seconds)
+	s.Buckets[i]++
+	s.Sum += seconds
+	s.Count++
+}
+
+// UpdateMetricsState is add the run to METRICS_STATE_PATH, nothing without it.
+func UpdateMetricsState(ctx context.Context, summary portfolio.Summary, counts map[string]*FetchCount, fetch time.Duration, t time.Time) error {
+	key := MetricsStatePath(ctx)
+	if key == "" {
+		return nil
+	}
+	state, err := LoadMetricsState(ctx)
+	if err != nil {
+		return err
+	}
+	state.Add(summary, counts, fetch, t)
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return storage.New(ConfigOf(ctx).Bucket).Put(ctx, key, b)
+}
+
+// PrometheusText is the state in the prometheus text exposition format.
+func PrometheusText(s MetricsState) string {
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("stock_profit_fetch_total", "counter", "Quotes by provider and result.")
+	providers := make([]string, 0, len(s.Fetches))
+	for p := range s.Fetches {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	for _, p := range providers {
+		fmt.Fprintf(&b, "stock_profit_fetch_total{provider=%q,result=\"success\"} %d\n", p, s.Fetches[p].Success)
+		fmt.Fprintf(&b, "stock_profit_fetch_total{provider=%q,result=\"failure\"} %d\n", p, s.Fetches[p].Failure)
+	}
+
+	metric("stock_profit_fetch_duration_seconds", "histogram", "Fetch duration of the runs.")
+	var cumulative int
+	for i, le := range fetchBuckets {
+		cumulative += s.Buckets[i]
+		fmt.Fprintf(&b, "stock_profit_fetch_duration_seconds_bucket{le=\"%g\"} %d\n", le, cumulative)
+	}
+	fmt.Fprintf(&b, "stock_profit_fetch_duration_seconds_bucket{le=\"+Inf\"} %d\n", s.Count)
+	fmt.Fprintf(&b, "stock_profit_fetch_duration_seconds_sum %g\n", s.Sum)
+	fmt.Fprintf(&b, "stock_profit_fetch_duration_seconds_count %d\n", s.Count)
+
+	metric("stock_profit_last_run_timestamp_seconds", "gauge", "Time of the last run.")
+	fmt.Fprintf(&b, "stock_profit_last_run_timestamp_seconds %d\n", s.LastRun)
+	metric("stock_profit_total_value", "gauge", "Total value of the last run.")
+	fmt.Fprintf(&b, "stock_profit_total_value %g\n", s.Value)
+	metric("stock_profit_profit_loss", "gauge", "Total profit loss of the last run.")
+	fmt.Fprintf(&b, "stock_profit_profit_loss %g\n", s.ProfitLoss)
+	return b.String()
+}
+
+// IsMetricsRequest is GET /metrics.
+func IsMetricsRequest(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodGet && path.Base(request.Path) == "metrics"
+}
+
+// MetricsHandler is the metrics of METRICS_STATE_PATH for the prometheus scrape.
+func MetricsHandler(ctx context.Context) (events.APIGatewayProxyResponse, error) {
+	if MetricsStatePath(ctx) == "" {
+		return ErrorResponse(http.StatusNotFound, "METRICS_STATE_PATH is not set."), nil
+	}
+	state, err := LoadMetricsState(ctx)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "text/plain; version=0.0.4"},
+		Body:       PrometheusText(state),
+	}, nil
+}
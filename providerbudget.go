@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/tora0091/stock-profit/awsclient"
+)
+
+// DynamoBudgetCounter is the provider requests of all lambda instances in the dynamodb table
+// (hash key `key` string, ttl `expires`), PROVIDER_BUDGET_TABLE. It can be the table of QUOTE_CACHE_TABLE.
+type DynamoBudgetCounter struct {
+	Table string
+}
+
+// Count is the count of the key, a consistent read.
+func (c *DynamoBudgetCounter) Count(ctx context.Context, key string) (int, error) {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return 0, err
+	}
+	out, err := dynamodb.New(sess).GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName:            aws.String(c.Table),
+		Key:                  map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		ProjectionExpression: aws.String("#c"),
+		ExpressionAttributeNames: map[string]*string{
+			"#c": aws.String("count"),
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if av, ok := out.Item["count"]; ok {
+		return strconv.Atoi(aws.StringValue(av.N))
+	}
+	return 0, nil
+}
+
+// Add is add one request to the count of the key.
+func (c *DynamoBudgetCounter) Add(ctx context.Context, key string, expires time.Time) (int, error) {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return 0, err
+	}
+	out, err := dynamodb.New(sess).UpdateItemWithContext(ctx, &dynamodb.UpdateItemInput{
+		TableName:        aws.String(c.Table),
+		Key:              map[string]*dynamodb.AttributeValue{"key": {S: aws.String(key)}},
+		UpdateExpression: aws.String("ADD #c :one SET #e = :expires"),
+		ExpressionAttributeNames: map[string]*string{
+			"#c": aws.String("count"),
+			"#e": aws.String("expires"),
+		},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":one":     {N: aws.String("1")},
+			":expires": {N: aws.String(strconv.FormatInt(expires.Unix(), 10))},
+		},
+		ReturnValues: aws.String(dynamodb.ReturnValueUpdatedNew),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(aws.StringValue(out.Attributes["count"].N))
+}
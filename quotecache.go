@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// defaultQuoteCacheTTL is life of the cached quote, QUOTE_CACHE_TTL.
+const defaultQuoteCacheTTL = 15 * time.Minute
+
+// lastQuoteTTL is life of the last quote of the symbol, the stale price when the providers are out of their budgets.
+const lastQuoteTTL = 7 * 24 * time.Hour
+
+// DynamoQuoteCache is the quote cache of the dynamodb table (hash key `key` string, ttl `expires`).
+// The key is the symbol and the date, the quote of yesterday is not used today.
+type DynamoQuoteCache struct {
+	Table string
+	TTL   time.Duration
+	svc   *dynamodb.DynamoDB
+}
+
+// NewDynamoQuoteCache is the cache of the table, TTL is QUOTE_CACHE_TTL.
+func NewDynamoQuoteCache(table string) (*DynamoQuoteCache, error) {
+	sess, err := awsclient.TracedSession(Region(""))
+	if err != nil {
+		return nil, err
+	}
+	ttl, err := time.ParseDuration(os.Getenv("QUOTE_CACHE_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = defaultQuoteCacheTTL
+	}
+	return &DynamoQuoteCache{Table: table, TTL: ttl, svc: dynamodb.New(sess)}, nil
+}
+
+// key is the item key of the symbol today.
+func (c *DynamoQuoteCache) key(symbol string) map[string]*dynamodb.AttributeValue {
+	date := time.Now().In(quotes.Location).Format("2006-01-02")
+	return map[string]*dynamodb.AttributeValue{"key": {S: aws.String("quote#" + symbol + "#" + date)}}
+}
+
+// lastKey is the item key of the last quote of the symbol.
+func (c *DynamoQuoteCache) lastKey(symbol string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{"key": {S: aws.String("quote#" + symbol + "#last")}}
+}
+
+// Get is the cached quote, the expired item which is not deleted yet is not used.
+func (c *DynamoQuoteCache) Get(ctx context.Context, symbol string) (quotes.Quote, bool, error) {
+	return c.get(ctx, c.key(symbol))
+}
+
+// Last is the last quote of the symbol of the last 7 days.
+func (c *DynamoQuoteCache) Last(ctx context.Context, symbol string) (quotes.Quote, bool, error) {
+	return c.get(ctx, c.lastKey(symbol))
+}
+
+// get is the quote of the item which is not expired.
+func (c *DynamoQuoteCache) get(ctx context.Context, key map[string]*dynamodb.AttributeValue) (quotes.Quote, bool, error) {
+	out, err := c.svc.GetItemWithContext(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.Table),
+		Key:       key,
+	})
+	if err != nil || out.Item == nil {
+		return quotes.Quote{}, false, err
+	}
+	expires, _ := strconv.ParseInt(aws.StringValue(out.Item["expires"].N), 10, 64)
+	if time.Now().Unix() >= expires {
+		return quotes.Quote{}, false, nil
+	}
+	var q quotes.Quote
+	if err := json.Unmarshal([]byte(aws.StringValue(out.Item["quote"].S)), &q); err != nil {
+		return quotes.Quote{}, false, err
+	}
+	return q, true, nil
+}
+
+// Put is cache the quote for TTL, and keep it as the last quote of the symbol for 7 days.
+func (c *DynamoQuoteCache) Put(ctx context.Context, symbol string, q quotes.Quote) error {
+	b, err := json.Marshal(q)
+	if err != nil {
+		return err
+	}
+	if err := c.put(ctx, c.key(symbol), b, c.TTL); err != nil {
+		return err
+	}
+	return c.put(ctx, c.lastKey(symbol), b, lastQuoteTTL)
+}
+
+// put is put the quote json to the item for ttl.
+func (c *DynamoQuoteCache) put(ctx context.Context, item map[string]*dynamodb.AttributeValue, b []byte, ttl time.Duration) error {
+	item["quote"] = &dynamodb.AttributeValue{S: aws.String(string(b))}
+	item["expires"] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(time.Now().Add(ttl).Unix(), 10))}
+	_, err := c.svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.Table),
+		Item:      item,
+	})
+	return err
+}
+
+// PriceProvider is the provider of PRICE_PROVIDER, the quotes are cached in QUOTE_CACHE_TABLE when it is set.
+// The requests of PROVIDER_BUDGETS are counted in PROVIDER_BUDGET_TABLE of all lambda instances when it is set.
+func PriceProvider() (quotes.Provider, error) {
+	if table := os.Getenv("PROVIDER_BUDGET_TABLE"); table != "" {
+		quotes.Counter = &DynamoBudgetCounter{Table: table}
+	}
+	provider, err := quotes.NewProvider(os.Getenv("PRICE_PROVIDER"))
+	if err != nil {
+		return nil, err
+	}
+	table := os.Getenv("QUOTE_CACHE_TABLE")
+	if table == "" {
+		return provider, nil
+	}
+	cache, err := NewDynamoQuoteCache(table)
+	if err != nil {
+		return nil, err
+	}
+	return &quotes.CachedProvider{Cache: cache, Next: provider}, nil
+}
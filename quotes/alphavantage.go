@@ -0,0 +1,85 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// alphaVantageURL is global quote api endpoint.
+const alphaVantageURL = "https://www.alphavantage.co/query"
+
+// AlphaVantageProvider is get stock price from alpha vantage global quote api.
+type AlphaVantageProvider struct {
+	APIKey  string
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewAlphaVantageProvider is alpha vantage provider with the api key.
+func NewAlphaVantageProvider(apiKey string) *AlphaVantageProvider {
+	return &AlphaVantageProvider{
+		APIKey:  apiKey,
+		BaseURL: alphaVantageURL,
+		Client:  HTTPClient,
+	}
+}
+
+// Name is provider name.
+func (p *AlphaVantageProvider) Name() string {
+	return "alphavantage"
+}
+
+// Quote is get global quote of the symbol.
+func (p *AlphaVantageProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	if p.APIKey == "" {
+		return Quote{}, fmt.Errorf("ALPHAVANTAGE_API_KEY is not set")
+	}
+
+	q := url.Values{}
+	q.Set("function", "GLOBAL_QUOTE")
+	q.Set("symbol", symbol)
+	q.Set("apikey", p.APIKey)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	var body struct {
+		GlobalQuote map[string]string `json:"Global Quote"`
+		Note        string            `json:"Note"`
+		Information string            `json:"Information"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Quote{}, err
+	}
+
+	// rate limit is returned as a note with 200
+	if body.Note != "" || body.Information != "" {
+		return Quote{}, fmt.Errorf("%s", strings.TrimSpace(body.Note+" "+body.Information))
+	}
+
+	price, err := strconv.ParseFloat(body.GlobalQuote["05. price"], 64)
+	if err != nil {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+	return Quote{
+		Price:    price,
+		AsOf:     body.GlobalQuote["07. latest trading day"],
+		Provider: p.Name(),
+	}, nil
+}
@@ -0,0 +1,225 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// ErrBudgetExhausted is the error of the provider whose request budget (PROVIDER_BUDGETS) is used up.
+var ErrBudgetExhausted = errors.New("request budget exhausted")
+
+// budgetWindows is the windows of PROVIDER_BUDGETS.
+var budgetWindows = map[string]time.Duration{
+	"minute": time.Minute,
+	"day":    24 * time.Hour,
+}
+
+// Budget is max requests to the provider in the window (a minute or a utc day).
+type Budget struct {
+	Limit  int
+	Window time.Duration
+}
+
+// ParseBudgets is PROVIDER_BUDGETS, the budgets of the provider names (e.g. alphavantage=25/day,finnhub=60/minute).
+// A provider can have both a minute and a day budget.
+func ParseBudgets(env string) (map[string][]Budget, error) {
+	budgets := map[string][]Budget{}
+	for _, s := range strings.Split(env, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(s, "=")
+		limit, window, ok2 := strings.Cut(value, "/")
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("%q is not provider=limit/day or provider=limit/minute", s)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(limit))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%q is not a number of requests", limit)
+		}
+		w, ok := budgetWindows[strings.ToLower(strings.TrimSpace(window))]
+		if !ok {
+			return nil, fmt.Errorf("%q is not day or minute", window)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		budgets[name] = append(budgets[name], Budget{Limit: n, Window: w})
+	}
+	return budgets, nil
+}
+
+// BudgetCounter is the count of the requests across the invocations, main sets it to the dynamodb table (PROVIDER_BUDGET_TABLE).
+type BudgetCounter interface {
+	// Count is the requests of the key, 0 when it isn't counted yet
+	Count(ctx context.Context, key string) (int, error)
+	// Add is add one request to the key and the count after it, the key is kept until expires
+	Add(ctx context.Context, key string, expires time.Time) (int, error)
+}
+
+// Counter is the counter of the budgets, default is the count of this lambda instance.
+var Counter BudgetCounter = &localCounter{m: map[string]int{}}
+
+// localCounter is the requests of this lambda instance.
+type localCounter struct {
+	sync.Mutex
+	m       map[string]int
+	expires map[string]time.Time
+}
+
+// Count is the requests of the key.
+func (c *localCounter) Count(ctx context.Context, key string) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.expire()
+	return c.m[key], nil
+}
+
+// Add is count the request, the expired keys are removed.
+func (c *localCounter) Add(ctx context.Context, key string, expires time.Time) (int, error) {
+	c.Lock()
+	defer c.Unlock()
+	c.expire()
+	c.m[key]++
+	c.expires[key] = expires
+	return c.m[key], nil
+}
+
+// expire is remove the expired keys, the lock is held.
+func (c *localCounter) expire() {
+	if c.expires == nil {
+		c.expires = map[string]time.Time{}
+	}
+	now := time.Now()
+	for k, e := range c.expires {
+		if !now.Before(e) {
+			delete(c.m, k)
+			delete(c.expires, k)
+		}
+	}
+}
+
+// exhausted is the windows already used up, they are not counted again until the next window.
+var exhausted = struct {
+	sync.Mutex
+	m map[string]time.Time
+}{m: map[string]time.Time{}}
+
+// BudgetedProvider is the provider under its request budgets, ErrBudgetExhausted without the request when a budget is used up.
+type BudgetedProvider struct {
+	Next    Provider
+	Budgets []Budget
+}
+
+// WithBudget is the provider under the budgets of its name in PROVIDER_BUDGETS, the provider itself without them.
+// A batch provider is still a batch provider, one batch is one request.
+func WithBudget(p Provider) Provider {
+	all, err := ParseBudgets(os.Getenv("PROVIDER_BUDGETS"))
+	if err != nil || len(all[p.Name()]) == 0 {
+		return p
+	}
+	bp := &BudgetedProvider{Next: p, Budgets: all[p.Name()]}
+	if _, ok := p.(BatchProvider); ok {
+		return &budgetedBatchProvider{bp}
+	}
+	return bp
+}
+
+// Name is name of the next provider.
+func (p *BudgetedProvider) Name() string {
+	return p.Next.Name()
+}
+
+// Quote is the quote of next when the budgets have a request left.
+func (p *BudgetedProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	if err := p.take(ctx); err != nil {
+		return Quote{}, err
+	}
+	return p.Next.Quote(ctx, symbol)
+}
+
+// take is use a request of every budget. Every window is checked before one is counted,
+// so the request refused by the day budget doesn't use the minute budget.
+// A counter error doesn't block the request on purpose, like the rate limit of the api keys,
+// an outage of PROVIDER_BUDGET_TABLE shouldn't stop the prices (the provider limits the requests itself).
+func (p *BudgetedProvider) take(ctx context.Context) error {
+	now := time.Now().UTC()
+	keys := make([]string, len(p.Budgets))
+	for i, b := range p.Budgets {
+		keys[i] = fmt.Sprintf("budget#%s#%d#%d", p.Name(), int(b.Window.Seconds()), now.Truncate(b.Window).Unix())
+
+		exhausted.Lock()
+		until, ok := exhausted.m[keys[i]]
+		exhausted.Unlock()
+		if ok && now.Before(until) {
+			return fmt.Errorf("%w (%d per %s)", ErrBudgetExhausted, b.Limit, windowName(b.Window))
+		}
+	}
+
+	for i, b := range p.Budgets {
+		count, err := Counter.Count(ctx, keys[i])
+		if err != nil {
+			logging.Warn(ctx, "request budget error", logging.Fields{"provider": p.Name(), "error": err})
+			continue
+		}
+		if count >= b.Limit {
+			return p.exhaust(ctx, keys[i], b, now)
+		}
+	}
+
+	// a concurrent request can take the last request between the count and the add, the count after the add is checked again
+	for i, b := range p.Budgets {
+		count, err := Counter.Add(ctx, keys[i], now.Truncate(b.Window).Add(2*b.Window))
+		if err != nil {
+			logging.Warn(ctx, "request budget error", logging.Fields{"provider": p.Name(), "error": err})
+			continue
+		}
+		if count > b.Limit {
+			return p.exhaust(ctx, keys[i], b, now)
+		}
+	}
+	return nil
+}
+
+// exhaust is mark the window of the key used up until its end and ErrBudgetExhausted of the budget.
+func (p *BudgetedProvider) exhaust(ctx context.Context, key string, b Budget, now time.Time) error {
+	exhausted.Lock()
+	for k, e := range exhausted.m {
+		if !now.Before(e) {
+			delete(exhausted.m, k)
+		}
+	}
+	exhausted.m[key] = now.Truncate(b.Window).Add(b.Window)
+	exhausted.Unlock()
+	logging.Warn(ctx, "request budget exhausted", logging.Fields{"provider": p.Name(), "limit": b.Limit, "window": windowName(b.Window)})
+	return fmt.Errorf("%w (%d per %s)", ErrBudgetExhausted, b.Limit, windowName(b.Window))
+}
+
+// windowName is day or minute.
+func windowName(w time.Duration) string {
+	for name, d := range budgetWindows {
+		if d == w {
+			return name
+		}
+	}
+	return w.String()
+}
+
+// budgetedBatchProvider is the budgeted batch provider.
+type budgetedBatchProvider struct {
+	*BudgetedProvider
+}
+
+// Quotes is the quotes of next when the budgets have a request left.
+func (p *budgetedBatchProvider) Quotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	if err := p.take(ctx); err != nil {
+		return nil, err
+	}
+	return p.Next.(BatchProvider).Quotes(ctx, symbols)
+}
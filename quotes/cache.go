@@ -0,0 +1,133 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// QuoteCache is a store of the quotes of the day.
+type QuoteCache interface {
+	// Get is the cached quote of the symbol, ok is false when it is not cached or expired
+	Get(ctx context.Context, symbol string) (q Quote, ok bool, err error)
+	Put(ctx context.Context, symbol string, q Quote) error
+}
+
+// LastQuoteCache is a quote cache which keeps the last quote of the symbol after it is expired.
+type LastQuoteCache interface {
+	QuoteCache
+	// Last is the last cached quote of the symbol, ok is false when it is not kept
+	Last(ctx context.Context, symbol string) (q Quote, ok bool, err error)
+}
+
+// refreshKey is context key of the refresh.
+type refreshKey struct{}
+
+// WithRefresh is the context which doesn't read the cache, the quotes are got again and cached.
+func WithRefresh(ctx context.Context) context.Context {
+	return context.WithValue(ctx, refreshKey{}, true)
+}
+
+// IsRefresh is check the context doesn't read the cache.
+func IsRefresh(ctx context.Context) bool {
+	refresh, _ := ctx.Value(refreshKey{}).(bool)
+	return refresh
+}
+
+// CachedProvider is the quotes of the cache, a missing symbol is got from next and put to the cache.
+// A cache error is logged and the quote is got from next.
+// The last quote of LastQuoteCache is the stale price when next is out of its request budget (PROVIDER_BUDGETS).
+type CachedProvider struct {
+	Cache QuoteCache
+	Next  Provider
+}
+
+// Name is name of the next provider.
+func (p *CachedProvider) Name() string {
+	return p.Next.Name()
+}
+
+// Quote is the cached quote or the quote of next.
+func (p *CachedProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	if q, ok := p.get(ctx, symbol); ok {
+		return q, nil
+	}
+	q, err := p.Next.Quote(ctx, symbol)
+	if errors.Is(err, ErrBudgetExhausted) {
+		if last, ok := p.last(ctx, symbol); ok {
+			return last, nil
+		}
+	}
+	if err != nil {
+		return q, err
+	}
+	p.put(ctx, symbol, q)
+	return q, nil
+}
+
+// Quotes is the cached quotes, the others are got from next when it is a batch provider.
+func (p *CachedProvider) Quotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	quotes := map[string]Quote{}
+	var missing []string
+	for _, s := range symbols {
+		if q, ok := p.get(ctx, s); ok {
+			quotes[s] = q
+		} else {
+			missing = append(missing, s)
+		}
+	}
+
+	bp, ok := p.Next.(BatchProvider)
+	if !ok || len(missing) == 0 {
+		return quotes, nil
+	}
+	fetched, err := bp.Quotes(ctx, missing)
+	for s, q := range fetched {
+		quotes[s] = q
+		p.put(ctx, s, q)
+	}
+	return quotes, err
+}
+
+// get is the cached quote, nothing with the refresh.
+func (p *CachedProvider) get(ctx context.Context, symbol string) (Quote, bool) {
+	if IsRefresh(ctx) {
+		return Quote{}, false
+	}
+	q, ok, err := p.Cache.Get(ctx, symbol)
+	if err != nil {
+		logging.Warn(ctx, "quote cache error", logging.Fields{"symbol": symbol, "error": err})
+		return Quote{}, false
+	}
+	if ok {
+		q.Cached = true
+		logging.Debug(ctx, "quote cache hit", logging.Fields{"symbol": symbol, "provider": q.Provider})
+	}
+	return q, ok
+}
+
+// last is the last quote of the cache as the stale price.
+func (p *CachedProvider) last(ctx context.Context, symbol string) (Quote, bool) {
+	lc, ok := p.Cache.(LastQuoteCache)
+	if !ok {
+		return Quote{}, false
+	}
+	q, ok, err := lc.Last(ctx, symbol)
+	if err != nil {
+		logging.Warn(ctx, "quote cache error", logging.Fields{"symbol": symbol, "error": err})
+		return Quote{}, false
+	}
+	if ok {
+		q.Cached, q.Stale, q.Degraded = true, true, true
+		logging.Info(ctx, "stale price of the request budget", logging.Fields{"symbol": symbol, "provider": q.Provider, "as_of": q.AsOf})
+	}
+	return q, ok
+}
+
+// put is cache the quote.
+func (p *CachedProvider) put(ctx context.Context, symbol string, q Quote) {
+	if err := p.Cache.Put(ctx, symbol, q); err != nil {
+		logging.Warn(ctx, "quote cache error", logging.Fields{"symbol": symbol, "error": err})
+	}
+}
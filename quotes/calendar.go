@@ -0,0 +1,150 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// exchangeTimezones is timezone of the exchange by suffix, empty is US market.
+var exchangeTimezones = map[string]string{
+	"":   "America/New_York",
+	"T":  "Asia/Tokyo",
+	"L":  "Europe/London",
+	"HK": "Asia/Hong_Kong",
+	"TO": "America/Toronto",
+	"WA": "Europe/Warsaw",
+	"PL": "Europe/Warsaw",
+}
+
+// marketHolidays is the holidays by suffix in addition to the built-in ones, "US" is the us market.
+// MARKET_HOLIDAYS (json, e.g. {"T": ["2024-01-08"]}) is them.
+var marketHolidays = map[string]map[string]bool{}
+
+func init() {
+	if env := os.Getenv("MARKET_HOLIDAYS"); env != "" {
+		var holidays map[string][]string
+		if err := json.Unmarshal([]byte(env), &holidays); err != nil {
+			logging.Warn(context.Background(), "invalid MARKET_HOLIDAYS", logging.Fields{"error": err})
+			return
+		}
+		for suffix, days := range holidays {
+			suffix = strings.ToUpper(suffix)
+			if suffix == "US" {
+				suffix = ""
+			}
+			marketHolidays[suffix] = map[string]bool{}
+			for _, d := range days {
+				marketHolidays[suffix][d] = true
+			}
+		}
+	}
+}
+
+// MarketName is name of the exchange of the suffix, US for the us market.
+func MarketName(suffix string) string {
+	if suffix == "" {
+		return "US"
+	}
+	return suffix
+}
+
+// IsTradingDay is check the exchange of the suffix trades on the date of t in its timezone.
+// Weekends, MARKET_HOLIDAYS, the nyse holidays of the us market and the year end of tokyo are closed.
+func IsTradingDay(suffix string, t time.Time) bool {
+	if name, ok := exchangeTimezones[suffix]; ok {
+		if loc, err := time.LoadLocation(name); err == nil {
+			t = t.In(loc)
+		}
+	}
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	date := t.Format("2006-01-02")
+	if marketHolidays[suffix][date] {
+		return false
+	}
+
+	switch suffix {
+	case "":
+		return !nyseHolidays(t.Year())[date]
+	case "T":
+		// 12/31 to 1/3 is the year end holidays
+		md := t.Format("01-02")
+		return md != "12-31" && md > "01-03"
+	}
+	return true
+}
+
+// nyseHolidays is the full day holidays of the nyse of the year.
+func nyseHolidays(year int) map[string]bool {
+	date := func(m time.Month, d int) time.Time { return time.Date(year, m, d, 0, 0, 0, 0, time.UTC) }
+	// nth weekday of the month, n < 0 is from the end
+	nth := func(m time.Month, w time.Weekday, n int) time.Time {
+		if n < 0 {
+			d := date(m+1, 1).AddDate(0, 0, -1)
+			for d.Weekday() != w {
+				d = d.AddDate(0, 0, -1)
+			}
+			return d
+		}
+		d := date(m, 1)
+		for d.Weekday() != w {
+			d = d.AddDate(0, 0, 1)
+		}
+		return d.AddDate(0, 0, 7*(n-1))
+	}
+	// saturday is observed on friday and sunday on monday
+	observed := func(d time.Time) time.Time {
+		switch d.Weekday() {
+		case time.Saturday:
+			return d.AddDate(0, 0, -1)
+		case time.Sunday:
+			return d.AddDate(0, 0, 1)
+		}
+		return d
+	}
+
+	days := []time.Time{
+		nth(time.January, time.Monday, 3),
+		nth(time.February, time.Monday, 3),
+		easter(year).AddDate(0, 0, -2),
+		nth(time.May, time.Monday, -1),
+		observed(date(time.July, 4)),
+		nth(time.September, time.Monday, 1),
+		nth(time.November, time.Thursday, 4),
+		observed(date(time.December, 25)),
+	}
+	// new year on saturday is not observed in the last year
+	if newYear := date(time.January, 1); newYear.Weekday() != time.Saturday {
+		days = append(days, observed(newYear))
+	}
+	if year >= 2022 {
+		days = append(days, observed(date(time.June, 19)))
+	}
+
+	holidays := map[string]bool{}
+	for _, d := range days {
+		holidays[d.Format("2006-01-02")] = true
+	}
+	return holidays
+}
+
+// easter is easter sunday of the year (anonymous gregorian algorithm).
+func easter(year int) time.Time {
+	a, b, c := year%19, year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,133 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// coinGeckoURL is simple price api endpoint.
+const coinGeckoURL = "https://api.coingecko.com/api/v3/simple/price"
+
+// CryptoRoute is route key of the crypto symbols.
+const CryptoRoute = "CRYPTO"
+
+// coinGeckoIDs is coin id by symbol (e.g. BTC-USD is BTC).
+// COINGECKO_IDS (json, e.g. {"DOGE": "dogecoin"}) is add or override it.
+var coinGeckoIDs = map[string]string{
+	"BTC":  "bitcoin",
+	"ETH":  "ethereum",
+	"SOL":  "solana",
+	"XRP":  "ripple",
+	"ADA":  "cardano",
+	"DOGE": "dogecoin",
+	"LTC":  "litecoin",
+	"BCH":  "bitcoin-cash",
+	"DOT":  "polkadot",
+}
+
+func init() {
+	if env := os.Getenv("COINGECKO_IDS"); env != "" {
+		var ids map[string]string
+		if err := json.Unmarshal([]byte(env), &ids); err != nil {
+			logging.Warn(context.Background(), "invalid COINGECKO_IDS", logging.Fields{"error": err})
+			return
+		}
+		for symbol, id := range ids {
+			coinGeckoIDs[strings.ToUpper(symbol)] = id
+		}
+	}
+}
+
+// CryptoPair is coin and currency of the crypto symbol (e.g. BTC-USD), ok is false for the others.
+func CryptoPair(symbol string) (coin, currency string, ok bool) {
+	i := strings.LastIndex(symbol, "-")
+	if i < 0 {
+		return "", "", false
+	}
+	coin, currency = symbol[:i], symbol[i+1:]
+	if _, known := coinGeckoIDs[coin]; !known || len(currency) != 3 {
+		return "", "", false
+	}
+	return coin, currency, true
+}
+
+// RouteKey is route of the symbol, CryptoRoute, FundRoute or the exchange suffix.
+func RouteKey(symbol string) string {
+	if _, _, ok := CryptoPair(symbol); ok {
+		return CryptoRoute
+	}
+	if IsFundCode(symbol) {
+		return FundRoute
+	}
+	return ExchangeSuffix(symbol)
+}
+
+// CoinGeckoProvider is get crypto price from coingecko simple price api.
+type CoinGeckoProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewCoinGeckoProvider is coingecko provider, base is COINGECKO_URL.
+func NewCoinGeckoProvider(baseURL string) *CoinGeckoProvider {
+	if baseURL == "" {
+		baseURL = coinGeckoURL
+	}
+	return &CoinGeckoProvider{BaseURL: baseURL, Client: HTTPClient}
+}
+
+// Name is provider name.
+func (p *CoinGeckoProvider) Name() string {
+	return "coingecko"
+}
+
+// Quote is get price of the crypto symbol in its currency.
+func (p *CoinGeckoProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	coin, currency, ok := CryptoPair(symbol)
+	if !ok {
+		return Quote{}, fmt.Errorf("not a crypto symbol")
+	}
+	id, vs := coinGeckoIDs[coin], strings.ToLower(currency)
+
+	q := url.Values{}
+	q.Set("ids", id)
+	q.Set("vs_currencies", vs)
+	q.Set("include_last_updated_at", "true")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	var body map[string]map[string]float64
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Quote{}, err
+	}
+	price := body[id][vs]
+	if price <= 0 {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+
+	quote := Quote{Price: price, Provider: p.Name()}
+	if updated := body[id]["last_updated_at"]; updated > 0 {
+		quote.AsOf = time.Unix(int64(updated), 0).In(Location).Format(time.RFC3339)
+	}
+	return quote, nil
+}
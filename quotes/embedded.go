@@ -0,0 +1,90 @@
+package quotes
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// rootAppMain is the json of the page state of the quote page, `root.App.main = {...};`.
+var rootAppMain = regexp.MustCompile(`(?m)root\.App\.main\s*=\s*(\{.*\});\s*$`)
+
+// EnvStrategies is the price strategies of YAHOO_PRICE_SELECTORS, before the built in ones.
+// The selectors are separated by ";", `%s` is symbol and `@attr` at the end is the attribute of the element instead of the text
+// (e.g. "fin-streamer[data-symbol='%s'][data-field='regularMarketPrice']@value").
+func EnvStrategies() []PriceStrategy {
+	var strategies []PriceStrategy
+	for i, selector := range strings.Split(os.Getenv("YAHOO_PRICE_SELECTORS"), ";") {
+		selector = strings.TrimSpace(selector)
+		if selector == "" {
+			continue
+		}
+		strategy := PriceStrategy{Name: "env-" + strconv.Itoa(i+1), Selector: selector}
+		if at := strings.LastIndex(selector, "@"); at > strings.LastIndex(selector, "]") {
+			attr := selector[at+1:]
+			strategy.Selector = selector[:at]
+			strategy.Extract = func(h *colly.HTMLElement) string { return h.Attr(attr) }
+		}
+		strategies = append(strategies, strategy)
+	}
+	return strategies
+}
+
+// EmbeddedPrice is the price of the symbol in the page when no selector matched.
+// YAHOO_PRICE_PATTERN (regexp, `%s` is symbol, the first group is the price) is tried first,
+// then regularMarketPrice of the symbol in the root.App.main json of the page.
+func EmbeddedPrice(body []byte, symbol string) (float64, string, bool) {
+	if pattern := os.Getenv("YAHOO_PRICE_PATTERN"); pattern != "" {
+		if re, err := regexp.Compile(strings.ReplaceAll(pattern, "%s", regexp.QuoteMeta(symbol))); err == nil {
+			if m := re.FindSubmatch(body); len(m) > 1 {
+				if v, err := strconv.ParseFloat(strings.ReplaceAll(string(m[1]), ",", ""), 64); err == nil && v > 0 {
+					return v, "pattern", true
+				}
+			}
+		}
+	}
+
+	m := rootAppMain.FindSubmatch(body)
+	if len(m) < 2 {
+		return 0, "", false
+	}
+	var state struct {
+		Context struct {
+			Dispatcher struct {
+				Stores struct {
+					QuoteSummaryStore struct {
+						Price struct {
+							Symbol             string `json:"symbol"`
+							RegularMarketPrice struct {
+								Raw float64 `json:"raw"`
+							} `json:"regularMarketPrice"`
+						} `json:"price"`
+					} `json:"QuoteSummaryStore"`
+					StreamDataStore struct {
+						QuoteData map[string]struct {
+							RegularMarketPrice struct {
+								Raw float64 `json:"raw"`
+							} `json:"regularMarketPrice"`
+						} `json:"quoteData"`
+					} `json:"StreamDataStore"`
+				} `json:"stores"`
+			} `json:"dispatcher"`
+		} `json:"context"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(m[1])).Decode(&state); err != nil {
+		return 0, "", false
+	}
+	stores := state.Context.Dispatcher.Stores
+	if q, ok := stores.StreamDataStore.QuoteData[symbol]; ok && q.RegularMarketPrice.Raw > 0 {
+		return q.RegularMarketPrice.Raw, "root-app-main", true
+	}
+	if p := stores.QuoteSummaryStore.Price; strings.EqualFold(p.Symbol, symbol) && p.RegularMarketPrice.Raw > 0 {
+		return p.RegularMarketPrice.Raw, "root-app-main", true
+	}
+	return 0, "", false
+}
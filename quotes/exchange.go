@@ -0,0 +1,86 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// exchangeSelectors is price selectors by exchange suffix, %s is symbol.
+// They are tried in order before the default price strategies.
+// EXCHANGE_SELECTORS (json, e.g. {"T": ["..."]}) is override it.
+var exchangeSelectors = map[string][]string{
+	// tokyo stock exchange
+	"T": {
+		"fin-streamer[data-field='regularMarketPrice'][data-symbol$='.T']",
+	},
+}
+
+// suffixAliases is normalize other notation of exchange suffix.
+var suffixAliases = map[string]string{
+	"TYO": "T",
+	"TSE": "T",
+	"JP":  "T",
+	"WSE": "WA",
+}
+
+func init() {
+	if env := os.Getenv("EXCHANGE_SELECTORS"); env != "" {
+		var selectors map[string][]string
+		if err := json.Unmarshal([]byte(env), &selectors); err != nil {
+			logging.Warn(context.Background(), "invalid EXCHANGE_SELECTORS", logging.Fields{"error": err})
+			return
+		}
+		for suffix, s := range selectors {
+			exchangeSelectors[strings.ToUpper(suffix)] = s
+		}
+	}
+}
+
+// NormalizeSymbol is upper case symbol and known exchange suffix (e.g. 7203.tyo -> 7203.T).
+func NormalizeSymbol(symbol string) string {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+	i := strings.LastIndex(symbol, ".")
+	if i < 0 {
+		return symbol
+	}
+	if alias, ok := suffixAliases[symbol[i+1:]]; ok {
+		return symbol[:i+1] + alias
+	}
+	return symbol
+}
+
+// ExchangeSuffix is exchange suffix of the symbol, empty is US market.
+// The fund code of a japanese investment trust is T, for the currency and the calendar of tokyo.
+func ExchangeSuffix(symbol string) string {
+	if IsFundCode(symbol) {
+		return "T"
+	}
+	i := strings.LastIndex(symbol, ".")
+	if i < 0 {
+		return ""
+	}
+	return symbol[i+1:]
+}
+
+// PriceStrategies is price strategies for the symbol in priority order.
+func PriceStrategies(symbol string) []PriceStrategy {
+	strategies := EnvStrategies()
+	suffix := ExchangeSuffix(symbol)
+	for i, selector := range exchangeSelectors[suffix] {
+		strategies = append(strategies, PriceStrategy{
+			Name:     fmt.Sprintf("exchange-%s-%d", suffix, i+1),
+			Selector: selector,
+		})
+	}
+	strategies = append(strategies, priceStrategies...)
+
+	for i := range strategies {
+		strategies[i].Selector = strings.ReplaceAll(strategies[i].Selector, "%s", symbol)
+	}
+	return strategies
+}
@@ -0,0 +1,44 @@
+package quotes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPriceStrategies(t *testing.T) {
+	names := func(strategies []PriceStrategy) string {
+		var n []string
+		for _, s := range strategies {
+			n = append(n, s.Name)
+		}
+		return strings.Join(n, ",")
+	}
+
+	// the exchange selectors are before the default strategies
+	s := PriceStrategies("7203.T")
+	if got, want := names(s), "exchange-T-1,quote-header-info,fin-streamer,fin-streamer-value,qsp-price"; got != want {
+		t.Errorf("PriceStrategies(7203.T) = %s, want %s", got, want)
+	}
+	if got, want := s[2].Selector, "fin-streamer[data-symbol='7203.T'][data-field='regularMarketPrice']"; got != want {
+		t.Errorf("Selector = %q, want the symbol in it %q", got, want)
+	}
+	if got, want := names(PriceStrategies("AAPL")), "quote-header-info,fin-streamer,fin-streamer-value,qsp-price"; got != want {
+		t.Errorf("PriceStrategies(AAPL) = %s, want %s", got, want)
+	}
+}
+
+func TestNormalizeSymbol(t *testing.T) {
+	tests := map[string]string{
+		"aapl":     "AAPL",
+		" 7203.t ": "7203.T",
+		"7203.tyo": "7203.T",
+		"6758.TSE": "6758.T",
+		"7203.JP":  "7203.T",
+		"BRK.B":    "BRK.B",
+	}
+	for in, want := range tests {
+		if got := NormalizeSymbol(in); got != want {
+			t.Errorf("NormalizeSymbol(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
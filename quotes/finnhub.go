@@ -0,0 +1,81 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// finnhubURL is quote api endpoint.
+const finnhubURL = "https://finnhub.io/api/v1/quote"
+
+// FinnhubProvider is get stock price from the finnhub quote api with the api token.
+type FinnhubProvider struct {
+	Token   string
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewFinnhubProvider is finnhub provider with the token, base is FINNHUB_URL.
+func NewFinnhubProvider(token, baseURL string) *FinnhubProvider {
+	if baseURL == "" {
+		baseURL = finnhubURL
+	}
+	return &FinnhubProvider{Token: token, BaseURL: baseURL, Client: HTTPClient}
+}
+
+// Name is provider name.
+func (p *FinnhubProvider) Name() string {
+	return "finnhub"
+}
+
+// Quote is get quote of the symbol, the token is sent in the header so it is not in the logs of the url.
+func (p *FinnhubProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	if p.Token == "" {
+		return Quote{}, fmt.Errorf("FINNHUB_API_KEY is not set")
+	}
+
+	q := url.Values{}
+	q.Set("symbol", symbol)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	req.Header.Set("X-Finnhub-Token", p.Token)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	// c is current price, pc is previous close and t is unix time, all zero for an unknown symbol
+	var body struct {
+		Current       float64 `json:"c"`
+		PreviousClose float64 `json:"pc"`
+		Time          int64   `json:"t"`
+		Error         string  `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Quote{}, err
+	}
+	if body.Error != "" {
+		return Quote{}, fmt.Errorf("%s", body.Error)
+	}
+	if body.Current <= 0 {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+
+	quote := Quote{Price: body.Current, PreviousClose: body.PreviousClose, Provider: p.Name()}
+	if body.Time > 0 {
+		quote.AsOf = time.Unix(body.Time, 0).In(Location).Format(time.RFC3339)
+	}
+	return quote, nil
+}
@@ -0,0 +1,117 @@
+package quotes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// FundRoute is route key of the japanese investment trusts (toushin).
+const FundRoute = "FUND"
+
+// fundCode is the 8 characters code of the investment trust association (e.g. 0331418A).
+var fundCode = regexp.MustCompile(`^[0-9][0-9A-Z]{6}[0-9A-Z]$`)
+
+// IsFundCode is true when the symbol is the fund code of a japanese investment trust.
+func IsFundCode(symbol string) bool {
+	if !fundCode.MatchString(symbol) {
+		return false
+	}
+	var digits int
+	for _, c := range symbol {
+		if c >= '0' && c <= '9' {
+			digits++
+		}
+	}
+	return digits >= 5
+}
+
+// FundProvider is get the nav (基準価額, per 10,000 units) of the fund from the yahoo finance japan fund page.
+type FundProvider struct {
+	// BaseURL is fund page url, %s is fund code
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewFundProvider is fund nav provider, base is FUND_NAV_URL.
+func NewFundProvider(baseURL string) *FundProvider {
+	if baseURL == "" {
+		baseURL = "https://finance.yahoo.co.jp/quote/%s"
+	}
+	return &FundProvider{BaseURL: baseURL, Client: HTTPClient}
+}
+
+// Name is provider name.
+func (p *FundProvider) Name() string {
+	return "fund"
+}
+
+// Quote is get the nav in the page state of the fund page.
+func (p *FundProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, QuoteURL(p.BaseURL, symbol), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	ScrapeRequest(req)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Quote{}, err
+	}
+	if err := ScrapeResponse(resp, b); err != nil {
+		return Quote{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+	return ParseFundState(b)
+}
+
+// ParseFundState is the nav of the page state json of the fund page.
+// The nav is of the previous business day, so it is stale.
+func ParseFundState(page []byte) (Quote, error) {
+	i := bytes.Index(page, []byte(preloadedState))
+	if i < 0 {
+		return Quote{}, fmt.Errorf("page state not found")
+	}
+
+	type prices struct {
+		Price      string `json:"price"`
+		UpdateDate string `json:"updateDate"`
+	}
+	var state struct {
+		MainFundPriceBoard struct {
+			FundPrices prices `json:"fundPrices"`
+			PriceBoard prices `json:"priceBoard"`
+		} `json:"mainFundPriceBoard"`
+	}
+	if err := json.NewDecoder(bytes.NewReader(page[i+len(preloadedState):])).Decode(&state); err != nil {
+		return Quote{}, fmt.Errorf("parse page state error. %s", err)
+	}
+
+	board := state.MainFundPriceBoard.FundPrices
+	if board.Price == "" {
+		board = state.MainFundPriceBoard.PriceBoard
+	}
+	price, err := strconv.ParseFloat(strings.ReplaceAll(board.Price, ",", ""), 64)
+	if err != nil || price <= 0 {
+		return Quote{}, fmt.Errorf("nav not found")
+	}
+	return Quote{
+		Price:    price,
+		AsOf:     board.UpdateDate,
+		Stale:    true,
+		Provider: "fund",
+	}, nil
+}
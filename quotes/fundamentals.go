@@ -0,0 +1,73 @@
+package quotes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fundamentals is 52 week range, p/e and market cap of the symbol, zero is unknown.
+type Fundamentals struct {
+	High52    float64 `json:"high_52w,omitempty"`
+	Low52     float64 `json:"low_52w,omitempty"`
+	PE        float64 `json:"pe,omitempty"`
+	MarketCap float64 `json:"market_cap,omitempty"`
+}
+
+// IsZero is check nothing is known.
+func (f Fundamentals) IsZero() bool {
+	return f == Fundamentals{}
+}
+
+// fundamentalSelectors is fin-streamer fields of the quote page, %s is symbol.
+var fundamentalSelectors = map[string]string{
+	"range": "fin-streamer[data-symbol='%s'][data-field='fiftyTwoWeekRange']",
+	"pe":    "fin-streamer[data-symbol='%s'][data-field='trailingPE']",
+	"cap":   "fin-streamer[data-symbol='%s'][data-field='marketCap']",
+}
+
+// capSuffixes is multiplier of the market cap text (e.g. 2.5T).
+var capSuffixes = map[byte]float64{'K': 1e3, 'M': 1e6, 'B': 1e9, 'T': 1e12}
+
+// ParseMarketCap is parse the market cap text, e.g. "2.53T" or "1,234,567".
+func ParseMarketCap(text string) (float64, error) {
+	text = strings.ReplaceAll(strings.TrimSpace(text), ",", "")
+	if text == "" {
+		return 0, fmt.Errorf("empty market cap")
+	}
+	multiplier := 1.0
+	if m, ok := capSuffixes[strings.ToUpper(text)[len(text)-1]]; ok {
+		multiplier = m
+		text = text[:len(text)-1]
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, err
+	}
+	return v * multiplier, nil
+}
+
+// ParseRange is parse the 52 week range text, e.g. "124.17 - 199.62".
+func ParseRange(text string) (low, high float64, err error) {
+	parts := strings.Split(text, "-")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid range %q", text)
+	}
+	if low, err = strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(parts[0]), ",", ""), 64); err != nil {
+		return 0, 0, err
+	}
+	if high, err = strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(parts[1]), ",", ""), 64); err != nil {
+		return 0, 0, err
+	}
+	return low, high, nil
+}
+
+// FormatMarketCap is market cap with the suffix, e.g. 2.53T.
+func FormatMarketCap(v float64) string {
+	for _, s := range []byte{'T', 'B', 'M', 'K'} {
+		if v >= capSuffixes[s] {
+			return fmt.Sprintf("%.2f%c", v/capSuffixes[s], s)
+		}
+	}
+	return fmt.Sprintf("%.0f", v)
+}
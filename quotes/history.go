@@ -0,0 +1,142 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// yahooChartURL is yahoo finance chart api endpoint, %s is symbol.
+const yahooChartURL = "https://query1.finance.yahoo.com/v8/finance/chart/%s"
+
+// Close is the close price of a day (YYYY-MM-DD) in the timezone of the exchange.
+type Close struct {
+	Date  string
+	Price float64
+}
+
+// HistoryProvider is a source of the daily closes.
+type HistoryProvider interface {
+	Name() string
+	History(ctx context.Context, symbol string, from, to time.Time) ([]Close, error)
+}
+
+// NewHistoryProvider is the daily closes of the yahoo chart api (YAHOO_CHART_URL), the symbols of STOOQ_ROUTES are of stooq.
+func NewHistoryProvider() HistoryProvider {
+	routes := map[string]HistoryProvider{}
+	stooq := NewStooqProvider(os.Getenv("STOOQ_URL"))
+	for _, suffix := range StooqRoutes() {
+		routes[suffix] = stooq
+	}
+	return &SuffixHistoryProvider{Routes: routes, Default: NewYahooChartProvider(os.Getenv("YAHOO_CHART_URL"))}
+}
+
+// SuffixHistoryProvider is route the symbols by RouteKey like SuffixProvider, the others are got from default.
+type SuffixHistoryProvider struct {
+	Routes  map[string]HistoryProvider
+	Default HistoryProvider
+}
+
+// Name is default provider name.
+func (p *SuffixHistoryProvider) Name() string {
+	return p.Default.Name()
+}
+
+// History is the closes of the provider of the symbol.
+func (p *SuffixHistoryProvider) History(ctx context.Context, symbol string, from, to time.Time) ([]Close, error) {
+	if route, ok := p.Routes[RouteKey(symbol)]; ok {
+		return route.History(ctx, symbol, from, to)
+	}
+	return p.Default.History(ctx, symbol, from, to)
+}
+
+// YahooChartProvider is get the daily closes from the yahoo finance chart api.
+type YahooChartProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewYahooChartProvider is yahoo chart api provider, empty baseURL is the default endpoint.
+func NewYahooChartProvider(baseURL string) *YahooChartProvider {
+	if baseURL == "" {
+		baseURL = yahooChartURL
+	}
+	return &YahooChartProvider{BaseURL: baseURL, Client: HTTPClient}
+}
+
+// Name is provider name.
+func (p *YahooChartProvider) Name() string {
+	return "yahoochart"
+}
+
+// History is the daily closes of the symbol from from to to, the days without the close are skipped.
+func (p *YahooChartProvider) History(ctx context.Context, symbol string, from, to time.Time) ([]Close, error) {
+	q := url.Values{}
+	q.Set("period1", strconv.FormatInt(from.Unix(), 10))
+	q.Set("period2", strconv.FormatInt(to.AddDate(0, 0, 1).Unix(), 10))
+	q.Set("interval", "1d")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, QuoteURL(p.BaseURL, url.PathEscape(symbol))+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Chart struct {
+			Result []struct {
+				Meta struct {
+					GMTOffset int64 `json:"gmtoffset"`
+				} `json:"meta"`
+				Timestamp  []int64 `json:"timestamp"`
+				Indicators struct {
+					Quote []struct {
+						Close []*float64 `json:"close"`
+					} `json:"quote"`
+				} `json:"indicators"`
+			} `json:"result"`
+			Error *struct {
+				Description string `json:"description"`
+			} `json:"error"`
+		} `json:"chart"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	if e := body.Chart.Error; e != nil {
+		return nil, fmt.Errorf("%s", e.Description)
+	}
+	if len(body.Chart.Result) == 0 || len(body.Chart.Result[0].Indicators.Quote) == 0 {
+		return nil, fmt.Errorf("history not found")
+	}
+
+	r := body.Chart.Result[0]
+	closes := r.Indicators.Quote[0].Close
+	var history []Close
+	for i, ts := range r.Timestamp {
+		if i >= len(closes) || closes[i] == nil || *closes[i] <= 0 {
+			continue
+		}
+		// the date of the exchange, the timestamp is the open of the day
+		date := time.Unix(ts+r.Meta.GMTOffset, 0).UTC().Format("2006-01-02")
+		if n := len(history); n > 0 && history[n-1].Date == date {
+			history[n-1].Price = *closes[i]
+			continue
+		}
+		history = append(history, Close{Date: date, Price: *closes[i]})
+	}
+	return history, nil
+}
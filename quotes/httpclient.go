@@ -0,0 +1,140 @@
+package quotes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/tracing"
+)
+
+// HTTPClient is shared http client of the providers and notifiers.
+// HTTP_TIMEOUT (default 10s), HTTP_RETRIES (default 3) and HTTP_RETRY_BUDGET (default 20 per invocation).
+var HTTPClient = NewHTTPClient()
+
+// retryBudget is retry count left in the invocation.
+var retryBudget = &RetryBudget{}
+
+// NewHTTPClient is http client with timeout and retry.
+func NewHTTPClient() *http.Client {
+	timeout, err := time.ParseDuration(os.Getenv("HTTP_TIMEOUT"))
+	if err != nil || timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	retries, err := strconv.Atoi(os.Getenv("HTTP_RETRIES"))
+	if err != nil || retries < 0 {
+		retries = 3
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &RetryTransport{
+			Base:       tracing.Transport(http.DefaultTransport),
+			MaxRetries: retries,
+			Backoff:    500 * time.Millisecond,
+			Budget:     retryBudget,
+		},
+	}
+}
+
+// ResetRetryBudget is reset the retry budget at the start of the invocation.
+func ResetRetryBudget() {
+	n, err := strconv.Atoi(os.Getenv("HTTP_RETRY_BUDGET"))
+	if err != nil || n < 0 {
+		n = 20
+	}
+	retryBudget.Reset(n)
+}
+
+// Retries is the retries of the invocation since ResetRetryBudget.
+func Retries() int {
+	return retryBudget.Used()
+}
+
+// RetryBudget is retry count shared by all requests.
+type RetryBudget struct {
+	remaining int64
+	used      int64
+}
+
+// Reset is set the retry count.
+func (b *RetryBudget) Reset(n int) {
+	atomic.StoreInt64(&b.remaining, int64(n))
+	atomic.StoreInt64(&b.used, 0)
+}
+
+// Take is use one retry, false when the budget is exhausted.
+func (b *RetryBudget) Take() bool {
+	if atomic.AddInt64(&b.remaining, -1) < 0 {
+		return false
+	}
+	atomic.AddInt64(&b.used, 1)
+	return true
+}
+
+// Used is the retries taken since the reset.
+func (b *RetryBudget) Used() int {
+	return int(atomic.LoadInt64(&b.used))
+}
+
+// RetryTransport is retry 429 and 5xx response with exponential backoff.
+type RetryTransport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	Backoff    time.Duration
+	Budget     *RetryBudget
+}
+
+// RoundTrip is send the request with retry.
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		resp, err := t.Base.RoundTrip(req)
+		if !IsRetryableResponse(resp, err) || attempt >= t.MaxRetries || !t.Budget.Take() {
+			return resp, err
+		}
+
+		// request body is read again for the retry
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, berr := req.GetBody()
+			if berr != nil {
+				return resp, err
+			}
+			req.Body = body
+		}
+
+		wait := t.Backoff << attempt
+		if resp != nil {
+			if s, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && s > 0 {
+				wait = time.Duration(s) * time.Second
+			}
+			resp.Body.Close()
+		}
+		logging.Warn(req.Context(), "http retry", logging.Fields{"host": req.URL.Host, "attempt": attempt + 1, "wait_ms": wait.Milliseconds()})
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// IsRetryableResponse is true for 429, 5xx and temporary network error.
+// Canceled or expired context is not retried.
+func IsRetryableResponse(resp *http.Response, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
@@ -0,0 +1,60 @@
+package quotes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRetryTransport(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	budget := &RetryBudget{}
+	budget.Reset(20)
+	client := &http.Client{Transport: &RetryTransport{Base: http.DefaultTransport, MaxRetries: 3, Backoff: time.Millisecond, Budget: budget}}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 {
+		t.Errorf("Get() = %d after %d calls, want 200 after 2 retries", resp.StatusCode, calls)
+	}
+
+	// the retries stop when the budget of the invocation is used
+	calls = 0
+	budget.Reset(1)
+	resp, err = client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || calls != 2 {
+		t.Errorf("Get() = %d after %d calls, want 503 after 1 retry", resp.StatusCode, calls)
+	}
+}
+
+func TestIsRetryableResponse(t *testing.T) {
+	tests := map[int]bool{
+		http.StatusOK:                  false,
+		http.StatusNotFound:            false,
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+	}
+	for code, want := range tests {
+		if got := IsRetryableResponse(&http.Response{StatusCode: code}, nil); got != want {
+			t.Errorf("IsRetryableResponse(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
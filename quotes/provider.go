@@ -0,0 +1,261 @@
+// Package quotes is current price of the symbols from the price providers.
+package quotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// Location is timezone of the quote time, main sets it to REPORT_TIMEZONE.
+var Location = time.Local
+
+// Quote is current price of the symbol.
+type Quote struct {
+	Price    float64
+	AsOf     string
+	Stale    bool
+	Provider string
+	// PreviousClose is close of the previous day, zero when the provider doesn't have it
+	PreviousClose float64
+	// Fundamentals is nil when the provider doesn't have them
+	Fundamentals *Fundamentals
+	// Session is trading session of the price, SessionPre or SessionPost, empty is the regular session (or unknown)
+	Session string
+	// Cached is the quote of the QuoteCache
+	Cached bool
+	// Degraded is the last cached quote used when the providers are out of their request budgets (PROVIDER_BUDGETS)
+	Degraded bool
+}
+
+// Session of the pre-market and after-hours prices.
+const (
+	SessionPre  = "pre"
+	SessionPost = "post"
+)
+
+// ExtendedHours is true when EXTENDED_HOURS is true, the pre-market and after-hours prices are used outside the regular session.
+func ExtendedHours() bool {
+	return os.Getenv("EXTENDED_HOURS") == "true"
+}
+
+// Provider is a source of the current stock price.
+type Provider interface {
+	Name() string
+	Quote(ctx context.Context, symbol string) (Quote, error)
+}
+
+// BatchProvider is a provider which gets many symbols in one request.
+type BatchProvider interface {
+	Provider
+	Quotes(ctx context.Context, symbols []string) (map[string]Quote, error)
+}
+
+// PrefetchedProvider is quotes got in advance, a missing symbol is got from next.
+type PrefetchedProvider struct {
+	Quotes map[string]Quote
+	Next   Provider
+}
+
+// Prefetch is get quotes of the symbols from the batch provider.
+func Prefetch(ctx context.Context, provider BatchProvider, symbols []string) *PrefetchedProvider {
+	seen := map[string]bool{}
+	var names []string
+	for _, s := range symbols {
+		if !seen[s] {
+			seen[s] = true
+			names = append(names, s)
+		}
+	}
+
+	quotes, err := provider.Quotes(ctx, names)
+	if err != nil {
+		logging.Warn(ctx, "batch quote error", logging.Fields{"provider": provider.Name(), "count": len(names), "error": err})
+	}
+	return &PrefetchedProvider{Quotes: quotes, Next: provider}
+}
+
+// Has is true when the symbol is prefetched.
+func (p *PrefetchedProvider) Has(symbol string) bool {
+	q, ok := p.Quotes[symbol]
+	return ok && q.Price > 0
+}
+
+// Name is next provider name.
+func (p *PrefetchedProvider) Name() string {
+	return p.Next.Name()
+}
+
+// Quote is the prefetched quote, or quote of next provider.
+func (p *PrefetchedProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	if p.Has(symbol) {
+		return p.Quotes[symbol], nil
+	}
+	return p.Next.Quote(ctx, symbol)
+}
+
+// providers is price provider constructors by PRICE_PROVIDER name.
+var providers = map[string]func() Provider{
+	"yahoo": func() Provider {
+		return NewYahooProvider(os.Getenv("YAHOO_BASE_URL"), DebugSnippetLength(os.Getenv("GETPRICE_DEBUG")))
+	},
+	"yahooapi": func() Provider {
+		return NewYahooAPIProvider(os.Getenv("YAHOO_API_URL"))
+	},
+	"alphavantage": func() Provider {
+		return NewAlphaVantageProvider(os.Getenv("ALPHAVANTAGE_API_KEY"))
+	},
+	"yahoojp": func() Provider {
+		return NewYahooJapanProvider(os.Getenv("YAHOO_JP_URL"))
+	},
+	"coingecko": func() Provider {
+		return NewCoinGeckoProvider(os.Getenv("COINGECKO_URL"))
+	},
+	"fund": func() Provider {
+		return NewFundProvider(os.Getenv("FUND_NAV_URL"))
+	},
+	"finnhub": func() Provider {
+		return NewFinnhubProvider(os.Getenv("FINNHUB_API_KEY"), os.Getenv("FINNHUB_URL"))
+	},
+	"stooq": func() Provider {
+		return NewStooqProvider(os.Getenv("STOOQ_URL"))
+	},
+}
+
+// newProvider is the provider of the name under its PROVIDER_BUDGETS.
+func newProvider(name string) Provider {
+	return WithBudget(providers[name]())
+}
+
+// NewProvider is the provider of the name.
+// Default is yahoo quote api, and the scraper for symbols the api doesn't return.
+// Finnhub is before them when FINNHUB_API_KEY is set, the official api is preferred over the scraping.
+// Tokyo (.T) symbols are tried on yahoo japan, crypto (e.g. BTC-USD) on coingecko before them.
+// The fund codes of japanese investment trusts (e.g. 0331418A) are the nav of the fund provider only.
+// The polish and european symbols of STOOQ_ROUTES (e.g. CDR.WA) are tried on stooq first.
+// Alpha vantage is the last fallback when ALPHAVANTAGE_API_KEY is set.
+// Each provider is under its request budgets of PROVIDER_BUDGETS.
+func NewProvider(name string) (Provider, error) {
+	var chain []Provider
+	if name == "" {
+		chain = []Provider{newProvider("yahooapi"), newProvider("yahoo")}
+		if os.Getenv("FINNHUB_API_KEY") != "" {
+			chain = append([]Provider{newProvider("finnhub")}, chain...)
+		}
+	} else {
+		name = strings.ToLower(name)
+		p, ok := providers[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown PRICE_PROVIDER %q", name)
+		}
+		chain = []Provider{WithBudget(p())}
+	}
+
+	if name != "alphavantage" && os.Getenv("ALPHAVANTAGE_API_KEY") != "" {
+		chain = append(chain, newProvider("alphavantage"))
+	}
+	var provider Provider = &FallbackProvider{Providers: chain}
+	if len(chain) == 1 {
+		provider = chain[0]
+	}
+
+	// tokyo symbols are got from yahoo japan, crypto from coingecko first by default
+	if name == "" {
+		routes := map[string]Provider{
+			"T":         &FallbackProvider{Providers: append([]Provider{newProvider("yahoojp")}, chain...)},
+			CryptoRoute: &FallbackProvider{Providers: append([]Provider{newProvider("coingecko")}, chain...)},
+			FundRoute:   newProvider("fund"),
+		}
+		stooq := &FallbackProvider{Providers: append([]Provider{newProvider("stooq")}, chain...)}
+		for _, suffix := range StooqRoutes() {
+			if _, ok := routes[suffix]; !ok {
+				routes[suffix] = stooq
+			}
+		}
+		provider = &SuffixProvider{Routes: routes, Default: provider}
+	}
+	return provider, nil
+}
+
+// FallbackProvider is try the providers in order until one returns the price.
+type FallbackProvider struct {
+	Providers []Provider
+}
+
+// Name is provider names joined by ">".
+func (f *FallbackProvider) Name() string {
+	names := make([]string, len(f.Providers))
+	for i, p := range f.Providers {
+		names[i] = p.Name()
+	}
+	return strings.Join(names, ">")
+}
+
+// Quotes is quotes of the batch providers in order, each provider gets the symbols still missing.
+func (f *FallbackProvider) Quotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	quotes := map[string]Quote{}
+	for _, p := range f.Providers {
+		bp, ok := p.(BatchProvider)
+		if !ok {
+			continue
+		}
+
+		var missing []string
+		for _, s := range symbols {
+			if _, ok := quotes[s]; !ok {
+				missing = append(missing, s)
+			}
+		}
+		if len(missing) == 0 || ctx.Err() != nil {
+			break
+		}
+
+		got, err := bp.Quotes(ctx, missing)
+		if err != nil {
+			logging.Warn(ctx, "batch quote error", logging.Fields{"provider": p.Name(), "count": len(missing), "error": err})
+			continue
+		}
+		for s, q := range got {
+			if q.Provider == "" {
+				q.Provider = p.Name()
+			}
+			quotes[s] = q
+		}
+	}
+	return quotes, nil
+}
+
+// Quote is the first quote of the providers, errors of all providers are returned when none succeed.
+// The rest of the providers are not tried after the context is done.
+// The error is ErrBudgetExhausted when a provider was skipped by its request budget.
+func (f *FallbackProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	var errs []string
+	var budget bool
+	for _, p := range f.Providers {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err().Error())
+			break
+		}
+		quote, err := p.Quote(ctx, symbol)
+		if err == nil && quote.Price > 0 {
+			if quote.Provider == "" {
+				quote.Provider = p.Name()
+			}
+			return quote, nil
+		}
+		if err == nil {
+			err = fmt.Errorf("price not found")
+		}
+		budget = budget || errors.Is(err, ErrBudgetExhausted)
+		errs = append(errs, fmt.Sprintf("%s: %s", p.Name(), err))
+	}
+	if budget {
+		return Quote{}, fmt.Errorf("%w: %s", ErrBudgetExhausted, strings.Join(errs, ", "))
+	}
+	return Quote{}, fmt.Errorf("%s", strings.Join(errs, ", "))
+}
@@ -0,0 +1,35 @@
+package quotes
+
+import (
+	"testing"
+)
+
+func TestNewProvider(t *testing.T) {
+	t.Setenv("ALPHAVANTAGE_API_KEY", "")
+	for _, name := range []string{"yahoo", "Yahoo"} {
+		if p, err := NewProvider(name); err != nil || p.Name() != "yahoo" {
+			t.Errorf("NewProvider(%q) = %v, %v, want yahoo", name, p, err)
+		}
+	}
+
+	// the default is the quote api and the scraper for the symbols the api doesn't return
+	p, err := NewProvider("")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	s, ok := p.(*SuffixProvider)
+	if !ok {
+		t.Fatalf("NewProvider() = %+v, want the routes of the suffixes", p)
+	}
+	f, ok := s.Default.(*FallbackProvider)
+	if !ok || len(f.Providers) != 2 || f.Providers[0].Name() != "yahooapi" || f.Providers[1].Name() != "yahoo" {
+		t.Errorf("Default = %+v, want yahooapi and yahoo", s.Default)
+	}
+	// tokyo symbols are tried on yahoo japan first
+	if f, ok := s.Routes["T"].(*FallbackProvider); !ok || len(f.Providers) != 3 || f.Providers[0].Name() != "yahoojp" {
+		t.Errorf("Routes[T] = %+v, want yahoojp before the default", s.Routes["T"])
+	}
+	if _, err := NewProvider("unknown"); err == nil {
+		t.Error("NewProvider(unknown) error = nil, want the unknown PRICE_PROVIDER")
+	}
+}
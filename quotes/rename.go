@@ -0,0 +1,72 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// knownRenames is the renamed symbols and the delisted ones (empty), for the suggestion of SYMBOL_ALIASES.
+var knownRenames = map[string]string{
+	"FB":   "META",
+	"ANTM": "ELV",
+	"SQ":   "XYZ",
+	"RTN":  "RTX",
+	"TWTR": "",
+	"ATVI": "",
+}
+
+// symbolAliases is SYMBOL_ALIASES (json, e.g. {"FB": "META", "TWTR": ""}), the symbol of the price by the symbol of the stock data.
+// An empty symbol is delisted.
+var symbolAliases = map[string]string{}
+
+func init() {
+	if env := os.Getenv("SYMBOL_ALIASES"); env != "" {
+		var aliases map[string]string
+		if err := json.Unmarshal([]byte(env), &aliases); err != nil {
+			logging.Warn(context.Background(), "invalid SYMBOL_ALIASES", logging.Fields{"error": err})
+			return
+		}
+		for from, to := range aliases {
+			symbolAliases[NormalizeSymbol(from)] = NormalizeSymbol(to)
+		}
+	}
+}
+
+// Alias is the symbol of SYMBOL_ALIASES of the symbol, ok is false when it is not in it and to is empty when it is delisted.
+func Alias(symbol string) (to string, ok bool) {
+	to, ok = symbolAliases[symbol]
+	return to, ok
+}
+
+// AliasOf is the symbol to get the price of the symbol, the symbol itself without the alias.
+func AliasOf(symbol string) string {
+	if to, ok := symbolAliases[symbol]; ok && to != "" {
+		return to
+	}
+	return symbol
+}
+
+// RenameSuggestion is the suggestion of SYMBOL_ALIASES for the known rename or delisting of the symbol, empty when it is not known or in it.
+func RenameSuggestion(symbol string) string {
+	if _, ok := symbolAliases[symbol]; ok {
+		return ""
+	}
+	to, ok := knownRenames[symbol]
+	if !ok {
+		return ""
+	}
+	if to == "" {
+		return fmt.Sprintf("%s is delisted? add {%q: \"\"} to SYMBOL_ALIASES", symbol, symbol)
+	}
+	return fmt.Sprintf("%s is renamed to %s? add {%q: %q} to SYMBOL_ALIASES", symbol, to, symbol, to)
+}
+
+// IsDelisted is true when the symbol is delisted in SYMBOL_ALIASES.
+func IsDelisted(symbol string) bool {
+	to, ok := symbolAliases[symbol]
+	return ok && to == ""
+}
@@ -0,0 +1,113 @@
+package quotes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// ErrBlocked is the consent or captcha page of the source instead of the quote.
+var ErrBlocked = errors.New("blocked by source")
+
+// defaultUserAgents is the user agents of the scraper when SCRAPE_USER_AGENTS is not set.
+var defaultUserAgents = []string{
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+	"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.2 Safari/605.1.15",
+	"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0",
+	"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36 Edg/120.0.0.0",
+}
+
+// ScrapeJar is the cookies of the sources (e.g. the consent of yahoo), kept while the lambda is warm.
+var ScrapeJar, _ = cookiejar.New(nil)
+
+// blockedMarkers is the text of the captcha and bot check pages.
+var blockedMarkers = [][]byte{
+	[]byte("captcha"),
+	[]byte("unusual traffic"),
+	[]byte("will be right back"),
+}
+
+// titlePattern is the title of the page.
+var titlePattern = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+
+// UserAgents is SCRAPE_USER_AGENTS (separated by "|"), default is the browsers.
+func UserAgents() []string {
+	var agents []string
+	for _, a := range strings.Split(os.Getenv("SCRAPE_USER_AGENTS"), "|") {
+		if a = strings.TrimSpace(a); a != "" {
+			agents = append(agents, a)
+		}
+	}
+	if len(agents) == 0 {
+		return defaultUserAgents
+	}
+	return agents
+}
+
+// UserAgent is a random user agent of UserAgents.
+func UserAgent() string {
+	agents := UserAgents()
+	return agents[rand.Intn(len(agents))]
+}
+
+// SetScrapeHeaders is set the browser headers of the request, the rotated user agent,
+// SCRAPE_ACCEPT_LANGUAGE (default en-US,en;q=0.9) and SCRAPE_COOKIES (e.g. the consent cookie, "name=value; name=value").
+func SetScrapeHeaders(h http.Header) {
+	lang := os.Getenv("SCRAPE_ACCEPT_LANGUAGE")
+	if lang == "" {
+		lang = "en-US,en;q=0.9"
+	}
+	h.Set("User-Agent", UserAgent())
+	h.Set("Accept-Language", lang)
+	if h.Get("Accept") == "" {
+		h.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	}
+	if cookies := os.Getenv("SCRAPE_COOKIES"); cookies != "" {
+		h.Add("Cookie", cookies)
+	}
+}
+
+// ScrapeRequest is set the headers and the cookies of ScrapeJar to the request.
+func ScrapeRequest(req *http.Request) {
+	SetScrapeHeaders(req.Header)
+	for _, c := range ScrapeJar.Cookies(req.URL) {
+		req.AddCookie(c)
+	}
+}
+
+// ScrapeResponse is keep the cookies of the response and check it is not blocked.
+func ScrapeResponse(resp *http.Response, body []byte) error {
+	ScrapeJar.SetCookies(resp.Request.URL, resp.Cookies())
+	return CheckBlocked(resp.Request.URL, resp.StatusCode, body)
+}
+
+// CheckBlocked is ErrBlocked when the page is the consent page (after the redirect) or the captcha.
+// The captcha is the marker in the title of the page, or in the page of the 403, 429 or 503 status.
+func CheckBlocked(u *url.URL, status int, body []byte) error {
+	if u != nil && (strings.HasPrefix(u.Host, "consent.") || strings.HasPrefix(u.Host, "guce.")) {
+		return fmt.Errorf("%w: consent page %s, set SCRAPE_COOKIES", ErrBlocked, u.Host)
+	}
+
+	text := bytes.ToLower(body)
+	switch status {
+	case http.StatusForbidden, http.StatusTooManyRequests, http.StatusServiceUnavailable:
+	default:
+		text = nil
+		if m := titlePattern.FindSubmatch(body); len(m) > 1 {
+			text = bytes.ToLower(m[1])
+		}
+	}
+	for _, marker := range blockedMarkers {
+		if bytes.Contains(text, bytes.ToLower(marker)) {
+			return fmt.Errorf("%w: %s page. %d", ErrBlocked, marker, status)
+		}
+	}
+	return nil
+}
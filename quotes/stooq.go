@@ -0,0 +1,183 @@
+package quotes
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stooqURL is csv quote api endpoint.
+const stooqURL = "https://stooq.com/q/l/"
+
+// stooqSuffixes is stooq market of the exchange suffix, empty is the warsaw stock exchange (no suffix on stooq).
+var stooqSuffixes = map[string]string{
+	"":   "us",
+	"US": "us",
+	"WA": "",
+	"PL": "",
+	"L":  "uk",
+	"UK": "uk",
+	"DE": "de",
+	"F":  "de",
+	"HU": "hu",
+	"T":  "jp",
+}
+
+// defaultStooqRoutes is exchange suffixes tried on stooq first by default, polish and european markets.
+const defaultStooqRoutes = "WA,PL,UK,HU"
+
+// StooqRoutes is exchange suffixes of STOOQ_ROUTES (comma separated, e.g. WA,DE), "none" is no route.
+func StooqRoutes() []string {
+	env := os.Getenv("STOOQ_ROUTES")
+	if env == "" {
+		env = defaultStooqRoutes
+	}
+	if strings.EqualFold(env, "none") {
+		return nil
+	}
+	var routes []string
+	for _, s := range strings.Split(env, ",") {
+		if s = strings.ToUpper(strings.TrimSpace(s)); s != "" {
+			routes = append(routes, s)
+		}
+	}
+	return routes
+}
+
+// StooqSymbol is the symbol on stooq (e.g. CDR.WA -> cdr, VOD.L -> vod.uk, AAPL -> aapl.us).
+func StooqSymbol(symbol string) string {
+	suffix := ExchangeSuffix(symbol)
+	base := strings.TrimSuffix(symbol, "."+suffix)
+	if suffix == "" {
+		base = symbol
+	}
+	market, ok := stooqSuffixes[suffix]
+	if !ok {
+		market = suffix
+	}
+	if market == "" {
+		return strings.ToLower(base)
+	}
+	return strings.ToLower(base + "." + market)
+}
+
+// StooqProvider is get the close of the day from the stooq csv api.
+type StooqProvider struct {
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewStooqProvider is stooq provider, base is STOOQ_URL.
+func NewStooqProvider(baseURL string) *StooqProvider {
+	if baseURL == "" {
+		baseURL = stooqURL
+	}
+	return &StooqProvider{BaseURL: baseURL, Client: HTTPClient}
+}
+
+// Name is provider name.
+func (p *StooqProvider) Name() string {
+	return "stooq"
+}
+
+// Quote is get the last close of the symbol, a symbol stooq doesn't have is N/D.
+func (p *StooqProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	q := url.Values{}
+	q.Set("s", StooqSymbol(symbol))
+	q.Set("f", "sd2t2c")
+	q.Set("h", "")
+	q.Set("e", "csv")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	// Symbol,Date,Time,Close
+	records, err := csv.NewReader(resp.Body).ReadAll()
+	if err != nil {
+		return Quote{}, fmt.Errorf("parse csv error. %s", err)
+	}
+	if len(records) < 2 || len(records[1]) < 4 {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+	row := records[1]
+	price, err := strconv.ParseFloat(row[3], 64)
+	if err != nil || price <= 0 {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+
+	quote := Quote{Price: price, AsOf: row[1], Provider: p.Name()}
+	if row[2] != "" && row[2] != "N/D" {
+		quote.AsOf = row[1] + " " + row[2]
+	}
+	return quote, nil
+}
+
+// stooqHistoryURL is the daily csv of the symbol.
+const stooqHistoryURL = "https://stooq.com/q/d/l/"
+
+// History is the daily closes of the symbol from the stooq daily csv (STOOQ_HISTORY_URL).
+func (p *StooqProvider) History(ctx context.Context, symbol string, from, to time.Time) ([]Close, error) {
+	base := os.Getenv("STOOQ_HISTORY_URL")
+	if base == "" {
+		base = stooqHistoryURL
+	}
+	q := url.Values{}
+	q.Set("s", StooqSymbol(symbol))
+	q.Set("d1", from.Format("20060102"))
+	q.Set("d2", to.Format("20060102"))
+	q.Set("i", "d")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	// Date,Open,High,Low,Close,Volume
+	r := csv.NewReader(resp.Body)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse csv error. %s", err)
+	}
+	var history []Close
+	for i, row := range records {
+		if i == 0 || len(row) < 5 {
+			continue
+		}
+		price, err := strconv.ParseFloat(row[4], 64)
+		if err != nil || price <= 0 {
+			continue
+		}
+		history = append(history, Close{Date: row[0], Price: price})
+	}
+	if len(history) == 0 {
+		return nil, fmt.Errorf("history not found")
+	}
+	return history, nil
+}
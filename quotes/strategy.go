@@ -0,0 +1,43 @@
+package quotes
+
+import (
+	"github.com/gocolly/colly/v2"
+)
+
+// PriceStrategy is a way to extract the price text from the quote page.
+type PriceStrategy struct {
+	Name     string
+	Selector string // %s is symbol
+	Extract  func(h *colly.HTMLElement) string
+}
+
+// Text is the price text of the element, default is element text.
+func (s PriceStrategy) Text(h *colly.HTMLElement) string {
+	if s.Extract == nil {
+		return h.Text
+	}
+	return s.Extract(h)
+}
+
+// priceStrategies is default price strategies in priority order, append here for new page layout.
+var priceStrategies = []PriceStrategy{
+	{
+		Name:     "quote-header-info",
+		Selector: "div#quote-header-info fin-streamer[data-symbol='%s'][data-field='regularMarketPrice']",
+	},
+	{
+		Name:     "fin-streamer",
+		Selector: "fin-streamer[data-symbol='%s'][data-field='regularMarketPrice']",
+	},
+	{
+		Name:     "fin-streamer-value",
+		Selector: "fin-streamer[data-symbol='%s'][data-field='regularMarketPrice'][value]",
+		Extract: func(h *colly.HTMLElement) string {
+			return h.Attr("value")
+		},
+	},
+	{
+		Name:     "qsp-price",
+		Selector: "[data-testid='qsp-price']",
+	},
+}
@@ -0,0 +1,227 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// YahooProvider is get stock price from yahoo finance web page.
+type YahooProvider struct {
+	// BaseURL is quote page url, %s is symbol
+	BaseURL string
+	// Debug is length of the scraped text in the error, 0 is disabled
+	Debug int
+	// ExtendedHours is use the pre-market and after-hours prices on the page
+	ExtendedHours bool
+}
+
+// NewYahooProvider is yahoo finance scraper.
+func NewYahooProvider(baseURL string, debug int) *YahooProvider {
+	return &YahooProvider{BaseURL: baseURL, Debug: debug, ExtendedHours: ExtendedHours()}
+}
+
+// extendedSelectors is the pre-market and after-hours prices of the quote page by session, %s is symbol.
+var extendedSelectors = map[string]string{
+	SessionPre:  "fin-streamer[data-symbol='%s'][data-field='preMarketPrice']",
+	SessionPost: "fin-streamer[data-symbol='%s'][data-field='postMarketPrice']",
+}
+
+// Name is provider name.
+func (p *YahooProvider) Name() string {
+	return "yahoo"
+}
+
+// Quote is scrape the quote page of the symbol.
+func (p *YahooProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	url := QuoteURL(p.BaseURL, symbol)
+	strategies := PriceStrategies(symbol)
+
+	var quote Quote
+
+	// first matched value of each strategy, strategies are in priority order
+	values := make([]float64, len(strategies))
+	var fetchErr, scraped string
+
+	c := colly.NewCollector()
+	// colly has no request context, the timeout is until the deadline of the context
+	timeout := HTTPClient.Timeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	c.WithTransport(HTTPClient.Transport)
+	c.SetRequestTimeout(timeout)
+	c.SetCookieJar(ScrapeJar)
+	c.OnRequest(func(r *colly.Request) {
+		SetScrapeHeaders(*r.Headers)
+	})
+	for i, strategy := range strategies {
+		i, strategy := i, strategy
+		c.OnHTML(strategy.Selector, func(h *colly.HTMLElement) {
+			if values[i] != 0 {
+				return
+			}
+			text := strings.TrimSpace(strategy.Text(h))
+			value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", ""), 64)
+			if err != nil {
+				fetchErr = fmt.Sprintf("parse price error. %s", err)
+				scraped = text
+				return
+			}
+			values[i] = value
+		})
+	}
+
+	// quote header text for debugging when no selector is matched
+	if p.Debug > 0 {
+		c.OnHTML("#quote-header-info, [data-testid='quote-hdr']", func(h *colly.HTMLElement) {
+			if scraped == "" {
+				scraped = strings.Join(strings.Fields(h.Text), " ")
+			}
+		})
+	}
+
+	// 52 week range, p/e and market cap of the statistics, a parse error is ignored
+	var f Fundamentals
+	fieldText := func(h *colly.HTMLElement) string {
+		if v := h.Attr("value"); v != "" {
+			return v
+		}
+		return strings.TrimSpace(h.Text)
+	}
+	c.OnHTML(strings.ReplaceAll(fundamentalSelectors["range"], "%s", symbol), func(h *colly.HTMLElement) {
+		if low, high, err := ParseRange(fieldText(h)); err == nil {
+			f.Low52, f.High52 = low, high
+		}
+	})
+	c.OnHTML(strings.ReplaceAll(fundamentalSelectors["pe"], "%s", symbol), func(h *colly.HTMLElement) {
+		if v, err := strconv.ParseFloat(strings.ReplaceAll(fieldText(h), ",", ""), 64); err == nil {
+			f.PE = v
+		}
+	})
+	c.OnHTML(strings.ReplaceAll(fundamentalSelectors["cap"], "%s", symbol), func(h *colly.HTMLElement) {
+		if v, err := ParseMarketCap(fieldText(h)); err == nil {
+			f.MarketCap = v
+		}
+	})
+
+	// the page has the price of the session outside the regular session only
+	extended := map[string]float64{}
+	if p.ExtendedHours {
+		for session, selector := range extendedSelectors {
+			session := session
+			c.OnHTML(strings.ReplaceAll(selector, "%s", symbol), func(h *colly.HTMLElement) {
+				if v, err := strconv.ParseFloat(strings.ReplaceAll(fieldText(h), ",", ""), 64); err == nil && v > 0 {
+					extended[session] = v
+				}
+			})
+		}
+	}
+
+	// market state, e.g. "At close: June 14 4:00PM EDT"
+	c.OnHTML(marketStateSelector, func(h *colly.HTMLElement) {
+		if quote.AsOf == "" {
+			quote.AsOf = strings.Join(strings.Fields(h.Text), " ")
+			quote.Stale = IsMarketClosed(quote.AsOf)
+		}
+	})
+
+	// the page is kept for the embedded json when no selector matched
+	var body []byte
+	var blocked error
+	c.OnResponse(func(r *colly.Response) {
+		body = r.Body
+		blocked = CheckBlocked(r.Request.URL, r.StatusCode, r.Body)
+	})
+
+	c.OnError(func(r *colly.Response, err error) {
+		fetchErr = fmt.Sprintf("fetch error. %d %s", r.StatusCode, err)
+		if blocked == nil {
+			blocked = CheckBlocked(r.Request.URL, r.StatusCode, r.Body)
+		}
+	})
+
+	if err := ctx.Err(); err != nil {
+		return quote, err
+	}
+	c.Visit(url)
+
+	for i, value := range values {
+		if value > 0 {
+			logging.Debug(ctx, "price found", logging.Fields{"symbol": symbol, "provider": p.Name(), "price": value, "strategy": strategies[i].Name})
+			quote.Price = value
+			if !f.IsZero() {
+				quote.Fundamentals = &f
+			}
+			for _, session := range []string{SessionPost, SessionPre} {
+				if v := extended[session]; v > 0 {
+					quote.Price, quote.Session, quote.Stale = v, session, false
+					break
+				}
+			}
+			return quote, nil
+		}
+	}
+
+	// the consent and captcha page has no price, it is not the layout change
+	if blocked != nil {
+		return quote, blocked
+	}
+	if value, name, ok := EmbeddedPrice(body, symbol); ok {
+		logging.Warn(ctx, "price found in the embedded json, the selectors need update", logging.Fields{"symbol": symbol, "provider": p.Name(), "price": value, "strategy": name})
+		quote.Price = value
+		return quote, nil
+	}
+
+	if fetchErr == "" {
+		fetchErr = "price not found"
+	}
+	if p.Debug > 0 {
+		fetchErr = fmt.Sprintf("%s [scraped: %q]", fetchErr, Truncate(scraped, p.Debug))
+	}
+	return quote, fmt.Errorf("%s", fetchErr)
+}
+
+// marketStateSelector is market state text on the quote page.
+const marketStateSelector = "#quote-market-notice, [data-testid='qsp-price-market-time'], [slot='marketTimeNotice']"
+
+// IsMarketClosed is true when the market state text shows the previous close.
+func IsMarketClosed(state string) bool {
+	state = strings.ToLower(state)
+	return strings.Contains(state, "at close") || strings.Contains(state, "market closed")
+}
+
+// QuoteURL is quote page url of the symbol, base is YAHOO_BASE_URL with %s placeholder.
+func QuoteURL(base, symbol string) string {
+	if base == "" {
+		base = "https://finance.yahoo.com/quote/%s"
+	}
+	if !strings.Contains(base, "%s") {
+		base = strings.TrimSuffix(base, "/") + "/%s"
+	}
+	return strings.ReplaceAll(base, "%s", symbol)
+}
+
+// DebugSnippetLength is length of the scraped text in the error (GETPRICE_DEBUG).
+// "true" is 200 characters, number is the length, otherwise debug is disabled.
+func DebugSnippetLength(env string) int {
+	if env == "true" {
+		return 200
+	}
+	n, _ := strconv.Atoi(env)
+	return n
+}
+
+// Truncate is first n characters of the text.
+func Truncate(text string, n int) string {
+	r := []rune(text)
+	if len(r) <= n {
+		return text
+	}
+	return string(r[:n]) + "..."
+}
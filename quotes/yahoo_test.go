@@ -0,0 +1,153 @@
+package quotes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// savedQuotePages is the server of the saved quote pages of testdata (yahoo_<symbol>.html).
+func savedQuotePages(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		symbol := strings.TrimPrefix(r.URL.Path, "/quote/")
+		b, err := os.ReadFile(filepath.Join("testdata", "yahoo_"+symbol+".html"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(b)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestYahooProviderExchanges(t *testing.T) {
+	p := NewYahooProvider(savedQuotePages(t).URL+"/quote", 0)
+
+	tests := []struct {
+		symbol string
+		price  float64
+	}{
+		// quote-header-info (AAPL), the fin-streamer without it (MSFT) and the qsp-price of the new layout (GOOG)
+		{"AAPL", 130.48},
+		{"MSFT", 257.89},
+		{"GOOG", 2513.93},
+		{"7203.T", 9813},
+	}
+	for _, tt := range tests {
+		if q, err := p.Quote(context.Background(), tt.symbol); err != nil || q.Price != tt.price {
+			t.Errorf("%s: Quote() = %v, %v, want %v", tt.symbol, q.Price, err, tt.price)
+		}
+	}
+	if _, err := p.Quote(context.Background(), "XXXX"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Quote(XXXX) error = %v, want the fetch error of 404", err)
+	}
+}
+
+func TestYahooProviderDebugSnippet(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><div id="quote-header-info"><h1>Apple Inc. (AAPL)</h1>
+			<span>Price temporarily unavailable</span></div></body></html>`)
+	}))
+	defer srv.Close()
+
+	_, err := NewYahooProvider(srv.URL+"/quote", 20).Quote(context.Background(), "AAPL")
+	if want := `price not found [scraped: "Apple Inc. (AAPL) Pr..."]`; err == nil || err.Error() != want {
+		t.Errorf("Quote() error = %v, want %q", err, want)
+	}
+	if _, err := NewYahooProvider(srv.URL+"/quote", 0).Quote(context.Background(), "AAPL"); err == nil || err.Error() != "price not found" {
+		t.Errorf("Quote() error without debug = %v, want price not found", err)
+	}
+}
+
+func TestDebugSnippetLength(t *testing.T) {
+	for env, want := range map[string]int{"": 0, "false": 0, "true": 200, "50": 50} {
+		if got := DebugSnippetLength(env); got != want {
+			t.Errorf("DebugSnippetLength(%q) = %d, want %d", env, got, want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	if got := Truncate("株価が見つかりません", 3); got != "株価が..." {
+		t.Errorf("Truncate() = %q, want the first 3 runes", got)
+	}
+	if got := Truncate("AAPL", 10); got != "AAPL" {
+		t.Errorf("Truncate() = %q, want the text as it is", got)
+	}
+}
+
+func TestQuoteURL(t *testing.T) {
+	tests := []struct {
+		base string
+		want string
+	}{
+		{"", "https://finance.yahoo.com/quote/AAPL"},
+		{"http://localhost:8080/quote/%s", "http://localhost:8080/quote/AAPL"},
+		{"http://localhost:8080/quote/%s?p=%s", "http://localhost:8080/quote/AAPL?p=AAPL"},
+		// the base without the placeholder is the path of the symbol
+		{"http://localhost:8080/quote", "http://localhost:8080/quote/AAPL"},
+		{"http://localhost:8080/quote/", "http://localhost:8080/quote/AAPL"},
+	}
+	for _, tt := range tests {
+		if got := QuoteURL(tt.base, "AAPL"); got != tt.want {
+			t.Errorf("QuoteURL(%q) = %s, want %s", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestYahooBaseURL(t *testing.T) {
+	srv := savedQuotePages(t)
+	t.Setenv("YAHOO_BASE_URL", srv.URL+"/quote")
+	t.Setenv("GETPRICE_DEBUG", "")
+
+	p, err := NewProvider("yahoo")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if q, err := p.Quote(context.Background(), "AAPL"); err != nil || q.Price != 130.48 {
+		t.Errorf("Quote() = %v, %v, want 130.48 of YAHOO_BASE_URL", q.Price, err)
+	}
+}
+
+func TestYahooProviderMarketState(t *testing.T) {
+	p := NewYahooProvider(savedQuotePages(t).URL+"/quote", 0)
+
+	tests := []struct {
+		symbol string
+		asOf   string
+		stale  bool
+	}{
+		{"AAPL", "At close: June 14 04:00PM EDT", true},
+		{"TSLA", "As of 10:15AM EDT. Market open.", false},
+		// the page without the market state is not stale
+		{"MSFT", "", false},
+	}
+	for _, tt := range tests {
+		q, err := p.Quote(context.Background(), tt.symbol)
+		if err != nil || q.AsOf != tt.asOf || q.Stale != tt.stale {
+			t.Errorf("%s: Quote() as of %q stale %v, %v, want %q %v", tt.symbol, q.AsOf, q.Stale, err, tt.asOf, tt.stale)
+		}
+	}
+}
+
+func TestIsMarketClosed(t *testing.T) {
+	tests := map[string]bool{
+		"At close: June 14 04:00PM EDT":   true,
+		"Market closed":                   true,
+		"As of 10:15AM EDT. Market open.": false,
+		"":                                false,
+	}
+	for state, want := range tests {
+		if got := IsMarketClosed(state); got != want {
+			t.Errorf("IsMarketClosed(%q) = %v, want %v", state, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package quotes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// yahooAPIURL is yahoo finance quote api endpoint.
+const yahooAPIURL = "https://query1.finance.yahoo.com/v7/finance/quote"
+
+// yahooAPIChunk is default of max symbols in one request (YAHOO_API_CHUNK).
+const yahooAPIChunk = 50
+
+// YahooAPIProvider is get stock prices from yahoo finance quote api.
+type YahooAPIProvider struct {
+	BaseURL string
+	Chunk   int
+	Client  *http.Client
+	// ExtendedHours is use the pre-market and after-hours prices
+	ExtendedHours bool
+}
+
+// NewYahooAPIProvider is yahoo quote api provider, empty baseURL is the default endpoint.
+func NewYahooAPIProvider(baseURL string) *YahooAPIProvider {
+	if baseURL == "" {
+		baseURL = yahooAPIURL
+	}
+	chunk, err := strconv.Atoi(os.Getenv("YAHOO_API_CHUNK"))
+	if err != nil || chunk <= 0 {
+		chunk = yahooAPIChunk
+	}
+	return &YahooAPIProvider{
+		BaseURL:       baseURL,
+		Chunk:         chunk,
+		Client:        HTTPClient,
+		ExtendedHours: ExtendedHours(),
+	}
+}
+
+// Name is provider name.
+func (p *YahooAPIProvider) Name() string {
+	return "yahooapi"
+}
+
+// Quote is get quote of the symbol.
+func (p *YahooAPIProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	quotes, err := p.Quotes(ctx, []string{symbol})
+	if err != nil {
+		return Quote{}, err
+	}
+	q, ok := quotes[symbol]
+	if !ok {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+	return q, nil
+}
+
+// Quotes is get quotes of the symbols, chunked at Chunk symbols per request.
+func (p *YahooAPIProvider) Quotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	quotes := map[string]Quote{}
+	for i := 0; i < len(symbols); i += p.Chunk {
+		end := i + p.Chunk
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+		if err := p.fetch(ctx, symbols[i:end], quotes); err != nil {
+			return quotes, err
+		}
+	}
+	return quotes, nil
+}
+
+// fetch is get one chunk of the symbols into quotes.
+func (p *YahooAPIProvider) fetch(ctx context.Context, symbols []string, quotes map[string]Quote) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.BaseURL+"?symbols="+url.QueryEscape(strings.Join(symbols, ",")), nil)
+	if err != nil {
+		return err
+	}
+	ScrapeRequest(req)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	ScrapeJar.SetCookies(resp.Request.URL, resp.Cookies())
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+
+	var body struct {
+		QuoteResponse struct {
+			Result []struct {
+				Symbol                     string  `json:"symbol"`
+				RegularMarketPrice         float64 `json:"regularMarketPrice"`
+				RegularMarketPreviousClose float64 `json:"regularMarketPreviousClose"`
+				RegularMarketTime          int64   `json:"regularMarketTime"`
+				MarketState                string  `json:"marketState"`
+				FiftyTwoWeekHigh           float64 `json:"fiftyTwoWeekHigh"`
+				FiftyTwoWeekLow            float64 `json:"fiftyTwoWeekLow"`
+				TrailingPE                 float64 `json:"trailingPE"`
+				MarketCap                  float64 `json:"marketCap"`
+				PreMarketPrice             float64 `json:"preMarketPrice"`
+				PreMarketTime              int64   `json:"preMarketTime"`
+				PostMarketPrice            float64 `json:"postMarketPrice"`
+				PostMarketTime             int64   `json:"postMarketTime"`
+			} `json:"result"`
+		} `json:"quoteResponse"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return err
+	}
+
+	for _, r := range body.QuoteResponse.Result {
+		if r.RegularMarketPrice <= 0 {
+			continue
+		}
+		q := Quote{
+			Price:         r.RegularMarketPrice,
+			Stale:         r.MarketState != "" && r.MarketState != "REGULAR",
+			Provider:      p.Name(),
+			PreviousClose: r.RegularMarketPreviousClose,
+		}
+		f := Fundamentals{High52: r.FiftyTwoWeekHigh, Low52: r.FiftyTwoWeekLow, PE: r.TrailingPE, MarketCap: r.MarketCap}
+		if !f.IsZero() {
+			q.Fundamentals = &f
+		}
+		q.AsOf = quoteTime(r.RegularMarketTime)
+
+		// PRE and PREPRE are before the open, POST and POSTPOST are after the close
+		if p.ExtendedHours {
+			state := strings.ToUpper(r.MarketState)
+			switch {
+			case strings.HasPrefix(state, "PRE") && r.PreMarketPrice > 0:
+				q.Price, q.Session, q.Stale = r.PreMarketPrice, SessionPre, false
+				q.AsOf = quoteTime(r.PreMarketTime)
+			case (strings.HasPrefix(state, "POST") || state == "CLOSED") && r.PostMarketPrice > 0:
+				q.Price, q.Session, q.Stale = r.PostMarketPrice, SessionPost, false
+				q.AsOf = quoteTime(r.PostMarketTime)
+			}
+		}
+		quotes[strings.ToUpper(r.Symbol)] = q
+	}
+	return nil
+}
+
+// quoteTime is unix time of the api in Location, empty when it is zero.
+func quoteTime(unix int64) string {
+	if unix <= 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).In(Location).Format(time.RFC3339)
+}
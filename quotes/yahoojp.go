@@ -0,0 +1,135 @@
+package quotes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// preloadedState is marker of the page state json on the yahoo japan quote page.
+const preloadedState = "window.__PRELOADED_STATE__ = "
+
+// YahooJapanProvider is get stock price from yahoo finance japan quote page.
+type YahooJapanProvider struct {
+	// BaseURL is quote page url, %s is symbol
+	BaseURL string
+	Client  *http.Client
+}
+
+// NewYahooJapanProvider is yahoo finance japan provider, base is YAHOO_JP_URL.
+func NewYahooJapanProvider(baseURL string) *YahooJapanProvider {
+	if baseURL == "" {
+		baseURL = "https://finance.yahoo.co.jp/quote/%s"
+	}
+	return &YahooJapanProvider{BaseURL: baseURL, Client: HTTPClient}
+}
+
+// Name is provider name.
+func (p *YahooJapanProvider) Name() string {
+	return "yahoojp"
+}
+
+// Quote is get the price board in the page state of the quote page.
+func (p *YahooJapanProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, QuoteURL(p.BaseURL, symbol), nil)
+	if err != nil {
+		return Quote{}, err
+	}
+	ScrapeRequest(req)
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return Quote{}, err
+	}
+	defer resp.Body.Close()
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Quote{}, err
+	}
+	if err := ScrapeResponse(resp, b); err != nil {
+		return Quote{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Quote{}, fmt.Errorf("fetch error. %d", resp.StatusCode)
+	}
+	return ParsePreloadedState(b)
+}
+
+// ParsePreloadedState is the quote of the page state json.
+func ParsePreloadedState(page []byte) (Quote, error) {
+	i := bytes.Index(page, []byte(preloadedState))
+	if i < 0 {
+		return Quote{}, fmt.Errorf("page state not found")
+	}
+
+	var state struct {
+		MainStocksPriceBoard struct {
+			PriceBoard struct {
+				Price         string `json:"price"`
+				PriceDateTime string `json:"priceDateTime"`
+			} `json:"priceBoard"`
+		} `json:"mainStocksPriceBoard"`
+	}
+	// decoder stops at the end of the json object, the rest of the script is ignored
+	if err := json.NewDecoder(bytes.NewReader(page[i+len(preloadedState):])).Decode(&state); err != nil {
+		return Quote{}, fmt.Errorf("parse page state error. %s", err)
+	}
+
+	board := state.MainStocksPriceBoard.PriceBoard
+	price, err := strconv.ParseFloat(strings.ReplaceAll(board.Price, ",", ""), 64)
+	if err != nil {
+		return Quote{}, fmt.Errorf("price not found")
+	}
+	return Quote{
+		Price:    price,
+		AsOf:     board.PriceDateTime,
+		Provider: "yahoojp",
+	}, nil
+}
+
+// SuffixProvider is route the symbols by RouteKey (exchange suffix or crypto), the others are got from default.
+type SuffixProvider struct {
+	Routes  map[string]Provider
+	Default Provider
+}
+
+// Name is default provider name.
+func (p *SuffixProvider) Name() string {
+	return p.Default.Name()
+}
+
+// provider is the provider of the symbol.
+func (p *SuffixProvider) provider(symbol string) Provider {
+	if route, ok := p.Routes[RouteKey(symbol)]; ok {
+		return route
+	}
+	return p.Default
+}
+
+// Quotes is batch quotes of the default provider, routed symbols are got one by one.
+func (p *SuffixProvider) Quotes(ctx context.Context, symbols []string) (map[string]Quote, error) {
+	bp, ok := p.Default.(BatchProvider)
+	if !ok {
+		return map[string]Quote{}, nil
+	}
+	var names []string
+	for _, s := range symbols {
+		if _, routed := p.Routes[RouteKey(s)]; !routed {
+			names = append(names, s)
+		}
+	}
+	if len(names) == 0 {
+		return map[string]Quote{}, nil
+	}
+	return bp.Quotes(ctx, names)
+}
+
+// Quote is quote of the provider of the symbol.
+func (p *SuffixProvider) Quote(ctx context.Context, symbol string) (Quote, error) {
+	return p.provider(symbol).Quote(ctx, symbol)
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// defaultQuotesMaxSymbols is max symbols of a quotes request.
+const defaultQuotesMaxSymbols = 50
+
+// QuoteItem is the current price of a symbol of the quotes request.
+type QuoteItem struct {
+	Symble       string               `json:"symble"`
+	Price        float64              `json:"price,omitempty"`
+	Currency     string               `json:"currency,omitempty"`
+	AsOf         string               `json:"as_of,omitempty"`
+	Stale        bool                 `json:"stale,omitempty"`
+	Session      string               `json:"session,omitempty"`
+	Provider     string               `json:"provider,omitempty"`
+	Fundamentals *quotes.Fundamentals `json:"fundamentals,omitempty"`
+	Error        string               `json:"error,omitempty"`
+}
+
+// QuotesResponse is api response of the quotes request.
+type QuotesResponse struct {
+	CreatedAt string                  `json:"created_at"`
+	Quotes    []QuoteItem             `json:"quotes"`
+	Failures  []portfolio.SymbolError `json:"failures,omitempty"`
+}
+
+// QuotesMaxSymbols is QUOTES_MAX_SYMBOLS, default 50.
+func QuotesMaxSymbols() int {
+	if n, err := strconv.Atoi(os.Getenv("QUOTES_MAX_SYMBOLS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultQuotesMaxSymbols
+}
+
+// IsQuotesRequest is GET /quotes (or ?action=quotes).
+func IsQuotesRequest(request events.APIGatewayProxyRequest) bool {
+	if request.QueryStringParameters["action"] == "quotes" {
+		return true
+	}
+	return request.HTTPMethod == http.MethodGet && path.Base(request.Path) == "quotes"
+}
+
+// QuoteSymbols is the symbols of the comma separated list, normalized and without the duplicates.
+func QuoteSymbols(list string) []string {
+	seen := map[string]bool{}
+	var symbols []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		s = quotes.NormalizeSymbol(s)
+		if !seen[s] {
+			seen[s] = true
+			symbols = append(symbols, s)
+		}
+	}
+	return symbols
+}
+
+// QuotesHandler is the current prices of ?symbols=AAPL,MSFT,7203.T by PRICE_PROVIDER, they don't need to be in the portfolio.
+// The status is 207 when some of the symbols failed and 502 when all of them failed, like the report.
+func QuotesHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	symbols := QuoteSymbols(request.QueryStringParameters["symbols"])
+	if len(symbols) == 0 {
+		return ErrorResponse(http.StatusBadRequest, "symbols is required."), nil
+	}
+	if max := QuotesMaxSymbols(); len(symbols) > max {
+		return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("%d symbols, max %d.", len(symbols), max)), nil
+	}
+
+	quotes.ResetRetryBudget()
+	provider, err := PriceProvider()
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+
+	tickers := make([]portfolio.Ticker, len(symbols))
+	for i, s := range symbols {
+		tickers[i] = portfolio.Ticker{Symble: s}
+	}
+	tickers = portfolio.FetchPrices(ctx, provider, tickers)
+
+	response := QuotesResponse{CreatedAt: time.Now().In(reportLocation).Format("2006-01-02"), Quotes: []QuoteItem{}}
+	for _, t := range tickers {
+		response.Quotes = append(response.Quotes, QuoteItem{
+			Symble:       t.Symble,
+			Price:        t.Value,
+			Currency:     t.Currency,
+			AsOf:         t.AsOf,
+			Stale:        t.Stale,
+			Session:      t.Session,
+			Provider:     t.Provider,
+			Fundamentals: t.Fundamentals,
+			Error:        t.Error,
+		})
+	}
+	response.Failures = portfolio.SymbolErrors(tickers)
+
+	b, err := json.Marshal(response)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	status := http.StatusOK
+	switch {
+	case len(response.Failures) == len(tickers):
+		status = http.StatusBadGateway
+	case len(response.Failures) > 0:
+		status = http.StatusMultiStatus
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: status,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
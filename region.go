@@ -0,0 +1,19 @@
+package main
+
+import (
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+)
+
+// Region is aws region of the service, the variable of the service (e.g. SES_REGION) or AWS_REGION (the region of the lambda).
+// Default is ap-northeast-1.
+func Region(name string) string {
+	if region := os.Getenv(name); name != "" && region != "" {
+		return region
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return endpoints.ApNortheast1RegionID
+}
@@ -0,0 +1,26 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// AlertContent is text of the alert notification.
+func AlertContent(alerts []portfolio.Ticker) string {
+	p := PricePrecision()
+	var content string
+	for _, t := range alerts {
+		if t.Alert == portfolio.AlertBuy {
+			content = content + fmt.Sprintf("%s %.*f is in the buy zone, target %.*f\n", t.Symble, p, t.Value, p, t.BuyTarget())
+			continue
+		}
+		threshold := t.AlertHigh
+		if t.Alert == "low" {
+			threshold = t.AlertLow
+		}
+		content = content + fmt.Sprintf("%s %.*f crossed alert_%s %.*f (bid %.*f, %+.2f%%)\n",
+			t.Symble, p, t.Value, t.Alert, p, threshold, p, t.Bid, t.Percent())
+	}
+	return content
+}
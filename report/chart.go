@@ -0,0 +1,55 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+)
+
+// ValuePoint is total value of the portfolio of a day.
+type ValuePoint struct {
+	Date  string  `json:"date"`
+	Value float64 `json:"value"`
+}
+
+// ValueChart is png line chart of the total values, in order of the date.
+// It needs two days at least.
+func ValueChart(points []ValuePoint) ([]byte, error) {
+	if len(points) < 2 {
+		return nil, fmt.Errorf("value chart needs 2 days, %d days", len(points))
+	}
+
+	series := chart.TimeSeries{
+		Name:  "Value",
+		Style: chart.Style{StrokeColor: chart.ColorBlue, StrokeWidth: 2},
+	}
+	for _, p := range points {
+		d, err := time.Parse("2006-01-02", p.Date)
+		if err != nil {
+			return nil, err
+		}
+		series.XValues = append(series.XValues, d)
+		series.YValues = append(series.YValues, p.Value)
+	}
+
+	graph := chart.Chart{
+		Width:  640,
+		Height: 240,
+		XAxis: chart.XAxis{
+			ValueFormatter: chart.TimeValueFormatterWithFormat("01-02"),
+		},
+		YAxis: chart.YAxis{
+			ValueFormatter: func(v interface{}) string {
+				return fmt.Sprintf("%.*f", PricePrecision(), v)
+			},
+		},
+		Series: []chart.Series{series},
+	}
+	buf := new(bytes.Buffer)
+	if err := graph.Render(chart.PNG, buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
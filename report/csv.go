@@ -0,0 +1,61 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// OutputFormats is parse OUTPUT_FORMATS (e.g. "json,csv"), default is json.
+func OutputFormats(env string) map[string]bool {
+	if env == "" {
+		return map[string]bool{"json": true}
+	}
+	formats := map[string]bool{}
+	for _, f := range strings.Split(env, ",") {
+		formats[strings.ToLower(strings.TrimSpace(f))] = true
+	}
+	return formats
+}
+
+// CSVFilePath is a sibling key of the json report.
+func CSVFilePath(filePath string) string {
+	return strings.TrimSuffix(filePath, path.Ext(filePath)) + ".csv"
+}
+
+// CSV is make csv report from the result.
+func CSV(result portfolio.Result) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+
+	w.Write([]string{"symbol", "bid", "value", "hold", "earnings", "percent", "market_value", "weight"})
+	for _, t := range result.Body {
+		earnings, percent, marketValue, weight := "", "", "", ""
+		if t.Priced() {
+			earnings = strconv.FormatFloat(t.Earning(), 'f', 2, 64)
+			percent = strconv.FormatFloat(t.Percent(), 'f', 2, 64)
+			marketValue = strconv.FormatFloat(t.Value*t.Hold, 'f', 2, 64)
+			weight = strconv.FormatFloat(t.Weight, 'f', 2, 64)
+		}
+		w.Write([]string{
+			t.Symble,
+			strconv.FormatFloat(t.Bid, 'f', -1, 64),
+			strconv.FormatFloat(t.Value, 'f', -1, 64),
+			portfolio.FormatHold(t.Hold),
+			earnings,
+			percent,
+			marketValue,
+			weight,
+		})
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,35 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestCSVFractionalHold(t *testing.T) {
+	b, err := CSV(portfolio.Result{Body: []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 110, Hold: 2.5, Dividend: 2},
+		{Symble: "MSFT", Bid: 200, Value: 210, Hold: 10},
+	}})
+	if err != nil {
+		t.Fatalf("CSV() error = %v", err)
+	}
+	records, _ := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if len(records) != 3 || records[1][3] != "2.5" || records[2][3] != "10" {
+		t.Errorf("CSV() = %q, want holds 2.5 and 10", records)
+	}
+}
+
+func TestOutputFormats(t *testing.T) {
+	if f := OutputFormats(""); !f["json"] || f["csv"] {
+		t.Errorf("OutputFormats(\"\") = %v, want json only", f)
+	}
+	if f := OutputFormats("JSON, csv"); !f["json"] || !f["csv"] {
+		t.Errorf("OutputFormats(\"JSON, csv\") = %v, want json and csv", f)
+	}
+	if got := CSVFilePath("stock/2021/06/14.json"); got != "stock/2021/06/14.csv" {
+		t.Errorf("CSVFilePath() = %q, want the csv sibling", got)
+	}
+}
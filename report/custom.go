@@ -0,0 +1,36 @@
+package report
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"text/template"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// CustomText is the text body of the report mail by the go template text (text/template) of the user.
+// The data is TemplateData ({{.Result}}, {{.Summary}}, {{.Gainers}}, ...) and the functions are of the html body (price, money, label, ...).
+func CustomText(text string, result portfolio.Result, opts HTMLOptions) (string, error) {
+	tmpl, err := template.New("text").Funcs(template.FuncMap(templateFuncs)).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, NewTemplateData(result, opts)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CustomHTML is the html body of the report mail by the go template text (html/template) of the user, the data and the functions are of CustomText.
+func CustomHTML(text string, result portfolio.Result, opts HTMLOptions) (string, error) {
+	tmpl, err := htmltemplate.New("html").Funcs(htmltemplate.FuncMap(templateFuncs)).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, NewTemplateData(result, opts)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
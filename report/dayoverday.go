@@ -0,0 +1,39 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// DayOverDayContent is change line of the report mail.
+func DayOverDayContent(dod *portfolio.DayOverDay) string {
+	if dod == nil {
+		return ""
+	}
+	return fmt.Sprintf("%40s%+10.*f (%+.2f%%)\n", "vs "+dod.Date+": ", PricePrecision(), dod.Change, dod.Percent)
+}
+
+// BenchmarkContent is the change of the benchmark next to the one of the portfolio.
+func BenchmarkContent(result portfolio.Result) string {
+	b := result.Benchmark
+	if b == nil {
+		return ""
+	}
+	if b.Error != "" {
+		return fmt.Sprintf("\nBenchmark %s: %s\n", b.Symble, b.Error)
+	}
+
+	content := fmt.Sprintf("\nBenchmark %s: %.*f\n", b.Symble, PricePrecision(), b.Value)
+	if b.DayChange != nil {
+		day := "-"
+		if result.DayOverDay != nil {
+			day = fmt.Sprintf("%+.2f%%", result.DayOverDay.Percent)
+		}
+		content = content + fmt.Sprintf("%40s%+9.2f%% (portfolio %s)\n", "Day: ", *b.DayChange, day)
+	}
+	if b.Change != nil {
+		content = content + fmt.Sprintf("%40s%+9.2f%% (portfolio %+.2f%%)\n", fmt.Sprintf("Since %.*f: ", PricePrecision(), b.Base), *b.Change, portfolio.Summarize(result.Body).Percent())
+	}
+	return content
+}
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// DigestSubject is subject of the weekly or monthly digest mail.
+func DigestSubject(mode string, d portfolio.Digest) string {
+	return fmt.Sprintf("Stock Profit %s digest %s - %s", mode, d.From, d.To)
+}
+
+// DigestContent is make text content of the digest, the change over the period and the best and worst performers.
+func DigestContent(mode string, d portfolio.Digest) string {
+	p := PricePrecision()
+	content := fmt.Sprintf("%s digest %s - %s\n", strings.ToUpper(mode[:1])+mode[1:], d.From, d.To)
+	content = content + fmt.Sprintln(strings.Repeat("-", 30))
+	content = content + fmt.Sprintf("%40s%10.*f\n", "Start Value: ", p, d.StartValue)
+	content = content + fmt.Sprintf("%40s%10.*f\n", "End Value: ", p, d.EndValue)
+	content = content + fmt.Sprintf("%40s%10.*f (%+.2f%%)\n", "Change: ", p, d.Change, d.Percent)
+	content = content + fmt.Sprintf("%40s%10.*f\n", "Profit Loss Change: ", p, d.ProfitLossChange)
+	if r := d.Returns; r != nil {
+		content = content + fmt.Sprintf("%40s%9.2f%%\n", "Time-Weighted Return: ", r.TWR)
+		content = content + fmt.Sprintf("%40s%9.2f%%\n", "Annualized Return: ", r.Annualized)
+		content = content + fmt.Sprintf("%40s%9.2f%%\n", "Money-Weighted Return (XIRR): ", r.XIRR)
+	}
+	if r := d.Risk; r != nil {
+		content = content + fmt.Sprintf("%40s%9.2f%%\n", "Volatility (annualized): ", r.Portfolio.Volatility)
+		content = content + fmt.Sprintf("%40s%9.2f%%\n", "Max Drawdown: ", r.Portfolio.MaxDrawdown)
+		content = content + fmt.Sprintf("%40s%10.2f\n", fmt.Sprintf("Sharpe Ratio (rf %g%%): ", r.RiskFree), r.Portfolio.Sharpe)
+		if len(r.Symbols) > 0 {
+			content = content + fmt.Sprintf("\nRisk by symbol:\n%-10s %10s %10s %8s\n", "", "volatility", "drawdown", "sharpe")
+			for _, s := range r.Symbols {
+				content = content + fmt.Sprintf("%-10s %9.2f%% %9.2f%% %8.2f\n", s.Symble, s.Volatility, s.MaxDrawdown, s.Sharpe)
+			}
+		}
+	}
+
+	line := func(c portfolio.SymbolChange) string {
+		return fmt.Sprintf("%-10s %+8.2f%% %10.*f -> %.*f\n", c.Symble, c.Percent, p, c.From, p, c.To)
+	}
+	if len(d.Best) > 0 {
+		content = content + "\nBest performers:\n"
+		for _, c := range d.Best {
+			content = content + line(c)
+		}
+	}
+	if len(d.Worst) > 0 {
+		content = content + "\nWorst performers:\n"
+		for _, c := range d.Worst {
+			content = content + line(c)
+		}
+	}
+	return content
+}
@@ -0,0 +1,78 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// discord limits of the message, fields of an embed and embeds.
+const (
+	discordMaxFields = 25
+	discordMaxEmbeds = 10
+)
+
+// discord colors of the embed, gain and loss.
+const (
+	discordGain = 0x2e7d32
+	discordLoss = 0xc62828
+)
+
+// DiscordMessage is discord webhook payload.
+type DiscordMessage struct {
+	Content string         `json:"content,omitempty"`
+	Embeds  []DiscordEmbed `json:"embeds,omitempty"`
+}
+
+// DiscordEmbed is a rich embed of the message.
+type DiscordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+}
+
+// DiscordField is a field of the embed.
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+// DiscordContent is make discord message of the report, the totals and a field of each position.
+// The color is by the total profit loss, the positions over 25 fields are in the next embeds.
+func DiscordContent(date string, result portfolio.Result) DiscordMessage {
+	p := PricePrecision()
+	summary := portfolio.Summarize(result.Body)
+	color := discordGain
+	if summary.ProfitLoss < 0 {
+		color = discordLoss
+	}
+
+	description := fmt.Sprintf("P/L **%.*f** (%+.2f%%)\nTotal Cost %.*f / Total Value %.*f", p, summary.ProfitLoss, summary.Percent(), p, summary.Cost, p, summary.Value)
+	if failed := summary.Count - summary.Priced; failed > 0 {
+		description = description + fmt.Sprintf("\n:warning: %d symbols price unavailable", failed)
+	}
+	msg := DiscordMessage{Embeds: []DiscordEmbed{{Title: "Stock Profit " + date, Description: description, Color: color}}}
+
+	for _, t := range result.Body {
+		value := "price unavailable"
+		if t.Priced() {
+			value = fmt.Sprintf("%.*f\n%+.2f%% (%.*f)", p, t.Value, t.Percent(), p, t.Earning())
+			if t.DayChange != nil {
+				value = value + fmt.Sprintf("\nday %+.2f%%", *t.DayChange)
+			}
+		}
+
+		last := &msg.Embeds[len(msg.Embeds)-1]
+		if len(last.Fields) == discordMaxFields {
+			if len(msg.Embeds) == discordMaxEmbeds {
+				break
+			}
+			msg.Embeds = append(msg.Embeds, DiscordEmbed{Color: color})
+			last = &msg.Embeds[len(msg.Embeds)-1]
+		}
+		last.Fields = append(last.Fields, DiscordField{Name: t.Symble, Value: value, Inline: true})
+	}
+	return msg
+}
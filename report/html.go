@@ -0,0 +1,200 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// templateFuncs is the functions of the html body and the custom templates.
+var templateFuncs = map[string]interface{}{
+	"price": func(v float64) string {
+		return fmt.Sprintf("%.*f", PricePrecision(), v)
+	},
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%+.2f%%", v)
+	},
+	"money": Money,
+	"label": Label,
+	"date":  FormatDate,
+	"deref": func(v *float64) float64 { return *v },
+	"hold":  portfolio.FormatHold,
+	"abs":   math.Abs,
+	"join":  strings.Join,
+	"color": ProfitColor,
+	"quote": func(symbol string) string {
+		return quotes.QuoteURL("", symbol)
+	},
+}
+
+// htmlTemplate is html body of the report mail.
+var htmlTemplate = template.Must(template.New("report").Funcs(templateFuncs).Parse(`<html>
+<body style="font-family: sans-serif;">
+<h3>{{label "Stock Profit"}} {{date .Result.CreatedAt}}{{with .Result.Currency}} ({{.}}){{end}}</h3>
+{{- with .Result.MarketClosed}}
+<p style="color: #996600;">{{label "Market closed"}} ({{join . ", "}}) - {{label "prices unchanged"}}</p>
+{{- end}}
+{{- if or .Gainers .Losers}}
+<p>{{label "Movers"}}{{with .Result.DayOverDay}} vs {{.Date}}{{end}}:
+{{- range .Gainers}} <span style="color: {{color 1.0}};">{{.Symble}} {{percent (deref .DayChange)}}</span>{{end}}
+{{- range .Losers}} <span style="color: {{color -1.0}};">{{.Symble}} {{percent (deref .DayChange)}}</span>{{end}}</p>
+{{- end}}
+<table cellpadding="4" style="border-collapse: collapse;">
+<tr style="background: #eeeeee;"><th align="left">{{label "Symbol"}}</th><th align="right">{{label "Bid"}}</th><th align="right">{{label "Value"}}</th><th align="right">{{label "Hold"}}</th><th align="right">{{label "Earnings"}}</th><th align="right">%</th><th align="right">{{label "Market Value"}}</th><th align="right">{{label "Weight"}}</th>{{if .Result.DayOverDay}}<th align="right">{{label "Day"}}</th>{{end}}</tr>
+{{- range .Result.Body}}
+{{- if .WatchOnly}}
+{{- else if .Priced}}
+<tr{{if .Alert}} style="background: #fff3cd;"{{end}}><td><a href="{{quote .Symble}}">{{.Symble}}</a>{{if .Degraded}} <small>({{label "stale price"}})</small>{{end}}{{if .Short}} <small>(short)</small>{{end}}{{if .Fixed}} <small>({{.Asset}})</small>{{end}}{{with .Alert}} <b>ALERT {{.}}</b>{{end}}</td><td align="right">{{price .Bid}}</td><td align="right">{{price .Value}}</td><td align="right">{{hold .Hold}}</td><td align="right" style="color: {{color .Earning}};">{{price .Earning}}</td><td align="right" style="color: {{color .Earning}};">{{percent .Percent}}</td><td align="right">{{price .MarketValue}}</td><td align="right">{{printf "%.1f%%" .Weight}}</td>{{if $.Result.DayOverDay}}<td align="right">{{with .DayChange}}<span style="color: {{color .}};">{{percent .}}</span>{{end}}</td>{{end}}</tr>
+{{- else}}
+<tr><td><a href="{{quote .Symble}}">{{.Symble}}</a></td><td align="right">{{price .Bid}}</td><td colspan="6">{{label "price unavailable"}}</td></tr>
+{{- end}}
+{{- end}}
+<tr style="border-top: 1px solid #999999;"><td colspan="4">{{label "Total Cost / Value"}}</td><td align="right" colspan="2">{{money .Summary.Cost}} / {{money .Summary.Value}}</td></tr>
+{{- if .Summary.Dividend}}
+<tr><td colspan="4">{{label "Dividend"}}</td><td align="right">{{money .Summary.Dividend}}</td><td></td></tr>
+{{- end}}
+<tr><td colspan="4"><b>{{label "Profit Loss"}}</b></td><td align="right" style="color: {{color .Summary.ProfitLoss}};"><b>{{money .Summary.ProfitLoss}}</b></td><td align="right" style="color: {{color .Summary.ProfitLoss}};">{{percent .Summary.Percent}}</td></tr>
+{{- if .Summary.Realized}}
+<tr><td colspan="4">{{label "Realized Profit Loss"}}</td><td align="right" style="color: {{color .Summary.Realized}};">{{money .Summary.Realized}}</td><td></td></tr>
+{{- end}}
+{{- with .Result.DayOverDay}}
+<tr><td colspan="4">vs {{.Date}}</td><td align="right" style="color: {{color .Change}};">{{price .Change}}</td><td align="right" style="color: {{color .Change}};">{{percent .Percent}}</td></tr>
+{{- end}}
+{{- if .Summary.Allocation}}
+<tr><td colspan="6">{{label "Allocation"}}:{{range $name := .Summary.CategoryNames}} {{$name}} {{printf "%.1f%%" (index $.Summary.Allocation $name)}}{{end}}</td></tr>
+{{- end}}
+{{- with .Result.Drawdown}}
+{{- if .Percent}}
+<tr><td colspan="4">{{label "Drawdown"}} ({{money .Peak}} {{date .PeakDate}})</td><td></td><td align="right" style="color: {{color .Percent}};">{{percent .Percent}}</td></tr>
+{{- else}}
+<tr><td colspan="4">{{label "All-time high"}}</td><td align="right">{{money .Peak}}</td><td></td></tr>
+{{- end}}
+{{- end}}
+{{- range .Result.Accounts}}
+<tr><td colspan="4">{{.Account}} ({{.Count}})</td><td align="right" style="color: {{color .ProfitLoss}};">{{money .ProfitLoss}}</td><td align="right" style="color: {{color .ProfitLoss}};">{{percent .Percent}}</td></tr>
+{{- end}}
+{{- with .Result.Benchmark}}
+<tr><td colspan="4">Benchmark {{.Symble}}</td><td align="right">{{if .Error}}{{.Error}}{{else}}{{price .Value}}{{end}}</td><td align="right">{{with .DayChange}}<span style="color: {{color .}};">{{percent .}}</span>{{end}}{{with .Change}} / since base <span style="color: {{color .}};">{{percent .}}</span>{{end}}</td></tr>
+{{- end}}
+</table>
+{{- with .Crosses}}
+<p>{{label "Signals"}}:{{range .}} <span style="color: {{if eq .Cross "golden"}}{{color 1.0}}{{else}}{{color -1.0}}{{end}};">{{.Symble}} {{.Cross}} cross</span>{{end}}</p>
+{{- end}}
+{{- with .BuyZone}}
+<p style="background: #d4edda; padding: 4px;"><b>{{label "Buy zone"}}</b>:{{range .}} <a href="{{quote .Symble}}">{{.Symble}}</a> {{price .Value}} &le; {{price .BuyTarget}}{{end}}</p>
+{{- end}}
+{{- with .Watchlist}}
+<p>{{label "Watchlist"}}:</p>
+<table cellpadding="4" style="border-collapse: collapse;">
+{{- range .}}
+<tr{{if .InBuyZone}} style="background: #d4edda;"{{end}}><td><a href="{{quote .Symble}}">{{.Symble}}</a></td>{{if .Priced}}<td align="right">{{price .Value}}</td><td align="right">{{with .DayChange}}<span style="color: {{color .}};">{{percent .}}</span>{{end}}</td>{{else}}<td colspan="2">{{label "price unavailable"}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Result.Rebalance}}
+<p>{{label "Rebalance"}}:</p>
+<table cellpadding="4" style="border-collapse: collapse;">
+{{- range .Result.Rebalance}}
+<tr><td>{{if lt .Amount 0.0}}sell{{else}}buy{{end}}</td><td>{{.Symble}}</td><td align="right">{{hold (abs .Shares)}}</td><td align="right">{{price (abs .Amount)}}</td><td align="right">{{printf "%.1f%%" .Weight}} &rarr; {{printf "%.1f%%" .Target}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with .Chart}}
+<p><img src="cid:{{.}}" alt="value of the last days" width="640" height="240"></p>
+{{- end}}
+{{- with .Statement}}
+<p><a href="{{.}}">Monthly statement (pdf)</a></p>
+{{- end}}
+{{- if .Result.Errors}}
+<p>{{label "Failed symbols"}}:</p>
+<ul>
+{{- range .Result.Errors}}
+<li>{{.Symble}}: {{.Error}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Result.ParseErrors}}
+<p>{{label "Stock data warnings"}}:</p>
+<ul>
+{{- range .Result.ParseErrors}}
+<li>line {{.Line}}: {{.Error}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Result.Discrepancies}}
+<p>{{label "Broker discrepancies"}}:</p>
+<ul>
+{{- range .Result.Discrepancies}}
+<li>{{.Symble}}: {{.Kind}} {{.Hold}} / {{.BrokerHold}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+// ProfitColor is green for gain and red for loss.
+func ProfitColor(v float64) string {
+	switch {
+	case v > 0:
+		return "#008000"
+	case v < 0:
+		return "#cc0000"
+	}
+	return "#000000"
+}
+
+// HTMLOptions is the extra content of the html body.
+type HTMLOptions struct {
+	// Chart is content id of the value chart image
+	Chart string
+	// Statement is link of the monthly statement
+	Statement string
+}
+
+// TemplateData is the data of the html body and the custom templates.
+type TemplateData struct {
+	Result          portfolio.Result
+	Summary         portfolio.Summary
+	Gainers, Losers []portfolio.Ticker
+	Watchlist       []portfolio.Ticker
+	BuyZone         []portfolio.Ticker
+	Crosses         []portfolio.Signal
+	HTMLOptions
+}
+
+// NewTemplateData is the data of the result.
+func NewTemplateData(result portfolio.Result, opts HTMLOptions) TemplateData {
+	data := TemplateData{
+		Result:      result,
+		Summary:     portfolio.Summarize(result.Body),
+		HTMLOptions: opts,
+	}
+	data.Gainers, data.Losers = portfolio.DailyMovers(result.Body, MoversCount())
+	data.BuyZone = portfolio.BuyZone(result.Body)
+	for _, s := range result.Signals {
+		if s.Cross != "" {
+			data.Crosses = append(data.Crosses, s)
+		}
+	}
+	for _, t := range result.Body {
+		if t.WatchOnly() {
+			data.Watchlist = append(data.Watchlist, t)
+		}
+	}
+	return data
+}
+
+// HTMLContent is make html body of the report mail.
+func HTMLContent(result portfolio.Result, opts HTMLOptions) (string, error) {
+	buf := new(bytes.Buffer)
+	if err := htmlTemplate.Execute(buf, NewTemplateData(result, opts)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
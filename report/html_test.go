@@ -0,0 +1,64 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestHTMLContent(t *testing.T) {
+	t.Setenv("PRICE_PRECISION", "")
+	html, err := HTMLContent(portfolio.NewResult("2021-06-14", []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5},
+		{Symble: "INTC", Bid: 60, Value: 45, Hold: 10},
+		{Symble: "<b>", Bid: 30, Hold: 1, Error: "price not found"},
+	}), HTMLOptions{})
+	if err != nil {
+		t.Fatalf("HTMLContent() error = %v", err)
+	}
+	for _, want := range []string{
+		"<h3>Stock Profit 2021-06-14</h3>",
+		`<a href="https://finance.yahoo.com/quote/AAPL">AAPL</a>`,
+		`style="color: #008000;">100.00</td>`,
+		`style="color: #cc0000;">-150.00</td>`,
+		"<li>&lt;b&gt;: price not found</li>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("%q is not in\n%s", want, html)
+		}
+	}
+}
+
+func TestHTMLContentChart(t *testing.T) {
+	result := portfolio.NewResult("2021-06-14", []portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5}})
+	html, err := HTMLContent(result, HTMLOptions{Chart: "value-chart", Statement: "https://example.com/2021-06.pdf"})
+	if err != nil || !strings.Contains(html, `<img src="cid:value-chart"`) || !strings.Contains(html, `<a href="https://example.com/2021-06.pdf">Monthly statement (pdf)</a>`) {
+		t.Errorf("HTMLContent() = %s, %v, want the chart image and the statement link", html, err)
+	}
+	if html, _ := HTMLContent(result, HTMLOptions{}); strings.Contains(html, "<img") || strings.Contains(html, "Monthly statement") {
+		t.Errorf("HTMLContent() without the options has the chart or the statement\n%s", html)
+	}
+}
+
+func TestValueChart(t *testing.T) {
+	png, err := ValueChart([]ValuePoint{{Date: "2021-06-13", Value: 1000}, {Date: "2021-06-14", Value: 1100}})
+	if err != nil || !bytes.HasPrefix(png, []byte("\x89PNG")) {
+		t.Errorf("ValueChart() = %d bytes, %v, want the png", len(png), err)
+	}
+	if _, err := ValueChart([]ValuePoint{{Date: "2021-06-14", Value: 1100}}); err == nil {
+		t.Error("ValueChart() of a day error = nil, want 2 days")
+	}
+	if _, err := ValueChart([]ValuePoint{{Date: "06/13", Value: 1000}, {Date: "06/14", Value: 1100}}); err == nil {
+		t.Error("ValueChart() of the invalid date error = nil")
+	}
+}
+
+func TestProfitColor(t *testing.T) {
+	for v, want := range map[float64]string{10: "#008000", -10: "#cc0000", 0: "#000000"} {
+		if got := ProfitColor(v); got != want {
+			t.Errorf("ProfitColor(%v) = %s, want %s", v, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// lineMovers is the movers of the line message when MOVERS_COUNT is not set.
+const lineMovers = 3
+
+// lineMaxMessage is max characters of the line notify message.
+const lineMaxMessage = 1000
+
+// LineContent is make compact line message of the report, the total profit loss and the top movers.
+// The movers are by the day change when the result has it, otherwise the top gainer and loser of the summary.
+func LineContent(date string, result portfolio.Result) string {
+	p := PricePrecision()
+	summary := portfolio.Summarize(result.Body)
+	content := fmt.Sprintf("\nStock Profit %s\nP/L %.*f (%+.2f%%)\nValue %.*f\n", date, p, summary.ProfitLoss, summary.Percent(), p, summary.Value)
+
+	n := MoversCount()
+	if n == 0 {
+		n = lineMovers
+	}
+	gainers, losers := portfolio.DailyMovers(result.Body, n)
+	for _, t := range gainers {
+		content = content + fmt.Sprintf("▲ %s %+.2f%%\n", t.Symble, *t.DayChange)
+	}
+	for _, t := range losers {
+		content = content + fmt.Sprintf("▼ %s %+.2f%%\n", t.Symble, *t.DayChange)
+	}
+	if len(gainers) == 0 && len(losers) == 0 && summary.Priced > summary.Watching {
+		content = content + fmt.Sprintf("▲ %s %+.2f%%\n▼ %s %+.2f%%\n", summary.Gainer.Symble, summary.Gainer.Percent(), summary.Loser.Symble, summary.Loser.Percent())
+	}
+	if failed := summary.Count - summary.Priced; failed > 0 {
+		content = content + fmt.Sprintf("%d symbols price unavailable\n", failed)
+	}
+	return LineTruncate(content)
+}
+
+// LineTruncate is cut the message to the line notify limit.
+func LineTruncate(message string) string {
+	r := []rune(message)
+	if len(r) <= lineMaxMessage {
+		return message
+	}
+	return string(r[:lineMaxMessage-3]) + "..."
+}
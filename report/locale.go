@@ -0,0 +1,127 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Locale is the labels, money and date format of the report mail.
+type Locale struct {
+	// Currency is the symbol of the totals, Decimals is the decimals of them
+	Currency   string
+	Decimals   int
+	DateLayout string
+	Labels     map[string]string
+}
+
+// locales is REPORT_LOCALE, en and ja.
+var locales = map[string]Locale{
+	"en": {Currency: "$", Decimals: 2, DateLayout: "Jan 2, 2006"},
+	"ja": {Currency: "¥", Decimals: 0, DateLayout: "2006年1月2日", Labels: map[string]string{
+		"Stock Profit":         "株式損益",
+		"Portfolio":            "ポートフォリオ",
+		"Symbol":               "銘柄",
+		"Bid":                  "取得単価",
+		"Value":                "現在値",
+		"Hold":                 "保有数",
+		"Earnings":             "損益",
+		"Market Value":         "評価額",
+		"Weight":               "比率",
+		"Day":                  "前日比",
+		"Dividend":             "配当",
+		"Total Cost":           "取得額合計",
+		"Total Value":          "評価額合計",
+		"Total Cost / Value":   "取得額 / 評価額合計",
+		"Unpriced Cost":        "未評価の取得額",
+		"Profit Loss":          "評価損益",
+		"Return":               "損益率",
+		"Allocation":           "配分",
+		"Accounts":             "口座別",
+		"Watchlist":            "ウォッチリスト",
+		"Buy zone":             "買い場",
+		"Signals":              "シグナル",
+		"Drawdown":             "高値からの下落率",
+		"All-time high":        "最高値",
+		"Realized Profit Loss": "実現損益",
+		"Top gainer":           "値上がり首位",
+		"Top loser":            "値下がり首位",
+		"Movers":               "値動き",
+		"Currency":             "通貨",
+		"Market closed":        "休場",
+		"prices unchanged":     "価格は前営業日のまま",
+		"price unavailable":    "価格取得不可",
+		"stale price":          "古い価格",
+		"Rebalance":            "リバランス",
+		"Lots":                 "ロット",
+		"Fundamentals":         "指標",
+		"Failed symbols":       "取得失敗",
+		"Stock data warnings":  "保有データの警告",
+		"Broker discrepancies": "証券会社との差異",
+	}},
+}
+
+// CurrentLocale is the locale of REPORT_LOCALE (en or ja), ok is false when it is not set.
+func CurrentLocale() (Locale, bool) {
+	l, ok := locales[strings.ToLower(os.Getenv("REPORT_LOCALE"))]
+	return l, ok
+}
+
+// IsLocale is check the name is a locale of REPORT_LOCALE.
+func IsLocale(name string) bool {
+	_, ok := locales[strings.ToLower(name)]
+	return ok
+}
+
+// Label is the label in REPORT_LOCALE, the label itself without the translation.
+func Label(label string) string {
+	l, _ := CurrentLocale()
+	if s, ok := l.Labels[label]; ok {
+		return s
+	}
+	return label
+}
+
+// Money is the total in the currency and decimals of REPORT_LOCALE with the separators (e.g. ¥32,400, -$1,234.50),
+// the number of PRICE_PRECISION without REPORT_LOCALE.
+func Money(v float64) string {
+	l, ok := CurrentLocale()
+	if !ok {
+		return fmt.Sprintf("%.*f", PricePrecision(), v)
+	}
+
+	s := strconv.FormatFloat(math.Abs(v), 'f', l.Decimals, 64)
+	integer, fraction := s, ""
+	if i := strings.Index(s, "."); i >= 0 {
+		integer, fraction = s[:i], s[i:]
+	}
+	var b strings.Builder
+	for i, c := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+
+	sign := ""
+	if v < 0 && s != strconv.FormatFloat(0, 'f', l.Decimals, 64) {
+		sign = "-"
+	}
+	return sign + l.Currency + b.String() + fraction
+}
+
+// FormatDate is the date (YYYY-MM-DD) in the layout of REPORT_LOCALE, the date itself without REPORT_LOCALE.
+func FormatDate(date string) string {
+	l, ok := CurrentLocale()
+	if !ok {
+		return date
+	}
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format(l.DateLayout)
+}
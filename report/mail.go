@@ -0,0 +1,430 @@
+// Package report is the mail, html, csv and slack content of the result.
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// PricePrecision is number of decimals in the report mail (PRICE_PRECISION), default 2.
+func PricePrecision() int {
+	p, err := strconv.Atoi(os.Getenv("PRICE_PRECISION"))
+	if err != nil || p < 0 {
+		return 2
+	}
+	return p
+}
+
+// MoversCount is top N of the daily movers section (MOVERS_COUNT), 0 is no section.
+func MoversCount() int {
+	n, err := strconv.Atoi(os.Getenv("MOVERS_COUNT"))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// DailyMoversContent is the top gainers and losers of the day change, empty without the previous result.
+func DailyMoversContent(result portfolio.Result) string {
+	gainers, losers := portfolio.DailyMovers(result.Body, MoversCount())
+	if len(gainers) == 0 && len(losers) == 0 {
+		return ""
+	}
+
+	p := PricePrecision()
+	content := Label("Movers")
+	if result.DayOverDay != nil {
+		content = content + " vs " + result.DayOverDay.Date
+	}
+	content = content + ":\n"
+	for _, t := range gainers {
+		content = content + fmt.Sprintf("  up   %-10s %+7.2f%% %10.*f\n", t.Symble, *t.DayChange, p, t.Value)
+	}
+	for _, t := range losers {
+		content = content + fmt.Sprintf("  down %-10s %+7.2f%% %10.*f\n", t.Symble, *t.DayChange, p, t.Value)
+	}
+	return content + "\n"
+}
+
+// MailContent is make report mail body text.
+func MailContent(result portfolio.Result) string {
+	p := PricePrecision()
+	summary := portfolio.Summarize(result.Body)
+
+	// provider is marked when it is not the one of the most rows (fallback)
+	count := map[string]int{}
+	var primary string
+	for _, r := range result.Body {
+		if r.Provider == "" {
+			continue
+		}
+		if count[r.Provider]++; primary == "" || count[r.Provider] > count[primary] {
+			primary = r.Provider
+		}
+	}
+
+	content := MoversContent(summary) + DailyMoversContent(result)
+	content = MarketClosedContent(result.MarketClosed) + content
+	if result.Currency != "" {
+		content = fmt.Sprintf("%s: %s\n\n", Label("Currency"), result.Currency) + content
+	}
+	groupBy := os.Getenv("GROUP_BY")
+	var group string
+	for i, r := range result.Body {
+		if r.WatchOnly() {
+			continue
+		}
+		if g := r.Group(groupBy); g != "" && (i == 0 || g != group) {
+			content = content + fmt.Sprintf("[%s]\n", g)
+			group = g
+		}
+		if !r.Priced() {
+			c := fmt.Sprintf("%s %10.*f %10s %6s %10s %8s %12s %6s  %s\n",
+				r.Symble, p, r.Bid, "-", portfolio.FormatHold(r.Hold), "-", "-", "-", "-", Label("price unavailable"))
+			content = content + c
+			continue
+		}
+		var stale string
+		switch {
+		case r.Degraded:
+			stale = "  (" + Label("stale price") + ")"
+		case r.Session == quotes.SessionPre:
+			stale = "  (pre-market)"
+		case r.Session == quotes.SessionPost:
+			stale = "  (after hours)"
+		case r.Stale:
+			stale = "  (prev close)"
+		}
+		if r.Short() {
+			stale = "  (short)" + stale
+		}
+		if r.Fixed() {
+			stale = "  (" + r.Asset + ")" + stale
+		}
+		if r.Alias != "" {
+			stale = stale + "  (as " + r.Alias + ")"
+		}
+		if r.Provider != "" && r.Provider != primary {
+			stale = stale + "  [" + r.Provider + "]"
+		}
+		if r.Alert != "" {
+			stale = stale + "  ALERT " + r.Alert
+		}
+		if result.Currency != "" && r.Currency != result.Currency {
+			stale = "  " + r.Currency + stale
+		}
+		if r.DayChange != nil {
+			stale = fmt.Sprintf("  %+.2f%% vs yesterday", *r.DayChange) + stale
+		}
+		c := fmt.Sprintf("%s %10.*f %10.*f %6s %10.*f %+7.2f%% %12.*f %5.1f%%%s\n",
+			r.Symble, p, r.Bid, p, r.Value, portfolio.FormatHold(r.Hold), p, r.Earning(), r.GainPercent, p, r.MarketValue, r.Weight, stale)
+		content = content + c
+	}
+	return content + SummaryContent(summary) + DrawdownContent(result.Drawdown) + AccountsContent(result.Accounts) + SignalsContent(result.Signals) + BuyZoneContent(result.Body) + WatchlistContent(result.Body) + DayOverDayContent(result.DayOverDay) + BenchmarkContent(result) + FundamentalsContent(result.Body) + RebalanceContent(result.Rebalance) + LotsContent(result.Body) + ErrorsContent(result.Errors) + ParseErrorsContent(result.ParseErrors) + DiscrepanciesContent(result.Discrepancies)
+}
+
+// FundamentalsContent is the 52 week range, p/e and market cap section (MAIL_FUNDAMENTALS=true).
+// Position is where the price is in the 52 week range, 0% is the low and 100% is the high.
+func FundamentalsContent(tickers []portfolio.Ticker) string {
+	if os.Getenv("MAIL_FUNDAMENTALS") != "true" {
+		return ""
+	}
+
+	p := PricePrecision()
+	var content string
+	for _, t := range tickers {
+		f := t.Fundamentals
+		if f == nil {
+			continue
+		}
+		line := fmt.Sprintf("%-10s", t.Symble)
+		if f.High52 > f.Low52 {
+			line = line + fmt.Sprintf(" 52w %.*f - %.*f (%3.0f%%)", p, f.Low52, p, f.High52, (t.Value-f.Low52)/(f.High52-f.Low52)*100)
+		}
+		if f.PE > 0 {
+			line = line + fmt.Sprintf("  P/E %.1f", f.PE)
+		}
+		if f.MarketCap > 0 {
+			line = line + "  cap " + quotes.FormatMarketCap(f.MarketCap)
+		}
+		content = content + line + "\n"
+	}
+	if content == "" {
+		return ""
+	}
+	return "\n" + Label("Fundamentals") + ":\n" + content
+}
+
+// MoversContent is top gainer and top loser lines by percent.
+// A single priced position is shown as gainer or loser by its sign.
+func MoversContent(summary portfolio.Summary) string {
+	p := PricePrecision()
+	line := func(label string, t portfolio.Ticker) string {
+		return fmt.Sprintf("%s: %s %+.2f%% %.*f\n", label, t.Symble, t.Percent(), p, t.Earning())
+	}
+
+	switch positions := summary.Priced - summary.Watching; {
+	case positions == 0:
+		return ""
+	case positions == 1 && summary.Gainer.Percent() < 0:
+		return line(Label("Top loser"), summary.Loser) + "\n"
+	case positions == 1:
+		return line(Label("Top gainer"), summary.Gainer) + "\n"
+	}
+	return line(Label("Top gainer"), summary.Gainer) + line(Label("Top loser"), summary.Loser) + "\n"
+}
+
+// SummaryContent is make total lines of the report mail, the totals are in the money format of REPORT_LOCALE.
+func SummaryContent(summary portfolio.Summary) string {
+	line := func(label string, value float64) string {
+		return fmt.Sprintf("%40s%10s\n", label+": ", Money(value))
+	}
+
+	content := fmt.Sprintln(strings.Repeat("-", 30))
+	if _, other := summary.Categories[portfolio.OtherCategory]; len(summary.Categories) > 1 || !other {
+		for _, name := range summary.CategoryNames() {
+			content = content + line(name, summary.Categories[name])
+		}
+	}
+	if summary.Dividend != 0 {
+		content = content + line(Label("Dividend"), summary.Dividend)
+	}
+	content = content + line(Label("Total Cost"), summary.Cost)
+	content = content + line(Label("Total Value"), summary.Value)
+	if summary.Priced < summary.Count {
+		content = content + line(fmt.Sprintf("%s (%d)", Label("Unpriced Cost"), summary.Count-summary.Priced), summary.UnpricedCost)
+	}
+	content = content + line(Label("Profit Loss"), summary.ProfitLoss)
+	content = content + fmt.Sprintf("%40s%9.2f%%\n", Label("Return")+": ", summary.Percent())
+	if allocation := summary.Allocation(); allocation != nil {
+		content = content + fmt.Sprintln(Label("Allocation"))
+		for _, name := range summary.CategoryNames() {
+			content = content + fmt.Sprintf("%40s%9.2f%%\n", name+": ", allocation[name])
+		}
+	}
+	if summary.Realized != 0 {
+		content = content + line(Label("Realized Profit Loss"), summary.Realized)
+	}
+	return content
+}
+
+// DrawdownContent is the drawdown line of the report mail, the total value from the all-time high.
+func DrawdownContent(d *portfolio.Drawdown) string {
+	if d == nil {
+		return ""
+	}
+	if d.Percent == 0 {
+		return fmt.Sprintf("%40s%10s\n", Label("All-time high")+": ", Money(d.Peak))
+	}
+	return fmt.Sprintf("%40s%9.2f%% (%s %s)\n", Label("Drawdown")+": ", d.Percent, Money(d.Peak), FormatDate(d.PeakDate))
+}
+
+// AccountsContent is the subtotals by account block of the report mail.
+func AccountsContent(accounts []portfolio.AccountSubtotal) string {
+	if len(accounts) == 0 {
+		return ""
+	}
+	content := "\n" + Label("Accounts") + ":\n"
+	for _, a := range accounts {
+		content = content + fmt.Sprintf("  %-12s %3d %12s %12s %12s %+7.2f%%\n", a.Account, a.Count, Money(a.Cost), Money(a.Value), Money(a.ProfitLoss), a.Percent)
+	}
+	return content
+}
+
+// WatchlistContent is the watch-only symbols block of the report mail, the price and the change from the previous result.
+func WatchlistContent(tickers []portfolio.Ticker) string {
+	p := PricePrecision()
+	var content string
+	for _, t := range tickers {
+		if !t.WatchOnly() {
+			continue
+		}
+		if !t.Priced() {
+			content = content + fmt.Sprintf("  %-10s %10s  %s\n", t.Symble, "-", Label("price unavailable"))
+			continue
+		}
+		line := fmt.Sprintf("  %-10s %10.*f", t.Symble, p, t.Value)
+		if target := t.BuyTarget(); target > 0 {
+			line = line + fmt.Sprintf("  target %.*f", p, target)
+		}
+		if t.DayChange != nil {
+			line = line + fmt.Sprintf("  %+.2f%% vs yesterday", *t.DayChange)
+		}
+		content = content + line + "\n"
+	}
+	if content == "" {
+		return ""
+	}
+	return "\n" + Label("Watchlist") + ":\n" + content
+}
+
+// SignalsContent is the golden and death crosses of the 50 and 200 day moving averages, the signals block of the report mail.
+func SignalsContent(signals []portfolio.Signal) string {
+	p := PricePrecision()
+	var content string
+	for _, s := range signals {
+		if s.Cross == "" {
+			continue
+		}
+		content = content + fmt.Sprintf("  %-10s %-6s cross  MA20 %.*f  MA50 %.*f  MA200 %.*f\n", s.Symble, s.Cross, p, s.MA20, p, s.MA50, p, s.MA200)
+	}
+	if content == "" {
+		return ""
+	}
+	return "\n" + Label("Signals") + ":\n" + content
+}
+
+// BuyZoneContent is the watch-only symbols at or under the target buy price, the highlighted block of the report mail.
+func BuyZoneContent(tickers []portfolio.Ticker) string {
+	zone := portfolio.BuyZone(tickers)
+	if len(zone) == 0 {
+		return ""
+	}
+	p := PricePrecision()
+	content := "\n*** " + Label("Buy zone") + " ***\n"
+	for _, t := range zone {
+		content = content + fmt.Sprintf("  %-10s %10.*f <= %.*f (%+.2f%%)\n", t.Symble, p, t.Value, p, t.BuyTarget(), (t.Value-t.BuyTarget())/t.BuyTarget()*100)
+	}
+	return content
+}
+
+// RebalanceContent is the rebalancing block of the report mail, buy or sell to the target weights.
+func RebalanceContent(trades []portfolio.Trade) string {
+	if len(trades) == 0 {
+		return ""
+	}
+	p := PricePrecision()
+	content := "\n" + Label("Rebalance") + ":\n"
+	for _, t := range trades {
+		side := "buy "
+		if t.Amount < 0 {
+			side = "sell"
+		}
+		content = content + fmt.Sprintf("  %s %-10s %10s %12.*f  %5.1f%% -> %5.1f%%\n",
+			side, t.Symble, portfolio.FormatHold(math.Abs(t.Shares)), p, math.Abs(t.Amount), t.Weight, t.Target)
+	}
+	return content
+}
+
+// LotsContent is per lot block of the report mail.
+func LotsContent(tickers []portfolio.Ticker) string {
+	p := PricePrecision()
+	var content string
+	for _, t := range tickers {
+		for i, l := range t.Lots {
+			earning := "-"
+			if t.Priced() {
+				earning = fmt.Sprintf("%.*f", p, l.Earning(t.Value))
+			}
+			content = content + fmt.Sprintf("%s #%d %10.*f %6s %10s\n", t.Symble, i+1, p, l.Bid, portfolio.FormatHold(l.Hold), earning)
+		}
+	}
+	if content == "" {
+		return ""
+	}
+	return "\n" + Label("Lots") + ":\n" + content
+}
+
+// ErrorsContent is failed symbols block of the report mail.
+func ErrorsContent(errs []portfolio.SymbolError) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	content := fmt.Sprintf("\n%s (%d):\n", Label("Failed symbols"), len(errs))
+	for _, e := range errs {
+		content = content + fmt.Sprintf("%s: %s\n", e.Symble, e.Error)
+	}
+	return content
+}
+
+// ParseErrorsContent is invalid lines block of the report mail.
+func ParseErrorsContent(errs []portfolio.ParseError) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	content := fmt.Sprintf("\n%s (%d):\n", Label("Stock data warnings"), len(errs))
+	for _, e := range errs {
+		content = content + fmt.Sprintf("line %d: %s\n", e.Line, e.Error)
+	}
+	return content
+}
+
+// MarketClosedContent is the closed markets line of the report mail, empty when every market is open.
+func MarketClosedContent(closed []string) string {
+	if len(closed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s (%s) - %s\n\n", Label("Market closed"), strings.Join(closed, ", "), Label("prices unchanged"))
+}
+
+// DiscrepanciesContent is the positions which differ from the broker block of the report mail.
+func DiscrepanciesContent(discrepancies []portfolio.Discrepancy) string {
+	if len(discrepancies) == 0 {
+		return ""
+	}
+	content := fmt.Sprintf("\n%s (%d):\n", Label("Broker discrepancies"), len(discrepancies))
+	for _, d := range discrepancies {
+		content = content + fmt.Sprintf("  %-10s %-8s %g / %g\n", d.Symble, d.Kind, d.Hold, d.BrokerHold)
+	}
+	return content
+}
+
+// DefaultMailSubject is the subject without MAIL_SUBJECT, e.g. "Portfolio +¥32,400 (+1.2%) — 2024-05-17".
+func DefaultMailSubject() string {
+	return Label("Portfolio") + " {{.Change}} ({{.Percent}}) — {{.Date}}"
+}
+
+// MailSubject is render MAIL_SUBJECT template with {{.Date}}, {{.Total}}, {{.Change}}, {{.Percent}}, {{.Value}} and {{.Count}},
+// the date and money are in the format of REPORT_LOCALE. Change is the signed profit loss and Percent is the return.
+// Empty subject is DefaultMailSubject, subject without placeholder or invalid template is used as it is.
+func MailSubject(subject, date string, summary portfolio.Summary) string {
+	if subject == "" {
+		subject = DefaultMailSubject()
+	}
+	if !strings.Contains(subject, "{{") {
+		return subject
+	}
+
+	tmpl, err := template.New("subject").Parse(subject)
+	if err != nil {
+		logging.Warn(context.Background(), "invalid MAIL_SUBJECT", logging.Fields{"error": err})
+		return subject
+	}
+
+	change := Money(summary.ProfitLoss)
+	if summary.ProfitLoss > 0 {
+		change = "+" + change
+	}
+	data := struct {
+		Date    string
+		Total   string
+		Change  string
+		Percent string
+		Value   string
+		Count   int
+	}{
+		Date:    FormatDate(date),
+		Total:   Money(summary.ProfitLoss),
+		Change:  change,
+		Percent: fmt.Sprintf("%+.1f%%", summary.Percent()),
+		Value:   Money(summary.Value),
+		Count:   summary.Count,
+	}
+
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, data); err != nil {
+		logging.Warn(context.Background(), "invalid MAIL_SUBJECT", logging.Fields{"error": err})
+		return subject
+	}
+	return buf.String()
+}
@@ -0,0 +1,234 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestMailSubject(t *testing.T) {
+	summary := portfolio.Summarize([]portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 10},
+		{Symble: "MSFT", Bid: 200, Value: 190, Hold: 5},
+	})
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Stock P/L {{.Date}}: {{.Total}}", "Stock P/L 2021-06-14: 150.00"},
+		{"{{.Count}} positions", "2 positions"},
+		// the literal subject and the invalid template are used as they are
+		{"Daily report", "Daily report"},
+		{"Stock P/L {{.Date", "Stock P/L {{.Date"},
+		{"{{.Unknown}}", "{{.Unknown}}"},
+	}
+	for _, tt := range tests {
+		if got := MailSubject(tt.subject, "2021-06-14", summary); got != tt.want {
+			t.Errorf("MailSubject(%q) = %q, want %q", tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestMailContentPricePrecision(t *testing.T) {
+	result := portfolio.Result{Body: []portfolio.Ticker{
+		{Symble: "PENY", Bid: 0.1234, Value: 0.2345, Hold: 1000},
+		{Symble: "7203.T", Bid: 9500, Value: 9813, Hold: 100},
+	}}
+	tests := []struct {
+		precision string
+		want      []string
+		not       []string
+	}{
+		{"0", []string{"PENY          0          0", "7203.T       9500       9813", "31411\n"}, []string{"9813.00"}},
+		{"4", []string{"PENY     0.1234     0.2345", "7203.T  9500.0000  9813.0000", "31411.1000\n"}, nil},
+		// the invalid precision is the default
+		{"", []string{"PENY       0.12       0.23", "31411.10\n"}, nil},
+		{"-1", []string{"PENY       0.12       0.23", "31411.10\n"}, nil},
+	}
+	for _, tt := range tests {
+		t.Setenv("PRICE_PRECISION", tt.precision)
+		content := MailContent(result)
+		for _, want := range tt.want {
+			if !strings.Contains(content, want) {
+				t.Errorf("PRICE_PRECISION %q: %q is not in\n%s", tt.precision, want, content)
+			}
+		}
+		for _, not := range tt.not {
+			if strings.Contains(content, not) {
+				t.Errorf("PRICE_PRECISION %q: %q is in\n%s", tt.precision, not, content)
+			}
+		}
+	}
+}
+
+func TestMoversContent(t *testing.T) {
+	t.Setenv("PRICE_PRECISION", "")
+	tests := []struct {
+		name    string
+		tickers []portfolio.Ticker
+		want    string
+	}{
+		{"gainer and loser", []portfolio.Ticker{
+			{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5},
+			{Symble: "MSFT", Bid: 50, Value: 55, Hold: 10},
+			{Symble: "INTC", Bid: 60, Value: 45, Hold: 10},
+		}, "Top gainer: AAPL +20.00% 100.00\nTop loser: INTC -25.00% -150.00\n\n"},
+		{"one gainer", []portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5}}, "Top gainer: AAPL +20.00% 100.00\n\n"},
+		{"one loser", []portfolio.Ticker{{Symble: "INTC", Bid: 60, Value: 45, Hold: 10}}, "Top loser: INTC -25.00% -150.00\n\n"},
+		// the unpriced ticker is not a mover
+		{"no price", []portfolio.Ticker{{Symble: "XXXX", Bid: 60, Hold: 10}}, ""},
+	}
+	for _, tt := range tests {
+		if got := MoversContent(portfolio.Summarize(tt.tickers)); got != tt.want {
+			t.Errorf("%s: MoversContent() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	content := MailContent(portfolio.Result{Body: []portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5}}})
+	if !strings.HasPrefix(content, "Top gainer: AAPL +20.00% 100.00\n\nAAPL ") {
+		t.Errorf("the movers are not before the positions in\n%s", content)
+	}
+}
+
+func TestSummaryContentCategories(t *testing.T) {
+	t.Setenv("PRICE_PRECISION", "")
+	content := SummaryContent(portfolio.Summarize([]portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5, Category: "Tech"},
+		{Symble: "VOO", Bid: 300, Value: 290, Hold: 2, Category: "ETF"},
+		{Symble: "T", Bid: 30, Value: 33, Hold: 10},
+	}))
+	for _, want := range []string{"ETF:     -20.00\n", "Tech:     100.00\n", "Other:      30.00\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q is not in\n%s", want, content)
+		}
+	}
+	if e, o := strings.Index(content, "ETF:"), strings.Index(content, "Other:"); e > o {
+		t.Errorf("Other is not after the categories\n%s", content)
+	}
+
+	// the subtotal of Other only is not written
+	content = SummaryContent(portfolio.Summarize([]portfolio.Ticker{{Symble: "T", Bid: 30, Value: 33, Hold: 10}}))
+	if strings.Contains(content, "Other:") {
+		t.Errorf("the subtotal of Other is in\n%s", content)
+	}
+}
+
+func TestSummaryContentTotals(t *testing.T) {
+	t.Setenv("PRICE_PRECISION", "")
+	content := SummaryContent(portfolio.Summarize([]portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5},
+		{Symble: "MSFT", Bid: 50, Value: 55, Hold: 10},
+		{Symble: "XXXX", Bid: 30, Hold: 10},
+	}))
+	for _, want := range []string{
+		"Total Cost:    1000.00\n",
+		"Total Value:    1150.00\n",
+		"Unpriced Cost (1):     300.00\n",
+		"Profit Loss:     150.00\n",
+		"Return:     15.00%\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q is not in\n%s", want, content)
+		}
+	}
+	if strings.Contains(SummaryContent(portfolio.Summarize([]portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5}})), "Unpriced Cost") {
+		t.Errorf("the unpriced cost of the priced positions is in the summary")
+	}
+	if got := (portfolio.Summary{}).Percent(); got != 0 {
+		t.Errorf("Percent() of no cost = %v, want 0", got)
+	}
+}
+
+func TestMailContentDividend(t *testing.T) {
+	content := MailContent(portfolio.Result{Body: []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 110, Hold: 10, Dividend: 2.5},
+		{Symble: "MSFT", Bid: 200, Value: 190, Hold: 5},
+	}})
+	// (110-100+2.5)*10 and (190-200)*5
+	for _, want := range []string{"    125.00 ", "    -50.00 ", "Dividend:      25.00\n", "Profit Loss:      75.00\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q is not in\n%s", want, content)
+		}
+	}
+
+	// the dividend line is only with the dividends
+	if content := MailContent(portfolio.Result{Body: []portfolio.Ticker{{Symble: "MSFT", Bid: 200, Value: 190, Hold: 5}}}); strings.Contains(content, "Dividend") {
+		t.Errorf("the dividend line is in\n%s", content)
+	}
+}
+
+func TestMailContentPriceUnavailable(t *testing.T) {
+	content := MailContent(portfolio.NewResult("2021-06-14", []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 150, Hold: 10, Error: "fetch error. 404 Not Found"},
+		{Symble: "MSFT", Bid: 200, Value: 210, Hold: 5},
+	}))
+	if !strings.Contains(content, "AAPL     150.00          -     10          -        -            -      -  price unavailable\n") {
+		t.Errorf("AAPL is not price unavailable in\n%s", content)
+	}
+	if !strings.HasSuffix(content, "\nFailed symbols (1):\nAAPL: fetch error. 404 Not Found\n") {
+		t.Errorf("AAPL is not in the failed symbols of\n%s", content)
+	}
+	// the unpriced position is not a loss
+	if !strings.Contains(content, "Profit Loss:      50.00\n") {
+		t.Errorf("the total is not 50.00 in\n%s", content)
+	}
+}
+
+func TestMailContentStale(t *testing.T) {
+	content := MailContent(portfolio.NewResult("2021-06-14", []portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 110, Hold: 1, Stale: true}}))
+	if !strings.Contains(content, "AAPL     100.00     110.00      1      10.00  +10.00%       110.00 100.0%  (prev close)\n") {
+		t.Errorf("AAPL is not the previous close in\n%s", content)
+	}
+}
+
+func TestMailContentFractionalHold(t *testing.T) {
+	content := MailContent(portfolio.NewResult("2021-06-14", []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 110, Hold: 2.5, Dividend: 2},
+		{Symble: "MSFT", Bid: 200, Value: 210, Hold: 10},
+	}))
+	// market value 275 and 2100 of 2375
+	for _, want := range []string{"AAPL     100.00     110.00    2.5      30.00  +10.00%       275.00  11.6%\n", "MSFT     200.00     210.00     10     100.00   +5.00%      2100.00  88.4%\n"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("%q is not in\n%s", want, content)
+		}
+	}
+}
+
+func TestParseErrorsContent(t *testing.T) {
+	if got := ParseErrorsContent(nil); got != "" {
+		t.Errorf("ParseErrorsContent(nil) = %q, want empty", got)
+	}
+	content := MailContent(portfolio.Result{
+		Body:        []portfolio.Ticker{{Symble: "AAPL", Bid: 100, Value: 110, Hold: 1}},
+		ParseErrors: []portfolio.ParseError{{Line: 4, Error: `invalid bid "abc"`}},
+	})
+	if !strings.HasSuffix(content, "\nStock data warnings (1):\nline 4: invalid bid \"abc\"\n") {
+		t.Errorf("stock data warnings are not in\n%s", content)
+	}
+}
+
+func TestAlertContent(t *testing.T) {
+	t.Setenv("PRICE_PRECISION", "")
+	content := AlertContent([]portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 130, Hold: 10, AlertHigh: 130, Alert: "high"},
+		{Symble: "MSFT", Bid: 200, Value: 150, Hold: 5, AlertHigh: 300, AlertLow: 160, Alert: "low"},
+	})
+	want := "AAPL 130.00 crossed alert_high 130.00 (bid 100.00, +30.00%)\nMSFT 150.00 crossed alert_low 160.00 (bid 200.00, -25.00%)\n"
+	if content != want {
+		t.Errorf("AlertContent() = %q, want %q", content, want)
+	}
+}
+
+func TestAccountsContent(t *testing.T) {
+	if got := AccountsContent(nil); got != "" {
+		t.Errorf("AccountsContent(nil) = %q, want empty", got)
+	}
+	content := MailContent(portfolio.NewResult("2021-06-14", []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 110, Hold: 1, Account: "NISA"},
+		{Symble: "MSFT", Bid: 200, Value: 180, Hold: 1},
+	}))
+	if !strings.Contains(content, "\nAccounts:\n  NISA") || !strings.Contains(content, "\n  Other ") {
+		t.Errorf("accounts are not in\n%s", content)
+	}
+}
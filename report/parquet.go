@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetRow is a row of the parquet report, a ticker of the day.
+type parquetRow struct {
+	Date        string  `parquet:"name=date, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Symbol      string  `parquet:"name=symbol, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Bid         float64 `parquet:"name=bid, type=DOUBLE"`
+	Value       float64 `parquet:"name=value, type=DOUBLE"`
+	Hold        float64 `parquet:"name=hold, type=DOUBLE"`
+	Earnings    float64 `parquet:"name=earnings, type=DOUBLE"`
+	Percent     float64 `parquet:"name=percent, type=DOUBLE"`
+	MarketValue float64 `parquet:"name=market_value, type=DOUBLE"`
+	Weight      float64 `parquet:"name=weight, type=DOUBLE"`
+	Dividend    float64 `parquet:"name=dividend, type=DOUBLE"`
+	Category    string  `parquet:"name=category, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Account     string  `parquet:"name=account, type=BYTE_ARRAY, convertedtype=UTF8"`
+	Currency    string  `parquet:"name=currency, type=BYTE_ARRAY, convertedtype=UTF8"`
+	FXRate      float64 `parquet:"name=fx_rate, type=DOUBLE"`
+	Priced      bool    `parquet:"name=priced, type=BOOLEAN"`
+	Error       string  `parquet:"name=error, type=BYTE_ARRAY, convertedtype=UTF8"`
+}
+
+// ParquetFilePath is key of the parquet report under the prefix, partitioned by year=/month=/day= of the date (YYYY-MM-DD).
+// The file name is the one of the json report, so the batches of a day are kept apart.
+func ParquetFilePath(prefix, date, filePath string) string {
+	parts := strings.SplitN(date, "-", 3)
+	if len(parts) != 3 {
+		parts = []string{date, "", ""}
+	}
+	name := strings.TrimSuffix(path.Base(filePath), path.Ext(filePath)) + ".parquet"
+	return path.Join(prefix, "year="+parts[0], "month="+parts[1], "day="+parts[2], name)
+}
+
+// Parquet is make parquet report from the result, for athena.
+func Parquet(result portfolio.Result) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	pw, err := writer.NewParquetWriterFromWriter(buf, new(parquetRow), 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range result.Body {
+		row := parquetRow{
+			Date:     result.CreatedAt,
+			Symbol:   t.Symble,
+			Bid:      t.Bid,
+			Value:    t.Value,
+			Hold:     t.Hold,
+			Dividend: t.Dividend,
+			Category: t.Category,
+			Account:  t.Account,
+			Currency: t.Currency,
+			FXRate:   t.FX(),
+			Priced:   t.Priced(),
+			Error:    t.Error,
+		}
+		if t.Priced() {
+			row.Earnings = t.Earning()
+			row.Percent = t.Percent()
+			row.MarketValue = t.MarketValue
+			row.Weight = t.Weight
+		}
+		if err := pw.Write(row); err != nil {
+			return nil, fmt.Errorf("parquet write error. %s", err)
+		}
+	}
+	if err := pw.WriteStop(); err != nil {
+		return nil, fmt.Errorf("parquet write error. %s", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,66 @@
+package report
+
+import (
+	"fmt"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// SlackMessage is slack incoming webhook payload, text is the fallback of blocks.
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a block kit block.
+type SlackBlock struct {
+	Type   string      `json:"type"`
+	Text   *SlackText  `json:"text,omitempty"`
+	Fields []SlackText `json:"fields,omitempty"`
+}
+
+// SlackText is a block kit text object.
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// slackField is a mrkdwn field of the section.
+func slackField(label, value string) SlackText {
+	return SlackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", label, value)}
+}
+
+// SlackContent is make slack message of the report summary.
+func SlackContent(date string, summary portfolio.Summary) SlackMessage {
+	p := PricePrecision()
+	text := fmt.Sprintf("Stock Profit %s: %.*f (%+.2f%%)", date, p, summary.ProfitLoss, summary.Percent())
+
+	msg := SlackMessage{
+		Text: text,
+		Blocks: []SlackBlock{
+			{Type: "header", Text: &SlackText{Type: "plain_text", Text: "Stock Profit " + date}},
+			{Type: "section", Fields: []SlackText{
+				slackField("Profit Loss", fmt.Sprintf("%.*f", p, summary.ProfitLoss)),
+				slackField("Return", fmt.Sprintf("%+.2f%%", summary.Percent())),
+				slackField("Total Cost", fmt.Sprintf("%.*f", p, summary.Cost)),
+				slackField("Total Value", fmt.Sprintf("%.*f", p, summary.Value)),
+			}},
+		},
+	}
+
+	if summary.Priced > summary.Watching {
+		msg.Blocks = append(msg.Blocks, SlackBlock{Type: "section", Fields: []SlackText{
+			slackField("Top gainer", fmt.Sprintf("%s %+.2f%% (%.*f)",
+				summary.Gainer.Symble, summary.Gainer.Percent(), p, summary.Gainer.Earning())),
+			slackField("Top loser", fmt.Sprintf("%s %+.2f%% (%.*f)",
+				summary.Loser.Symble, summary.Loser.Percent(), p, summary.Loser.Earning())),
+		}})
+	}
+	if failed := summary.Count - summary.Priced; failed > 0 {
+		msg.Blocks = append(msg.Blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: fmt.Sprintf(":warning: %d symbols price unavailable", failed)},
+		})
+	}
+	return msg
+}
@@ -0,0 +1,27 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestSlackContent(t *testing.T) {
+	t.Setenv("PRICE_PRECISION", "")
+	msg := SlackContent("2021-06-14", portfolio.Summarize([]portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 10},
+		{Symble: "MSFT", Bid: 200, Value: 190, Hold: 5},
+		{Symble: "XXXX", Bid: 50, Hold: 1},
+	}))
+	if !strings.Contains(msg.Text, "2021-06-14") || !strings.Contains(msg.Text, "150.00") {
+		t.Errorf("text = %q, want the date and the total 150.00", msg.Text)
+	}
+	b, _ := json.Marshal(msg.Blocks)
+	for _, want := range []string{"Top gainer", "AAPL", "Top loser", "MSFT", "1 symbols price unavailable"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("blocks %s don't have %q", b, want)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// statementColumns is header and width (mm) of the positions table of the statement.
+var statementColumns = []struct {
+	Header string
+	Width  float64
+}{
+	{"Symbol", 30}, {"Hold", 20}, {"Bid", 25}, {"Value", 25}, {"Market Value", 30}, {"Earnings", 30}, {"%", 20},
+}
+
+// Statement is pdf monthly statement of the result, the positions, profit loss and dividends.
+// chart is png of the values of the month, nil is no chart.
+func Statement(result portfolio.Result, month string, chart []byte) ([]byte, error) {
+	summary := portfolio.Summarize(result.Body)
+	price := func(v float64) string { return fmt.Sprintf("%.*f", PricePrecision(), v) }
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	title := "Stock Profit Statement " + month
+	if result.Currency != "" {
+		title += " (" + result.Currency + ")"
+	}
+	pdf.CellFormat(0, 10, title, "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.CellFormat(0, 6, "as of "+result.CreatedAt, "", 1, "L", false, 0, "")
+	pdf.Ln(4)
+
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(238, 238, 238)
+	for i, c := range statementColumns {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(c.Width, 7, c.Header, "B", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, t := range result.Body {
+		if !t.Priced() {
+			pdf.CellFormat(statementColumns[0].Width, 6, tr(t.Symble), "", 0, "L", false, 0, "")
+			pdf.CellFormat(statementColumns[1].Width, 6, portfolio.FormatHold(t.Hold), "", 0, "R", false, 0, "")
+			pdf.CellFormat(statementColumns[2].Width, 6, price(t.Bid), "", 0, "R", false, 0, "")
+			pdf.CellFormat(0, 6, "price unavailable", "", 1, "L", false, 0, "")
+			continue
+		}
+		cells := []string{tr(t.Symble), portfolio.FormatHold(t.Hold), price(t.Bid), price(t.Value), price(t.MarketValue), price(t.Earning()), fmt.Sprintf("%+.2f%%", t.Percent())}
+		for i, c := range statementColumns {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(c.Width, 6, cells[i], "", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	pdf.Ln(4)
+
+	// unrealized is the price change of the held shares, the dividends are apart of it
+	rows := [][2]string{
+		{"Total Cost", price(summary.Cost)},
+		{"Total Value", price(summary.Value)},
+		{"Unrealized Profit Loss", price(summary.Value - summary.Cost)},
+		{"Dividends", price(summary.Dividend)},
+		{"Profit Loss", fmt.Sprintf("%s (%+.2f%%)", price(summary.ProfitLoss), summary.Percent())},
+		{"Realized Profit Loss", price(summary.Realized)},
+	}
+	for _, r := range rows {
+		pdf.CellFormat(60, 6, r[0], "", 0, "L", false, 0, "")
+		pdf.CellFormat(40, 6, r[1], "", 1, "R", false, 0, "")
+	}
+
+	if chart != nil {
+		pdf.Ln(6)
+		opt := gofpdf.ImageOptions{ImageType: "PNG", ReadDpi: true}
+		pdf.RegisterImageOptionsReader("chart", opt, bytes.NewReader(chart))
+		pdf.ImageOptions("chart", pdf.GetX(), pdf.GetY(), 180, 0, true, opt, 0, "")
+	}
+
+	buf := new(bytes.Buffer)
+	if err := pdf.Output(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
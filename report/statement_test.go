@@ -0,0 +1,25 @@
+package report
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestStatement(t *testing.T) {
+	result := portfolio.NewResult("2021-06-30", []portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 5, Dividend: 1},
+		{Symble: "XXXX", Bid: 30, Hold: 1, Error: "price not found"},
+	})
+	chart, err := ValueChart([]ValuePoint{{Date: "2021-06-01", Value: 500}, {Date: "2021-06-30", Value: 600}})
+	if err != nil {
+		t.Fatalf("ValueChart() error = %v", err)
+	}
+	for _, c := range [][]byte{nil, chart} {
+		pdf, err := Statement(result, "2021-06", c)
+		if err != nil || !bytes.HasPrefix(pdf, []byte("%PDF-")) {
+			t.Errorf("Statement() = %d bytes, %v, want the pdf", len(pdf), err)
+		}
+	}
+}
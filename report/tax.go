@@ -0,0 +1,48 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// TaxCSV is make csv of the tax report, a row by symbol and the total row at the end.
+func TaxCSV(r portfolio.TaxReport) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := csv.NewWriter(buf)
+
+	amount := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	w.Write([]string{"symbol", "sales", "proceeds", "cost", "realized_gain", "dividends"})
+	for _, t := range append(r.Symbols, r.Total) {
+		w.Write([]string{t.Symble, strconv.Itoa(t.Sales), amount(t.Proceeds), amount(t.Cost), amount(t.Gain), amount(t.Dividends)})
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// TaxSubject is subject of the tax report mail.
+func TaxSubject(r portfolio.TaxReport) string {
+	return fmt.Sprintf("Tax report %d (%s - %s)", r.FiscalYear, r.From, r.To)
+}
+
+// TaxContent is text of the tax report mail, amounts are in the currency of each symbol.
+func TaxContent(r portfolio.TaxReport) string {
+	p := PricePrecision()
+	content := fmt.Sprintf("Tax report of the fiscal year %d, %s - %s (cost basis: %s)\n", r.FiscalYear, r.From, r.To, r.CostBasis)
+	content = content + fmt.Sprintln(strings.Repeat("-", 30))
+	content = content + fmt.Sprintf("%-10s %6s %12s %12s %12s %12s\n", "Symbol", "Sales", "Proceeds", "Cost", "Gain", "Dividends")
+	for _, t := range append(r.Symbols, r.Total) {
+		content = content + fmt.Sprintf("%-10s %6d %12.*f %12.*f %12.*f %12.*f\n", t.Symble, t.Sales, p, t.Proceeds, p, t.Cost, p, t.Gain, p, t.Dividends)
+	}
+	return content
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// telegramMaxMessage is max characters of the telegram message.
+const telegramMaxMessage = 4096
+
+// TelegramContent is make telegram message (html parse mode) of the report, the totals and the top movers.
+// positions is add the line of each symbol, e.g. the reply of /portfolio.
+func TelegramContent(date string, result portfolio.Result, positions bool) string {
+	p := PricePrecision()
+	summary := portfolio.Summarize(result.Body)
+	content := fmt.Sprintf("<b>Stock Profit %s</b>\nP/L %.*f (%+.2f%%)\nValue %.*f\n", html.EscapeString(date), p, summary.ProfitLoss, summary.Percent(), p, summary.Value)
+
+	n := MoversCount()
+	if n == 0 {
+		n = lineMovers
+	}
+	gainers, losers := portfolio.DailyMovers(result.Body, n)
+	for _, t := range gainers {
+		content = content + fmt.Sprintf("▲ %s %+.2f%%\n", html.EscapeString(t.Symble), *t.DayChange)
+	}
+	for _, t := range losers {
+		content = content + fmt.Sprintf("▼ %s %+.2f%%\n", html.EscapeString(t.Symble), *t.DayChange)
+	}
+
+	if positions {
+		content = content + "<pre>"
+		for _, t := range result.Body {
+			if !t.Priced() {
+				content = content + fmt.Sprintf("%-10s price unavailable\n", html.EscapeString(t.Symble))
+				continue
+			}
+			content = content + fmt.Sprintf("%-10s %12.*f %+7.2f%%\n", html.EscapeString(t.Symble), p, t.Value, t.Percent())
+		}
+		content = content + "</pre>"
+	} else if failed := summary.Count - summary.Priced; failed > 0 {
+		content = content + fmt.Sprintf("%d symbols price unavailable\n", failed)
+	}
+	return TelegramTruncate(content)
+}
+
+// TelegramTruncate is cut the message to the telegram limit, a cut <pre> block is closed.
+func TelegramTruncate(message string) string {
+	r := []rune(message)
+	if len(r) <= telegramMaxMessage {
+		return message
+	}
+	cut := string(r[:telegramMaxMessage-10]) + "..."
+	if strings.Count(cut, "<pre>") > strings.Count(cut, "</pre>") {
+		cut = cut + "</pre>"
+	}
+	return cut
+}
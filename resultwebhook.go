@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// PostResult is post the payload json to RESULT_WEBHOOK_URL after every run, quiet and digest days too.
+// The request has the stock-timestamp (unix seconds) and stock-signature headers,
+// the signature is Sign of RESULT_WEBHOOK_SECRET and SigningPayload of the timestamp, POST, the url path, the url query and the body.
+// Nothing is posted when RESULT_WEBHOOK_URL is not set.
+func PostResult(ctx context.Context, payload interface{}, t time.Time) error {
+	endpoint := os.Getenv("RESULT_WEBHOOK_URL")
+	if endpoint == "" {
+		return nil
+	}
+	secret := os.Getenv("RESULT_WEBHOOK_SECRET")
+	if secret == "" {
+		return fmt.Errorf("RESULT_WEBHOOK_SECRET is not set")
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return postJSON(ctx, endpoint, b, map[string]string{
+		"stock-timestamp": timestamp,
+		"stock-signature": Sign(secret, SigningPayload(timestamp, "POST", u.EscapedPath(), CanonicalQuery(u.Query()), b)),
+	})
+}
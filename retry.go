@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// FailedSymbol is a message of RETRY_QUEUE_URL, the symbol which failed in the report of the key.
+type FailedSymbol struct {
+	Date   string `json:"date"`
+	Symble string `json:"symble"`
+	Key    string `json:"key"`
+	Tenant string `json:"tenant,omitempty"`
+	Error  string `json:"error"`
+}
+
+// QueueFailedSymbols is send the failed symbols of the result to RETRY_QUEUE_URL, the retry handler gets them later.
+// Nothing is sent when RETRY_QUEUE_URL is not set.
+func QueueFailedSymbols(ctx context.Context, result portfolio.Result, key string) error {
+	queue := os.Getenv("RETRY_QUEUE_URL")
+	if queue == "" || len(result.Errors) == 0 {
+		return nil
+	}
+
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return err
+	}
+	svc := sqs.New(sess)
+
+	// max 10 messages of a batch
+	for i := 0; i < len(result.Errors); i += 10 {
+		end := i + 10
+		if end > len(result.Errors) {
+			end = len(result.Errors)
+		}
+		var entries []*sqs.SendMessageBatchRequestEntry
+		for j, e := range result.Errors[i:end] {
+			b, err := json.Marshal(FailedSymbol{Date: result.CreatedAt, Symble: e.Symble, Key: key, Tenant: ConfigOf(ctx).Tenant, Error: e.Error})
+			if err != nil {
+				return err
+			}
+			entries = append(entries, &sqs.SendMessageBatchRequestEntry{
+				Id:          aws.String(fmt.Sprint(i + j)),
+				MessageBody: aws.String(string(b)),
+			})
+		}
+		out, err := svc.SendMessageBatchWithContext(ctx, &sqs.SendMessageBatchInput{
+			QueueUrl: aws.String(queue),
+			Entries:  entries,
+		})
+		if err != nil {
+			return err
+		}
+		if len(out.Failed) > 0 {
+			return fmt.Errorf("%d failed symbols are not queued. %s", len(out.Failed), aws.StringValue(out.Failed[0].Message))
+		}
+	}
+	return nil
+}
+
+// SQSBatchResponse is the messages to retry of the sqs event (ReportBatchItemFailures of the event source mapping).
+type SQSBatchResponse struct {
+	BatchItemFailures []SQSBatchItemFailure `json:"batchItemFailures"`
+}
+
+// SQSBatchItemFailure is a message to retry.
+type SQSBatchItemFailure struct {
+	ItemIdentifier string `json:"itemIdentifier"`
+}
+
+// RetryHandler is get the price of the failed symbols of RETRY_QUEUE_URL again and patch the report and the history.
+// A symbol which fails again is returned to the queue, the dead letter queue of it gets the symbol after maxReceiveCount.
+func RetryHandler(ctx context.Context, event events.SQSEvent) (SQSBatchResponse, error) {
+	tenants, err := LoadTenants(ctx)
+	if err != nil {
+		return SQSBatchResponse{}, err
+	}
+
+	response := SQSBatchResponse{BatchItemFailures: []SQSBatchItemFailure{}}
+	for _, m := range event.Records {
+		var failed FailedSymbol
+		if err := json.Unmarshal([]byte(m.Body), &failed); err != nil {
+			// the message never succeeds, it is dropped
+			logging.Error(ctx, "invalid retry message", logging.Fields{"message_id": m.MessageId, "error": err})
+			continue
+		}
+
+		rctx := ctx
+		if failed.Tenant != "" {
+			t, ok := TenantByName(tenants, failed.Tenant)
+			if !ok {
+				logging.Error(ctx, "tenant of the retry is not in TENANTS_FILE", logging.Fields{"tenant": failed.Tenant, "symbol": failed.Symble})
+				continue
+			}
+			rctx = WithConfig(ctx, TenantConfig(ConfigOf(ctx), t))
+		}
+
+		if err := RetrySymbol(rctx, failed); err != nil {
+			logging.Warn(rctx, "retry error", logging.Fields{"symbol": failed.Symble, "date": failed.Date, "error": err})
+			response.BatchItemFailures = append(response.BatchItemFailures, SQSBatchItemFailure{ItemIdentifier: m.MessageId})
+		}
+	}
+	return response, nil
+}
+
+// RetrySymbol is price the failed symbol of the stored report and put the patched report and history.
+// The report which is overwritten by a later day is not patched, only the history.
+func RetrySymbol(ctx context.Context, failed FailedSymbol) error {
+	data, err := DownloadFile(ctx, ConfigOf(ctx).Bucket, failed.Key)
+	if err != nil && !errors.Is(err, storage.ErrNoSuchKey) {
+		return err
+	}
+	var result portfolio.Result
+	if err == nil {
+		if result, err = portfolio.ParseResult(data); err != nil {
+			return fmt.Errorf("%s: %s", failed.Key, err)
+		}
+	}
+	if result.CreatedAt != failed.Date {
+		logging.Info(ctx, "report of the day is replaced, skip retry", logging.Fields{"symbol": failed.Symble, "date": failed.Date, "key": failed.Key})
+		return nil
+	}
+
+	i := -1
+	for j, t := range result.Body {
+		if t.Symble == failed.Symble && !t.Priced() {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return nil
+	}
+
+	provider, err := PriceProvider()
+	if err != nil {
+		return err
+	}
+	ticker := portfolio.GetStockPrice(ctx, provider, result.Body[i])
+	if !ticker.Priced() {
+		return fmt.Errorf("%s: %s", ticker.Symble, ticker.Error)
+	}
+	result.Body[i] = portfolio.ApplyCurrency(ctx, provider, []portfolio.Ticker{ticker})[0]
+
+	// weights and allocation are of the patched body
+	portfolio.ApplyWeights(result.Body)
+	result.Errors = portfolio.SymbolErrors(result.Body)
+	result.Allocation = portfolio.Summarize(result.Body).Allocation()
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := UploadReport(ctx, result, b, failed.Key); err != nil {
+		return err
+	}
+	if table := ConfigOf(ctx).HistoryTable; table != "" {
+		items := append(HistoryItems(result.CreatedAt, result.Body), HistoryTotalItem(result.CreatedAt, portfolio.Summarize(result.Body)))
+		if err := WriteHistory(ctx, table, items); err != nil {
+			return err
+		}
+	}
+	logging.Info(ctx, "retry patched", logging.Fields{"symbol": failed.Symble, "date": failed.Date, "key": failed.Key})
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// Rollup is daily totals of the month (ROLLUP_FILE_PATH).
+type Rollup struct {
+	Month string      `json:"month"`
+	Days  []RollupDay `json:"days"`
+}
+
+// RollupDay is totals of a day of the rollup.
+type RollupDay struct {
+	Date string `json:"date"`
+	portfolio.Summary
+}
+
+// UpdateRollup is put the totals of the day to the rollup of the month, the day of the last run is replaced.
+// Nothing is done when ROLLUP_FILE_PATH is not set.
+func UpdateRollup(ctx context.Context, t time.Time, summary portfolio.Summary) error {
+	layout := ConfigOf(ctx).RollupPath
+	if layout == "" {
+		return nil
+	}
+	key := ReportFilePath(layout, t)
+	store := storage.New(ConfigOf(ctx).Bucket)
+
+	rollup := Rollup{Month: t.Format("2006-01")}
+	data, err := store.Get(ctx, key)
+	if err != nil && !errors.Is(err, storage.ErrNoSuchKey) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &rollup); err != nil {
+			return fmt.Errorf("%s: %s", key, err)
+		}
+	}
+
+	date := t.Format("2006-01-02")
+	days := []RollupDay{{Date: date, Summary: summary}}
+	for _, d := range rollup.Days {
+		if d.Date != date {
+			days = append(days, d)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	rollup.Days = days
+
+	b, err := json.Marshal(rollup)
+	if err != nil {
+		return err
+	}
+	return store.Put(ctx, key, b)
+}
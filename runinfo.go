@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// notifyLogKey is context key of the notification log of the run.
+type notifyLogKey struct{}
+
+// notifyLog is the outcomes of the notifications of the run.
+type notifyLog struct {
+	mu       sync.Mutex
+	outcomes []portfolio.NotifyOutcome
+}
+
+// WithNotifyLog is the context whose notifications are logged for NotifyOutcomes.
+func WithNotifyLog(ctx context.Context) context.Context {
+	return context.WithValue(ctx, notifyLogKey{}, &notifyLog{})
+}
+
+// RecordNotify is log the outcome of the notification of the channel, nothing without WithNotifyLog.
+func RecordNotify(ctx context.Context, channel, subject, status string, err error) {
+	log, ok := ctx.Value(notifyLogKey{}).(*notifyLog)
+	if !ok {
+		return
+	}
+	outcome := portfolio.NotifyOutcome{Channel: channel, Subject: subject, Status: status}
+	if err != nil {
+		outcome.Error = err.Error()
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	log.outcomes = append(log.outcomes, outcome)
+}
+
+// NotifyOutcomes is the notifications logged in the context in the order of them.
+func NotifyOutcomes(ctx context.Context) []portfolio.NotifyOutcome {
+	log, ok := ctx.Value(notifyLogKey{}).(*notifyLog)
+	if !ok {
+		return nil
+	}
+	log.mu.Lock()
+	defer log.mu.Unlock()
+	return append([]portfolio.NotifyOutcome(nil), log.outcomes...)
+}
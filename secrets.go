@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// secrets is the values loaded from SECRETS_ID and SSM_PARAMETER_PATH, kept while the lambda is warm.
+var secrets struct {
+	sync.Mutex
+	loaded time.Time
+	// set is the keys which were set from the secrets, they are updated on reload
+	set map[string]bool
+}
+
+// LoadSecrets is set the environment variables from the secrets manager secret (SECRETS_ID, json of the names and the values)
+// and the parameters under SSM_PARAMETER_PATH (the name is the last element of the parameter).
+// They are loaded at the first invocation and again after SECRETS_TTL (e.g. 1h, default never).
+// A variable set in the lambda environment is not overwritten.
+func LoadSecrets(ctx context.Context) error {
+	secretID, paramPath := os.Getenv("SECRETS_ID"), os.Getenv("SSM_PARAMETER_PATH")
+	if secretID == "" && paramPath == "" {
+		return nil
+	}
+
+	secrets.Lock()
+	defer secrets.Unlock()
+	if !secrets.loaded.IsZero() {
+		ttl, err := time.ParseDuration(os.Getenv("SECRETS_TTL"))
+		if err != nil || ttl <= 0 || time.Since(secrets.loaded) < ttl {
+			return nil
+		}
+	}
+
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return err
+	}
+
+	values := map[string]string{}
+	if secretID != "" {
+		out, err := secretsmanager.New(sess).GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(secretID),
+		})
+		if err != nil {
+			return fmt.Errorf("secret %s: %s", secretID, err)
+		}
+		if err := json.Unmarshal([]byte(aws.StringValue(out.SecretString)), &values); err != nil {
+			return fmt.Errorf("secret %s is not json of the names and the values. %s", secretID, err)
+		}
+	}
+	if paramPath != "" {
+		err := ssm.New(sess).GetParametersByPathPagesWithContext(ctx, &ssm.GetParametersByPathInput{
+			Path:           aws.String(paramPath),
+			Recursive:      aws.Bool(true),
+			WithDecryption: aws.Bool(true),
+		}, func(out *ssm.GetParametersByPathOutput, last bool) bool {
+			for _, p := range out.Parameters {
+				values[path.Base(aws.StringValue(p.Name))] = aws.StringValue(p.Value)
+			}
+			return true
+		})
+		if err != nil {
+			return fmt.Errorf("parameters %s: %s", paramPath, err)
+		}
+	}
+
+	if secrets.set == nil {
+		secrets.set = map[string]bool{}
+	}
+	for k, v := range values {
+		if _, ok := os.LookupEnv(k); ok && !secrets.set[k] {
+			continue
+		}
+		os.Setenv(k, v)
+		secrets.set[k] = true
+	}
+	secrets.loaded = time.Now()
+	logging.Info(ctx, "secrets loaded", logging.Fields{"count": len(values)})
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// MailTemplate is SES_TEMPLATE, the ses template of the report mail.
+func MailTemplate() string {
+	return os.Getenv("SES_TEMPLATE")
+}
+
+// MailTemplateData is the variables of the ses template, e.g. {{subject}}, {{summary.value}}, {{#each result.body}}.
+// The totals are formatted in money (REPORT_LOCALE) too, text and html are the content made by the lambda.
+type MailTemplateData struct {
+	Subject string            `json:"subject"`
+	Date    string            `json:"date"`
+	Summary portfolio.Summary `json:"summary"`
+	Money   map[string]string `json:"money"`
+	Text    string            `json:"text"`
+	HTML    string            `json:"html,omitempty"`
+	// Result is the payload of the report (Result, BatchResult or Digest)
+	Result interface{} `json:"result,omitempty"`
+}
+
+// TemplateData is json of the template variables of the report.
+func TemplateData(subject string, r Report) (string, error) {
+	b, err := json.Marshal(MailTemplateData{
+		Subject: subject,
+		Date:    r.Date,
+		Summary: r.Summary,
+		Money: map[string]string{
+			"cost":        report.Money(r.Summary.Cost),
+			"value":       report.Money(r.Summary.Value),
+			"profit_loss": report.Money(r.Summary.ProfitLoss),
+			"realized":    report.Money(r.Summary.Realized),
+		},
+		Text:   r.Text,
+		HTML:   r.HTML,
+		Result: r.Payload,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
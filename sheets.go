@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// sheetsScope is the oauth scope of the sheets api.
+const sheetsScope = "https://www.googleapis.com/auth/spreadsheets"
+
+// sheetsAPI is the values api of the spreadsheets.
+const sheetsAPI = "https://sheets.googleapis.com/v4/spreadsheets/"
+
+// ServiceAccount is the json key of the google service account, GOOGLE_SERVICE_ACCOUNT.
+type ServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// sheetsToken is the access token of the service account, kept while the lambda is warm.
+var sheetsToken struct {
+	sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// SheetsEnabled is true when SHEETS_ID is set.
+func SheetsEnabled() bool {
+	return os.Getenv("SHEETS_ID") != ""
+}
+
+// SheetsTotalRow is the row of the totals, date, cost, value, profit loss, return %, symbols and priced symbols.
+func SheetsTotalRow(date string, summary portfolio.Summary) []interface{} {
+	return []interface{}{date, summary.Cost, summary.Value, summary.ProfitLoss, summary.Percent(), summary.Count, summary.Priced}
+}
+
+// SheetsPositionRows is the rows of the priced positions, date, symble, hold, bid, price, profit loss and return %.
+func SheetsPositionRows(date string, tickers []portfolio.Ticker) [][]interface{} {
+	var rows [][]interface{}
+	for _, t := range tickers {
+		if !t.Priced() {
+			continue
+		}
+		rows = append(rows, []interface{}{date, t.Symble, t.Hold, t.Bid, t.Value, t.Earning(), t.Percent()})
+	}
+	return rows
+}
+
+// ExportSheets is append the totals of the day to SHEETS_RANGE (default Totals) of the spreadsheet SHEETS_ID,
+// and the positions to SHEETS_POSITIONS_RANGE when it is set. tickers is nil for the batch mode.
+// Nothing is exported when SHEETS_ID is not set.
+func ExportSheets(ctx context.Context, date string, summary portfolio.Summary, tickers []portfolio.Ticker) error {
+	if !SheetsEnabled() {
+		return nil
+	}
+	token, err := SheetsToken(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	id := os.Getenv("SHEETS_ID")
+	totals := os.Getenv("SHEETS_RANGE")
+	if totals == "" {
+		totals = "Totals"
+	}
+	if err := AppendSheet(ctx, token, id, totals, [][]interface{}{SheetsTotalRow(date, summary)}); err != nil {
+		return err
+	}
+	if positions := os.Getenv("SHEETS_POSITIONS_RANGE"); positions != "" && len(tickers) > 0 {
+		return AppendSheet(ctx, token, id, positions, SheetsPositionRows(date, tickers))
+	}
+	return nil
+}
+
+// AppendSheet is append the rows after the table of the range.
+func AppendSheet(ctx context.Context, token, id, sheetRange string, rows [][]interface{}) error {
+	b, err := json.Marshal(map[string]interface{}{"values": rows})
+	if err != nil {
+		return err
+	}
+	endpoint := sheetsAPI + url.PathEscape(id) + "/values/" + url.PathEscape(sheetRange) + ":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := quotes.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("sheets %s error. %d", sheetRange, resp.StatusCode)
+	}
+	return nil
+}
+
+// SheetsToken is the access token of GOOGLE_SERVICE_ACCOUNT (the json key, e.g. a value of SECRETS_ID),
+// by the signed jwt of the service account. It is reused until a minute before it expires.
+func SheetsToken(ctx context.Context, now time.Time) (string, error) {
+	sheetsToken.Lock()
+	defer sheetsToken.Unlock()
+	if sheetsToken.token != "" && now.Before(sheetsToken.expires.Add(-time.Minute)) {
+		return sheetsToken.token, nil
+	}
+
+	var account ServiceAccount
+	if err := json.Unmarshal([]byte(os.Getenv("GOOGLE_SERVICE_ACCOUNT")), &account); err != nil {
+		return "", fmt.Errorf("GOOGLE_SERVICE_ACCOUNT: %s", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	assertion, err := SignJWT(account, now)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"}, "assertion": {assertion}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := quotes.HTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("%s error. %d", resp.Request.URL.Host, resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	sheetsToken.token = token.AccessToken
+	sheetsToken.expires = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return token.AccessToken, nil
+}
+
+// SignJWT is the jwt (rs256) of the service account for the token request, valid for an hour.
+func SignJWT(account ServiceAccount, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(account.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("private_key of %s is not pem", account.ClientEmail)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return "", fmt.Errorf("private_key of %s is not rsa", account.ClientEmail)
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   account.ClientEmail,
+		"scope": sheetsScope,
+		"aud":   account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	sum := sha256.Sum256([]byte(unsigned))
+	sig, err := rsa.SignPKCS1v15(nil, rsaKey, crypto.SHA256, sum[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// signalDays is the calendar days of the history for the 200 day moving average.
+const signalDays = 300
+
+// MovingAverageSignals is the moving averages and the crosses of the positions of the result (MA_SIGNALS=true).
+// The daily prices are of HISTORY_TABLE when it is set, otherwise the reports in s3.
+func MovingAverageSignals(ctx context.Context, result portfolio.Result, t time.Time) ([]portfolio.Signal, error) {
+	if os.Getenv("MA_SIGNALS") != "true" {
+		return nil, nil
+	}
+	cfg := ConfigOf(ctx)
+	from, to := t.AddDate(0, 0, -signalDays), t.AddDate(0, 0, -1)
+
+	var history []portfolio.Result
+	var err error
+	if cfg.HistoryTable != "" {
+		history, err = ReadHistory(ctx, cfg.HistoryTable, from, to)
+	} else {
+		history, err = ReadReports(ctx, cfg.Bucket, cfg.FilePath, from, to)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return portfolio.Signals(history, result), nil
+}
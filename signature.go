@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/tora0091/stock-profit/awsclient"
+)
+
+// defaultSignatureTolerance is max age of the signed request, SIGNATURE_TOLERANCE.
+const defaultSignatureTolerance = 5 * time.Minute
+
+// RequireSignature is true when SIGNATURE_REQUIRED is true, the static stock-api-key header is not accepted.
+func RequireSignature() bool {
+	return os.Getenv("SIGNATURE_REQUIRED") == "true"
+}
+
+// SignatureTolerance is SIGNATURE_TOLERANCE (e.g. 5m), a request signed before or after it is rejected.
+func SignatureTolerance() (time.Duration, error) {
+	env := os.Getenv("SIGNATURE_TOLERANCE")
+	if env == "" {
+		return defaultSignatureTolerance, nil
+	}
+	d, err := time.ParseDuration(env)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid SIGNATURE_TOLERANCE %s", env)
+	}
+	return d, nil
+}
+
+// SigningPayload is the signed content of the request, the timestamp, the method, the path, the canonical query (CanonicalQuery)
+// and the body, separated by newlines.
+func SigningPayload(timestamp, method, path, query string, body []byte) []byte {
+	payload := []byte(timestamp + "\n" + method + "\n" + path + "\n" + query + "\n")
+	return append(payload, body...)
+}
+
+// CanonicalQuery is the query params sorted by name and url encoded (e.g. dry_run=true&force=true), empty without them.
+// The values of a repeated param are in the order of the request.
+func CanonicalQuery(values url.Values) string {
+	return values.Encode()
+}
+
+// RequestQuery is the query params of the request, the multi value params when api gateway has them.
+func RequestQuery(request events.APIGatewayProxyRequest) url.Values {
+	values := url.Values{}
+	if len(request.MultiValueQueryStringParameters) > 0 {
+		for k, vs := range request.MultiValueQueryStringParameters {
+			values[k] = append([]string{}, vs...)
+		}
+		return values
+	}
+	for k, v := range request.QueryStringParameters {
+		values.Set(k, v)
+	}
+	return values
+}
+
+// Sign is hex of the hmac-sha256 of the payload.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature is the key of the signed request.
+// The request has the stock-key-name, stock-timestamp (unix seconds) and stock-signature headers,
+// a signature is accepted once while it is in SIGNATURE_TOLERANCE.
+func VerifySignature(ctx context.Context, keys []APIKey, request events.APIGatewayProxyRequest, now time.Time) (APIKey, bool, error) {
+	tolerance, err := SignatureTolerance()
+	if err != nil {
+		return APIKey{}, false, err
+	}
+
+	var key APIKey
+	var found bool
+	name := HeaderValue(request.Headers, "stock-key-name")
+	for _, k := range keys {
+		if k.Name == name && k.Secret != "" {
+			key, found = k, true
+			break
+		}
+	}
+	if !found {
+		return APIKey{}, false, nil
+	}
+
+	timestamp := HeaderValue(request.Headers, "stock-timestamp")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return APIKey{}, false, nil
+	}
+	signedAt := time.Unix(unix, 0)
+	if signedAt.Before(now.Add(-tolerance)) || signedAt.After(now.Add(tolerance)) {
+		return APIKey{}, false, nil
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		if body, err = base64.StdEncoding.DecodeString(request.Body); err != nil {
+			return APIKey{}, false, nil
+		}
+	}
+	signature := HeaderValue(request.Headers, "stock-signature")
+	expected := Sign(key.Secret, SigningPayload(timestamp, request.HTTPMethod, request.Path, CanonicalQuery(RequestQuery(request)), body))
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return APIKey{}, false, nil
+	}
+
+	// the same signature again is a replay
+	fresh, err := MarkSignature(ctx, key.Name+"#"+signature, signedAt.Add(tolerance))
+	if err != nil {
+		return APIKey{}, false, err
+	}
+	return key, fresh, nil
+}
+
+// seenSignatures is the accepted signatures and their expiry, of this lambda instance (without RATE_LIMIT_TABLE).
+var seenSignatures = struct {
+	sync.Mutex
+	m map[string]time.Time
+}{m: map[string]time.Time{}}
+
+// MarkSignature is record the signature until expires, false is it is already recorded.
+// RATE_LIMIT_TABLE records the signatures of all lambda instances.
+func MarkSignature(ctx context.Context, id string, expires time.Time) (bool, error) {
+	table := os.Getenv("RATE_LIMIT_TABLE")
+	if table == "" {
+		seenSignatures.Lock()
+		defer seenSignatures.Unlock()
+		now := time.Now()
+		for k, e := range seenSignatures.m {
+			if e.Before(now) {
+				delete(seenSignatures.m, k)
+			}
+		}
+		if _, ok := seenSignatures.m[id]; ok {
+			return false, nil
+		}
+		seenSignatures.m[id] = expires
+		return true, nil
+	}
+
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return false, err
+	}
+	_, err = dynamodb.New(sess).PutItemWithContext(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item: map[string]*dynamodb.AttributeValue{
+			"key":     {S: aws.String("signature#" + id)},
+			"expires": {N: aws.String(strconv.FormatInt(expires.Unix(), 10))},
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#k)"),
+		ExpressionAttributeNames: map[string]*string{"#k": aws.String("key")},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tora0091/stock-profit/report"
+)
+
+// PostSlack is post the message to slack incoming webhook.
+func PostSlack(ctx context.Context, url string, msg report.SlackMessage) error {
+	if url == "" {
+		return fmt.Errorf("SLACK_WEBHOOK_URL is not set")
+	}
+	return PostJSON(ctx, url, msg)
+}
+
+// SlackNotifier is post the report summary to slack.
+type SlackNotifier struct {
+	URL string
+}
+
+// Name is channel name.
+func (n *SlackNotifier) Name() string {
+	return "slack"
+}
+
+// Notify is post the report summary.
+func (n *SlackNotifier) Notify(ctx context.Context, r Report) error {
+	if r.Subject != "" {
+		return PostSlack(ctx, n.URL, report.SlackMessage{Text: "*" + r.Subject + "*\n" + r.Text})
+	}
+	return PostSlack(ctx, n.URL, report.SlackContent(r.Date, r.Summary))
+}
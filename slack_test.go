@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+)
+
+func TestPostSlackSummary(t *testing.T) {
+	var got report.SlackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", ct)
+		}
+		b, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &got); err != nil {
+			t.Errorf("payload %q is not json: %s", b, err)
+		}
+	}))
+	defer srv.Close()
+
+	summary := portfolio.Summarize([]portfolio.Ticker{
+		{Symble: "AAPL", Bid: 100, Value: 120, Hold: 10},
+		{Symble: "MSFT", Bid: 200, Value: 190, Hold: 5},
+	})
+	if err := PostSlack(context.Background(), srv.URL, report.SlackContent("2021-06-14", summary)); err != nil {
+		t.Fatalf("PostSlack() error = %v", err)
+	}
+	if !strings.Contains(got.Text, "2021-06-14") || !strings.Contains(got.Text, "150.00") {
+		t.Errorf("text = %q, want the date and the total 150.00", got.Text)
+	}
+	b, _ := json.Marshal(got.Blocks)
+	for _, want := range []string{"Top gainer", "AAPL", "Top loser", "MSFT"} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("blocks %s don't have %q", b, want)
+		}
+	}
+}
+
+func TestPostSlackError(t *testing.T) {
+	noRetries(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := PostSlack(context.Background(), srv.URL, report.SlackMessage{Text: "text"}); err == nil {
+		t.Error("PostSlack() error = nil, want the webhook error")
+	}
+	if err := PostSlack(context.Background(), "", report.SlackMessage{Text: "text"}); err == nil {
+		t.Error("PostSlack() error = nil, want SLACK_WEBHOOK_URL is not set")
+	}
+}
+
+func TestNotifyChannels(t *testing.T) {
+	t.Setenv("SLACK_WEBHOOK_URL", "")
+	if c := NotifyChannels(""); !c["mail"] || c["slack"] {
+		t.Errorf("NotifyChannels(\"\") = %v, want mail only", c)
+	}
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T000/B000/XXXX")
+	if c := NotifyChannels(""); !c["mail"] || !c["slack"] {
+		t.Errorf("NotifyChannels(\"\") = %v, want mail and slack", c)
+	}
+	if c := NotifyChannels(" Slack "); c["mail"] || !c["slack"] {
+		t.Errorf("NotifyChannels(\" Slack \") = %v, want slack only", c)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+// snsMaxMessage is max bytes of the sns message.
+const snsMaxMessage = 256 * 1024
+
+// SNSNotifier is publish the payload json to the sns topic.
+type SNSNotifier struct {
+	TopicArn string
+}
+
+// Name is channel name.
+func (n *SNSNotifier) Name() string {
+	return "sns"
+}
+
+// PayloadType is the type attribute of the sns message, result, batch, digest or alert.
+func PayloadType(payload interface{}) string {
+	switch payload.(type) {
+	case portfolio.Result, Response:
+		return "result"
+	case BatchResult:
+		return "batch"
+	case portfolio.Digest:
+		return "digest"
+	}
+	return "alert"
+}
+
+// Notify is publish the payload, the message attributes type, date and tenant are for the subscription filter policy.
+// A payload over the sns limit is published as the key of the report in BUCKET.
+func (n *SNSNotifier) Notify(ctx context.Context, report Report) error {
+	if n.TopicArn == "" {
+		return fmt.Errorf("SNS_TOPIC_ARN is not set")
+	}
+
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(report.Payload)
+	if err != nil {
+		return err
+	}
+	kind := PayloadType(report.Payload)
+	if len(b) > snsMaxMessage {
+		if report.Key == "" {
+			return fmt.Errorf("%s payload is %d bytes, over the sns limit", kind, len(b))
+		}
+		if b, err = json.Marshal(map[string]interface{}{"type": kind, "date": report.Date, "bucket": ConfigOf(ctx).Bucket, "key": report.Key, "truncated": true}); err != nil {
+			return err
+		}
+	}
+
+	attributes := map[string]*sns.MessageAttributeValue{
+		"type": {DataType: aws.String("String"), StringValue: aws.String(kind)},
+		"date": {DataType: aws.String("String"), StringValue: aws.String(report.Date)},
+	}
+	if tenant := ConfigOf(ctx).Tenant; tenant != "" {
+		attributes["tenant"] = &sns.MessageAttributeValue{DataType: aws.String("String"), StringValue: aws.String(tenant)}
+	}
+
+	svc := sns.New(sess)
+	_, err = svc.PublishWithContext(ctx, &sns.PublishInput{
+		TopicArn:          aws.String(n.TopicArn),
+		Subject:           aws.String("Stock Profit " + report.Date),
+		Message:           aws.String(string(b)),
+		MessageAttributes: attributes,
+	})
+	return err
+}
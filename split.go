@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// LoadSplits is the splits of SPLITS_LOG in the BUCKET, nil when it is not set or can't be read.
+func LoadSplits(ctx context.Context) []portfolio.Split {
+	key := os.Getenv("SPLITS_LOG")
+	if key == "" {
+		return nil
+	}
+
+	data, err := DownloadFile(ctx, ConfigOf(ctx).Bucket, key)
+	if err != nil {
+		logging.Warn(ctx, "splits log error", logging.Fields{"key": key, "error": err})
+		return nil
+	}
+	splits, errs := portfolio.ParseSplits(data)
+	for _, e := range errs {
+		logging.Warn(ctx, "invalid splits log line", logging.Fields{"key": key, "line": e.Line, "error": e.Error})
+	}
+	return splits
+}
+
+// ParseStockData is parse the stock data of the key in the bucket, adjusted by the splits after it was modified.
+func ParseStockData(ctx context.Context, bucket, key string, data []byte) ([]portfolio.Ticker, []portfolio.ParseError, error) {
+	splits := LoadSplits(ctx)
+	if len(splits) == 0 {
+		return portfolio.ParseWatchlist(key, data)
+	}
+
+	modified, err := storage.New(bucket).Modified(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return portfolio.ParseWatchlistSplits(key, data, splits, modified.In(reportLocation).Format("2006-01-02"))
+}
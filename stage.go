@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// Stages of the step functions run.
+const (
+	StageFetch  = "fetch"
+	StageReport = "report"
+	StageDone   = "done"
+)
+
+// StageEvent is input and output of the step functions stages, {"stage": "fetch"} starts the run.
+// The output of the fetch stage is the input of the report stage.
+type StageEvent struct {
+	Stage string `json:"stage"`
+	// Key is the fetched result in BUCKET, Time is the run time of it (RFC3339)
+	Key     string `json:"key,omitempty"`
+	Time    string `json:"time,omitempty"`
+	FetchMs int64  `json:"fetch_ms,omitempty"`
+	// Tenant is name of the tenant of TENANTS_FILE, empty is S3_STOCK_DATA
+	Tenant string `json:"tenant,omitempty"`
+}
+
+// StageFilePath is key of the fetched result of t under STAGE_PREFIX (default stage/).
+func StageFilePath(tenant string, t time.Time) string {
+	prefix := os.Getenv("STAGE_PREFIX")
+	if prefix == "" {
+		prefix = "stage"
+	}
+	return path.Join(prefix, tenant, t.Format("2006-01-02T150405")+".json")
+}
+
+// StageHandler is run the stage of the event, an error fails the state and step functions retries it.
+func StageHandler(ctx context.Context, event StageEvent) (StageEvent, error) {
+	if event.Tenant != "" {
+		tenants, err := LoadTenants(ctx)
+		if err != nil {
+			return event, err
+		}
+		t, ok := TenantByName(tenants, event.Tenant)
+		if !ok {
+			return event, fmt.Errorf("tenant %s is not in TENANTS_FILE", event.Tenant)
+		}
+		ctx = WithConfig(ctx, TenantConfig(ConfigOf(ctx), t))
+	}
+	logging.Info(ctx, "stage", logging.Fields{"stage": event.Stage, "key": event.Key, "tenant": event.Tenant})
+
+	switch event.Stage {
+	case StageFetch:
+		return FetchStage(ctx, event)
+	case StageReport:
+		return ReportStage(ctx, event)
+	}
+	return event, fmt.Errorf("unknown stage %q, %s or %s", event.Stage, StageFetch, StageReport)
+}
+
+// FetchStage is price the stock data and put the result to the stage key, BATCH_SIZE is not used.
+func FetchStage(ctx context.Context, event StageEvent) (StageEvent, error) {
+	quotes.ResetRetryBudget()
+
+	cfg := ConfigOf(ctx)
+	data, err := DownloadFile(ctx, cfg.Bucket, cfg.StockData)
+	if err != nil {
+		return event, err
+	}
+	symbols, parseErrors, err := ParseStockData(ctx, cfg.Bucket, cfg.StockData, data)
+	if err != nil {
+		return event, err
+	}
+	if symbols, err = PrepareSymbols(ctx, symbols); err != nil {
+		return event, err
+	}
+	provider, err := PriceProvider()
+	if err != nil {
+		return event, err
+	}
+
+	t := time.Now().In(reportLocation)
+	start := time.Now()
+	result := FetchResult(ctx, provider, symbols, parseErrors, t)
+	fetch := time.Since(start)
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return event, err
+	}
+	key := StageFilePath(cfg.Tenant, t)
+	if err := storage.New(cfg.Bucket).Put(ctx, key, b); err != nil {
+		return event, err
+	}
+	return StageEvent{Stage: StageReport, Key: key, Time: t.Format(time.RFC3339), FetchMs: fetch.Milliseconds(), Tenant: event.Tenant}, nil
+}
+
+// ReportStage is upload and notify the fetched result of the stage key.
+func ReportStage(ctx context.Context, event StageEvent) (StageEvent, error) {
+	t, err := time.Parse(time.RFC3339, event.Time)
+	if err != nil {
+		return event, fmt.Errorf("invalid time of the stage %q", event.Time)
+	}
+	data, err := storage.New(ConfigOf(ctx).Bucket).Get(ctx, event.Key)
+	if err != nil {
+		return event, err
+	}
+	result, err := portfolio.ParseResult(data)
+	if err != nil {
+		return event, fmt.Errorf("%s: %s", event.Key, err)
+	}
+
+	if _, err := ReportResult(ctx, result, t.In(reportLocation), time.Duration(event.FetchMs)*time.Millisecond); err != nil {
+		return event, err
+	}
+	event.Stage = StageDone
+	return event, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// defaultStatementLinkTTL is expiry of the statement link, it is max of the s3 presigned url.
+const defaultStatementLinkTTL = 7 * 24 * time.Hour
+
+// IsStatementDay is check t is the last weekday of the month, the statement is made on it.
+func IsStatementDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	for d := t.AddDate(0, 0, 1); d.Month() == t.Month(); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			return false
+		}
+	}
+	return true
+}
+
+// StatementLinkTTL is STATEMENT_LINK_TTL (e.g. 72h), default 7 days.
+func StatementLinkTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("STATEMENT_LINK_TTL")); err == nil && d > 0 && d <= defaultStatementLinkTTL {
+		return d
+	}
+	return defaultStatementLinkTTL
+}
+
+// UploadStatement is put the pdf statement of the month of t to STATEMENT_FILE_PATH, link is the presigned url of it.
+// The chart of the month is in it when the values are stored (HISTORY_TABLE or ROLLUP_FILE_PATH).
+func UploadStatement(ctx context.Context, result portfolio.Result, t time.Time) (string, error) {
+	var chart []byte
+	first := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	if points, err := ValueSeries(ctx, first, t); err != nil {
+		return "", err
+	} else if len(points) >= 2 {
+		if chart, err = report.ValueChart(points); err != nil {
+			return "", err
+		}
+	}
+
+	pdf, err := report.Statement(result, t.Format("2006-01"), chart)
+	if err != nil {
+		return "", err
+	}
+	key := ReportFilePath(ConfigOf(ctx).StatementPath, t)
+	store := storage.New(ConfigOf(ctx).Bucket)
+	if err := store.Put(ctx, key, pdf); err != nil {
+		return "", fmt.Errorf("%s: %s", key, err)
+	}
+	return store.Presign(key, StatementLinkTTL())
+}
@@ -1,272 +1,748 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"regexp"
+	"os/signal"
+	"path"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
-	"github.com/aws/aws-sdk-go/aws/endpoints"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/aws/aws-sdk-go/service/ses"
-	"github.com/gocolly/colly/v2"
+	"github.com/aws/aws-sdk-go/service/ses/sesiface"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/report"
+	"github.com/tora0091/stock-profit/storage"
 )
 
-type Ticker struct {
-	Symble string  `json:"symble"`
-	Bid    float64 `json:"bid"`
-	Value  float64 `json:"value"`
-	Hold   int     `json:"hold"`
+type ErrorBody struct {
+	Error string `json:"error"`
+	Code  int    `json:"code"`
 }
 
-type Result struct {
-	CreatedAt string   `json:"created_at"`
-	Body      []Ticker `json:"body"`
+// Response is api response of the report.
+type Response struct {
+	portfolio.Result
+	// NotifyErrors and Failures (the failed symbols of the partial result) are only in the api response
+	NotifyErrors []NotifyError           `json:"notify_errors,omitempty"`
+	Failures     []portfolio.SymbolError `json:"failures,omitempty"`
 }
 
-// var r = regexp.MustCompile(`watchlist(\d+.\d+)`)
-var r = regexp.MustCompile(`trend2W10W9M(\d+.\d+)`)
+// reportLocation is timezone of the report date.
+var reportLocation = time.Local
 
 func main() {
-	lambda.Start(Handler)
+	reportLocation = LoadReportLocation(os.Getenv("REPORT_TIMEZONE"))
+	quotes.Location = reportLocation
+
+	// command line mode outside of lambda
+	if os.Getenv("AWS_LAMBDA_RUNTIME_API") == "" && len(os.Args) > 1 {
+		// logs go to stderr, stdout is only the report
+		out := os.Stdout
+		os.Stdout = os.Stderr
+		// interrupt cancels the outstanding fetches, the fetched prices are still printed
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		if err := RunCLI(ctx, os.Args[1:], out); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// the config is validated at the cold start, or the first invocation after the secrets are loaded
+	if os.Getenv("SECRETS_ID") == "" && os.Getenv("SSM_PARAMETER_PATH") == "" {
+		if err := InitConfig(); err != nil {
+			logging.Error(context.Background(), "config error", logging.Fields{"error": err})
+			os.Exit(1)
+		}
+	}
+	lambda.StartWithContext(context.Background(), Invoke)
 }
 
-// Handler is lambda function start point.
-func Handler(request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	// response
-	res := events.APIGatewayProxyResponse{}
+// LoadReportLocation is load IANA timezone, invalid name is fallback to UTC.
+func LoadReportLocation(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		logging.Warn(context.Background(), "invalid REPORT_TIMEZONE, fallback to UTC", logging.Fields{"timezone": name, "error": err})
+		return time.UTC
+	}
+	return loc
+}
+
+// Handler is api gateway request handler, the responses have the cors headers of CORS_ALLOW_ORIGINS.
+func Handler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	origin := CORSOrigin(HeaderValue(request.Headers, "Origin"))
+	if request.HTTPMethod == http.MethodOptions && origin != "" {
+		return PreflightResponse(origin), nil
+	}
+	response, err := HandleRequest(ctx, request)
+	return WithCORS(response, origin), err
+}
+
+// HandleRequest is route the api request, every request is recorded to the audit log (AUDIT_TABLE or AUDIT_LOG_PREFIX).
+func HandleRequest(ctx context.Context, request events.APIGatewayProxyRequest) (response events.APIGatewayProxyResponse, err error) {
+	begin := time.Now()
+	var key APIKey
+	defer func() {
+		if AuditEnabled() {
+			Audit(ctx, NewAuditEntry(request, key.Name, key.Tenant, response, err, begin))
+		}
+	}()
+
+	// the bot webhook has the secret token of telegram instead of the api key
+	if IsTelegramRequest(request) {
+		key.Name = "telegram"
+		return TelegramHandler(ctx, request)
+	}
 
 	// check api key
-	if request.Headers["stock-api-key"] != os.Getenv("STOCK_API_KEY") {
-		res.StatusCode = http.StatusBadRequest
-		res.Body = "status bad request."
-		return res, fmt.Errorf("status bad request. %d", http.StatusBadRequest)
+	key, ok, err := Authenticate(ctx, request)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	// a rejected request is the response, not the error of the function
+	if !ok {
+		logging.Info(ctx, "unauthorized request", logging.Fields{"path": request.Path})
+		return ErrorResponse(http.StatusBadRequest, "status bad request."), nil
+	}
+
+	// a rate limit error doesn't block the request
+	if allowed, retry, err := AllowRequest(ctx, key, time.Now()); err != nil {
+		logging.Warn(ctx, "rate limit error", logging.Fields{"key": key.Name, "error": err})
+	} else if !allowed {
+		logging.Info(ctx, "rate limited", logging.Fields{"key": key.Name, "rate_limit": key.RateLimit})
+		response := ErrorResponse(http.StatusTooManyRequests, "too many requests.")
+		response.Headers["Retry-After"] = strconv.Itoa(int(retry.Seconds()) + 1)
+		return response, nil
+	}
+
+	// the key of a tenant is only its portfolio
+	if key.Tenant != "" {
+		tenants, err := LoadTenants(ctx)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		t, ok := TenantByName(tenants, key.Tenant)
+		if !ok {
+			err := fmt.Errorf("tenant %s of the key %s is not in TENANTS_FILE", key.Tenant, key.Name)
+			return ErrorResponse(http.StatusForbidden, err.Error()), err
+		}
+		ctx = WithConfig(ctx, TenantConfig(ConfigOf(ctx), t))
+	}
+
+	if request.QueryStringParameters["action"] == "health" {
+		return HealthCheck(ctx, request.QueryStringParameters["symbol"], false), nil
+	}
+	if IsMetricsRequest(request) {
+		return MetricsHandler(ctx)
+	}
+	if IsHealthRequest(request) {
+		return HealthCheck(ctx, HealthSymbol(request.QueryStringParameters["symbol"]), true), nil
+	}
+
+	if IsAuditRequest(request) {
+		return AuditHandler(ctx, request)
+	}
+
+	if IsVacationRequest(request) {
+		return VacationHandler(ctx, request)
+	}
+
+	if IsDiffRequest(request) {
+		return DiffHandler(ctx, request)
+	}
+
+	if IsHistoryRequest(request) {
+		return HistoryHandler(ctx, request)
+	}
+
+	if IsGraphQLRequest(request) {
+		return GraphQLHandler(ctx, request)
+	}
+
+	if IsPositionRequest(request) {
+		return PositionHandler(ctx, request)
+	}
+
+	if IsQuotesRequest(request) {
+		return QuotesHandler(ctx, request)
+	}
+
+	if IsTradesRequest(request) {
+		return TradesHandler(ctx, request)
+	}
+
+	if IsPortfolioRequest(request) {
+		return PortfolioHandler(ctx, request)
+	}
+
+	format, err := RequestFormat(request)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), err
+	}
+	ctx = WithFormat(ctx, format)
+
+	if request.QueryStringParameters["dry_run"] == "true" {
+		ctx = WithDryRun(ctx)
+	}
+	if request.QueryStringParameters["force"] == "true" {
+		ctx = WithForce(ctx)
+	}
+	if request.QueryStringParameters["refresh"] == "true" {
+		ctx = quotes.WithRefresh(ctx)
+	}
+	if mode := request.QueryStringParameters["report_mode"]; mode != "" {
+		if mode, err = ParseReportMode(mode); err != nil {
+			return ErrorResponse(http.StatusBadRequest, err.Error()), err
+		}
+		ctx = WithReportMode(ctx, mode)
 	}
 
-	data, err := DownloadFile()
+	// watchlist in the request body is valued on the fly, report=true is make the report of it instead of s3
+	if symbols, parseErrors, ok, err := RequestWatchlist(request); ok {
+		if err != nil {
+			return ErrorResponse(http.StatusBadRequest, err.Error()), err
+		}
+		// the watchlist of the request is not the run of the day
+		if request.QueryStringParameters["report"] == "true" {
+			return Run(WithForce(ctx), symbols, parseErrors)
+		}
+		return Valuate(ctx, symbols, parseErrors)
+	}
+
+	return RunPortfolios(ctx)
+}
+
+// RunStockData is make the report of S3_STOCK_DATA.
+func RunStockData(ctx context.Context) (events.APIGatewayProxyResponse, error) {
+	cfg := ConfigOf(ctx)
+	key := cfg.StockData
+	data, err := DownloadFile(ctx, cfg.Bucket, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNoSuchKey) {
+			return ErrorResponse(http.StatusNotFound, err.Error()), err
+		}
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+
+	symbols, parseErrors, err := ParseStockData(ctx, cfg.Bucket, key, data)
 	if err != nil {
-		res.StatusCode = http.StatusInternalServerError
-		res.Body = err.Error()
-		return res, err
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
 	}
+	return Run(ctx, symbols, parseErrors)
+}
 
-	activeThreads := 0
-	doneTicker := make(chan Ticker)
+// Run is make the report of the symbols, upload and notify it.
+// parseErrors of the stock data are reported with it.
+func Run(ctx context.Context, symbols []portfolio.Ticker, parseErrors []portfolio.ParseError) (events.APIGatewayProxyResponse, error) {
+	quotes.ResetRetryBudget()
 
-	var tickers []Ticker
-	for _, symbol := range GetTickerSymbles(data) {
-		go GetStockPrice(symbol, doneTicker)
-		activeThreads++
+	symbols, err := PrepareSymbols(ctx, symbols)
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), err
 	}
 
-	for activeThreads > 0 {
-		tickers = append(tickers, <-doneTicker)
-		activeThreads--
+	t := time.Now().In(reportLocation)
+	ctx = WithSuppress(ctx, SuppressReason(ctx, t))
+
+	// the repeated run of the day returns the result of the first one (RUN_MARKER_PREFIX)
+	if !IsForce(ctx) && !IsDryRun(ctx) {
+		marker, ok, err := LoadRunMarker(ctx, t)
+		if err != nil {
+			logging.Warn(ctx, "run marker error", logging.Fields{"error": err})
+		} else if ok {
+			return ReplayRun(ctx, marker)
+		}
 	}
 
-	t := time.Now().Local()
-	result := Result{
-		CreatedAt: t.Format("2006-01-02"),
-		Body:      tickers,
+	provider, err := PriceProvider()
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
 	}
 
+	// the positions are checked against the broker before the valuation
+	discrepancies := SyncBroker(ctx, symbols)
+
+	// large watchlist is processed in batches
+	if size, _ := strconv.Atoi(os.Getenv("BATCH_SIZE")); size > 0 && len(symbols) > size {
+		return RunBatches(ctx, provider, symbols, parseErrors, discrepancies, size, t, ReportFilePath(ConfigOf(ctx).FilePath, t))
+	}
+
+	start := time.Now()
+	result := FetchResult(ctx, provider, symbols, parseErrors, t)
+	result.Discrepancies = discrepancies
+	return ReportResult(ctx, result, t, time.Since(start))
+}
+
+// PrepareSymbols is the symbols of the report, filtered, lots aggregated and the dividend log applied.
+// ErrEmptyWatchlist when nothing is left (unless ALLOW_EMPTY_REPORT).
+func PrepareSymbols(ctx context.Context, symbols []portfolio.Ticker) ([]portfolio.Ticker, error) {
+	symbols = portfolio.AggregateLots(portfolio.FilterSymbols(symbols, os.Getenv("INCLUDE_SYMBOLS"), os.Getenv("EXCLUDE_SYMBOLS")))
+	if len(symbols) == 0 && os.Getenv("ALLOW_EMPTY_REPORT") != "true" {
+		logging.Error(ctx, "empty watchlist", logging.Fields{"error": portfolio.ErrEmptyWatchlist})
+		return nil, portfolio.ErrEmptyWatchlist
+	}
+	return ApplyDividendLog(ctx, symbols), nil
+}
+
+// FetchResult is price the symbols at t, the result of the day.
+func FetchResult(ctx context.Context, provider quotes.Provider, symbols []portfolio.Ticker, parseErrors []portfolio.ParseError, t time.Time) portfolio.Result {
+	result := portfolio.NewResult(t.Format("2006-01-02"), portfolio.ApplyCurrency(ctx, provider, portfolio.FetchPrices(ctx, provider, symbols)))
+	result.Currency = strings.ToUpper(os.Getenv("BASE_CURRENCY"))
+	result.ParseErrors = parseErrors
+	result.Benchmark = portfolio.FetchBenchmark(ctx, provider)
+	return result
+}
+
+// ReportResult is upload and notify the result fetched at t, fetch is the latency of it.
+func ReportResult(ctx context.Context, result portfolio.Result, t time.Time, fetch time.Duration) (events.APIGatewayProxyResponse, error) {
+	cfg := ConfigOf(ctx)
+	filePath := ReportFilePath(cfg.FilePath, t)
+	dryRun := IsDryRun(ctx)
+
+	// the run metadata of the result, the notifications of the run are added to the response
+	begin := time.Now()
+	ctx = WithNotifyLog(ctx)
+	result.Run = portfolio.NewRunInfo(begin.Add(-fetch))
+	result.Run.FetchMs = fetch.Milliseconds()
+	result.Run.Count(result.Body)
+
+	// dry run only returns the result, nothing is uploaded, notified or measured
+	var alertErrors []NotifyError
+	if !dryRun {
+		EmitMetrics(ctx, RunMetrics(portfolio.Summarize(result.Body), fetch))
+		counts := map[string]*FetchCount{}
+		CountFetches(counts, result.Body)
+		if err := UpdateMetricsState(ctx, portfolio.Summarize(result.Body), counts, fetch, t); err != nil {
+			logging.Error(ctx, "metrics state error", logging.Fields{"error": err})
+		}
+
+		// alert is sent before the report
+		alertErrors = NotifyAlerts(ctx, result.CreatedAt, portfolio.CheckAlerts(result.Body))
+
+		var drawdownErrors []NotifyError
+		result.Drawdown, drawdownErrors = TrackDrawdown(ctx, result.CreatedAt, portfolio.Summarize(result.Body))
+		alertErrors = append(alertErrors, drawdownErrors...)
+	}
+
+	// previous result is read before the upload overwrites it, the movers are from it too
+	if os.Getenv("DAY_OVER_DAY") == "true" || report.MoversCount() > 0 {
+		prev, ok, err := PreviousResult(ctx, t)
+		if err != nil {
+			logging.Warn(ctx, "day over day error", logging.Fields{"error": err})
+		} else if ok {
+			portfolio.ApplyDayOverDay(&result, prev)
+		}
+	}
+
+	signals, err := MovingAverageSignals(ctx, result, t)
+	if err != nil {
+		logging.Warn(ctx, "moving average error", logging.Fields{"error": err})
+	}
+	result.Signals = signals
+
+	quiet := QuietRun(ctx, &result, filePath, t)
+
 	// make json
+	result.Run.Retries = quotes.Retries()
+	result.Run.DurationMs = (fetch + time.Since(begin)).Milliseconds()
 	b, err := json.Marshal(result)
 	if err != nil {
-		res.StatusCode = http.StatusInternalServerError
-		res.Body = err.Error()
-		return res, err
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+
+	if dryRun {
+		logging.Info(ctx, "dry run, skip upload and notification", logging.Fields{"key": filePath})
+		return ResultResponse(ctx, result, b)
 	}
 
 	// file upload to s3
-	if err := UploadFile(b, t); err != nil {
-		res.StatusCode = http.StatusInternalServerError
-		res.Body = err.Error()
-		return res, err
+	if err := UploadReport(ctx, result, b, filePath); err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	if err := QueueFailedSymbols(ctx, result, filePath); err != nil {
+		logging.Error(ctx, "retry queue error", logging.Fields{"error": err})
 	}
 
-	// send mail
-	if err := SenderMail(result); err != nil {
-		fmt.Println(err)
+	if err := UpdateRollup(ctx, t, portfolio.Summarize(result.Body)); err != nil {
+		logging.Error(ctx, "rollup error", logging.Fields{"error": err})
+	}
+	if err := ExportSheets(ctx, result.CreatedAt, portfolio.Summarize(result.Body), result.Body); err != nil {
+		logging.Error(ctx, "sheets export error", logging.Fields{"error": err})
 	}
 
-	res.StatusCode = http.StatusOK
-	res.Body = string(b)
-	return res, nil
-}
+	// daily snapshot to the history table, a failure doesn't stop the report
+	if table := cfg.HistoryTable; table != "" {
+		items := append(HistoryItems(result.CreatedAt, result.Body), HistoryTotalItem(result.CreatedAt, portfolio.Summarize(result.Body)))
+		if err := WriteHistory(ctx, table, items); err != nil {
+			logging.Error(ctx, "history write error", logging.Fields{"table": table, "error": err})
+		}
+	}
 
-// GetTickerSymbles is my stock symbole.
-func GetTickerSymbles(buf []byte) []Ticker {
-	var tickers []Ticker
+	// the statement of the month is on the last weekday, after the rollup of the day
+	var statement string
+	if cfg.StatementPath != "" && IsStatementDay(t) {
+		if statement, err = UploadStatement(ctx, result, t); err != nil {
+			logging.Error(ctx, "statement error", logging.Fields{"error": err})
+		}
+	}
 
-	for {
-		advance, token, err := bufio.ScanLines(buf, false)
+	// the tax report of the last fiscal year is once, on the first run of the fiscal year
+	if cfg.TaxPath != "" {
+		if err := UploadTaxReport(ctx, t); err != nil {
+			logging.Error(ctx, "tax report error", logging.Fields{"error": err})
+		}
+	}
+
+	// the weekly and monthly digest is sent instead of the daily report, the daily one when nothing is stored in the period
+	response := Response{Result: result, NotifyErrors: alertErrors, Failures: result.Errors}
+	var digested bool
+	if !quiet {
+		var digestErrors []NotifyError
+		digestErrors, digested = NotifyDigest(ctx, result, t)
+		response.NotifyErrors = append(response.NotifyErrors, digestErrors...)
+	}
+
+	// send notification
+	if !quiet && !digested {
+		var attachments []Attachment
+		if os.Getenv("ATTACH_JSON") == "true" {
+			attachments = append(attachments, Attachment{
+				Filename:    fmt.Sprintf("stock-profit-%s.json", result.CreatedAt),
+				ContentType: "application/json",
+				Data:        b,
+			})
+		}
+		if os.Getenv("ATTACH_CSV") == "true" {
+			if data, err := report.CSV(result); err != nil {
+				logging.Error(ctx, "csv attachment error", logging.Fields{"error": err})
+			} else {
+				// the byte order mark is make excel read it as utf-8
+				attachments = append(attachments, Attachment{
+					Filename:    fmt.Sprintf("stock-profit-%s.csv", result.CreatedAt),
+					ContentType: "text/csv; charset=UTF-8",
+					Data:        append([]byte("\xef\xbb\xbf"), data...),
+				})
+			}
+		}
+		// the chart is from the history, so today is in it already
+		var chart string
+		if os.Getenv("MAIL_CHART") == "true" {
+			if a, ok, err := ValueChartAttachment(ctx, t); err != nil {
+				logging.Error(ctx, "value chart error", logging.Fields{"error": err})
+			} else if ok {
+				attachments = append(attachments, a)
+				chart = a.ContentID
+			}
+		}
+		opts := report.HTMLOptions{Chart: chart, Statement: statement}
+		html, err := report.HTMLContent(result, opts)
 		if err != nil {
-			return nil
+			logging.Error(ctx, "html content error", logging.Fields{"error": err})
 		}
-		if advance == 0 {
-			break
+		text := report.MailContent(result)
+		if statement != "" {
+			text += fmt.Sprintf("\nMonthly statement: %s\n", statement)
 		}
+		// the bodies of the templates of the user in the bucket
+		text, html = CustomContent(ctx, result, opts, text, html)
+		response.NotifyErrors = append(response.NotifyErrors, Notify(ctx, Report{
+			Date:        result.CreatedAt,
+			Text:        text,
+			HTML:        html,
+			Summary:     portfolio.Summarize(result.Body),
+			Payload:     result,
+			Attachments: attachments,
+			Key:         filePath,
+		})...)
+	}
 
-		stocks := strings.Split(string(token), ",")
+	if quiet || digested {
+		response.NotifyErrors = append(response.NotifyErrors, NotifyFeed(ctx, result.CreatedAt, result, filePath)...)
+	}
+	if err := PostResult(ctx, result, time.Now()); err != nil {
+		logging.Error(ctx, "result webhook error", logging.Fields{"error": err})
+		response.NotifyErrors = append(response.NotifyErrors, NotifyError{Channel: "result_webhook", Error: err.Error()})
+		RecordNotify(ctx, "result_webhook", "", portfolio.NotifyFailed, err)
+	} else if os.Getenv("RESULT_WEBHOOK_URL") != "" {
+		RecordNotify(ctx, "result_webhook", "", portfolio.NotifySent, nil)
+	}
+
+	EmitMetrics(ctx, NotifyMetrics(response.NotifyErrors))
 
-		if len(stocks) == 4 {
-			symble := stocks[0]
-			bid, _ := strconv.ParseFloat(stocks[1], 64)
-			value, _ := strconv.ParseFloat(stocks[2], 64)
-			hold, _ := strconv.Atoi(stocks[3])
+	// response has the notifications of the run, the notification failures and the failed symbols too
+	run := *result.Run
+	run.Retries = quotes.Retries()
+	run.DurationMs = (fetch + time.Since(begin)).Milliseconds()
+	run.Notifications = NotifyOutcomes(ctx)
+	response.Run = &run
+	if b, err = json.Marshal(response); err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	if err := SaveRunMarker(ctx, t, filePath, b, false); err != nil {
+		logging.Error(ctx, "run marker error", logging.Fields{"error": err})
+	}
 
-			t := Ticker{
-				Symble: symble,
-				Bid:    bid,
-				Value:  value,
-				Hold:   hold,
+	return ResultResponse(ctx, result, b)
+}
+
+// QuietRun is check the report of the run is not notified, the change from the last run is under NOTIFY_MIN_CHANGE_PCT
+// or every market is closed (MARKET_CALENDAR=skip). The closed markets are marked in the result.
+func QuietRun(ctx context.Context, result *portfolio.Result, filePath string, t time.Time) bool {
+	var quiet bool
+	if min, _ := strconv.ParseFloat(os.Getenv("NOTIFY_MIN_CHANGE_PCT"), 64); min > 0 {
+		last, ok, err := LastRunResult(ctx, filePath, t)
+		if err != nil {
+			logging.Warn(ctx, "last result error", logging.Fields{"error": err})
+		} else if ok {
+			if change := portfolio.ProfitLossChange(last, *result); change < min {
+				logging.Info(ctx, "change is under NOTIFY_MIN_CHANGE_PCT, skip notification", logging.Fields{"change_pct": change, "min_pct": min})
+				quiet = true
 			}
-			tickers = append(tickers, t)
 		}
+	}
 
-		if advance <= len(buf) {
-			buf = buf[advance:]
+	// closed markets are marked, skip is no notification when every market is closed
+	if mode := os.Getenv("MARKET_CALENDAR"); mode != "" {
+		var all bool
+		result.MarketClosed, all = portfolio.ClosedMarkets(result.Body, t)
+		if all && mode == "skip" {
+			logging.Info(ctx, "market closed, skip notification", logging.Fields{"markets": strings.Join(result.MarketClosed, ",")})
+			quiet = true
 		}
 	}
-	return tickers
+	return quiet
 }
 
-// UploadFile is an uploader, make json file to S3 upload.
-func UploadFile(b []byte, t time.Time) error {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(endpoints.ApNortheast1RegionID),
-	})
+// NotifyDigest is send the weekly or monthly digest of REPORT_MODE instead of the daily report,
+// digested is false when the daily report is sent (the daily mode or nothing stored in the period).
+func NotifyDigest(ctx context.Context, result portfolio.Result, t time.Time) (errs []NotifyError, digested bool) {
+	mode := ReportMode(ctx)
+	if mode == "daily" {
+		return nil, false
+	}
+	digest, ok, err := DigestReport(ctx, mode, result, t)
 	if err != nil {
-		return err
+		logging.Error(ctx, "digest error", logging.Fields{"mode": mode, "error": err})
+		return nil, false
+	}
+	if !ok {
+		logging.Warn(ctx, "no result in the period, daily report is sent", logging.Fields{"mode": mode})
+		return nil, false
 	}
+	return Notify(ctx, digest), true
+}
 
-	filePath := fmt.Sprintf(os.Getenv("S3_FILE_PATH"), t.Year(), t.Month())
-	uploader := s3manager.NewUploader(sess)
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket: aws.String(os.Getenv("BUCKET")),
-		Key:    aws.String(filePath),
-		Body:   bytes.NewReader(b),
-	})
-	if err != nil {
-		return err
+// NotifyFeed is send the payload to sns on the quiet and digest days, sns is the feed of every result.
+func NotifyFeed(ctx context.Context, date string, payload interface{}, filePath string) []NotifyError {
+	if !NotifyChannels(os.Getenv("NOTIFY_CHANNELS"))["sns"] {
+		return nil
 	}
+	n := notifiers["sns"]()
+	if err := n.Notify(ctx, Report{Date: date, Payload: payload, Key: filePath}); err != nil {
+		logging.Error(ctx, "notify error", logging.Fields{"channel": n.Name(), "error": err})
+		RecordNotify(ctx, n.Name(), "", portfolio.NotifyFailed, err)
+		return []NotifyError{{Channel: n.Name(), Error: err.Error()}}
+	}
+	RecordNotify(ctx, n.Name(), "", portfolio.NotifySent, nil)
 	return nil
 }
 
-// DownloadFile get a stock data file
-func DownloadFile() ([]byte, error) {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(endpoints.ApNortheast1RegionID),
-	})
-	if err != nil {
-		return nil, err
+// UploadReport is upload the report in OUTPUT_FORMATS.
+func UploadReport(ctx context.Context, result portfolio.Result, b []byte, filePath string) error {
+	formats := report.OutputFormats(os.Getenv("OUTPUT_FORMATS"))
+	if formats["json"] {
+		if err := UploadFile(ctx, b, filePath); err != nil {
+			return err
+		}
 	}
-
-	filePath := os.Getenv("S3_STOCK_DATA")
-	svc := s3.New(sess)
-	obj, err := svc.GetObject(&s3.GetObjectInput{
-		Bucket: aws.String(os.Getenv("BUCKET")),
-		Key:    aws.String(filePath),
-	})
-	if err != nil {
-		return nil, err
+	if formats["csv"] {
+		c, err := report.CSV(result)
+		if err != nil {
+			return err
+		}
+		if err := UploadFile(ctx, c, report.CSVFilePath(filePath)); err != nil {
+			return err
+		}
 	}
-	defer obj.Body.Close()
 
-	buf := new(bytes.Buffer)
-	buf.ReadFrom(obj.Body)
+	// parquet is not compressed by GZIP_UPLOAD and replaced, for athena
+	if prefix := ConfigOf(ctx).ParquetPrefix; prefix != "" {
+		p, err := report.Parquet(result)
+		if err != nil {
+			return err
+		}
+		if err := storage.New(ConfigOf(ctx).Bucket).Put(ctx, report.ParquetFilePath(prefix, result.CreatedAt, filePath), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	return buf.Bytes(), nil
+// ErrorResponse is make json error response.
+func ErrorResponse(code int, message string) events.APIGatewayProxyResponse {
+	b, _ := json.Marshal(ErrorBody{Error: message, Code: code})
+	return events.APIGatewayProxyResponse{
+		StatusCode: code,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}
 }
 
-// GetStockPrice is get stock price from yahoo finance web page.
-func GetStockPrice(symbol Ticker, doneTicker chan Ticker) {
-	url := fmt.Sprintf("https://finance.yahoo.com/quote/%s", symbol.Symble)
-	selector := fmt.Sprintf("fin-streamer[data-symbol='%s'][data-field='regularMarketPrice']", symbol.Symble)
+// UploadFile is an uploader, make report file to S3 upload.
+func UploadFile(ctx context.Context, b []byte, filePath string) error {
+	store := storage.New(ConfigOf(ctx).Bucket)
 
-	ticker := Ticker{
-		Symble: symbol.Symble,
-		Bid:    symbol.Bid,
-		Value:  0.0,
-		Hold:   symbol.Hold,
+	// OVERWRITE_MODE: replace (default), skip or version
+	if mode := os.Getenv("OVERWRITE_MODE"); mode == "skip" || mode == "version" {
+		exists, err := store.Exists(ctx, filePath)
+		if err != nil {
+			return err
+		}
+		if exists && mode == "skip" {
+			logging.Info(ctx, "already exists, skip upload", logging.Fields{"url": store.URL(filePath)})
+			return nil
+		}
+		if exists {
+			filePath = VersionFilePath(filePath, time.Now().In(reportLocation))
+		}
 	}
 
-	c := colly.NewCollector()
-	c.OnHTML(selector, func(h *colly.HTMLElement) {
-		value, err := strconv.ParseFloat(h.Text, 64)
+	// GZIP_UPLOAD: the report is compressed, it is decompressed on read
+	if os.Getenv("GZIP_UPLOAD") == "true" {
+		gz, err := storage.Gzip(b)
 		if err != nil {
-			doneTicker <- Ticker{}
-			return
+			return err
 		}
-		ticker.Value = value
-	})
+		b = gz
+	}
+	return store.Put(ctx, filePath, b)
+}
 
-	c.OnError(func(r *colly.Response, err error) {
-		doneTicker <- Ticker{}
-	})
+// VersionFilePath is append timestamp to the key (e.g. 06.json -> 06-20210614150405.json).
+func VersionFilePath(filePath string, t time.Time) string {
+	ext := path.Ext(filePath)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(filePath, ext), t.Format("20060102150405"), ext)
+}
 
-	c.Visit(url)
+// ReportFilePath is make s3 key from S3_FILE_PATH.
+// "%d" style path is formatted with year and month, otherwise path is a Go time layout.
+func ReportFilePath(path string, t time.Time) string {
+	if strings.Contains(path, "%") {
+		return fmt.Sprintf(path, t.Year(), t.Month())
+	}
+	return t.Format(path)
+}
 
-	doneTicker <- ticker
+// DownloadFile get a stock data file
+func DownloadFile(ctx context.Context, bucket, filePath string) ([]byte, error) {
+	return storage.New(bucket).Get(ctx, filePath)
 }
 
 // send report mail
-func SenderMail(result Result) error {
-	sess, err := session.NewSession(&aws.Config{
-		Region: aws.String(endpoints.ApNortheast1RegionID),
-	})
+func SenderMail(ctx context.Context, subject string, report Report) error {
+	svc, err := awsclient.SES(Region("SES_REGION"))
 	if err != nil {
 		return err
 	}
 
-	var sum float64
-	var content string
-	for _, r := range result.Body {
-		earn := (r.Value - r.Bid) * float64(r.Hold)
-		c := fmt.Sprintf("%s %10.2f %10.2f %6d %10.2f\n",
-			r.Symble, r.Bid, r.Value, r.Hold, earn)
-		content = content + c
-		sum += earn
-	}
-	content = content + fmt.Sprintln(strings.Repeat("-", 30))
-	content = content + fmt.Sprintf("%sProfit Loss: %10.2f\n", strings.Repeat(" ", 27), sum)
-
-	svc := ses.New(sess)
 	input := &ses.SendEmailInput{
-		Destination: &ses.Destination{
-			ToAddresses: []*string{
-				aws.String(os.Getenv("MAIL_TO_ADDRESS")),
-			},
-		},
 		Message: &ses.Message{
 			Body: &ses.Body{
 				Text: &ses.Content{
 					Charset: aws.String("UTF-8"),
-					Data:    aws.String(content),
+					Data:    aws.String(report.Text),
 				},
 			},
 			Subject: &ses.Content{
 				Charset: aws.String("UTF-8"),
-				Data:    aws.String(os.Getenv("MAIL_SUBJECT")),
+				Data:    aws.String(subject),
 			},
 		},
-		Source: aws.String(os.Getenv("MAIL_SENDER_ADDRESS")),
+		Source: aws.String(ConfigOf(ctx).MailSender),
+	}
+
+	if report.HTML != "" {
+		input.Message.Body.Html = &ses.Content{
+			Charset: aws.String("UTF-8"),
+			Data:    aws.String(report.HTML),
+		}
+	}
+
+	// ses message has max 50 recipients, each chunk is a mail
+	cfg := ConfigOf(ctx)
+	dest := MailDestination{To: cfg.MailTo, Cc: cfg.MailCc, Bcc: cfg.MailBcc}
+	for _, d := range dest.Chunks(sesMaxRecipients) {
+		if err := sendMail(ctx, svc, input, d, subject, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendMail is send the mail to the destination, attachments need a raw mime message.
+func sendMail(ctx context.Context, svc sesiface.SESAPI, input *ses.SendEmailInput, dest MailDestination, subject string, report Report) error {
+	send := func() error {
+		in := *input
+		in.Destination = &ses.Destination{
+			ToAddresses:  aws.StringSlice(dest.To),
+			CcAddresses:  aws.StringSlice(dest.Cc),
+			BccAddresses: aws.StringSlice(dest.Bcc),
+		}
+		_, err := svc.SendEmailWithContext(ctx, &in)
+		return err
+	}
+	// the template is rendered by ses, the attachments need the raw message made here
+	if tmpl := MailTemplate(); tmpl != "" && len(report.Attachments) == 0 {
+		data, err := TemplateData(subject, report)
+		if err != nil {
+			return err
+		}
+		send = func() error {
+			_, err := svc.SendTemplatedEmailWithContext(ctx, &ses.SendTemplatedEmailInput{
+				Source: input.Source,
+				Destination: &ses.Destination{
+					ToAddresses:  aws.StringSlice(dest.To),
+					CcAddresses:  aws.StringSlice(dest.Cc),
+					BccAddresses: aws.StringSlice(dest.Bcc),
+				},
+				Template:     aws.String(tmpl),
+				TemplateData: aws.String(data),
+			})
+			return err
+		}
+	} else if len(report.Attachments) > 0 {
+		raw, err := RawMessage(*input.Source, dest, subject, report)
+		if err != nil {
+			return err
+		}
+		send = func() error {
+			_, err := svc.SendRawEmailWithContext(ctx, &ses.SendRawEmailInput{
+				Destinations: aws.StringSlice(append(append(append([]string{}, dest.To...), dest.Cc...), dest.Bcc...)),
+				RawMessage:   &ses.RawMessage{Data: raw},
+			})
+			return err
+		}
 	}
 
-	_, err = svc.SendEmail(input)
+	err := SendWithRetry(ctx, send)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			switch aerr.Code() {
@@ -276,6 +752,8 @@ func SenderMail(result Result) error {
 				return fmt.Errorf("%s, %s", ses.ErrCodeMailFromDomainNotVerifiedException, aerr.Error())
 			case ses.ErrCodeConfigurationSetDoesNotExistException:
 				return fmt.Errorf("%s, %s", ses.ErrCodeConfigurationSetDoesNotExistException, aerr.Error())
+			case ses.ErrCodeTemplateDoesNotExistException:
+				return fmt.Errorf("%s, %s", ses.ErrCodeTemplateDoesNotExistException, aerr.Error())
 			default:
 				return fmt.Errorf("%s", aerr.Error())
 			}
@@ -285,3 +763,44 @@ func SenderMail(result Result) error {
 	}
 	return nil
 }
+
+// mailRetries is max retry count of the mail send.
+const mailRetries = 3
+
+// mailBackoff is first wait of the mail send retry, it is doubled each retry.
+var mailBackoff = 500 * time.Millisecond
+
+// SendWithRetry is send the mail, throttling and 5xx error is retried with backoff.
+func SendWithRetry(ctx context.Context, send func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := send()
+		if err == nil || attempt >= mailRetries || !IsRetryableMailError(err) {
+			return err
+		}
+		wait := mailBackoff << attempt
+		logging.Warn(ctx, "send mail retry", logging.Fields{"attempt": attempt + 1, "wait_ms": wait.Milliseconds(), "error": err})
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+	}
+}
+
+// IsRetryableMailError is true for ses throttling and 5xx error.
+func IsRetryableMailError(err error) bool {
+	aerr, ok := err.(awserr.Error)
+	if !ok {
+		return false
+	}
+	switch aerr.Code() {
+	case ses.ErrCodeMessageRejected, ses.ErrCodeMailFromDomainNotVerifiedException:
+		return false
+	case "Throttling", "ThrottlingException":
+		return true
+	}
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		return rerr.StatusCode() >= 500
+	}
+	return false
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+func TestReportFilePathTimezoneMidnight(t *testing.T) {
+	// 2021-06-30 23:30 in UTC is 2021-07-01 08:30 in Tokyo, and 15:30 UTC is just past midnight there
+	tests := []struct {
+		timezone string
+		at       time.Time
+		want     string
+	}{
+		{"UTC", time.Date(2021, 6, 30, 15, 30, 0, 0, time.UTC), "stock/2021/06/30.json"},
+		{"Asia/Tokyo", time.Date(2021, 6, 30, 15, 30, 0, 0, time.UTC), "stock/2021/07/01.json"},
+		{"Asia/Tokyo", time.Date(2021, 6, 30, 14, 59, 0, 0, time.UTC), "stock/2021/06/30.json"},
+		{"America/New_York", time.Date(2021, 7, 1, 3, 0, 0, 0, time.UTC), "stock/2021/06/30.json"},
+	}
+	for _, tt := range tests {
+		loc := LoadReportLocation(tt.timezone)
+		if got := ReportFilePath("stock/2006/01/02.json", tt.at.In(loc)); got != tt.want {
+			t.Errorf("%s %s: ReportFilePath() = %q, want %q", tt.timezone, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestReportFilePathPercentLayout(t *testing.T) {
+	at := time.Date(2021, 12, 31, 15, 0, 0, 0, time.UTC).In(LoadReportLocation("Asia/Tokyo"))
+	if got, want := ReportFilePath("stock/%d/%02d.json", at), "stock/2022/01.json"; got != want {
+		t.Errorf("ReportFilePath() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadReportLocationInvalid(t *testing.T) {
+	if loc := LoadReportLocation("Mars/Olympus"); loc != time.UTC {
+		t.Errorf("LoadReportLocation() = %v, want UTC", loc)
+	}
+	if loc := LoadReportLocation(""); loc != time.Local {
+		t.Errorf("LoadReportLocation(\"\") = %v, want Local", loc)
+	}
+}
+
+func TestErrorResponseJSON(t *testing.T) {
+	response := ErrorResponse(http.StatusNotFound, "stock data file not found")
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode = %d, want 404", response.StatusCode)
+	}
+	if got := response.Headers["Content-Type"]; got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	var body ErrorBody
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		t.Fatalf("body %q is not json: %s", response.Body, err)
+	}
+	if body.Error != "stock data file not found" || body.Code != http.StatusNotFound {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestHandlerErrorBody(t *testing.T) {
+	t.Setenv("STOCK_API_KEY", "secret")
+	tests := []struct {
+		name    string
+		headers map[string]string
+	}{
+		{"bad api key", map[string]string{"stock-api-key": "wrong"}},
+		{"no api key", nil},
+	}
+	for _, tt := range tests {
+		response, err := Handler(context.Background(), events.APIGatewayProxyRequest{Headers: tt.headers})
+		if err != nil || response.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: Handler() = %d, %v, want 400 without the error", tt.name, response.StatusCode, err)
+		}
+		if got := response.Headers["Content-Type"]; got != "application/json" {
+			t.Errorf("%s: Content-Type = %q, want application/json", tt.name, got)
+		}
+		var body ErrorBody
+		if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+			t.Fatalf("%s: body %q is not json: %s", tt.name, response.Body, err)
+		}
+		if body.Error == "" || body.Code != http.StatusBadRequest {
+			t.Errorf("%s: body = %+v, want the error and code 400", tt.name, body)
+		}
+	}
+}
+
+func TestHandlerUploadJSONAndCSV(t *testing.T) {
+	fake := newFakeS3(t, "test-bucket")
+	quotePages(t, map[string]string{"AAPL": "120", "MSFT": "190"})
+	t.Setenv("STOCK_API_KEY", "")
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("S3_FILE_PATH", "stock/report.json")
+	t.Setenv("OUTPUT_FORMATS", "json,csv")
+	t.Setenv("NOTIFY_CHANNELS", "none")
+	fake.put("test-bucket", "data/stock.csv", strings.NewReader("AAPL,100,0,10\nMSFT,200,0,5\nXXXX,50,0,3\n"))
+
+	useConfig(t)
+	// XXXX is not found, the partial result is 207 with the failures
+	response, err := Handler(context.Background(), events.APIGatewayProxyRequest{})
+	if err != nil || response.StatusCode != http.StatusMultiStatus {
+		t.Fatalf("Handler() = %d %s, %v", response.StatusCode, response.Body, err)
+	}
+	var body Response
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil || len(body.Failures) != 1 || body.Failures[0].Symble != "XXXX" {
+		t.Errorf("failures of %s, want XXXX", response.Body)
+	}
+
+	var result portfolio.Result
+	b, _ := fake.Object("stock/report.json")
+	if err := json.Unmarshal(b, &result); err != nil || len(result.Body) != 3 {
+		t.Errorf("json report %q, %v, want the 3 symbols", b, err)
+	}
+	b, ok := fake.Object("stock/report.csv")
+	if !ok {
+		t.Fatalf("csv report is not written, keys %v", fake.Keys())
+	}
+	records, err := csv.NewReader(bytes.NewReader(b)).ReadAll()
+	if err != nil {
+		t.Fatalf("csv report: %s", err)
+	}
+	want := map[string][]string{
+		"AAPL": {"AAPL", "100", "120", "10", "200.00", "20.00", "1200.00", "55.81"},
+		"MSFT": {"MSFT", "200", "190", "5", "-50.00", "-5.00", "950.00", "44.19"},
+		// the unpriced symbol has no earnings
+		"XXXX": {"XXXX", "50", "0", "3", "", "", "", ""},
+	}
+	if len(records) != 4 || strings.Join(records[0], ",") != "symbol,bid,value,hold,earnings,percent,market_value,weight" {
+		t.Fatalf("csv report = %q, want the header and 3 symbols", records)
+	}
+	for _, r := range records[1:] {
+		if strings.Join(r, ",") != strings.Join(want[r[0]], ",") {
+			t.Errorf("csv line %q, want %q", r, want[r[0]])
+		}
+	}
+}
+
+func TestHandlerUploadDefaultJSON(t *testing.T) {
+	fake := newFakeS3(t, "test-bucket")
+	quotePages(t, map[string]string{"AAPL": "120"})
+	t.Setenv("STOCK_API_KEY", "")
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("S3_FILE_PATH", "stock/report.json")
+	t.Setenv("OUTPUT_FORMATS", "")
+	t.Setenv("NOTIFY_CHANNELS", "none")
+	fake.put("test-bucket", "data/stock.csv", strings.NewReader("AAPL,100,0,10\n"))
+
+	useConfig(t)
+	if response, err := Handler(context.Background(), events.APIGatewayProxyRequest{}); err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Handler() = %d %s, %v", response.StatusCode, response.Body, err)
+	}
+	if keys := strings.Join(fake.Keys(), ","); keys != "data/stock.csv,stock/report.json" {
+		t.Errorf("keys = %s, want the json report only", keys)
+	}
+}
+
+func TestDownloadFileNoSuchKey(t *testing.T) {
+	newFakeS3(t, "test-bucket")
+
+	_, err := DownloadFile(context.Background(), "test-bucket", "data/missing.csv")
+	if !errors.Is(err, storage.ErrNoSuchKey) {
+		t.Fatalf("DownloadFile() error = %v, want storage.ErrNoSuchKey", err)
+	}
+	if !strings.Contains(err.Error(), "s3://test-bucket/data/missing.csv") {
+		t.Errorf("DownloadFile() error = %q, want the bucket and the key", err)
+	}
+}
+
+func TestHandlerStockDataNoSuchKey(t *testing.T) {
+	newFakeS3(t, "test-bucket")
+	t.Setenv("STOCK_API_KEY", "")
+	t.Setenv("S3_STOCK_DATA", "data/missing.csv")
+
+	useConfig(t)
+	response, err := Handler(context.Background(), events.APIGatewayProxyRequest{})
+	if response.StatusCode != http.StatusNotFound {
+		t.Errorf("StatusCode = %d, want 404", response.StatusCode)
+	}
+	if !errors.Is(err, storage.ErrNoSuchKey) {
+		t.Errorf("Handler() error = %v, want storage.ErrNoSuchKey", err)
+	}
+}
+
+func TestHandlerEmptyWatchlist(t *testing.T) {
+	fake := newFakeS3(t, "test-bucket")
+	t.Setenv("STOCK_API_KEY", "")
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("S3_FILE_PATH", "stock/report.json")
+	t.Setenv("NOTIFY_CHANNELS", "none")
+	fake.put("test-bucket", "data/stock.csv", strings.NewReader("# nothing yet\n\n"))
+
+	useConfig(t)
+	response, err := Handler(context.Background(), events.APIGatewayProxyRequest{})
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("StatusCode = %d, want 400", response.StatusCode)
+	}
+	if !errors.Is(err, portfolio.ErrEmptyWatchlist) || !strings.Contains(response.Body, "no valid tickers in watchlist") {
+		t.Errorf("Handler() = %s, %v, want the empty watchlist", response.Body, err)
+	}
+	if keys := fake.Keys(); len(keys) != 1 {
+		t.Errorf("keys = %v, want no report", keys)
+	}
+
+	t.Setenv("ALLOW_EMPTY_REPORT", "true")
+	if response, err := Handler(context.Background(), events.APIGatewayProxyRequest{}); err != nil || response.StatusCode != http.StatusOK {
+		t.Errorf("ALLOW_EMPTY_REPORT: Handler() = %d, %v, want 200", response.StatusCode, err)
+	}
+	if _, ok := fake.Object("stock/report.json"); !ok {
+		t.Errorf("ALLOW_EMPTY_REPORT: keys = %v, want the empty report", fake.Keys())
+	}
+}
+
+func TestUploadFileOverwriteMode(t *testing.T) {
+	tests := []struct {
+		mode string
+		want map[string]string
+	}{
+		{"", map[string]string{"stock/14.json": "new"}},
+		{"replace", map[string]string{"stock/14.json": "new"}},
+		{"skip", map[string]string{"stock/14.json": "old"}},
+		{"version", map[string]string{"stock/14.json": "old", "stock/14-*.json": "new"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			fake := newFakeS3(t, "test-bucket")
+			fake.put("test-bucket", "stock/14.json", strings.NewReader("old"))
+			t.Setenv("OVERWRITE_MODE", tt.mode)
+
+			useConfig(t)
+			if err := UploadFile(context.Background(), []byte("new"), "stock/14.json"); err != nil {
+				t.Fatalf("UploadFile() error = %v", err)
+			}
+			keys := fake.Keys()
+			if len(keys) != len(tt.want) {
+				t.Fatalf("keys = %v, want %d", keys, len(tt.want))
+			}
+			for _, key := range keys {
+				pattern := key
+				if strings.HasPrefix(key, "stock/14-") {
+					pattern = "stock/14-*.json"
+				}
+				b, _ := fake.Object(key)
+				if want, ok := tt.want[pattern]; !ok || string(b) != want {
+					t.Errorf("%s = %q, want %q", key, b, want)
+				}
+			}
+		})
+	}
+
+	// the key which doesn't exist is written in every mode
+	fake := newFakeS3(t, "test-bucket")
+	t.Setenv("OVERWRITE_MODE", "skip")
+	useConfig(t)
+	if err := UploadFile(context.Background(), []byte("new"), "stock/15.json"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if b, ok := fake.Object("stock/15.json"); !ok || string(b) != "new" {
+		t.Errorf("stock/15.json = %q, %v, want new", b, ok)
+	}
+}
+
+func TestVersionFilePath(t *testing.T) {
+	at := time.Date(2021, 6, 14, 15, 4, 5, 0, time.UTC)
+	if got, want := VersionFilePath("stock/2021/06.json", at), "stock/2021/06-20210614150405.json"; got != want {
+		t.Errorf("VersionFilePath() = %q, want %q", got, want)
+	}
+}
+
+func TestResultStatus(t *testing.T) {
+	priced := portfolio.Ticker{Symble: "AAPL", Bid: 100, Value: 120, Hold: 1}
+	failed := portfolio.Ticker{Symble: "XXXX", Bid: 50, Hold: 1, Error: "price not found"}
+	tests := []struct {
+		tickers []portfolio.Ticker
+		want    int
+	}{
+		{[]portfolio.Ticker{priced}, http.StatusOK},
+		{[]portfolio.Ticker{priced, failed}, http.StatusMultiStatus},
+		{[]portfolio.Ticker{failed}, http.StatusBadGateway},
+	}
+	for _, tt := range tests {
+		result := portfolio.NewResult("2021-06-14", tt.tickers)
+		if got := ResultStatus(result); got != tt.want {
+			t.Errorf("ResultStatus(%d of %d failed) = %d, want %d", len(result.Errors), len(tt.tickers), got, tt.want)
+		}
+		batch := BatchResult{Summary: portfolio.Summarize(result.Body), Errors: result.Errors}
+		if got := BatchStatus(batch); got != tt.want {
+			t.Errorf("BatchStatus(%d of %d failed) = %d, want %d", len(result.Errors), len(tt.tickers), got, tt.want)
+		}
+	}
+}
+
+func TestUploadFileConfigOf(t *testing.T) {
+	global := newFakeS3(t, "test-bucket")
+	useConfig(t)
+	tenant := newFakeS3(t, "tenant-bucket")
+	t.Setenv("RUN_MARKER_PREFIX", "runs")
+	ctx := WithConfig(context.Background(), Config{Bucket: "tenant-bucket", Tenant: "alice"})
+	at := time.Date(2021, 6, 14, 9, 0, 0, 0, time.UTC)
+
+	if err := UploadFile(ctx, []byte("new"), "alice/14.json"); err != nil {
+		t.Fatalf("UploadFile() error = %v", err)
+	}
+	if err := SaveRunMarker(ctx, at, "alice/14.json", []byte(`{}`), false); err != nil {
+		t.Fatalf("SaveRunMarker() error = %v", err)
+	}
+	if marker, ok, err := LoadRunMarker(ctx, at); err != nil || !ok || marker.Key != "alice/14.json" {
+		t.Errorf("LoadRunMarker() = %+v %v %v, want the marker of alice/14.json", marker, ok, err)
+	}
+	if keys := tenant.Keys(); strings.Join(keys, ",") != "alice/14.json,alice/runs/2021-06-14.json" {
+		t.Errorf("keys of the bucket of the context = %v, want the report and the marker", keys)
+	}
+	if keys := global.Keys(); len(keys) != 0 {
+		t.Errorf("keys of BUCKET = %v, want none", keys)
+	}
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/tora0091/stock-profit/awsclient"
+)
+
+// encryptedMagic is the header of the client side encrypted object.
+var encryptedMagic = []byte("SPENC1")
+
+// ErrDecrypt is returned when the encrypted object can't be decrypted.
+var ErrDecrypt = errors.New("object decryption error")
+
+// KeyService is the data keys of the envelope encryption.
+type KeyService interface {
+	// GenerateDataKey is a new aes-256 data key, the plain key and the key encrypted by the master key
+	GenerateDataKey(ctx context.Context) (plain, encrypted []byte, err error)
+	// Decrypt is the plain key of the encrypted one
+	Decrypt(ctx context.Context, encrypted []byte) ([]byte, error)
+}
+
+// maxDataKeys is the decrypted data keys kept by KMSKeys, each written object has a data key of its own.
+const maxDataKeys = 64
+
+// KMSKeys is the data keys of the kms key, the last maxDataKeys decrypted keys are kept while the lambda is warm.
+type KMSKeys struct {
+	KeyID  string
+	Region string
+
+	mu    sync.Mutex
+	plain map[string][]byte
+	// order is the encrypted keys of plain, the oldest first
+	order []string
+}
+
+// NewKMSKeys is the keys of the kms key id (or arn or alias), the region of the arn or Region.
+func NewKMSKeys(keyID string) *KMSKeys {
+	region := Region()
+	// arn:aws:kms:region:account:key/id
+	if parts := strings.Split(keyID, ":"); len(parts) > 3 && parts[0] == "arn" {
+		region = parts[3]
+	}
+	return &KMSKeys{KeyID: keyID, Region: region, plain: map[string][]byte{}}
+}
+
+// GenerateDataKey is a new data key of the kms key.
+func (k *KMSKeys) GenerateDataKey(ctx context.Context) ([]byte, []byte, error) {
+	svc, err := awsclient.KMS(k.Region)
+	if err != nil {
+		return nil, nil, err
+	}
+	out, err := svc.GenerateDataKeyWithContext(ctx, &kms.GenerateDataKeyInput{
+		KeyId:   aws.String(k.KeyID),
+		KeySpec: aws.String(kms.DataKeySpecAes256),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return out.Plaintext, out.CiphertextBlob, nil
+}
+
+// Decrypt is the plain key by kms, once for each key while it is kept.
+func (k *KMSKeys) Decrypt(ctx context.Context, encrypted []byte) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if plain, ok := k.plain[string(encrypted)]; ok {
+		return plain, nil
+	}
+	svc, err := awsclient.KMS(k.Region)
+	if err != nil {
+		return nil, err
+	}
+	out, err := svc.DecryptWithContext(ctx, &kms.DecryptInput{CiphertextBlob: encrypted})
+	if err != nil {
+		return nil, err
+	}
+	if len(k.order) >= maxDataKeys {
+		delete(k.plain, k.order[0])
+		k.order = k.order[1:]
+	}
+	k.plain[string(encrypted)] = out.Plaintext
+	k.order = append(k.order, string(encrypted))
+	return out.Plaintext, nil
+}
+
+// IsEncrypted is check b starts with the header of the encrypted object.
+func IsEncrypted(b []byte) bool {
+	return bytes.HasPrefix(b, encryptedMagic)
+}
+
+// Encrypt is b encrypted by a new data key of the keys (aes-256-gcm).
+// The object is the header, the length of the encrypted data key (2 bytes), the encrypted data key, the nonce and the sealed b.
+func Encrypt(ctx context.Context, keys KeyService, b []byte) ([]byte, error) {
+	plain, encrypted, err := keys.GenerateDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(plain)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	out := append([]byte{}, encryptedMagic...)
+	size := make([]byte, 2)
+	binary.BigEndian.PutUint16(size, uint16(len(encrypted)))
+	out = append(out, size...)
+	out = append(out, encrypted...)
+	out = append(out, nonce...)
+	return gcm.Seal(out, nonce, b, encryptedMagic), nil
+}
+
+// Decrypt is the plain object of the encrypted one, b as it is when it isn't encrypted.
+func Decrypt(ctx context.Context, keys KeyService, b []byte, name string) ([]byte, error) {
+	if !IsEncrypted(b) {
+		return b, nil
+	}
+	rest := b[len(encryptedMagic):]
+	if len(rest) < 2 {
+		return nil, fmt.Errorf("%w: %s is truncated", ErrDecrypt, name)
+	}
+	n := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+	if len(rest) < n {
+		return nil, fmt.Errorf("%w: %s is truncated", ErrDecrypt, name)
+	}
+	plain, err := keys.Decrypt(ctx, rest[:n])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrDecrypt, name, err)
+	}
+	gcm, err := newGCM(plain)
+	if err != nil {
+		return nil, err
+	}
+	rest = rest[n:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: %s is truncated", ErrDecrypt, name)
+	}
+	out, err := gcm.Open(nil, rest[:gcm.NonceSize()], rest[gcm.NonceSize():], encryptedMagic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %s", ErrDecrypt, name, err)
+	}
+	return out, nil
+}
+
+// newGCM is aes-gcm of the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// EncryptedStorage is the storage whose objects are encrypted on the client side (CLIENT_ENCRYPTION_KEY_ID).
+// A plain object is still read, it is encrypted when it is written again.
+type EncryptedStorage struct {
+	Storage
+	Keys KeyService
+}
+
+// Get is the decrypted object.
+func (s *EncryptedStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := s.Storage.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	b, err = Decrypt(ctx, s.Keys, b, s.URL(key))
+	if err != nil {
+		return nil, err
+	}
+	return gunzipLimited(b, s.URL(key))
+}
+
+// Put is upload the encrypted object.
+func (s *EncryptedStorage) Put(ctx context.Context, key string, b []byte) error {
+	encrypted, err := Encrypt(ctx, s.Keys, b)
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.URL(key), err)
+	}
+	return s.Storage.Put(ctx, key, encrypted)
+}
+
+// GetVersion is the decrypted object and the version of the encrypted one.
+func (s *EncryptedStorage) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	b, version, err := s.Storage.GetVersion(ctx, key)
+	if err != nil {
+		return nil, "", err
+	}
+	b, err = Decrypt(ctx, s.Keys, b, s.URL(key))
+	if err != nil {
+		return nil, "", err
+	}
+	b, err = gunzipLimited(b, s.URL(key))
+	return b, version, err
+}
+
+// PutIf is upload the encrypted object when the key is still the version.
+func (s *EncryptedStorage) PutIf(ctx context.Context, key string, b []byte, version string) error {
+	encrypted, err := Encrypt(ctx, s.Keys, b)
+	if err != nil {
+		return fmt.Errorf("%s: %s", s.URL(key), err)
+	}
+	return s.Storage.PutIf(ctx, key, encrypted, version)
+}
+
+// Presign is an error, the link would be the encrypted object.
+func (s *EncryptedStorage) Presign(key string, expires time.Duration) (string, error) {
+	return "", fmt.Errorf("%s is encrypted, it can't be presigned", s.URL(key))
+}
@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/kms"
+	"github.com/aws/aws-sdk-go/service/kms/kmsiface"
+	"github.com/tora0091/stock-profit/awsclient"
+)
+
+// wrappedPrefix is the data key "encrypted" by fakeKMS.
+var wrappedPrefix = []byte("wrapped:")
+
+// fakeKMS is the data keys of kms in memory, the encrypted key is the plain one with wrappedPrefix.
+type fakeKMS struct {
+	kmsiface.KMSAPI
+
+	mu       sync.Mutex
+	decrypts int
+}
+
+func (f *fakeKMS) GenerateDataKeyWithContext(ctx aws.Context, input *kms.GenerateDataKeyInput, opts ...request.Option) (*kms.GenerateDataKeyOutput, error) {
+	plain := make([]byte, 32)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, err
+	}
+	return &kms.GenerateDataKeyOutput{KeyId: input.KeyId, Plaintext: plain, CiphertextBlob: append(append([]byte{}, wrappedPrefix...), plain...)}, nil
+}
+
+func (f *fakeKMS) DecryptWithContext(ctx aws.Context, input *kms.DecryptInput, opts ...request.Option) (*kms.DecryptOutput, error) {
+	f.mu.Lock()
+	f.decrypts++
+	f.mu.Unlock()
+	if !bytes.HasPrefix(input.CiphertextBlob, wrappedPrefix) || len(input.CiphertextBlob) != len(wrappedPrefix)+32 {
+		return nil, fmt.Errorf("InvalidCiphertextException")
+	}
+	return &kms.DecryptOutput{Plaintext: input.CiphertextBlob[len(wrappedPrefix):]}, nil
+}
+
+// Decrypts is the number of the Decrypt calls.
+func (f *fakeKMS) Decrypts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.decrypts
+}
+
+// useFakeKMS is the local storage of the test encrypted by the fake kms of CLIENT_ENCRYPTION_KEY_ID.
+func useFakeKMS(t *testing.T) (*fakeKMS, string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("STORAGE", "local")
+	t.Setenv("STORAGE_DIR", dir)
+	t.Setenv("S3_REGION", "ap-northeast-1")
+	t.Setenv("CLIENT_ENCRYPTION_KEY_ID", "alias/"+t.Name())
+	f := &fakeKMS{}
+	awsclient.SetKMS("ap-northeast-1", f)
+	t.Cleanup(func() { awsclient.SetKMS("ap-northeast-1", nil) })
+	return f, filepath.Join(dir, "test-bucket")
+}
+
+func TestEncryptedStorage(t *testing.T) {
+	ctx := context.Background()
+	f, dir := useFakeKMS(t)
+	s := New("test-bucket")
+	if _, ok := s.(*EncryptedStorage); !ok {
+		t.Fatalf("New() = %T, want *EncryptedStorage", s)
+	}
+
+	plain := []byte("AAPL,100,0,10\n")
+	if err := s.Put(ctx, "data/stock.csv", plain); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "data", "stock.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(raw) || bytes.Contains(raw, plain) {
+		t.Errorf("stored object = %q, want it encrypted", raw)
+	}
+	if b, err := s.Get(ctx, "data/stock.csv"); err != nil || !bytes.Equal(b, plain) {
+		t.Errorf("Get() = %q %v, want %q", b, err, plain)
+	}
+
+	b, version, err := s.GetVersion(ctx, "data/stock.csv")
+	if err != nil || !bytes.Equal(b, plain) {
+		t.Fatalf("GetVersion() = %q %v, want %q", b, err, plain)
+	}
+	next := append(b, "MSFT,200,0,5\n"...)
+	if err := s.PutIf(ctx, "data/stock.csv", next, version); err != nil {
+		t.Fatalf("PutIf() error = %v", err)
+	}
+	if err := s.PutIf(ctx, "data/stock.csv", next, version); !errors.Is(err, ErrConflict) {
+		t.Errorf("PutIf() of the old version error = %v, want ErrConflict", err)
+	}
+	if b, err := s.Get(ctx, "data/stock.csv"); err != nil || !bytes.Equal(b, next) {
+		t.Errorf("Get() = %q %v, want %q", b, err, next)
+	}
+	// the data key of the object is decrypted once
+	if n := f.Decrypts(); n != 2 {
+		t.Errorf("kms Decrypt calls = %d, want 2 (one for each written object)", n)
+	}
+}
+
+func TestEncryptedStoragePlainObject(t *testing.T) {
+	ctx := context.Background()
+	_, dir := useFakeKMS(t)
+	plain := []byte(`{"body": []}`)
+	if err := os.MkdirAll(filepath.Join(dir, "stock"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stock", "report.json"), plain, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gz, err := Gzip(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "stock", "report.json.gz"), gz, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New("test-bucket")
+	for _, key := range []string{"stock/report.json", "stock/report.json.gz"} {
+		if b, err := s.Get(ctx, key); err != nil || !bytes.Equal(b, plain) {
+			t.Errorf("Get(%s) = %q %v, want the plain object", key, b, err)
+		}
+	}
+}
+
+func TestDecryptTampered(t *testing.T) {
+	ctx := context.Background()
+	useFakeKMS(t)
+	keys := NewKMSKeys("alias/" + t.Name())
+	encrypted, err := Encrypt(ctx, keys, []byte("AAPL,100,0,10\n"))
+	if err != nil {
+		t.Fatalf("Encrypt() error = %v", err)
+	}
+	header := len(encryptedMagic) + 2
+	keySize := len(wrappedPrefix) + 32
+
+	tamper := func(i int) []byte {
+		b := append([]byte{}, encrypted...)
+		b[i] ^= 0x01
+		return b
+	}
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"key length", tamper(len(encryptedMagic) + 1)},
+		{"data key", tamper(header + keySize - 1)},
+		{"nonce", tamper(header + keySize)},
+		{"ciphertext", tamper(len(encrypted) - 20)},
+		{"tag", tamper(len(encrypted) - 1)},
+		{"truncated header", encrypted[:len(encryptedMagic)+1]},
+		{"truncated key", encrypted[:header+keySize-1]},
+		{"truncated nonce", encrypted[:header+keySize+4]},
+	}
+	for _, tt := range tests {
+		// each data key is decrypted again, not of the cache of the untampered one
+		if b, err := Decrypt(ctx, NewKMSKeys(keys.KeyID), tt.b, "stock.csv"); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Decrypt(%s) = %q %v, want ErrDecrypt", tt.name, b, err)
+		}
+	}
+	if b, err := Decrypt(ctx, keys, encrypted, "stock.csv"); err != nil || string(b) != "AAPL,100,0,10\n" {
+		t.Errorf("Decrypt() = %q %v, want the plain object", b, err)
+	}
+}
+
+func TestKMSKeysCache(t *testing.T) {
+	ctx := context.Background()
+	f, _ := useFakeKMS(t)
+	keys := NewKMSKeys("alias/" + t.Name())
+
+	var first []byte
+	for i := 0; i <= maxDataKeys; i++ {
+		_, encrypted, err := keys.GenerateDataKey(ctx)
+		if err != nil {
+			t.Fatalf("GenerateDataKey() error = %v", err)
+		}
+		if i == 0 {
+			first = encrypted
+		}
+		for j := 0; j < 2; j++ {
+			if _, err := keys.Decrypt(ctx, encrypted); err != nil {
+				t.Fatalf("Decrypt() error = %v", err)
+			}
+		}
+	}
+	if n := f.Decrypts(); n != maxDataKeys+1 {
+		t.Errorf("kms Decrypt calls = %d, want %d (once for each key)", n, maxDataKeys+1)
+	}
+	if len(keys.plain) != maxDataKeys || len(keys.order) != maxDataKeys {
+		t.Errorf("cached keys = %d %d, want %d", len(keys.plain), len(keys.order), maxDataKeys)
+	}
+	// the oldest key was dropped
+	if _, err := keys.Decrypt(ctx, first); err != nil || f.Decrypts() != maxDataKeys+2 {
+		t.Errorf("Decrypt() of the oldest key = %v, %d calls, want it decrypted by kms again", err, f.Decrypts())
+	}
+}
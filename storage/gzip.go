@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// IsGzip is check b starts with the gzip header.
+func IsGzip(b []byte) bool {
+	return len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b
+}
+
+// Gzip is compress b, it is uploaded with Content-Encoding: gzip.
+func Gzip(b []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write(b); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipLimited is Gunzip up to MaxObjectSize of the decompressed file.
+func gunzipLimited(b []byte, name string) ([]byte, error) {
+	if !IsGzip(b) {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	defer r.Close()
+	return readLimited(r, -1, MaxObjectSize(), name)
+}
+
+// Gunzip is decompress b when it is gzip, otherwise b as it is.
+func Gunzip(b []byte) ([]byte, error) {
+	if !IsGzip(b) {
+		return b, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// ErrTooLarge is returned when the object is over S3_MAX_OBJECT_SIZE.
+var ErrTooLarge = errors.New("object is too large")
+
+// ErrChecksum is returned when the object doesn't match its checksum (S3_VERIFY_CHECKSUM).
+var ErrChecksum = errors.New("object checksum mismatch")
+
+// defaultMaxObjectSize is max size of the object, a watchlist or a report is far smaller.
+const defaultMaxObjectSize = 32 << 20
+
+// checksumMetadata is user metadata of the sha256 of the uploaded object.
+const checksumMetadata = "sha256"
+
+// MaxObjectSize is S3_MAX_OBJECT_SIZE in bytes, default 32MB. It is the limit of the decompressed file too.
+func MaxObjectSize() int64 {
+	if n, err := strconv.ParseInt(os.Getenv("S3_MAX_OBJECT_SIZE"), 10, 64); err == nil && n > 0 {
+		return n
+	}
+	return defaultMaxObjectSize
+}
+
+// VerifyChecksum is S3_VERIFY_CHECKSUM, the object is checked by its sha256 metadata or the md5 etag.
+func VerifyChecksum() bool {
+	return os.Getenv("S3_VERIFY_CHECKSUM") == "true"
+}
+
+// Checksum is hex of the sha256 of b, it is the sha256 metadata of the upload.
+func Checksum(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// metadata is the user metadata of the key, the sdk has it in the canonical header case (e.g. Sha256).
+func metadata(m map[string]*string, key string) string {
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return aws.StringValue(v)
+		}
+	}
+	return ""
+}
+
+// readLimited is read r up to max bytes, ErrTooLarge over it.
+// size is the content length (-1 unknown), a shorter body is an error.
+func readLimited(r io.Reader, size, max int64, name string) ([]byte, error) {
+	if size > max {
+		return nil, fmt.Errorf("%w: %s is %d bytes, max %d", ErrTooLarge, name, size, max)
+	}
+	b, err := io.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	if int64(len(b)) > max {
+		return nil, fmt.Errorf("%w: %s is over %d bytes", ErrTooLarge, name, max)
+	}
+	if size >= 0 && int64(len(b)) != size {
+		return nil, fmt.Errorf("%s: read %d bytes of %d", name, len(b), size)
+	}
+	return b, nil
+}
+
+// verify is check b by the sha256 metadata, otherwise by the etag when it is the md5 (not multipart or kms).
+func verify(b []byte, sha, etag string, kms bool, name string) error {
+	if sha != "" {
+		if Checksum(b) != sha {
+			return fmt.Errorf("%w: %s sha256", ErrChecksum, name)
+		}
+		return nil
+	}
+	etag = strings.Trim(etag, `"`)
+	if etag == "" || kms || strings.Contains(etag, "-") {
+		return nil
+	}
+	sum := md5.Sum(b)
+	if hex.EncodeToString(sum[:]) != etag {
+		return fmt.Errorf("%w: %s etag", ErrChecksum, name)
+	}
+	return nil
+}
@@ -0,0 +1,449 @@
+// Package storage is the store of the stock data and the reports, s3 or local directory.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+	"github.com/tora0091/stock-profit/awsclient"
+)
+
+// ErrNoSuchKey is returned when the stock data file is missing.
+var ErrNoSuchKey = errors.New("stock data file not found")
+
+// ErrConflict is returned by PutIf when the key was written after its version was read.
+var ErrConflict = errors.New("object was changed by another writer")
+
+// Storage is a store of the stock data and the reports.
+type Storage interface {
+	// Get is the file of the key, ErrNoSuchKey when it doesn't exist
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, b []byte) error
+	Exists(ctx context.Context, key string) (bool, error)
+	// Modified is last modified time of the key, ErrNoSuchKey when it doesn't exist
+	Modified(ctx context.Context, key string) (time.Time, error)
+	// URL is location of the key for the log
+	URL(key string) string
+	// Presign is temporary download link of the key
+	Presign(key string, expires time.Duration) (string, error)
+	// List is the keys under the prefix, in the order of the keys
+	List(ctx context.Context, prefix string) ([]string, error)
+	// GetVersion is the file of the key like Get and its version for PutIf
+	GetVersion(ctx context.Context, key string) ([]byte, string, error)
+	// PutIf is put the file only when the key is still the version (empty version is the key doesn't exist), ErrConflict when it isn't
+	PutIf(ctx context.Context, key string, b []byte, version string) error
+}
+
+// New is the storage of the bucket by STORAGE, s3 (default) or local.
+// Local is files under STORAGE_DIR/bucket (default current directory).
+// The objects are encrypted on the client side by the kms key of CLIENT_ENCRYPTION_KEY_ID when it is set.
+func New(bucket string) Storage {
+	var s Storage
+	if os.Getenv("STORAGE") == "local" {
+		dir := os.Getenv("STORAGE_DIR")
+		if dir == "" {
+			dir = "."
+		}
+		s = &LocalStorage{Dir: filepath.Join(dir, bucket)}
+	} else {
+		s = &S3Storage{Bucket: bucket, Region: Region(), SSE: os.Getenv("S3_SSE"), KMSKeyID: os.Getenv("S3_KMS_KEY_ID")}
+	}
+	if keyID := os.Getenv("CLIENT_ENCRYPTION_KEY_ID"); keyID != "" {
+		return &EncryptedStorage{Storage: s, Keys: kmsKeys(keyID)}
+	}
+	return s
+}
+
+var (
+	keysMu sync.Mutex
+	keys   = map[string]*KMSKeys{}
+)
+
+// kmsKeys is the shared keys of the kms key, the decrypted data keys are kept across the storages.
+func kmsKeys(keyID string) *KMSKeys {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	if k, ok := keys[keyID]; ok {
+		return k
+	}
+	k := NewKMSKeys(keyID)
+	keys[keyID] = k
+	return k
+}
+
+// Region is region of the bucket, S3_REGION or AWS_REGION (the region of the lambda). Default is ap-northeast-1.
+func Region() string {
+	if region := os.Getenv("S3_REGION"); region != "" {
+		return region
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return endpoints.ApNortheast1RegionID
+}
+
+// S3Storage is a s3 bucket.
+// Region is region of the bucket, SSE is server side encryption of the uploads (AES256 or aws:kms), KMSKeyID is the kms key of aws:kms (default is the aws managed key).
+type S3Storage struct {
+	Bucket   string
+	Region   string
+	SSE      string
+	KMSKeyID string
+}
+
+// client is the shared s3 client of the region of the storage.
+func (s *S3Storage) client() (s3iface.S3API, error) {
+	return awsclient.S3(s.Region)
+}
+
+// Get is get the object.
+func (s *S3Storage) Get(ctx context.Context, key string) ([]byte, error) {
+	b, _, err := s.GetVersion(ctx, key)
+	return b, err
+}
+
+// GetVersion is get the object and its etag.
+func (s *S3Storage) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	svc, err := s.client()
+	if err != nil {
+		return nil, "", err
+	}
+
+	obj, err := svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, "", fmt.Errorf("%w: %s", ErrNoSuchKey, s.URL(key))
+		}
+		return nil, "", err
+	}
+	defer obj.Body.Close()
+
+	// a truncated, corrupted or huge object is an error, not a garbage report
+	size := int64(-1)
+	if obj.ContentLength != nil {
+		size = *obj.ContentLength
+	}
+	b, err := readLimited(obj.Body, size, MaxObjectSize(), s.URL(key))
+	if err != nil {
+		return nil, "", err
+	}
+	// the checksum is of the gzip, unless the http client decompressed it already
+	if VerifyChecksum() && (aws.StringValue(obj.ContentEncoding) != "gzip" || IsGzip(b)) {
+		kms := aws.StringValue(obj.ServerSideEncryption) == s3.ServerSideEncryptionAwsKms
+		if err := verify(b, metadata(obj.Metadata, checksumMetadata), aws.StringValue(obj.ETag), kms, s.URL(key)); err != nil {
+			return nil, "", err
+		}
+	}
+	b, err = gunzipLimited(b, s.URL(key))
+	return b, aws.StringValue(obj.ETag), err
+}
+
+// Put is upload the object.
+func (s *S3Storage) Put(ctx context.Context, key string, b []byte) error {
+	svc, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(b),
+		// the checksum is verified on get by S3_VERIFY_CHECKSUM
+		Metadata: map[string]*string{checksumMetadata: aws.String(Checksum(b))},
+	}
+	if IsGzip(b) {
+		input.ContentEncoding = aws.String("gzip")
+	}
+	// kms encrypted object is decrypted by s3 on get, kms:Decrypt of the key is needed
+	if s.SSE != "" {
+		input.ServerSideEncryption = aws.String(s.SSE)
+	}
+	if s.KMSKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(s.KMSKeyID)
+	}
+
+	uploader := s3manager.NewUploaderWithClient(svc)
+	_, err = uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+// PutIf is put the object with If-Match of the etag, or If-None-Match when the version is empty.
+// The object is one request, not the multipart upload of Put.
+func (s *S3Storage) PutIf(ctx context.Context, key string, b []byte, version string) error {
+	svc, err := s.client()
+	if err != nil {
+		return err
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket:   aws.String(s.Bucket),
+		Key:      aws.String(key),
+		Body:     bytes.NewReader(b),
+		Metadata: map[string]*string{checksumMetadata: aws.String(Checksum(b))},
+	}
+	if IsGzip(b) {
+		input.ContentEncoding = aws.String("gzip")
+	}
+	if s.SSE != "" {
+		input.ServerSideEncryption = aws.String(s.SSE)
+	}
+	if s.KMSKeyID != "" {
+		input.ServerSideEncryption = aws.String(s3.ServerSideEncryptionAwsKms)
+		input.SSEKMSKeyId = aws.String(s.KMSKeyID)
+	}
+	header := map[string]string{"If-None-Match": "*"}
+	if version != "" {
+		header = map[string]string{"If-Match": version}
+	}
+
+	_, err = svc.PutObjectWithContext(ctx, input, request.WithSetRequestHeaders(header))
+	if rerr, ok := err.(awserr.RequestFailure); ok {
+		// 412 is the other etag, 409 the concurrent conditional put and 404 the deleted object of If-Match
+		switch rerr.StatusCode() {
+		case http.StatusPreconditionFailed, http.StatusConflict, http.StatusNotFound:
+			return fmt.Errorf("%w: %s", ErrConflict, s.URL(key))
+		}
+	}
+	return err
+}
+
+// Exists is check the key exists in the bucket.
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	svc, err := s.client()
+	if err != nil {
+		return false, err
+	}
+
+	_, err = svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Modified is last modified time of the object.
+func (s *S3Storage) Modified(ctx context.Context, key string) (time.Time, error) {
+	svc, err := s.client()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	out, err := svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if aerr, ok := err.(awserr.Error); ok && aerr.Code() == "NotFound" {
+			return time.Time{}, fmt.Errorf("%w: %s", ErrNoSuchKey, s.URL(key))
+		}
+		return time.Time{}, err
+	}
+	return aws.TimeValue(out.LastModified), nil
+}
+
+// List is the keys of the objects under the prefix.
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	svc, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	err = svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(prefix),
+	}, func(out *s3.ListObjectsV2Output, last bool) bool {
+		for _, obj := range out.Contents {
+			keys = append(keys, aws.StringValue(obj.Key))
+		}
+		return true
+	})
+	return keys, err
+}
+
+// URL is s3 url of the key.
+func (s *S3Storage) URL(key string) string {
+	return fmt.Sprintf("s3://%s/%s", s.Bucket, key)
+}
+
+// Presign is presigned get url of the object, the content type is of the extension of the key.
+func (s *S3Storage) Presign(key string, expires time.Duration) (string, error) {
+	svc, err := s.client()
+	if err != nil {
+		return "", err
+	}
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}
+	if contentType := mime.TypeByExtension(path.Ext(key)); contentType != "" {
+		input.ResponseContentType = aws.String(contentType)
+	}
+	req, _ := svc.GetObjectRequest(input)
+	return req.Presign(expires)
+}
+
+// LocalStorage is a local directory, key is the path under it. The context is not used.
+type LocalStorage struct {
+	Dir string
+}
+
+// localPutMu is the lock of PutIf, the conditional writes are of this process.
+var localPutMu sync.Mutex
+
+// path is file path of the key.
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+// Get is read the file.
+func (s *LocalStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	b, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNoSuchKey, s.URL(key))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > MaxObjectSize() {
+		return nil, fmt.Errorf("%w: %s is %d bytes, max %d", ErrTooLarge, s.URL(key), len(b), MaxObjectSize())
+	}
+	return gunzipLimited(b, s.URL(key))
+}
+
+// Put is write the file, parent directories are made.
+func (s *LocalStorage) Put(ctx context.Context, key string, b []byte) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0o644)
+}
+
+// GetVersion is read the file, the version is the checksum of the file.
+func (s *LocalStorage) GetVersion(ctx context.Context, key string) ([]byte, string, error) {
+	localPutMu.Lock()
+	raw, err := os.ReadFile(s.path(key))
+	localPutMu.Unlock()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", fmt.Errorf("%w: %s", ErrNoSuchKey, s.URL(key))
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(raw)) > MaxObjectSize() {
+		return nil, "", fmt.Errorf("%w: %s is %d bytes, max %d", ErrTooLarge, s.URL(key), len(raw), MaxObjectSize())
+	}
+	b, err := gunzipLimited(raw, s.URL(key))
+	return b, Checksum(raw), err
+}
+
+// PutIf is write the file when its checksum is still the version.
+func (s *LocalStorage) PutIf(ctx context.Context, key string, b []byte, version string) error {
+	localPutMu.Lock()
+	defer localPutMu.Unlock()
+	current := ""
+	raw, err := os.ReadFile(s.path(key))
+	if err == nil {
+		current = Checksum(raw)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if current != version {
+		return fmt.Errorf("%w: %s", ErrConflict, s.URL(key))
+	}
+	return s.Put(ctx, key, b)
+}
+
+// Exists is check the file exists.
+func (s *LocalStorage) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Modified is modification time of the file.
+func (s *LocalStorage) Modified(ctx context.Context, key string) (time.Time, error) {
+	info, err := os.Stat(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrNoSuchKey, s.URL(key))
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// List is the keys of the files under the directory of the prefix whose keys start with the prefix.
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	root := s.path(path.Dir(prefix))
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Dir, p)
+		if err != nil {
+			return err
+		}
+		if key := filepath.ToSlash(rel); strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	sort.Strings(keys)
+	return keys, err
+}
+
+// URL is file path of the key.
+func (s *LocalStorage) URL(key string) string {
+	return s.path(key)
+}
+
+// Presign is file url of the key, it doesn't expire.
+func (s *LocalStorage) Presign(key string, expires time.Duration) (string, error) {
+	p, err := filepath.Abs(s.path(key))
+	if err != nil {
+		return "", err
+	}
+	return "file://" + filepath.ToSlash(p), nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/tora0091/stock-profit/awsclient"
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// HistoryVacation is date of the vacation flag row in the history table, its symble is HistoryTotal.
+const HistoryVacation = "_VACATION"
+
+// VacationItem is the vacation flag in the history table, Until is the last day of it (YYYY-MM-DD), empty is until it is removed.
+type VacationItem struct {
+	Date   string `dynamodbav:"date" json:"-"`
+	Symble string `dynamodbav:"symble" json:"-"`
+	Until  string `dynamodbav:"until" json:"until,omitempty"`
+	Reason string `dynamodbav:"reason" json:"reason,omitempty"`
+}
+
+// suppressKey is context key of the reason of the notification suppression.
+type suppressKey struct{}
+
+// WithSuppress is the context whose notifications are suppressed by the reason, empty is not suppressed.
+// It is checked once by the run, so the notifications of the run don't read the flag again.
+func WithSuppress(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, suppressKey{}, reason)
+}
+
+// SuppressReason is why the notifications are suppressed at t, empty is not suppressed.
+// The context of WithSuppress is used as is, otherwise QUIET_HOURS, VACATION and the vacation flag are checked.
+func SuppressReason(ctx context.Context, t time.Time) string {
+	if reason, ok := ctx.Value(suppressKey{}).(string); ok {
+		return reason
+	}
+	if from, to, ok, _ := ParseQuietHours(os.Getenv("QUIET_HOURS")); ok && InQuietHours(from, to, t) {
+		return "quiet hours " + os.Getenv("QUIET_HOURS")
+	}
+	date := t.Format("2006-01-02")
+	ranges, _ := ParseVacation(os.Getenv("VACATION"))
+	for _, r := range ranges {
+		if date >= r[0] && date <= r[1] {
+			return fmt.Sprintf("vacation %s to %s", r[0], r[1])
+		}
+	}
+	if table := ConfigOf(ctx).HistoryTable; table != "" {
+		item, ok, err := LoadVacation(ctx, table)
+		if err != nil {
+			logging.Warn(ctx, "vacation flag error", logging.Fields{"table": table, "error": err})
+		} else if ok && (item.Until == "" || date <= item.Until) {
+			return strings.TrimSpace("vacation " + item.Reason)
+		}
+	}
+	return ""
+}
+
+// ParseQuietHours is the minutes of the day of QUIET_HOURS (e.g. 22:00-07:00), the end is exclusive.
+// ok is false when it is not set.
+func ParseQuietHours(env string) (from, to int, ok bool, err error) {
+	if env == "" {
+		return 0, 0, false, nil
+	}
+	parts := strings.Split(env, "-")
+	if len(parts) != 2 {
+		return 0, 0, false, fmt.Errorf("%q is not HH:MM-HH:MM", env)
+	}
+	var minutes [2]int
+	for i, p := range parts {
+		t, err := time.Parse("15:04", strings.TrimSpace(p))
+		if err != nil {
+			return 0, 0, false, fmt.Errorf("%q is not HH:MM-HH:MM", env)
+		}
+		minutes[i] = t.Hour()*60 + t.Minute()
+	}
+	return minutes[0], minutes[1], true, nil
+}
+
+// InQuietHours is check the time of the day of t is in from and to, the hours over midnight when from is after to.
+func InQuietHours(from, to int, t time.Time) bool {
+	m := t.Hour()*60 + t.Minute()
+	if from <= to {
+		return m >= from && m < to
+	}
+	return m >= from || m < to
+}
+
+// ParseVacation is the date ranges of VACATION (e.g. 2024-08-01:2024-08-15,2024-12-28:2025-01-03), both days are included.
+// A single date is the day.
+func ParseVacation(env string) ([][2]string, error) {
+	var ranges [][2]string
+	for _, s := range strings.Split(env, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		from, to := s, s
+		if i := strings.Index(s, ":"); i >= 0 {
+			from, to = strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		}
+		for _, d := range []string{from, to} {
+			if _, err := time.Parse("2006-01-02", d); err != nil {
+				return nil, fmt.Errorf("%q is not YYYY-MM-DD:YYYY-MM-DD", s)
+			}
+		}
+		if to < from {
+			return nil, fmt.Errorf("%q ends before it starts", s)
+		}
+		ranges = append(ranges, [2]string{from, to})
+	}
+	return ranges, nil
+}
+
+// vacationKey is key of the vacation flag row.
+func vacationKey() map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{
+		"date":   {S: aws.String(HistoryVacation)},
+		"symble": {S: aws.String(HistoryTotal)},
+	}
+}
+
+// LoadVacation is the vacation flag of the history table, ok is false when it is not set.
+func LoadVacation(ctx context.Context, table string) (VacationItem, bool, error) {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return VacationItem{}, false, err
+	}
+	out, err := dynamodb.New(sess).GetItemWithContext(ctx, &dynamodb.GetItemInput{TableName: aws.String(table), Key: vacationKey()})
+	if err != nil {
+		return VacationItem{}, false, err
+	}
+	if len(out.Item) == 0 {
+		return VacationItem{}, false, nil
+	}
+	var item VacationItem
+	if err := dynamodbattribute.UnmarshalMap(out.Item, &item); err != nil {
+		return VacationItem{}, false, err
+	}
+	return item, true, nil
+}
+
+// SaveVacation is put the vacation flag to the history table, nil is remove it.
+func SaveVacation(ctx context.Context, table string, item *VacationItem) error {
+	sess, err := awsclient.Session(Region(""))
+	if err != nil {
+		return err
+	}
+	svc := dynamodb.New(sess)
+	if item == nil {
+		_, err := svc.DeleteItemWithContext(ctx, &dynamodb.DeleteItemInput{TableName: aws.String(table), Key: vacationKey()})
+		return err
+	}
+	item.Date, item.Symble = HistoryVacation, HistoryTotal
+	av, err := dynamodbattribute.MarshalMap(item)
+	if err != nil {
+		return err
+	}
+	_, err = svc.PutItemWithContext(ctx, &dynamodb.PutItemInput{TableName: aws.String(table), Item: av})
+	return err
+}
+
+// IsVacationRequest is /vacation.
+func IsVacationRequest(request events.APIGatewayProxyRequest) bool {
+	return path.Base(strings.TrimSuffix(request.Path, "/")) == "vacation"
+}
+
+// VacationHandler is the vacation flag of the HISTORY_TABLE.
+// GET /vacation is the flag, PUT /vacation is set it by the json body ({"until":"2024-08-15","reason":"..."}) and DELETE /vacation is remove it.
+func VacationHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	table := ConfigOf(ctx).HistoryTable
+	if table == "" {
+		return ErrorResponse(http.StatusNotFound, "HISTORY_TABLE is not set."), nil
+	}
+
+	var item VacationItem
+	switch request.HTTPMethod {
+	case http.MethodGet:
+		v, ok, err := LoadVacation(ctx, table)
+		if err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if !ok {
+			return ErrorResponse(http.StatusNotFound, "vacation is not set."), nil
+		}
+		item = v
+	case http.MethodPut:
+		if body := strings.TrimSpace(request.Body); body != "" {
+			if err := json.Unmarshal([]byte(body), &item); err != nil {
+				return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+			}
+		}
+		if item.Until != "" {
+			if _, err := time.Parse("2006-01-02", item.Until); err != nil {
+				return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("until %q is not YYYY-MM-DD", item.Until)), nil
+			}
+		}
+		if err := SaveVacation(ctx, table, &item); err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		logging.Info(ctx, "vacation set", logging.Fields{"until": item.Until, "reason": item.Reason})
+	case http.MethodDelete:
+		if err := SaveVacation(ctx, table, nil); err != nil {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		logging.Info(ctx, "vacation removed", logging.Fields{"table": table})
+		return events.APIGatewayProxyResponse{StatusCode: http.StatusNoContent}, nil
+	default:
+		return ErrorResponse(http.StatusMethodNotAllowed, "method not allowed."), nil
+	}
+
+	b, err := json.Marshal(item)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/report"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// FiscalYearStart is TAX_YEAR_START (MM-DD), default january 1st.
+func FiscalYearStart() (time.Month, int) {
+	if v := os.Getenv("TAX_YEAR_START"); v != "" {
+		if month, day, err := portfolio.ParseFiscalYearStart(v); err == nil {
+			return month, day
+		}
+	}
+	return time.January, 1
+}
+
+// UploadTaxReport is put the tax report csv of the last fiscal year to TAX_REPORT_PATH (the first day of the fiscal year is the layout),
+// on the first run of the new fiscal year. It is mailed too when TAX_REPORT_MAIL is true.
+// The realized gains are of the transaction log of the stock data (COST_BASIS), the dividends are of DIVIDEND_LOG.
+func UploadTaxReport(ctx context.Context, t time.Time) error {
+	cfg := ConfigOf(ctx)
+	month, day := FiscalYearStart()
+	end := portfolio.FiscalYear(t, month, day)
+	start := end.AddDate(-1, 0, 0)
+
+	key := ReportFilePath(cfg.TaxPath, start)
+	store := storage.New(cfg.Bucket)
+	if ok, err := store.Exists(ctx, key); err != nil || ok {
+		return err
+	}
+
+	data, err := DownloadFile(ctx, cfg.Bucket, cfg.StockData)
+	if err != nil {
+		return err
+	}
+	var sales []portfolio.Sale
+	if portfolio.IsTransactionLog(data) {
+		transactions, _ := portfolio.ParseTransactions(data)
+		_, sales = portfolio.Ledger(portfolio.AdjustTransactions(transactions, LoadSplits(ctx)), portfolio.CostBasis())
+	}
+
+	r := portfolio.NewTaxReport(start.Year(), start.Format("2006-01-02"), end.AddDate(0, 0, -1).Format("2006-01-02"), portfolio.CostBasis(), sales, LoadDividends(ctx))
+	b, err := report.TaxCSV(r)
+	if err != nil {
+		return err
+	}
+	if err := store.Put(ctx, key, b); err != nil {
+		return fmt.Errorf("%s: %s", key, err)
+	}
+	logging.Info(ctx, "tax report uploaded", logging.Fields{"key": key, "fiscal_year": r.FiscalYear, "symbols": len(r.Symbols)})
+
+	if os.Getenv("TAX_REPORT_MAIL") != "true" {
+		return nil
+	}
+	return SenderMail(ctx, report.TaxSubject(r), Report{
+		Date: t.Format("2006-01-02"),
+		Text: report.TaxContent(r),
+		Attachments: []Attachment{{
+			Filename:    fmt.Sprintf("stock-profit-tax-%d.csv", r.FiscalYear),
+			ContentType: "text/csv; charset=UTF-8",
+			Data:        append([]byte("\xef\xbb\xbf"), b...),
+		}},
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+	"github.com/tora0091/stock-profit/report"
+)
+
+// telegramAPI is the telegram bot api.
+const telegramAPI = "https://api.telegram.org/bot"
+
+// TelegramNotifier is send the report to the telegram chat by the bot.
+type TelegramNotifier struct {
+	Token  string
+	ChatID string
+}
+
+// Name is channel name.
+func (n *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+// Notify is send the totals and the top movers, the other reports (e.g. alert) are the subject and the text.
+func (n *TelegramNotifier) Notify(ctx context.Context, r Report) error {
+	if n.Token == "" || n.ChatID == "" {
+		return fmt.Errorf("TELEGRAM_BOT_TOKEN or TELEGRAM_CHAT_ID is not set")
+	}
+	if result, ok := r.Payload.(portfolio.Result); ok && r.Subject == "" {
+		return SendTelegram(ctx, n.Token, n.ChatID, report.TelegramContent(r.Date, result, false))
+	}
+	subject := r.Subject
+	if subject == "" {
+		subject = "Stock Profit " + r.Date
+	}
+	return SendTelegram(ctx, n.Token, n.ChatID, report.TelegramTruncate("<b>"+html.EscapeString(subject)+"</b>\n"+html.EscapeString(r.Text)))
+}
+
+// SendTelegram is send the html message to the chat.
+func SendTelegram(ctx context.Context, token, chatID, text string) error {
+	return PostJSON(ctx, telegramAPI+token+"/sendMessage", map[string]string{
+		"chat_id":    chatID,
+		"text":       text,
+		"parse_mode": "HTML",
+	})
+}
+
+// TelegramUpdate is the update of the bot webhook, only the message is used.
+type TelegramUpdate struct {
+	Message *struct {
+		Text string `json:"text"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+}
+
+// IsTelegramRequest is check the request is the bot webhook (POST .../telegram).
+func IsTelegramRequest(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodPost && path.Base(request.Path) == "telegram"
+}
+
+// TelegramHandler is the bot webhook, /portfolio is value S3_STOCK_DATA and reply it to the chat.
+// The webhook is set with the secret_token TELEGRAM_WEBHOOK_SECRET, and only TELEGRAM_CHAT_ID is answered.
+// The response is 200 except the wrong secret, telegram would resend the update.
+func TelegramHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	secret := os.Getenv("TELEGRAM_WEBHOOK_SECRET")
+	token := HeaderValue(request.Headers, "X-Telegram-Bot-Api-Secret-Token")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(token)) != 1 {
+		return ErrorResponse(http.StatusForbidden, "forbidden."), nil
+	}
+	ok := events.APIGatewayProxyResponse{StatusCode: http.StatusOK}
+
+	var update TelegramUpdate
+	if err := json.Unmarshal([]byte(request.Body), &update); err != nil || update.Message == nil {
+		return ok, nil
+	}
+	chatID := strconv.FormatInt(update.Message.Chat.ID, 10)
+	if chatID != os.Getenv("TELEGRAM_CHAT_ID") {
+		logging.Warn(ctx, "telegram chat is not allowed", logging.Fields{"chat_id": chatID})
+		return ok, nil
+	}
+
+	botToken := os.Getenv("TELEGRAM_BOT_TOKEN")
+	// the command in a group is /portfolio@botname
+	command := strings.SplitN(strings.TrimSpace(update.Message.Text), " ", 2)[0]
+	var text string
+	switch strings.SplitN(command, "@", 2)[0] {
+	case "/portfolio":
+		result, err := TelegramPortfolio(ctx)
+		if err != nil {
+			logging.Error(ctx, "telegram command error", logging.Fields{"command": "/portfolio", "error": err})
+			text = "error: " + html.EscapeString(err.Error())
+			break
+		}
+		text = report.TelegramContent(result.CreatedAt, result, true)
+	default:
+		text = "/portfolio is the valuation of the portfolio"
+	}
+
+	if err := SendTelegram(ctx, botToken, chatID, text); err != nil {
+		logging.Error(ctx, "telegram reply error", logging.Fields{"error": err})
+	}
+	return ok, nil
+}
+
+// TelegramPortfolio is the result of S3_STOCK_DATA now, nothing is uploaded or notified.
+func TelegramPortfolio(ctx context.Context) (portfolio.Result, error) {
+	quotes.ResetRetryBudget()
+
+	cfg := ConfigOf(ctx)
+	data, err := DownloadFile(ctx, cfg.Bucket, cfg.StockData)
+	if err != nil {
+		return portfolio.Result{}, err
+	}
+	symbols, parseErrors, err := ParseStockData(ctx, cfg.Bucket, cfg.StockData, data)
+	if err != nil {
+		return portfolio.Result{}, err
+	}
+	if symbols, err = PrepareSymbols(ctx, symbols); err != nil {
+		return portfolio.Result{}, err
+	}
+	provider, err := PriceProvider()
+	if err != nil {
+		return portfolio.Result{}, err
+	}
+	return FetchResult(ctx, provider, symbols, parseErrors, time.Now().In(reportLocation)), nil
+}
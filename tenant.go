@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/logging"
+)
+
+// Tenant is a portfolio of TENANTS_FILE and its recipients.
+type Tenant struct {
+	Name      string   `json:"name"`
+	StockData string   `json:"stock_data"`
+	FilePath  string   `json:"file_path,omitempty"`
+	MailTo    []string `json:"mail_to"`
+	MailCc    []string `json:"mail_cc,omitempty"`
+	MailBcc   []string `json:"mail_bcc,omitempty"`
+}
+
+// TenantResult is status of the report of a tenant.
+type TenantResult struct {
+	Name       string `json:"name"`
+	StatusCode int    `json:"status_code"`
+	Error      string `json:"error,omitempty"`
+}
+
+// LoadTenants is the tenants of TENANTS_FILE (json list) in the BUCKET, nil when it is not set.
+func LoadTenants(ctx context.Context) ([]Tenant, error) {
+	key := os.Getenv("TENANTS_FILE")
+	if key == "" {
+		return nil, nil
+	}
+	data, err := DownloadFile(ctx, ConfigOf(ctx).Bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("%s: %s", key, err)
+	}
+	for i, t := range tenants {
+		if t.Name == "" || t.StockData == "" || len(t.MailTo) == 0 {
+			return nil, fmt.Errorf("%s: tenant %d needs name, stock_data and mail_to", key, i+1)
+		}
+	}
+	return tenants, nil
+}
+
+// TenantConfig is the config of the tenant, the reports are under the name of the tenant (unless file_path is set).
+// The history table is not used, its keys are not per tenant.
+func TenantConfig(c Config, t Tenant) Config {
+	c.Tenant = t.Name
+	c.StockData = t.StockData
+	if t.FilePath != "" {
+		c.FilePath = t.FilePath
+	} else {
+		c.FilePath = path.Join(t.Name, c.FilePath)
+	}
+	if c.RollupPath != "" {
+		c.RollupPath = path.Join(t.Name, c.RollupPath)
+	}
+	if c.StatementPath != "" {
+		c.StatementPath = path.Join(t.Name, c.StatementPath)
+	}
+	if c.TaxPath != "" {
+		c.TaxPath = path.Join(t.Name, c.TaxPath)
+	}
+	if c.ParquetPrefix != "" {
+		c.ParquetPrefix = path.Join(c.ParquetPrefix, "tenant="+t.Name)
+	}
+	c.HistoryTable = ""
+	c.MailTo, c.MailCc, c.MailBcc = t.MailTo, t.MailCc, t.MailBcc
+	return c
+}
+
+// TenantOf is the tenant of the stock data key.
+func TenantOf(tenants []Tenant, key string) (Tenant, bool) {
+	for _, t := range tenants {
+		if t.StockData == key {
+			return t, true
+		}
+	}
+	return Tenant{}, false
+}
+
+// TenantByName is the tenant of the name.
+func TenantByName(tenants []Tenant, name string) (Tenant, bool) {
+	for _, t := range tenants {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return Tenant{}, false
+}
+
+// RunTenants is make the report of every tenant in turn, a failed tenant doesn't stop the others.
+func RunTenants(ctx context.Context, tenants []Tenant) (events.APIGatewayProxyResponse, error) {
+	results := []TenantResult{}
+	var failed error
+	for _, t := range tenants {
+		logging.Info(ctx, "tenant run", logging.Fields{"tenant": t.Name, "key": t.StockData})
+		response, err := RunStockData(WithConfig(ctx, TenantConfig(ConfigOf(ctx), t)))
+		r := TenantResult{Name: t.Name, StatusCode: response.StatusCode}
+		if err != nil {
+			logging.Error(ctx, "tenant error", logging.Fields{"tenant": t.Name, "error": err})
+			r.Error = err.Error()
+			failed = fmt.Errorf("tenant %s: %w", t.Name, err)
+		}
+		results = append(results, r)
+	}
+
+	b, err := json.Marshal(results)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, failed
+}
+
+// RunPortfolios is make the reports of the tenants of TENANTS_FILE, or S3_STOCK_DATA without it.
+// The context of a tenant is only its report.
+func RunPortfolios(ctx context.Context) (events.APIGatewayProxyResponse, error) {
+	if ConfigOf(ctx).Tenant != "" {
+		return RunStockData(ctx)
+	}
+	tenants, err := LoadTenants(ctx)
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	if tenants == nil {
+		return RunStockData(ctx)
+	}
+	return RunTenants(ctx, tenants)
+}
@@ -0,0 +1,53 @@
+// Package tracing is x-ray subsegments of the aws and http calls (XRAY_TRACING).
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-xray-sdk-go/strategy/ctxmissing"
+	"github.com/aws/aws-xray-sdk-go/xray"
+)
+
+// Enabled is true when XRAY_TRACING is true, active tracing of the lambda function is needed.
+var Enabled = os.Getenv("XRAY_TRACING") == "true"
+
+func init() {
+	if Enabled {
+		// a call without the invocation context (e.g. the scraper) is not traced
+		xray.Configure(xray.Config{ContextMissingStrategy: ctxmissing.NewDefaultIgnoreErrorStrategy()})
+	}
+}
+
+// Session is the aws session whose clients are traced.
+func Session(sess *session.Session) *session.Session {
+	if !Enabled {
+		return sess
+	}
+	return xray.AWSSession(sess)
+}
+
+// Transport is the http transport which traces the requests.
+func Transport(rt http.RoundTripper) http.RoundTripper {
+	if !Enabled {
+		return rt
+	}
+	return xray.RoundTripper(rt)
+}
+
+// Capture is run fn in the subsegment of the name.
+func Capture(ctx context.Context, name string, fn func(context.Context) error) error {
+	if !Enabled {
+		return fn(ctx)
+	}
+	return xray.Capture(ctx, name, fn)
+}
+
+// Annotate is add the annotation to the current subsegment, it is searchable in the x-ray console.
+func Annotate(ctx context.Context, key string, value interface{}) {
+	if Enabled {
+		xray.AddAnnotation(ctx, key, value)
+	}
+}
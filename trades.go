@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/logging"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/storage"
+)
+
+// tradesWriteAttempts is the read-modify-write attempts of the transaction log, the log written by the other request is read again.
+const tradesWriteAttempts = 3
+
+// TradesResponse is api response of the trades, the appended transactions, the execution ids already in the log and the holdings of the log after them.
+type TradesResponse struct {
+	Appended  []portfolio.Transaction `json:"appended"`
+	Skipped   []string                `json:"skipped,omitempty"`
+	Positions []portfolio.Ticker      `json:"positions"`
+}
+
+// IsTradesRequest is POST /trades.
+func IsTradesRequest(request events.APIGatewayProxyRequest) bool {
+	return request.HTTPMethod == http.MethodPost && path.Base(strings.TrimSuffix(request.Path, "/")) == "trades"
+}
+
+// TradesHandler is append the trades of the body to the transaction log of S3_STOCK_DATA.
+// The body is the json trade confirmations of the broker webhook or the csv of the transaction log format (Content-Type: text/csv).
+// The log is made when S3_STOCK_DATA doesn't exist, the other formats of the stock data are 409.
+// The trade of an execution id already in the log is skipped, so a redelivered webhook is not recorded twice,
+// and the log is written only when it is still the version read (409 after tradesWriteAttempts conflicts).
+func TradesHandler(ctx context.Context, request events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	cfg := ConfigOf(ctx)
+	bucket, key := cfg.Bucket, cfg.StockData
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		var err error
+		if body, err = base64.StdEncoding.DecodeString(request.Body); err != nil {
+			return ErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid base64 body. %s", err)), nil
+		}
+	}
+	var trades []portfolio.Transaction
+	var err error
+	if strings.Contains(strings.ToLower(HeaderValue(request.Headers, "Content-Type")), "csv") {
+		trades, err = portfolio.ParseTradesCSV(body)
+	} else {
+		trades, err = portfolio.ParseTradesJSON(body, reportLocation)
+	}
+	if err != nil {
+		return ErrorResponse(http.StatusBadRequest, err.Error()), nil
+	}
+	if len(trades) == 0 {
+		return ErrorResponse(http.StatusBadRequest, "no trade in the body."), nil
+	}
+
+	store := storage.New(bucket)
+	var data []byte
+	var transactions, appended []portfolio.Transaction
+	var skipped []string
+	for attempt := 1; ; attempt++ {
+		var version string
+		data, version, err = store.GetVersion(ctx, key)
+		if err != nil && !errors.Is(err, storage.ErrNoSuchKey) {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if len(data) > 0 && !portfolio.IsTransactionLog(data) {
+			return ErrorResponse(http.StatusConflict, "stock data is not a transaction log, the trades can't be appended to it"), nil
+		}
+		transactions, _ = portfolio.ParseTransactions(data)
+		if appended, skipped = portfolio.UnrecordedTrades(transactions, trades); len(appended) == 0 {
+			break
+		}
+
+		data = portfolio.AppendTransactions(data, appended)
+		err = store.PutIf(ctx, key, data, version)
+		if err == nil {
+			transactions, _ = portfolio.ParseTransactions(data)
+			break
+		}
+		if !errors.Is(err, storage.ErrConflict) {
+			return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+		}
+		if attempt == tradesWriteAttempts {
+			return ErrorResponse(http.StatusConflict, "transaction log was changed by other requests, the trades are not appended"), nil
+		}
+		logging.Warn(ctx, "transaction log conflict", logging.Fields{"key": key, "attempt": attempt})
+	}
+	logging.Info(ctx, "trades appended", logging.Fields{"key": key, "trades": len(appended), "skipped": len(skipped)})
+
+	if appended == nil {
+		appended = []portfolio.Transaction{}
+	}
+	positions := portfolio.Holdings(portfolio.AdjustTransactions(transactions, LoadSplits(ctx)))
+	if positions == nil {
+		positions = []portfolio.Ticker{}
+	}
+	b, err := json.Marshal(TradesResponse{Appended: appended, Skipped: skipped, Positions: positions})
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return events.APIGatewayProxyResponse{
+		StatusCode: http.StatusOK,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(b),
+	}, nil
+}
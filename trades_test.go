@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// tradesOf is the trades response of the body of POST /trades.
+func tradesOf(t *testing.T, body string) (int, TradesResponse) {
+	t.Helper()
+	response, err := TradesHandler(context.Background(), events.APIGatewayProxyRequest{HTTPMethod: http.MethodPost, Path: "/trades", Body: body})
+	if err != nil {
+		t.Fatalf("TradesHandler() error = %v", err)
+	}
+	var trades TradesResponse
+	if response.StatusCode == http.StatusOK {
+		if err := json.Unmarshal([]byte(response.Body), &trades); err != nil {
+			t.Fatalf("body %q: %s", response.Body, err)
+		}
+	}
+	return response.StatusCode, trades
+}
+
+func TestTradesHandlerSkipsRecorded(t *testing.T) {
+	s3 := newFakeS3(t, "test-bucket")
+	t.Setenv("S3_STOCK_DATA", "data/transactions.csv")
+	useConfig(t)
+
+	trade := `{"id": "E1", "date": "2021-06-14", "symbol": "aapl", "qty": 10, "price": 130, "side": "buy"}`
+	code, trades := tradesOf(t, trade)
+	if code != http.StatusOK || len(trades.Appended) != 1 || len(trades.Skipped) != 0 {
+		t.Fatalf("TradesHandler() = %d %+v, want 200 and the trade appended", code, trades)
+	}
+	// the redelivered webhook
+	code, trades = tradesOf(t, `[`+trade+`, {"id": "E2", "date": "2021-06-15", "symbol": "AAPL", "qty": 5, "price": 135, "side": "buy"}]`)
+	if code != http.StatusOK || len(trades.Appended) != 1 || trades.Appended[0].ID != "E2" || len(trades.Skipped) != 1 || trades.Skipped[0] != "E1" {
+		t.Fatalf("TradesHandler() = %d %+v, want E2 appended and E1 skipped", code, trades)
+	}
+	if len(trades.Positions) != 1 || trades.Positions[0].Hold != 15 {
+		t.Errorf("Positions = %+v, want 15 of AAPL", trades.Positions)
+	}
+
+	want := "date,symbol,qty,price,side,lot,id\n2021-06-14,AAPL,10,130,buy,,E1\n2021-06-15,AAPL,5,135,buy,,E2\n"
+	if b, _ := s3.Object("data/transactions.csv"); string(b) != want {
+		t.Errorf("transaction log = %q, want %q", b, want)
+	}
+}
+
+func TestTradesHandlerConflict(t *testing.T) {
+	s3 := newFakeS3(t, "test-bucket")
+	t.Setenv("S3_STOCK_DATA", "data/transactions.csv")
+	s3.put("test-bucket", "data/transactions.csv", strings.NewReader("date,symbol,qty,price,side,lot,id\n2021-06-14,AAPL,10,130,buy,,E1\n"))
+	useConfig(t)
+
+	// the other request appends E2 between the read and the write of the first attempt
+	s3.beforePutIf = func(key string) {
+		s3.beforePutIf = nil
+		s3.put("test-bucket", key, strings.NewReader("date,symbol,qty,price,side,lot,id\n2021-06-14,AAPL,10,130,buy,,E1\n2021-06-15,MSFT,1,250,buy,,E2\n"))
+	}
+	code, trades := tradesOf(t, `{"id": "E3", "date": "2021-06-16", "symbol": "VOO", "qty": 2, "price": 390, "side": "buy"}`)
+	if code != http.StatusOK || len(trades.Appended) != 1 || trades.Appended[0].ID != "E3" {
+		t.Fatalf("TradesHandler() = %d %+v, want 200 and E3 appended", code, trades)
+	}
+	want := "date,symbol,qty,price,side,lot,id\n2021-06-14,AAPL,10,130,buy,,E1\n2021-06-15,MSFT,1,250,buy,,E2\n2021-06-16,VOO,2,390,buy,,E3\n"
+	if b, _ := s3.Object("data/transactions.csv"); string(b) != want {
+		t.Errorf("transaction log = %q, want %q", b, want)
+	}
+
+	// the log is changed before every write
+	s3.beforePutIf = func(key string) {
+		b, _ := s3.Object(key)
+		s3.put("test-bucket", key, strings.NewReader(string(b)+"\n"))
+	}
+	if code, _ := tradesOf(t, `{"id": "E4", "date": "2021-06-16", "symbol": "VOO", "qty": 1, "price": 390, "side": "buy"}`); code != http.StatusConflict {
+		t.Errorf("TradesHandler() = %d, want 409 after the conflicts", code)
+	}
+}
+
+func TestTradesHandlerInjection(t *testing.T) {
+	s3 := newFakeS3(t, "test-bucket")
+	t.Setenv("S3_STOCK_DATA", "data/transactions.csv")
+	useConfig(t)
+
+	for _, body := range []string{
+		`{"id": "E1", "date": "2021-06-14", "symbol": "AAPL", "qty": 1, "price": 130, "side": "buy", "lot": "2021-06-01\n2021-06-14,MSFT,1000,0,buy"}`,
+		`{"id": "E1", "date": "2021-06-14", "symbol": "AAPL", "qty": 1, "price": 130, "side": "buy", "lot": "2021-06-01,X"}`,
+		`{"id": "E1", "date": "2021-06-14", "symbol": "AAPL,1000", "qty": 1, "price": 130, "side": "buy"}`,
+		`{"id": "E1", "date": "2021-06-14", "symbol": "AAPL\r\n2021-06-14,MSFT", "qty": 1, "price": 130, "side": "buy"}`,
+		`{"id": "E1,E2", "date": "2021-06-14", "symbol": "AAPL", "qty": 1, "price": 130, "side": "buy"}`,
+	} {
+		if code, _ := tradesOf(t, body); code != http.StatusBadRequest {
+			t.Errorf("TradesHandler(%s) = %d, want 400", body, code)
+		}
+	}
+	if keys := s3.Keys(); len(keys) != 0 {
+		t.Errorf("keys = %v, want no transaction log", keys)
+	}
+}
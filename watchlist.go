@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+	"github.com/tora0091/stock-profit/quotes"
+)
+
+// HeaderValue is case-insensitive header lookup.
+func HeaderValue(headers map[string]string, name string) string {
+	if v, ok := headers[name]; ok {
+		return v
+	}
+	for k, v := range headers {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return ""
+}
+
+// RequestWatchlist is the watchlist in the request body and its invalid lines.
+// ok is false when the body is empty or its content type is not csv or json.
+func RequestWatchlist(request events.APIGatewayProxyRequest) (symbols []portfolio.Ticker, parseErrors []portfolio.ParseError, ok bool, err error) {
+	if request.Body == "" {
+		return nil, nil, false, nil
+	}
+
+	contentType := strings.ToLower(HeaderValue(request.Headers, "Content-Type"))
+	isCSV := strings.Contains(contentType, "csv")
+	isJSON := strings.Contains(contentType, "json")
+	if !isCSV && !isJSON {
+		return nil, nil, false, nil
+	}
+
+	body := []byte(request.Body)
+	if request.IsBase64Encoded {
+		if body, err = base64.StdEncoding.DecodeString(request.Body); err != nil {
+			return nil, nil, true, fmt.Errorf("invalid base64 body. %s", err)
+		}
+	}
+
+	if isCSV {
+		symbols, parseErrors, err = portfolio.ParseWatchlist("", body)
+		return symbols, parseErrors, true, err
+	}
+	symbols, err = portfolio.ParseWatchlistJSON(body)
+	return symbols, nil, true, err
+}
+
+// Valuation is api response of the ad-hoc valuation.
+type Valuation struct {
+	portfolio.Result
+	Summary  portfolio.Summary       `json:"summary"`
+	Failures []portfolio.SymbolError `json:"failures,omitempty"`
+}
+
+// Valuate is price the watchlist in the request body and return it.
+// Nothing is uploaded to s3 or notified.
+func Valuate(ctx context.Context, symbols []portfolio.Ticker, parseErrors []portfolio.ParseError) (events.APIGatewayProxyResponse, error) {
+	quotes.ResetRetryBudget()
+
+	symbols = portfolio.AggregateLots(symbols)
+	if len(symbols) == 0 {
+		return ErrorResponse(http.StatusBadRequest, portfolio.ErrEmptyWatchlist.Error()), nil
+	}
+
+	provider, err := PriceProvider()
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+
+	t := time.Now().In(reportLocation)
+	result := FetchResult(ctx, provider, symbols, parseErrors, t)
+
+	b, err := json.Marshal(Valuation{Result: result, Summary: portfolio.Summarize(result.Body), Failures: result.Errors})
+	if err != nil {
+		return ErrorResponse(http.StatusInternalServerError, err.Error()), err
+	}
+	return ResultResponse(ctx, result, b)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/tora0091/stock-profit/portfolio"
+)
+
+func TestRequestWatchlist(t *testing.T) {
+	csv := "# watchlist\nAAPL,120,0,10\nMSFT,200,0,5\n"
+	tests := []struct {
+		name    string
+		request events.APIGatewayProxyRequest
+		symbols int
+		ok      bool
+		err     bool
+	}{
+		{"csv", events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "text/csv"}, Body: csv}, 2, true, false},
+		{"base64 csv", events.APIGatewayProxyRequest{Headers: map[string]string{"content-type": "text/csv"}, Body: base64.StdEncoding.EncodeToString([]byte(csv)), IsBase64Encoded: true}, 2, true, false},
+		{"json", events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "application/json"}, Body: `[{"symble":"aapl","bid":120,"hold":10},{"symble":"MSFT","bid":200,"hold":5},{"symble":" "}]`}, 2, true, false},
+		{"invalid json", events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "application/json"}, Body: `[{"symble":`}, 0, true, true},
+		{"invalid base64", events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "text/csv"}, Body: "!!", IsBase64Encoded: true}, 0, true, true},
+		// the body of the other content type is not a watchlist, S3_STOCK_DATA is used
+		{"text", events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "text/plain"}, Body: csv}, 0, false, false},
+		{"empty", events.APIGatewayProxyRequest{Headers: map[string]string{"Content-Type": "text/csv"}}, 0, false, false},
+	}
+	for _, tt := range tests {
+		symbols, _, ok, err := RequestWatchlist(tt.request)
+		if len(symbols) != tt.symbols || ok != tt.ok || (err != nil) != tt.err {
+			t.Errorf("%s: RequestWatchlist() = %d symbols, %v, %v, want %d, %v and error %v",
+				tt.name, len(symbols), ok, err, tt.symbols, tt.ok, tt.err)
+		}
+	}
+}
+
+func TestParseWatchlistJSONNormalize(t *testing.T) {
+	symbols, err := portfolio.ParseWatchlistJSON([]byte(`[{"symble":"7203.tyo","bid":9500,"hold":100}]`))
+	if err != nil || len(symbols) != 1 || symbols[0].Symble != "7203.T" {
+		t.Errorf("portfolio.ParseWatchlistJSON() = %+v, %v, want 7203.T", symbols, err)
+	}
+}
+
+func TestHandlerRequestWatchlist(t *testing.T) {
+	quotePages(t, map[string]string{"AAPL": "130"})
+	fake := newFakeS3(t, "test-bucket")
+	t.Setenv("S3_STOCK_DATA", "data/stock.csv")
+	t.Setenv("S3_FILE_PATH", "stock/report.json")
+	t.Setenv("OUTPUT_FORMATS", "")
+	t.Setenv("NOTIFY_CHANNELS", "none")
+	t.Setenv("STOCK_API_KEY", "")
+
+	// the body is valued and returned, nothing is uploaded
+	useConfig(t)
+	response, err := Handler(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "text/csv"},
+		Body:    "AAPL,120,0,10\n",
+	})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Handler() = %d %s, %v", response.StatusCode, response.Body, err)
+	}
+	var valuation Valuation
+	if err := json.Unmarshal([]byte(response.Body), &valuation); err != nil {
+		t.Fatalf("body %q: %s", response.Body, err)
+	}
+	if len(valuation.Body) != 1 || valuation.Body[0].Value != 130 || valuation.Summary.ProfitLoss != 100 {
+		t.Errorf("Handler() body = %s, want AAPL at 130 and profit loss 100", response.Body)
+	}
+	if keys := fake.Keys(); len(keys) != 0 {
+		t.Errorf("objects %v are written by the valuation", keys)
+	}
+
+	// report=true is make the report of the body instead of S3_STOCK_DATA
+	response, err = Handler(context.Background(), events.APIGatewayProxyRequest{
+		Headers:               map[string]string{"Content-Type": "text/csv"},
+		QueryStringParameters: map[string]string{"report": "true"},
+		Body:                  "AAPL,120,0,10\n",
+	})
+	if err != nil || response.StatusCode != http.StatusOK {
+		t.Fatalf("Handler(report=true) = %d %s, %v", response.StatusCode, response.Body, err)
+	}
+	b, ok := fake.Object("stock/report.json")
+	if !ok {
+		t.Fatal("stock/report.json is not written")
+	}
+	var result portfolio.Result
+	if err := json.Unmarshal(b, &result); err != nil || len(result.Body) != 1 || result.Body[0].Value != 130 {
+		t.Errorf("stock/report.json = %s, want AAPL at 130", b)
+	}
+
+	response, err = Handler(context.Background(), events.APIGatewayProxyRequest{
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `[{"symble":`,
+	})
+	if err == nil || response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Handler(invalid json) = %d, %v, want 400", response.StatusCode, err)
+	}
+}
+
+func TestValuateEmpty(t *testing.T) {
+	response, _ := Valuate(context.Background(), nil, nil)
+	if response.StatusCode != http.StatusBadRequest {
+		t.Errorf("Valuate() = %d, want 400 of the empty watchlist", response.StatusCode)
+	}
+}